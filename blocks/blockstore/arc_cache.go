@@ -100,6 +100,20 @@ func (b *arccache) Get(k *cid.Cid) (blocks.Block, error) {
 	return bl, err
 }
 
+func (b *arccache) GetSize(k *cid.Cid) (int, error) {
+	if has, ok := b.hasCached(k); ok && !has {
+		return -1, ErrNotFound
+	}
+
+	size, err := b.blockstore.GetSize(k)
+	if err == ErrNotFound {
+		b.addCache(k, false)
+	} else if err == nil {
+		b.addCache(k, true)
+	}
+	return size, err
+}
+
 func (b *arccache) Put(bl blocks.Block) error {
 	if has, ok := b.hasCached(bl.Cid()); ok && has {
 		return nil
@@ -40,6 +40,9 @@ type Blockstore interface {
 	DeleteBlock(*cid.Cid) error
 	Has(*cid.Cid) (bool, error)
 	Get(*cid.Cid) (blocks.Block, error)
+	// GetSize returns the size of the data of the block with the given
+	// key, without building the block.
+	GetSize(*cid.Cid) (int, error)
 	Put(blocks.Block) error
 	PutMany([]blocks.Block) error
 	// AllKeysChan returns a channel from which
@@ -142,6 +145,26 @@ func (bs *blockstore) Get(k *cid.Cid) (blocks.Block, error) {
 	return blocks.NewBlockWithCid(bdata, k)
 }
 
+func (bs *blockstore) GetSize(k *cid.Cid) (int, error) {
+	if k == nil {
+		log.Error("nil cid in blockstore")
+		return -1, ErrNotFound
+	}
+
+	maybeData, err := bs.datastore.Get(dshelp.CidToDsKey(k))
+	if err == ds.ErrNotFound {
+		return -1, ErrNotFound
+	}
+	if err != nil {
+		return -1, err
+	}
+	bdata, ok := maybeData.([]byte)
+	if !ok {
+		return -1, ErrValueTypeMismatch
+	}
+	return len(bdata), nil
+}
+
 func (bs *blockstore) Put(block blocks.Block) error {
 	k := dshelp.CidToDsKey(block.Cid())
 
@@ -142,6 +142,14 @@ func (b *bloomcache) Get(k *cid.Cid) (blocks.Block, error) {
 	return b.blockstore.Get(k)
 }
 
+func (b *bloomcache) GetSize(k *cid.Cid) (int, error) {
+	if has, ok := b.hasCached(k); ok && !has {
+		return -1, ErrNotFound
+	}
+
+	return b.blockstore.GetSize(k)
+}
+
 func (b *bloomcache) Put(bl blocks.Block) error {
 	// See comment in PutMany
 	err := b.blockstore.Put(bl)
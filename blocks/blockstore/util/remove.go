@@ -27,13 +27,17 @@ type RemovedBlock struct {
 type RmBlocksOpts struct {
 	Prefix string
 	Quiet  bool
-	Force  bool
+	// Force ignores nonexistent blocks and removes pinned blocks instead
+	// of skipping them. The blocks holding the pin sets themselves are
+	// skipped even then.
+	Force bool
 }
 
 // RmBlocks removes the blocks provided in the cids slice.
 // It returns a channel where objects of type RemovedBlock are placed, when
 // not using the Quiet option. Block removal is asynchronous and will
-// skip any pinned blocks.
+// skip any pinned blocks unless the Force option is set. The pinner's own
+// blocks are always skipped, as removing them would corrupt the pin state.
 func RmBlocks(blocks bs.GCBlockstore, pins pin.Pinner, cids []*cid.Cid, opts RmBlocksOpts) (<-chan interface{}, error) {
 	// make the channel large enough to hold any result to avoid
 	// blocking while holding the GCLock
@@ -44,7 +48,12 @@ func RmBlocks(blocks bs.GCBlockstore, pins pin.Pinner, cids []*cid.Cid, opts RmB
 		unlocker := blocks.GCLock()
 		defer unlocker.Unlock()
 
-		stillOkay := FilterPinned(pins, out, cids)
+		var stillOkay []*cid.Cid
+		if opts.Force {
+			stillOkay = FilterInternalPins(pins, out, cids)
+		} else {
+			stillOkay = FilterPinned(pins, out, cids)
+		}
 
 		for _, c := range stillOkay {
 			err := blocks.DeleteBlock(c)
@@ -85,6 +94,29 @@ func FilterPinned(pins pin.Pinner, out chan<- interface{}, cids []*cid.Cid) []*c
 	return stillOkay
 }
 
+// FilterInternalPins takes a slice of Cids and returns it without the Cids of
+// the blocks the pinner stores its pin sets in, placing a RemovedBlock with
+// an error in out for each of them.
+func FilterInternalPins(pins pin.Pinner, out chan<- interface{}, cids []*cid.Cid) []*cid.Cid {
+	internal := cid.NewSet()
+	for _, c := range pins.InternalPins() {
+		internal.Add(c)
+	}
+
+	stillOkay := make([]*cid.Cid, 0, len(cids))
+	for _, c := range cids {
+		if internal.Has(c) {
+			out <- &RemovedBlock{
+				Hash:  c.String(),
+				Error: "block is part of the pin state and cannot be removed",
+			}
+			continue
+		}
+		stillOkay = append(stillOkay, c)
+	}
+	return stillOkay
+}
+
 // ProcRmOutput takes the channel returned by RmBlocks and writes
 // to stdout/stderr according to the RemovedBlock objects received in
 // that channel.
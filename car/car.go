@@ -0,0 +1,252 @@
+// Package car reads and writes CARv1 archives, a stream of the blocks of
+// one or more dags.
+package car
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+	bserv "github.com/ipfs/go-ipfs/blockservice"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+
+	ipldcbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+// A CARv1 stream is a dag-cbor header followed by the blocks. The header and
+// every block are written as a section: a uvarint length followed by that
+// many bytes. A block section holds the binary cid followed by the block
+// data.
+
+// carVersion is the only CAR version supported.
+const carVersion = 1
+
+// maxCarSectionSize bounds the size of a single section read from a CAR, so
+// that a corrupt length prefix cannot make us allocate arbitrary amounts of
+// memory.
+const maxCarSectionSize = 4 << 20
+
+func writeCarSection(w io.Writer, parts ...[]byte) error {
+	size := 0
+	for _, p := range parts {
+		size += len(p)
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(size))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+
+	for _, p := range parts {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCarSection reads the next section. It returns io.EOF if the stream
+// ends before the section starts.
+func readCarSection(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxCarSectionSize {
+		return nil, fmt.Errorf("car section of %d bytes exceeds the maximum of %d bytes", size, maxCarSectionSize)
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+func writeCarHeader(w io.Writer, roots []*cid.Cid) error {
+	nd, err := ipldcbor.WrapObject(map[string]interface{}{
+		"roots":   roots,
+		"version": carVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeCarSection(w, nd.RawData())
+}
+
+// readCarHeader reads the header of a CAR and returns its roots.
+func readCarHeader(r *bufio.Reader) ([]*cid.Cid, error) {
+	data, err := readCarSection(r)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("invalid car header: %s", err)
+	}
+
+	nd, err := ipldcbor.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid car header: %s", err)
+	}
+
+	v, _, err := nd.Resolve([]string{"version"})
+	if err != nil {
+		return nil, fmt.Errorf("invalid car header: %s", err)
+	}
+
+	var version uint64
+	switch v := v.(type) {
+	case int:
+		version = uint64(v)
+	case int64:
+		version = uint64(v)
+	case uint64:
+		version = v
+	default:
+		return nil, fmt.Errorf("invalid car header: version is a %T", v)
+	}
+	if version != carVersion {
+		return nil, fmt.Errorf("unsupported car version %d", version)
+	}
+
+	var roots []*cid.Cid
+	for _, l := range nd.Links() {
+		roots = append(roots, l.Cid)
+	}
+	return roots, nil
+}
+
+// WriteCar writes a CAR with root as its only root, holding every block
+// reachable from root exactly once. Blocks are written in depth-first order.
+func WriteCar(ctx context.Context, w io.Writer, ds dag.DAGService, root node.Node) error {
+	if err := writeCarHeader(w, []*cid.Cid{root.Cid()}); err != nil {
+		return err
+	}
+
+	seen := cid.NewSet()
+	var walk func(nd node.Node) error
+	walk = func(nd node.Node) error {
+		if !seen.Visit(nd.Cid()) {
+			return nil
+		}
+
+		if err := writeCarSection(w, nd.Cid().Bytes(), nd.RawData()); err != nil {
+			return err
+		}
+
+		for _, l := range nd.Links() {
+			if seen.Has(l.Cid) {
+				continue
+			}
+
+			child, err := ds.Get(ctx, l.Cid)
+			if err != nil {
+				return err
+			}
+
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return walk(root)
+}
+
+// LoadCar reads a CAR from r and adds all of its blocks to bs. It returns the
+// roots listed in the CAR's header and the number of blocks read. Every block
+// is rehashed, and the load fails at the first block that does not match its
+// cid. Blocks read before that are kept.
+func LoadCar(bs bserv.BlockService, r io.Reader) ([]*cid.Cid, int, error) {
+	br := bufio.NewReader(r)
+	roots, err := readCarHeader(br)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count := 0
+	for {
+		section, err := readCarSection(br)
+		if err == io.EOF {
+			return roots, count, nil
+		}
+		if err != nil {
+			return nil, count, fmt.Errorf("reading block %d: %s", count+1, err)
+		}
+
+		c, data, err := splitCarBlock(section)
+		if err != nil {
+			return nil, count, err
+		}
+
+		sum, err := c.Prefix().Sum(data)
+		if err != nil {
+			return nil, count, err
+		}
+		if !sum.Equals(c) {
+			return nil, count, fmt.Errorf("block %s does not match its data, which hashes to %s", c, sum)
+		}
+
+		b, err := blocks.NewBlockWithCid(data, c)
+		if err != nil {
+			return nil, count, err
+		}
+		if _, err := bs.AddBlock(b); err != nil {
+			return nil, count, err
+		}
+		count++
+	}
+}
+
+// splitCarBlock splits a block section into its cid and data.
+func splitCarBlock(section []byte) (*cid.Cid, []byte, error) {
+	n, err := cidLength(section)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, err := cid.Cast(section[:n])
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, section[n:], nil
+}
+
+// cidLength returns the length of the binary cid at the start of data.
+func cidLength(data []byte) (int, error) {
+	// a CIDv0 is a bare sha2-256 multihash
+	if len(data) >= 34 && data[0] == 0x12 && data[1] == 0x20 {
+		return 34, nil
+	}
+
+	// a CIDv1 is <version><codec><multihash code><digest length><digest>
+	n := 0
+	var fields [4]uint64
+	for i := range fields {
+		v, l := binary.Uvarint(data[n:])
+		if l <= 0 {
+			return 0, fmt.Errorf("invalid cid in car block")
+		}
+		fields[i] = v
+		n += l
+	}
+	if fields[0] != 1 {
+		return 0, fmt.Errorf("invalid cid version %d in car block", fields[0])
+	}
+
+	if fields[3] > uint64(len(data)-n) {
+		return 0, fmt.Errorf("invalid cid in car block")
+	}
+	return n + int(fields[3]), nil
+}
@@ -100,5 +100,6 @@ var cmdDetailsMap = map[*cmds.Command]cmdDetails{
 	commands.LogCmd:                       {cannotRunOnClient: true},
 	commands.ActiveReqsCmd:                {cannotRunOnClient: true},
 	commands.RepoFsckCmd:                  {cannotRunOnDaemon: true},
+	commands.RepoMigrateCmd:               {cannotRunOnDaemon: true},
 	commands.ConfigCmd.Subcommand("edit"): {cannotRunOnDaemon: true, doesNotUseRepo: true},
 }
@@ -46,6 +46,12 @@ func Parse(input []string, stdin *os.File, root *cmds.Command) (cmds.Request, *c
 			stdin = nil
 		}
 	}
+	// 'swarm disconnect --all' takes no addresses, so don't wait for them on
+	// stdin either.
+	if len(path) == 2 && path[0] == "swarm" && path[1] == "disconnect" &&
+		(opts["all"] == true || opts["a"] == true) {
+		stdin = nil
+	}
 
 	stringArgs, fileArgs, err := ParseArgs(req, stringVals, stdin, cmd.Arguments, root)
 	if err != nil {
@@ -86,7 +92,52 @@ func ParseArgs(req cmds.Request, inputs []string, stdin *os.File, argDefs []cmds
 			return nil, nil, u.ErrCast()
 		}
 	}
-	return parseArgs(inputs, stdin, argDefs, recursive, hidden, root)
+
+	// '--ignore' and '--ignore-rules-path' skip matching paths
+	var ignoreRules []string
+	if ignoreOpt := req.Option("ignore"); ignoreOpt != nil {
+		rules, _, err := ignoreOpt.String()
+		if err != nil {
+			return nil, nil, u.ErrCast()
+		}
+		if rules != "" {
+			ignoreRules = splitIgnoreRules(rules)
+		}
+	}
+	var ignoreFile string
+	if ignoreFileOpt := req.Option("ignore-rules-path"); ignoreFileOpt != nil {
+		ignoreFile, _, err = ignoreFileOpt.String()
+		if err != nil {
+			return nil, nil, u.ErrCast()
+		}
+	}
+
+	filter, err := files.NewFilter(ignoreFile, ignoreRules, hidden)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseArgs(inputs, stdin, argDefs, recursive, filter, root)
+}
+
+// splitIgnoreRules splits the value of '--ignore' into patterns at each
+// comma. A comma preceded by a backslash is part of the pattern instead, and
+// loses the backslash; all other backslashes are kept for the patterns.
+func splitIgnoreRules(s string) []string {
+	var rules []string
+	var cur []byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			cur = append(cur, ',')
+			i++
+		case s[i] == ',':
+			rules = append(rules, string(cur))
+			cur = cur[:0]
+		default:
+			cur = append(cur, s[i])
+		}
+	}
+	return append(rules, string(cur))
 }
 
 // Parse a command line made up of sub-commands, short arguments, long arguments and positional arguments
@@ -256,7 +307,7 @@ func parseOpts(args []string, root *cmds.Command) (
 
 const msgStdinInfo = "ipfs: Reading from %s; send Ctrl-d to stop."
 
-func parseArgs(inputs []string, stdin *os.File, argDefs []cmds.Argument, recursive, hidden bool, root *cmds.Command) ([]string, []files.File, error) {
+func parseArgs(inputs []string, stdin *os.File, argDefs []cmds.Argument, recursive bool, filter *files.Filter, root *cmds.Command) ([]string, []files.File, error) {
 	// ignore stdin on Windows
 	if osh.IsWindows() {
 		stdin = nil
@@ -329,7 +380,7 @@ func parseArgs(inputs []string, stdin *os.File, argDefs []cmds.Argument, recursi
 					fpath = stdin.Name()
 					file = files.NewReaderFile("", fpath, r, nil)
 				} else {
-					nf, err := appendFile(fpath, argDef, recursive, hidden)
+					nf, err := appendFile(fpath, argDef, recursive, filter)
 					if err != nil {
 						return nil, nil, err
 					}
@@ -399,7 +450,7 @@ const notRecursiveFmtStr = "'%s' is a directory, use the '-%s' flag to specify d
 const dirNotSupportedFmtStr = "Invalid path '%s', argument '%s' does not support directories"
 const winDriveLetterFmtStr = "%q is a drive letter, not a drive path"
 
-func appendFile(fpath string, argDef *cmds.Argument, recursive, hidden bool) (files.File, error) {
+func appendFile(fpath string, argDef *cmds.Argument, recursive bool, filter *files.Filter) (files.File, error) {
 	// resolve Windows relative dot paths like `X:.\somepath`
 	if osh.IsWindows() {
 		if len(fpath) >= 3 && fpath[1:3] == ":." {
@@ -440,10 +491,10 @@ func appendFile(fpath string, argDef *cmds.Argument, recursive, hidden bool) (fi
 	}
 
 	if osh.IsWindows() {
-		return windowsParseFile(fpath, hidden, stat)
+		return windowsParseFile(fpath, filter, stat)
 	}
 
-	return files.NewSerialFile(path.Base(fpath), fpath, hidden, stat)
+	return files.NewSerialFileWithFilter(path.Base(fpath), fpath, filter, stat)
 }
 
 // Inform the user if a file is waiting on input
@@ -496,7 +547,7 @@ func (r *messageReader) Close() error {
 	return r.r.Close()
 }
 
-func windowsParseFile(fpath string, hidden bool, stat os.FileInfo) (files.File, error) {
+func windowsParseFile(fpath string, filter *files.Filter, stat os.FileInfo) (files.File, error) {
 	// special cases for Windows drive roots i.e. `X:\` and their long form `\\?\X:\`
 	// drive path must be preserved as `X:\` (or it's longform) and not converted to `X:`, `X:.`, `\`, or `/` here
 	switch len(fpath) {
@@ -507,7 +558,7 @@ func windowsParseFile(fpath string, hidden bool, stat os.FileInfo) (files.File,
 		}
 		// `X:\` needs to preserve the `\`, path.Base(filepath.ToSlash(fpath)) results in `X:` which is not valid
 		if fpath[1:3] == ":\\" {
-			return files.NewSerialFile(fpath, fpath, hidden, stat)
+			return files.NewSerialFileWithFilter(fpath, fpath, filter, stat)
 		}
 	case 6:
 		// `\\?\X:` long prefix form of `X:`, still ambiguous
@@ -518,9 +569,9 @@ func windowsParseFile(fpath string, hidden bool, stat os.FileInfo) (files.File,
 		// `\\?\X:\` long prefix form is translated into short form `X:\`
 		if fpath[:4] == "\\\\?\\" && fpath[5] == ':' && fpath[6] == '\\' {
 			fpath = string(fpath[4]) + ":\\"
-			return files.NewSerialFile(fpath, fpath, hidden, stat)
+			return files.NewSerialFileWithFilter(fpath, fpath, filter, stat)
 		}
 	}
 
-	return files.NewSerialFile(path.Base(filepath.ToSlash(fpath)), fpath, hidden, stat)
+	return files.NewSerialFileWithFilter(path.Base(filepath.ToSlash(fpath)), fpath, filter, stat)
 }
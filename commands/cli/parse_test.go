@@ -309,3 +309,19 @@ func TestArgumentParsing(t *testing.T) {
 	fstdin = fileToSimulateStdin(t, "stdin1")
 	test([]string{"optionalsecond", "value1", "value2"}, fstdin, []string{"value1", "value2"})
 }
+
+func TestSplitIgnoreRules(t *testing.T) {
+	test := func(s string, expected words) {
+		actual := splitIgnoreRules(s)
+		if !sameWords(actual, expected) {
+			t.Errorf("splitIgnoreRules(%q) = %q, expected %q", s, actual, expected)
+		}
+	}
+
+	test("a", words{"a"})
+	test("node_modules/,*.log,!important.log", words{"node_modules/", "*.log", "!important.log"})
+	test(`a\,b,c`, words{"a,b", "c"})
+	test(`\,`, words{","})
+	test(`\#notes,\!x`, words{`\#notes`, `\!x`})
+	test("a,", words{"a", ""})
+}
@@ -0,0 +1,133 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// Filter decides which entries of a directory read by a serial file are
+// skipped. Hidden files are skipped unless IncludeHidden is set. Ignore
+// rules use gitignore syntax, and are applied in order, so the last rule
+// matching an entry decides whether it is ignored.
+type Filter struct {
+	IncludeHidden bool
+
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	segments []string
+}
+
+// NewFilter returns a Filter using the rules in ignoreFile, if it is not
+// empty, followed by rules.
+func NewFilter(ignoreFile string, rules []string, includeHidden bool) (*Filter, error) {
+	f := &Filter{IncludeHidden: includeHidden}
+
+	if ignoreFile != "" {
+		fi, err := os.Open(ignoreFile)
+		if err != nil {
+			return nil, err
+		}
+		defer fi.Close()
+
+		scan := bufio.NewScanner(fi)
+		for scan.Scan() {
+			f.addRule(scan.Text())
+		}
+		if err := scan.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range rules {
+		f.addRule(r)
+	}
+	return f, nil
+}
+
+func (f *Filter) addRule(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var r ignoreRule
+	switch {
+	case strings.HasPrefix(line, "!"):
+		r.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// Patterns with a slash other than a trailing one match paths
+	// relative to the root; others match names at any depth.
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	r.segments = strings.Split(line, "/")
+	if !r.anchored {
+		r.segments = append([]string{"**"}, r.segments...)
+	}
+	f.rules = append(f.rules, r)
+}
+
+// ShouldExclude reports whether the entry at relPath, a slash separated path
+// relative to the directory being read, is skipped.
+func (f *Filter) ShouldExclude(relPath string, isDir bool) bool {
+	if !f.IncludeHidden && strings.HasPrefix(path.Base(relPath), ".") {
+		return true
+	}
+
+	segments := strings.Split(relPath, "/")
+	exclude := false
+	for _, r := range f.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(r.segments, segments) {
+			exclude = !r.negate
+		}
+	}
+	return exclude
+}
+
+// matchSegments matches the path segments against the pattern segments,
+// where a "**" segment matches any number of path segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,128 @@
+package files
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFilterShouldExclude(t *testing.T) {
+	f, err := NewFilter("", []string{
+		"# comment",
+		"*.log",
+		"!keep.log",
+		"node_modules/",
+		"/build",
+		"docs/**/*.tmp",
+		`\!bang`,
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		exclude bool
+	}{
+		{"a.log", false, true},
+		{"sub/b.log", false, true},
+		{"sub/keep.log", false, false},
+		{".git", true, true},
+		{"sub/.hidden", false, true},
+		{"node_modules", true, true},
+		{"sub/node_modules", true, true},
+		{"node_modules", false, false},
+		{"build", true, true},
+		{"sub/build", true, false},
+		{"docs/x.tmp", false, true},
+		{"docs/a/b/x.tmp", false, true},
+		{"x.tmp", false, false},
+		{"!bang", false, true},
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		if got := f.ShouldExclude(c.path, c.isDir); got != c.exclude {
+			t.Errorf("%s (dir: %t): expected exclude %t, got %t", c.path, c.isDir, c.exclude, got)
+		}
+	}
+
+	f.IncludeHidden = true
+	if f.ShouldExclude(".git", true) {
+		t.Error(".git should be included with IncludeHidden")
+	}
+}
+
+func TestSerialFileWithFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serialfile-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, p := range []string{"a.txt", "b.log", "sub/c.txt", "sub/d.log", "skip/e.txt", ".ipfsignore"} {
+		p = filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignoreFile := filepath.Join(dir, ".ipfsignore")
+	if err := ioutil.WriteFile(ignoreFile, []byte("*.log\nskip/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := NewFilter(ignoreFile, []string{"!sub/d.log"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, err := NewSerialFileWithFilter("root", dir, filter, stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var walk func(f File)
+	walk = func(f File) {
+		for {
+			nf, err := f.NextFile()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, nf.FileName())
+			if nf.IsDirectory() {
+				walk(nf)
+			}
+		}
+	}
+	walk(sf)
+	sort.Strings(names)
+
+	expected := []string{"root/a.txt", "root/sub", "root/sub/c.txt", "root/sub/d.log"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestNewFilterMissingFile(t *testing.T) {
+	if _, err := NewFilter("/does/not/exist/.ipfsignore", nil, false); err == nil {
+		t.Fatal("expected an error for a missing ignore file")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/url"
+	"os"
 )
 
 const (
@@ -26,6 +27,8 @@ type MultipartFile struct {
 	Part      *multipart.Part
 	Reader    *multipart.Reader
 	Mediatype string
+
+	stat os.FileInfo
 }
 
 func NewFileFromPart(part *multipart.Part) (File, error) {
@@ -51,6 +54,7 @@ func NewFileFromPart(part *multipart.Part) (File, error) {
 			filename: f.FileName(),
 			abspath:  part.Header.Get("abspath"),
 			fullpath: f.FullPath(),
+			stat:     statFromHeader(f.FileName(), false, part.Header),
 		}, nil
 	}
 
@@ -60,6 +64,9 @@ func NewFileFromPart(part *multipart.Part) (File, error) {
 		return nil, err
 	}
 
+	if f.IsDirectory() {
+		f.stat = statFromHeader(f.FileName(), true, part.Header)
+	}
 	return f, nil
 }
 
@@ -100,6 +107,12 @@ func (f *MultipartFile) FullPath() string {
 	return f.FileName()
 }
 
+// Stat returns the mode and modification time the sender recorded for this
+// file, or nil if it did not record any.
+func (f *MultipartFile) Stat() os.FileInfo {
+	return f.stat
+}
+
 func (f *MultipartFile) Read(p []byte) (int, error) {
 	if f.IsDirectory() {
 		return 0, ErrNotReader
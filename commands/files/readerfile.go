@@ -63,7 +63,7 @@ func (f *ReaderFile) Stat() os.FileInfo {
 }
 
 func (f *ReaderFile) Size() (int64, error) {
-	if f.stat == nil {
+	if f.stat == nil || f.stat.Size() < 0 {
 		return 0, errors.New("File size unknown")
 	}
 	return f.stat.Size(), nil
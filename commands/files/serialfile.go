@@ -5,8 +5,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
 	"syscall"
 )
 
@@ -14,16 +14,26 @@ import (
 // No more than one file will be opened at a time (directories will advance
 // to the next file when NextFile() is called).
 type serialFile struct {
-	name              string
-	path              string
-	files             []os.FileInfo
-	stat              os.FileInfo
-	current           *File
-	handleHiddenFiles bool
+	name    string
+	path    string
+	relPath string // path relative to the root of the serial file
+	files   []os.FileInfo
+	stat    os.FileInfo
+	current *File
+	filter  *Filter
 }
 
 func NewSerialFile(name, path string, hidden bool, stat os.FileInfo) (File, error) {
+	return NewSerialFileWithFilter(name, path, &Filter{IncludeHidden: hidden}, stat)
+}
+
+// NewSerialFileWithFilter is like NewSerialFile, but skips the entries of
+// directories excluded by filter.
+func NewSerialFileWithFilter(name, path string, filter *Filter, stat os.FileInfo) (File, error) {
+	return newSerialFile(name, path, "", filter, stat)
+}
 
+func newSerialFile(name, path, relPath string, filter *Filter, stat os.FileInfo) (File, error) {
 	switch mode := stat.Mode(); {
 	case mode.IsRegular():
 		file, err := os.Open(path)
@@ -38,7 +48,7 @@ func NewSerialFile(name, path string, hidden bool, stat os.FileInfo) (File, erro
 		if err != nil {
 			return nil, err
 		}
-		return &serialFile{name, path, contents, stat, nil, hidden}, nil
+		return &serialFile{name, path, relPath, contents, stat, nil, filter}, nil
 	case mode&os.ModeSymlink != 0:
 		target, err := os.Readlink(path)
 		if err != nil {
@@ -70,14 +80,16 @@ func (f *serialFile) NextFile() (File, error) {
 
 	stat := f.files[0]
 	f.files = f.files[1:]
+	relPath := path.Join(f.relPath, stat.Name())
 
-	for !f.handleHiddenFiles && strings.HasPrefix(stat.Name(), ".") {
+	for f.filter.ShouldExclude(relPath, stat.IsDir()) {
 		if len(f.files) == 0 {
 			return nil, io.EOF
 		}
 
 		stat = f.files[0]
 		f.files = f.files[1:]
+		relPath = path.Join(f.relPath, stat.Name())
 	}
 
 	// open the next file
@@ -87,7 +99,7 @@ func (f *serialFile) NextFile() (File, error) {
 	// recursively call the constructor on the next file
 	// if it's a regular file, we will open it as a ReaderFile
 	// if it's a directory, files in it will be opened serially
-	sf, err := NewSerialFile(fileName, filePath, f.handleHiddenFiles, stat)
+	sf, err := newSerialFile(fileName, filePath, relPath, f.filter, stat)
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +129,7 @@ func (f *serialFile) Close() error {
 		if err != nil && err != syscall.EINVAL {
 			return err
 		}
+		f.current = nil
 	}
 
 	return nil
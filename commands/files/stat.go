@@ -0,0 +1,77 @@
+package files
+
+import (
+	"fmt"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	modeHeader  = "Mode"
+	mtimeHeader = "Mtime"
+)
+
+// SetStatHeaders records the permission bits and modification time of stat
+// in the headers of a multipart file, so that the receiving side can return
+// them from Stat.
+func SetStatHeaders(header textproto.MIMEHeader, stat os.FileInfo) {
+	header.Set(modeHeader, strconv.FormatUint(uint64(stat.Mode().Perm()), 8))
+	mtime := stat.ModTime()
+	header.Set(mtimeHeader, fmt.Sprintf("%d.%09d", mtime.Unix(), mtime.Nanosecond()))
+}
+
+// statFromHeader returns the file info recorded by SetStatHeaders, or nil if
+// the headers do not carry any.
+func statFromHeader(name string, isDir bool, header textproto.MIMEHeader) os.FileInfo {
+	modeStr := header.Get(modeHeader)
+	mtimeStr := header.Get(mtimeHeader)
+	if modeStr == "" || mtimeStr == "" {
+		return nil
+	}
+
+	mode, err := strconv.ParseUint(modeStr, 8, 32)
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.SplitN(mtimeStr, ".", 2)
+	secs, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil
+	}
+	var nsecs int64
+	if len(parts) == 2 {
+		nsecs, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil
+		}
+	}
+
+	fi := &headerFileInfo{
+		name:  name,
+		mode:  os.FileMode(mode) & os.ModePerm,
+		mtime: time.Unix(secs, nsecs),
+	}
+	if isDir {
+		fi.mode |= os.ModeDir
+	}
+	return fi
+}
+
+// headerFileInfo is the os.FileInfo of a file received as part of a
+// multipart request. Only the name, mode and modification time are known.
+type headerFileInfo struct {
+	name  string
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (fi *headerFileInfo) Name() string       { return fi.name }
+func (fi *headerFileInfo) Size() int64        { return -1 }
+func (fi *headerFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *headerFileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *headerFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *headerFileInfo) Sys() interface{}   { return nil }
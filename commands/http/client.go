@@ -168,6 +168,10 @@ func getResponse(httpRes *http.Response, req cmds.Request) (cmds.Response, error
 		res.SetLength(length)
 	}
 
+	for _, m := range httpRes.Header[messageHeader] {
+		res.AddMessage(m)
+	}
+
 	rr := &httpResponseReader{httpRes}
 	res.SetCloser(rr)
 
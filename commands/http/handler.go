@@ -45,6 +45,7 @@ const (
 	streamHeader             = "X-Stream-Output"
 	channelHeader            = "X-Chunked-Output"
 	extraContentLengthHeader = "X-Content-Length"
+	messageHeader            = "X-Message"
 	uaHeader                 = "User-Agent"
 	contentTypeHeader        = "Content-Type"
 	applicationJson          = "application/json"
@@ -52,7 +53,7 @@ const (
 	plainText                = "text/plain"
 )
 
-var AllowedExposedHeadersArr = []string{streamHeader, channelHeader, extraContentLengthHeader}
+var AllowedExposedHeadersArr = []string{streamHeader, channelHeader, extraContentLengthHeader, messageHeader}
 var AllowedExposedHeaders = strings.Join(AllowedExposedHeadersArr, ", ")
 
 const (
@@ -245,6 +246,10 @@ func sendResponse(w http.ResponseWriter, r *http.Request, res cmds.Response, req
 		h.Set("X-Content-Length", strconv.FormatUint(res.Length(), 10))
 	}
 
+	for _, m := range res.Messages() {
+		h.Add(messageHeader, sanitizedHeaderStr(m))
+	}
+
 	if _, ok := res.Output().(io.Reader); ok {
 		// set streams output type to text to avoid issues with browsers rendering
 		// html pages on priveleged api ports
@@ -323,7 +328,10 @@ func flushCopy(w io.Writer, r io.Reader) error {
 }
 
 func sanitizedErrStr(err error) string {
-	s := err.Error()
+	return sanitizedHeaderStr(err.Error())
+}
+
+func sanitizedHeaderStr(s string) string {
 	s = strings.Split(s, "\n")[0]
 	s = strings.Split(s, "\r")[0]
 	return s
@@ -105,6 +105,11 @@ type Response interface {
 	SetLength(uint64)
 	Length() uint64
 
+	// Adds/Returns notes for the user that are not part of the output, such as
+	// how the arguments were interpreted. Clients write them to stderr.
+	AddMessage(string)
+	Messages() []string
+
 	// underlying http connections need to be cleaned up, this is for that
 	Close() error
 	SetCloser(io.Closer)
@@ -127,6 +132,7 @@ type response struct {
 	value  interface{}
 	out    io.Reader
 	length uint64
+	msgs   []string
 	stdout io.Writer
 	stderr io.Writer
 	closer io.Closer
@@ -152,6 +158,14 @@ func (r *response) SetLength(l uint64) {
 	r.length = l
 }
 
+func (r *response) AddMessage(m string) {
+	r.msgs = append(r.msgs, m)
+}
+
+func (r *response) Messages() []string {
+	return r.msgs
+}
+
 func (r *response) Error() *Error {
 	return r.err
 }
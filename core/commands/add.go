@@ -1,16 +1,21 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	gopath "path"
 	"strings"
+	"time"
 
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
 	blockservice "github.com/ipfs/go-ipfs/blockservice"
+	car "github.com/ipfs/go-ipfs/car"
 	cmds "github.com/ipfs/go-ipfs/commands"
 	files "github.com/ipfs/go-ipfs/commands/files"
 	core "github.com/ipfs/go-ipfs/core"
+	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
 	"github.com/ipfs/go-ipfs/core/coreunix"
 	offline "github.com/ipfs/go-ipfs/exchange/offline"
 	dag "github.com/ipfs/go-ipfs/merkledag"
@@ -18,32 +23,45 @@ import (
 	mfs "github.com/ipfs/go-ipfs/mfs"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	mh "gx/ipfs/QmVGtdTZdTFaLsaj2RwdVG8jcjNNcp1DE914DKZ2kHmXHw/go-multihash"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
-	"gx/ipfs/QmeWjRodbcZFKe5tMN7poEx3izym6osrLSnTLf9UjJZBbs/pb"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
 // Error indicating the max depth has been exceded.
 var ErrDepthLimitExceeded = fmt.Errorf("depth limit exceeded")
 
 const (
-	quietOptionName       = "quiet"
-	quieterOptionName     = "quieter"
-	silentOptionName      = "silent"
-	progressOptionName    = "progress"
-	trickleOptionName     = "trickle"
-	wrapOptionName        = "wrap-with-directory"
-	hiddenOptionName      = "hidden"
-	onlyHashOptionName    = "only-hash"
-	chunkerOptionName     = "chunker"
-	pinOptionName         = "pin"
-	rawLeavesOptionName   = "raw-leaves"
-	noCopyOptionName      = "nocopy"
-	fstoreCacheOptionName = "fscache"
-	cidVersionOptionName  = "cid-version"
-	hashOptionName        = "hash"
+	quietOptionName         = "quiet"
+	quieterOptionName       = "quieter"
+	silentOptionName        = "silent"
+	progressOptionName      = "progress"
+	trickleOptionName       = "trickle"
+	wrapOptionName          = "wrap-with-directory"
+	hiddenOptionName        = "hidden"
+	ignoreOptionName        = "ignore"
+	ignoreRulesOptionName   = "ignore-rules-path"
+	onlyHashOptionName      = "only-hash"
+	chunkerOptionName       = "chunker"
+	pinOptionName           = "pin"
+	rawLeavesOptionName     = "raw-leaves"
+	noCopyOptionName        = "nocopy"
+	fstoreCacheOptionName   = "fscache"
+	cidVersionOptionName    = "cid-version"
+	hashOptionName          = "hash"
+	toFilesOptionName       = "to-files"
+	inputOptionName         = "input"
+	preserveModeOptionName  = "preserve-mode"
+	preserveMtimeOptionName = "preserve-mtime"
+	deterministicOptionName = "deterministic"
 )
 
+// deterministicShardThreshold is the number of entries above which
+// directories are sharded with '--deterministic'. Changing it changes the
+// hashes of large directories added in that mode, so it must stay fixed.
+const deterministicShardThreshold = 1000
+
 const adderOutChanSize = 8
 
 var AddCmd = &cmds.Command{
@@ -71,6 +89,74 @@ its filename. For example:
 You can now refer to the added file in a gateway, like so:
 
   /ipfs/QmaG4FuMqEBnQNn3C8XJ5bpW8kLs7zq2ZXgHptJHbKDDVx/example.jpg
+
+When adding directories, '--ignore' skips files and directories matching a
+comma separated list of patterns, and '--ignore-rules-path' those matching
+the patterns in a file, one per line. Patterns use gitignore syntax: '*',
+'?' and '[...]' match within a name, '**' matches any number of
+directories, a trailing '/' only matches directories, a pattern containing
+another '/' is matched against the path relative to the added directory,
+and a leading '!' re-includes paths excluded by an earlier pattern. The
+patterns of the rules file come first, then those of '--ignore', and when
+several patterns match a path the last one wins. Ignored directories are
+not read at all, so nothing inside them can be re-included. Hidden files
+are still only added with '--hidden', whatever the patterns say. A pattern
+that contains a comma has to escape it as '\,', and '--ignore' can only be
+given once; put longer lists in a rules file:
+
+  > ipfs add -r --ignore='node_modules/,*.log,!important.log' project
+  > ipfs add -r -H --ignore-rules-path=.ipfsignore --ignore=.git/ project
+
+The '--hash' option selects the multihash function used for every block
+of the resulting DAG, including the leaves and the root. CIDv0 can only
+represent sha2-256 hashes, so choosing any hash function implies
+'--cid-version=1'. For example:
+
+  > ipfs add --hash=blake2b-256 example.jpg
+
+With '--nocopy', files are referenced through the filestore instead of
+being copied into the blockstore. Files that have no path on disk, such as
+data piped in on stdin, cannot be referenced and are copied with a warning.
+A summary of referenced and copied files is printed at the end.
+
+The '--to-files' option copies the added root into the mutable file system
+(see 'ipfs files') at the given path, creating parent directories as needed:
+
+  > ipfs add --to-files=/photos/example.jpg example.jpg
+  added QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH example.jpg
+  copied QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH to /photos/example.jpg
+
+With '--preserve-mode' and '--preserve-mtime', the permission bits and the
+modification time of every file and directory added are stored on its unixfs
+node, and 'ipfs get' applies them again. Storing them changes the hashes of
+the nodes, so they are left out by default. Nodes of files that fit in a
+single raw leaf are wrapped in a unixfs file node to make room for them.
+
+With '--deterministic', adding the same files gives the same root hash on
+every machine and with every node config. Directory entries are ordered
+bytewise by name, and directories are sharded once, and only once, they hold
+more than 1000 entries, whatever Experimental.ShardingEnabled is set to. All
+other parameters are pinned to their defaults:
+
+  chunker      size-262144
+  layout       balanced (no '--trickle')
+  leaves       unixfs protobuf nodes (no '--raw-leaves')
+  cid-version  0
+  hash         sha2-256
+  metadata     none (no '--preserve-mode' or '--preserve-mtime')
+
+Giving any of the options above, or '--nocopy', together with
+'--deterministic' is an error. Only '--hidden', '--ignore',
+'--ignore-rules-path' and '--wrap-with-directory' change which nodes are
+produced.
+
+With '--input=car', every file given is read as a CARv1 archive, for example
+one written by 'ipfs dag export'. Its blocks are stored as they are, without
+chunking anything, so the dag keeps its original layout and CIDs. The roots
+of each archive are printed, and pinned unless '--pin=false' is given:
+
+  > ipfs add --input=car dag.car
+  added QmRoot dag.car
 `,
 	},
 
@@ -87,6 +173,8 @@ You can now refer to the added file in a gateway, like so:
 		cmds.BoolOption(onlyHashOptionName, "n", "Only chunk and hash - do not write to disk."),
 		cmds.BoolOption(wrapOptionName, "w", "Wrap files with a directory object."),
 		cmds.BoolOption(hiddenOptionName, "H", "Include files that are hidden. Only takes effect on recursive add."),
+		cmds.StringOption(ignoreOptionName, "Comma separated gitignore-style patterns of paths to skip, with '\\,' for a literal comma. Only takes effect on recursive add."),
+		cmds.StringOption(ignoreRulesOptionName, "A file with gitignore-style patterns of paths to skip. Only takes effect on recursive add."),
 		cmds.StringOption(chunkerOptionName, "s", "Chunking algorithm to use."),
 		cmds.BoolOption(pinOptionName, "Pin this object when adding.").Default(true),
 		cmds.BoolOption(rawLeavesOptionName, "Use raw blocks for leaf nodes. (experimental)"),
@@ -94,6 +182,11 @@ You can now refer to the added file in a gateway, like so:
 		cmds.BoolOption(fstoreCacheOptionName, "Check the filestore for pre-existing blocks. (experimental)"),
 		cmds.IntOption(cidVersionOptionName, "Cid version. Non-zero value will change default of 'raw-leaves' to true. (experimental)").Default(0),
 		cmds.StringOption(hashOptionName, "Hash function to use. Will set Cid version to 1 if used. (experimental)").Default("sha2-256"),
+		cmds.StringOption(toFilesOptionName, "Copy the added root to this mfs path."),
+		cmds.BoolOption(preserveModeOptionName, "Store the permission bits of files and directories.").Default(false),
+		cmds.BoolOption(preserveMtimeOptionName, "Store the modification time of files and directories.").Default(false),
+		cmds.BoolOption(deterministicOptionName, "Pin the dag layout and sharding so the root hash is reproducible.").Default(false),
+		cmds.StringOption(inputOptionName, "How to read the input: 'file' to chunk it, 'car' to store the blocks of CAR archives.").Default("file"),
 	},
 	PreRun: func(req cmds.Request) error {
 		quiet, _, _ := req.Option(quietOptionName).Bool()
@@ -169,6 +262,87 @@ You can now refer to the added file in a gateway, like so:
 		fscache, _, _ := req.Option(fstoreCacheOptionName).Bool()
 		cidVer, _, _ := req.Option(cidVersionOptionName).Int()
 		hashFunStr, hfset, _ := req.Option(hashOptionName).String()
+		toFiles, _, _ := req.Option(toFilesOptionName).String()
+		preserveMode, _, _ := req.Option(preserveModeOptionName).Bool()
+		preserveMtime, _, _ := req.Option(preserveMtimeOptionName).Bool()
+		deterministic, _, _ := req.Option(deterministicOptionName).Bool()
+
+		if toFiles != "" {
+			if hash {
+				res.SetError(fmt.Errorf("'--%s' cannot be used with '--%s'", toFilesOptionName, onlyHashOptionName), cmds.ErrClient)
+				return
+			}
+			if !strings.HasPrefix(toFiles, "/") {
+				res.SetError(fmt.Errorf("'--%s' must be an absolute mfs path", toFilesOptionName), cmds.ErrClient)
+				return
+			}
+			toFiles = gopath.Clean(toFiles)
+			if toFiles == "/" {
+				res.SetError(fmt.Errorf("'--%s' cannot replace the mfs root", toFilesOptionName), cmds.ErrClient)
+				return
+			}
+		}
+
+		input, _, _ := req.Option(inputOptionName).String()
+		switch input {
+		case "file":
+		case "car":
+			conflicts := []struct {
+				name string
+				set  bool
+			}{
+				{onlyHashOptionName, hash},
+				{wrapOptionName, wrap},
+				{noCopyOptionName, nocopy},
+				{toFilesOptionName, toFiles != ""},
+				{preserveModeOptionName, preserveMode},
+				{preserveMtimeOptionName, preserveMtime},
+				{deterministicOptionName, deterministic},
+			}
+			for _, c := range conflicts {
+				if c.set {
+					res.SetError(fmt.Errorf("'--%s' cannot be used with '--%s=car'", c.name, inputOptionName), cmds.ErrClient)
+					return
+				}
+			}
+
+			outChan := make(chan interface{}, adderOutChanSize)
+			res.SetOutput((<-chan interface{})(outChan))
+
+			go func() {
+				defer close(outChan)
+				if err := addCars(req.Context(), n, req.Files(), dopin, outChan); err != nil {
+					res.SetError(err, cmds.ErrNormal)
+				}
+			}()
+			return
+		default:
+			res.SetError(fmt.Errorf("unrecognized input: %s", input), cmds.ErrClient)
+			return
+		}
+
+		if deterministic {
+			_, cidVerSet, _ := req.Option(cidVersionOptionName).Int()
+			conflicts := []struct {
+				name string
+				set  bool
+			}{
+				{chunkerOptionName, chunker != ""},
+				{trickleOptionName, trickle},
+				{rawLeavesOptionName, rbset},
+				{cidVersionOptionName, cidVerSet},
+				{hashOptionName, hfset},
+				{noCopyOptionName, nocopy},
+				{preserveModeOptionName, preserveMode},
+				{preserveMtimeOptionName, preserveMtime},
+			}
+			for _, c := range conflicts {
+				if c.set {
+					res.SetError(fmt.Errorf("'--%s' cannot be used with '--%s'", c.name, deterministicOptionName), cmds.ErrClient)
+					return
+				}
+			}
+		}
 
 		if nocopy && !cfg.Experimental.FilestoreEnabled {
 			res.SetError(errors.New("filestore is not enabled, see https://git.io/vy4XN"),
@@ -195,13 +369,13 @@ You can now refer to the added file in a gateway, like so:
 
 		prefix, err := dag.PrefixForCidVersion(cidVer)
 		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
+			res.SetError(err, cmds.ErrClient)
 			return
 		}
 
 		hashFunCode, ok := mh.Names[strings.ToLower(hashFunStr)]
 		if !ok {
-			res.SetError(fmt.Errorf("unrecognized hash function: %s", strings.ToLower(hashFunStr)), cmds.ErrNormal)
+			res.SetError(fmt.Errorf("unrecognized hash function: %s", strings.ToLower(hashFunStr)), cmds.ErrClient)
 			return
 		}
 
@@ -254,7 +428,22 @@ You can now refer to the added file in a gateway, like so:
 		fileAdder.Silent = silent
 		fileAdder.RawLeaves = rawblks
 		fileAdder.NoCopy = nocopy
+		fileAdder.PreserveMode = preserveMode
+		fileAdder.PreserveMtime = preserveMtime
 		fileAdder.Prefix = &prefix
+		if deterministic {
+			fileAdder.ShardThreshold = deterministicShardThreshold
+		}
+
+		// the input only has a size when this process reads it from disk,
+		// not when it is streamed to the daemon
+		if sizeFile, ok := req.Files().(files.SizeFile); ok && progress {
+			go func() {
+				if size, err := sizeFile.Size(); err == nil {
+					fileAdder.SetTotalSize(size)
+				}
+			}()
+		}
 
 		if hash {
 			md := dagtest.Mock()
@@ -285,7 +474,7 @@ You can now refer to the added file in a gateway, like so:
 			}
 
 			// copy intermediary nodes from editor to our actual dagservice
-			_, err := fileAdder.Finalize()
+			root, err := fileAdder.Finalize()
 			if err != nil {
 				return err
 			}
@@ -294,7 +483,14 @@ You can now refer to the added file in a gateway, like so:
 				return nil
 			}
 
-			return fileAdder.PinRoot()
+			if err := fileAdder.PinRoot(); err != nil {
+				return err
+			}
+
+			if toFiles == "" {
+				return nil
+			}
+			return addToFiles(n, root, toFiles, outChan)
 		}
 
 		go func() {
@@ -323,16 +519,6 @@ You can now refer to the added file in a gateway, like so:
 
 		progress, _, _ := req.Option(progressOptionName).Bool()
 
-		var bar *pb.ProgressBar
-		if progress {
-			bar = pb.New64(0).SetUnits(pb.U_BYTES)
-			bar.ManualUpdate = true
-			bar.ShowTimeLeft = false
-			bar.ShowPercent = false
-			bar.Output = res.Stderr()
-			bar.Start()
-		}
-
 		var sizeChan chan int64
 		s, found := req.Values()["size"]
 		if found {
@@ -341,7 +527,10 @@ You can now refer to the added file in a gateway, like so:
 
 		lastFile := ""
 		lastHash := ""
-		var totalProgress, prevFiles, lastBytes int64
+		var totalProgress, totalSize, prevFiles, lastBytes int64
+		var rate float64
+		var eta time.Duration
+		var filestored, copied int
 
 	LOOP:
 		for {
@@ -351,17 +540,34 @@ You can now refer to the added file in a gateway, like so:
 					if quieter {
 						fmt.Fprintln(res.Stdout(), lastHash)
 					}
+					if filestored+copied > 0 && !quiet {
+						fmt.Fprintf(res.Stderr(), "nocopy: %d file(s) referenced in the filestore, %d copied into the blockstore\n", filestored, copied)
+					}
 					break LOOP
 				}
 				output := out.(*coreunix.AddedObject)
-				if len(output.Hash) > 0 {
+				switch output.Storage {
+				case coreunix.StorageFilestore:
+					filestored++
+				case coreunix.StorageBlockstore:
+					copied++
+					if progress {
+						fmt.Fprintf(res.Stderr(), "\033[2K\r")
+					}
+					fmt.Fprintf(res.Stderr(), "warning: %s cannot be referenced in the filestore, its data was copied into the blockstore\n", output.Name)
+				}
+				if len(output.To) > 0 {
+					if !quiet {
+						fmt.Fprintf(res.Stdout(), "copied %s to %s\n", output.Hash, output.To)
+					}
+				} else if len(output.Hash) > 0 {
 					lastHash = output.Hash
 					if quieter {
 						continue
 					}
 
 					if progress {
-						// clear progress bar line before we print "added x" output
+						// clear progress line before we print "added x" output
 						fmt.Fprintf(res.Stderr(), "\033[2K\r")
 					}
 					if quiet {
@@ -384,20 +590,15 @@ You can now refer to the added file in a gateway, like so:
 						lastFile = output.Name
 					}
 					lastBytes = output.Bytes
-					delta := prevFiles + lastBytes - totalProgress
-					totalProgress = bar.Add64(delta)
+					totalProgress = prevFiles + lastBytes
+					rate, eta = output.Rate, output.ETA
 				}
 
 				if progress {
-					bar.Update()
+					fmt.Fprintf(res.Stderr(), "\033[2K\r%s", formatAddProgress(totalProgress, totalSize, rate, eta))
 				}
 			case size := <-sizeChan:
-				if progress {
-					bar.Total = size
-					bar.ShowPercent = true
-					bar.ShowBar = true
-					bar.ShowTimeLeft = true
-				}
+				totalSize = size
 			case <-req.Context().Done():
 				res.SetError(req.Context().Err(), cmds.ErrNormal)
 				return
@@ -406,3 +607,98 @@ You can now refer to the added file in a gateway, like so:
 	},
 	Type: coreunix.AddedObject{},
 }
+
+// addToFiles copies the added root into mfs at dst, creating parent
+// directories as needed, and reports the copy on out.
+func addToFiles(n *core.IpfsNode, root node.Node, dst string, out chan<- interface{}) error {
+	if dir := gopath.Dir(dst); dir != "/" {
+		if err := mfs.Mkdir(n.FilesRoot, dir, true, false); err != nil {
+			return err
+		}
+	}
+
+	if err := mfs.PutNode(n.FilesRoot, dst, root); err != nil {
+		return fmt.Errorf("cannot copy to %s: %s", dst, err)
+	}
+
+	if err := mfs.FlushPath(n.FilesRoot, dst); err != nil {
+		return err
+	}
+
+	out <- &coreunix.AddedObject{
+		Name: gopath.Base(dst),
+		Hash: root.Cid().String(),
+		To:   dst,
+	}
+	return nil
+}
+
+// addCars loads each top-level file of f as a CAR archive, optionally pins
+// its roots, and reports every root on out.
+func addCars(ctx context.Context, n *core.IpfsNode, f files.File, dopin bool, out chan<- interface{}) error {
+	defer n.Blockstore.PinLock().Unlock()
+
+	for {
+		file, err := f.NextFile()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if file.IsDirectory() {
+			return fmt.Errorf("%s is a directory, '--%s=car' expects CAR files", file.FileName(), inputOptionName)
+		}
+
+		roots, _, err := car.LoadCar(n.Blocks, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s is not a valid CAR archive: %s", file.FileName(), err)
+		}
+
+		for _, c := range roots {
+			if dopin {
+				if _, err := corerepo.Pin(n, ctx, []string{c.String()}, true); err != nil {
+					return err
+				}
+			}
+
+			out <- &coreunix.AddedObject{
+				Name: file.FileName(),
+				Hash: c.String(),
+			}
+		}
+	}
+}
+
+// formatAddProgress renders a progress line such as
+// "1.2 GiB / 4.0 GiB (34 MiB/s, ETA 01:23)" from the rate and ETA of the
+// last progress update. If the update has no ETA, because the node does not
+// know the total size, it is estimated from the total seen by the client. If
+// the total size is unknown as well, only the throughput is shown.
+func formatAddProgress(done, total int64, rate float64, eta time.Duration) string {
+	speed := fmt.Sprintf("%s/s", humanize.IBytes(uint64(rate)))
+	if total <= 0 {
+		return fmt.Sprintf("%s (%s)", humanize.IBytes(uint64(done)), speed)
+	}
+
+	if eta <= 0 && rate > 0 && done <= total {
+		eta = time.Duration(float64(total-done) / rate * float64(time.Second))
+	}
+	etaStr := "--:--"
+	if eta > 0 {
+		etaStr = formatETA(eta)
+	}
+	return fmt.Sprintf("%s / %s (%s, ETA %s)", humanize.IBytes(uint64(done)), humanize.IBytes(uint64(total)), speed, etaStr)
+}
+
+// formatETA formats a duration as mm:ss, or hh:mm:ss for durations of an
+// hour or more.
+func formatETA(d time.Duration) string {
+	secs := int64(d.Seconds() + 0.5)
+	h, m, sec := secs/3600, (secs/60)%60, secs%60
+	if h > 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%02d:%02d", m, sec)
+}
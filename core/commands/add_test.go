@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatAddProgress(t *testing.T) {
+	out := formatAddProgress(1288490188, 4<<30, 34<<20, 84*time.Second)
+	if out != "1.2 GiB / 4.0 GiB (34 MiB/s, ETA 01:24)" {
+		t.Fatalf("unexpected progress line: %q", out)
+	}
+
+	out = formatAddProgress(1288490188, 4<<30, 34<<20, 0)
+	if out != "1.2 GiB / 4.0 GiB (34 MiB/s, ETA 01:24)" {
+		t.Fatalf("unexpected progress line without an eta: %q", out)
+	}
+
+	out = formatAddProgress(1288490188, 4<<30, 0, 0)
+	if out != "1.2 GiB / 4.0 GiB (0 B/s, ETA --:--)" {
+		t.Fatalf("unexpected progress line without a rate: %q", out)
+	}
+
+	out = formatAddProgress(5<<20, 0, 34<<20, 0)
+	if out != "5.0 MiB (34 MiB/s)" {
+		t.Fatalf("unexpected progress line for unknown size: %q", out)
+	}
+}
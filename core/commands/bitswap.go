@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	bitswap "github.com/ipfs/go-ipfs/exchange/bitswap"
 	decision "github.com/ipfs/go-ipfs/exchange/bitswap/decision"
+	wantlist "github.com/ipfs/go-ipfs/exchange/bitswap/wantlist"
 
 	"gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
@@ -21,10 +25,11 @@ var BitswapCmd = &cmds.Command{
 		ShortDescription: ``,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"wantlist": showWantlistCmd,
-		"stat":     bitswapStatCmd,
-		"unwant":   unwantCmd,
-		"ledger":   ledgerCmd,
+		"wantlist":  showWantlistCmd,
+		"stat":      bitswapStatCmd,
+		"unwant":    unwantCmd,
+		"ledger":    ledgerCmd,
+		"reprovide": reprovideCmd,
 	},
 }
 
@@ -68,16 +73,60 @@ var unwantCmd = &cmds.Command{
 	},
 }
 
+// WantlistOutput is the output of 'ipfs bitswap wantlist'. Entries is only
+// populated when --full is given.
+type WantlistOutput struct {
+	KeyList
+	Entries []*WantlistEntry `json:",omitempty"`
+}
+
+// WantlistEntry is a single want along with its priority and how long it
+// has been on the wantlist.
+type WantlistEntry struct {
+	Cid      *cid.Cid
+	Priority int
+	Age      time.Duration
+}
+
+type wantlistByAge []*wantlist.Entry
+
+func (es wantlistByAge) Len() int           { return len(es) }
+func (es wantlistByAge) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es wantlistByAge) Less(i, j int) bool { return es[i].Added.Before(es[j].Added) }
+
+type wantlistByPriority []*wantlist.Entry
+
+func (es wantlistByPriority) Len() int           { return len(es) }
+func (es wantlistByPriority) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es wantlistByPriority) Less(i, j int) bool { return es[i].Priority > es[j].Priority }
+
 var showWantlistCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Show blocks currently on the wantlist.",
 		ShortDescription: `
 Print out all blocks currently on the bitswap wantlist for the local peer.`,
+		LongDescription: `
+Print out all blocks currently on the bitswap wantlist for the local peer,
+or, with --peer, the blocks a remote peer has asked us for.
+
+With --full, each want is printed along with its priority and how long it has
+been outstanding:
+
+  <cid> <priority> <age>
+
+For the local wantlist the age is measured from when the want was added to
+the wantmanager; for a remote peer, from when we received the want.
+
+--sort orders the output by 'priority' (highest first, the default) or by
+'age' (oldest first).
+`,
 	},
 	Options: []cmds.Option{
 		cmds.StringOption("peer", "p", "Specify which peer to show wantlist for. Default: self."),
+		cmds.BoolOption("full", "f", "Show priority and age of each want.").Default(false),
+		cmds.StringOption("sort", "s", "Sort wants by 'priority' or 'age'.").Default("priority"),
 	},
-	Type: KeyList{},
+	Type: WantlistOutput{},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -96,33 +145,111 @@ Print out all blocks currently on the bitswap wantlist for the local peer.`,
 			return
 		}
 
+		full, _, _ := req.Option("full").Bool()
+		order, _, _ := req.Option("sort").String()
+
+		var sorter func([]*wantlist.Entry) sort.Interface
+		switch order {
+		case "priority":
+			sorter = func(es []*wantlist.Entry) sort.Interface { return wantlistByPriority(es) }
+		case "age":
+			sorter = func(es []*wantlist.Entry) sort.Interface { return wantlistByAge(es) }
+		default:
+			res.SetError(fmt.Errorf("unknown sort order %q, must be 'priority' or 'age'", order), cmds.ErrClient)
+			return
+		}
+
 		pstr, found, err := req.Option("peer").String()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		var entries []*wantlist.Entry
 		if found {
 			pid, err := peer.IDB58Decode(pstr)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
-			res.SetOutput(&KeyList{bs.WantlistForPeer(pid)})
+			entries = bs.WantlistEntriesForPeer(pid)
 		} else {
-			res.SetOutput(&KeyList{bs.GetWantlist()})
+			entries = bs.GetWantlistEntries()
+		}
+		sort.Stable(sorter(entries))
+
+		out := new(WantlistOutput)
+		now := time.Now()
+		for _, e := range entries {
+			out.Keys = append(out.Keys, e.Cid)
+			if full {
+				out.Entries = append(out.Entries, &WantlistEntry{
+					Cid:      e.Cid,
+					Priority: e.Priority,
+					Age:      now.Sub(e.Added),
+				})
+			}
 		}
+		res.SetOutput(out)
 	},
 	Marshalers: cmds.MarshalerMap{
-		cmds.Text: KeyListTextMarshaler,
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*WantlistOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			if out.Entries == nil {
+				for _, k := range out.Keys {
+					fmt.Fprintln(buf, k.String())
+				}
+				return buf, nil
+			}
+
+			for _, e := range out.Entries {
+				age := e.Age - e.Age%time.Second
+				fmt.Fprintf(buf, "%s\t%d\t%s\n", e.Cid, e.Priority, age)
+			}
+			return buf, nil
+		},
 	},
 }
 
+// BitswapStatOutput is the output of 'ipfs bitswap stat'. Ledgers is only
+// populated when --peer or --peers is given.
+type BitswapStatOutput struct {
+	bitswap.Stat
+	Ledgers []*PeerLedger `json:",omitempty"`
+}
+
+// PeerLedger summarizes the ledger kept for a single bitswap partner.
+type PeerLedger struct {
+	decision.Receipt
+	// Wants is the number of blocks the peer currently wants from us.
+	Wants int
+	// Overlap is the number of those blocks also on our own wantlist.
+	Overlap int
+}
+
 var bitswapStatCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
-		Tagline:          "Show some diagnostic information on the bitswap agent.",
-		ShortDescription: ``,
+		Tagline: "Show some diagnostic information on the bitswap agent.",
+		ShortDescription: `
+Prints aggregate bitswap statistics. With --peer or --peers, prints ledger
+details instead, one line per peer in the form:
+
+  <peer> <debt ratio> <bytes sent> <bytes received> <exchanges> <wants> <overlap>
+
+where <wants> is the size of the peer's wantlist and <overlap> is how many of
+those blocks are also on our own wantlist.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("peer", "Show the ledger for the given peer."),
+		cmds.BoolOption("peers", "Show ledgers for all partners.").Default(false),
 	},
-	Type: bitswap.Stat{},
+	Type: BitswapStatOutput{},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -141,21 +268,65 @@ var bitswapStatCmd = &cmds.Command{
 			return
 		}
 
+		pstr, havePeer, _ := req.Option("peer").String()
+		allPeers, _, _ := req.Option("peers").Bool()
+		if havePeer && allPeers {
+			res.SetError(fmt.Errorf("--peer and --peers are mutually exclusive"), cmds.ErrClient)
+			return
+		}
+
 		st, err := bs.Stat()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		res.SetOutput(st)
+		out := &BitswapStatOutput{Stat: *st}
+		switch {
+		case havePeer:
+			pid, err := peer.IDB58Decode(pstr)
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+
+			// LedgerForPeer creates a ledger when none exists, so only
+			// look up peers we are actually exchanging with.
+			if !containsString(st.Peers, pid.Pretty()) {
+				res.SetError(fmt.Errorf("no ledger for peer %s", pid.Pretty()), cmds.ErrClient)
+				return
+			}
+			out.Ledgers = []*PeerLedger{peerLedger(bs, pid, st.Wantlist)}
+		case allPeers:
+			out.Ledgers = []*PeerLedger{}
+			for _, p := range st.Peers {
+				pid, err := peer.IDB58Decode(p)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				out.Ledgers = append(out.Ledgers, peerLedger(bs, pid, st.Wantlist))
+			}
+		}
+
+		res.SetOutput(out)
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			out, ok := res.Output().(*bitswap.Stat)
+			out, ok := res.Output().(*BitswapStatOutput)
 			if !ok {
 				return nil, u.ErrCast()
 			}
 			buf := new(bytes.Buffer)
+
+			if out.Ledgers != nil {
+				for _, l := range out.Ledgers {
+					fmt.Fprintf(buf, "%s\t%f\t%d\t%d\t%d\t%d\t%d\n", l.Peer, l.Value,
+						l.Sent, l.Recv, l.Exchanged, l.Wants, l.Overlap)
+				}
+				return buf, nil
+			}
+
 			fmt.Fprintln(buf, "bitswap status")
 			fmt.Fprintf(buf, "\tprovides buffer: %d / %d\n", out.ProvideBufLen, bitswap.HasBlockBufferSize)
 			fmt.Fprintf(buf, "\tblocks received: %d\n", out.BlocksReceived)
@@ -177,6 +348,37 @@ var bitswapStatCmd = &cmds.Command{
 	},
 }
 
+func peerLedger(bs *bitswap.Bitswap, p peer.ID, ours []*cid.Cid) *PeerLedger {
+	theirs := bs.WantlistForPeer(p)
+
+	wanted := make(map[string]struct{}, len(ours))
+	for _, c := range ours {
+		wanted[c.KeyString()] = struct{}{}
+	}
+
+	overlap := 0
+	for _, c := range theirs {
+		if _, ok := wanted[c.KeyString()]; ok {
+			overlap++
+		}
+	}
+
+	return &PeerLedger{
+		Receipt: *bs.LedgerForPeer(p),
+		Wants:   len(theirs),
+		Overlap: overlap,
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 var ledgerCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Show the current ledger for a peer.",
@@ -184,8 +386,13 @@ var ledgerCmd = &cmds.Command{
 The Bitswap decision engine tracks the number of bytes exchanged between IPFS
 nodes, and stores this information as a collection of ledgers. This command
 prints the ledger associated with a given peer.
+
+'ipfs bitswap ledger reset' clears the ledger of a peer.
 `,
 	},
+	Subcommands: map[string]*cmds.Command{
+		"reset": ledgerResetCmd,
+	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("peer", true, false, "The PeerID (B58) of the ledger to inspect."),
 	},
@@ -233,3 +440,119 @@ prints the ledger associated with a given peer.
 		},
 	},
 }
+
+var ledgerResetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Reset the ledger for a peer.",
+		ShortDescription: `
+Clears the debt ratio, exchange count and byte counts the Bitswap decision
+engine recorded for a given peer, so that exchange with it starts afresh, and
+prints the ledger as it was before the reset. The blocks the peer wants are
+kept. Only peers we have a ledger for can be reset.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, false, "The PeerID (B58) of the ledger to reset."),
+	},
+	Type: decision.Receipt{},
+	Run: func(req cmds.Request, res cmds.Response) {
+		nd, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if !nd.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		bs, ok := nd.Exchange.(*bitswap.Bitswap)
+		if !ok {
+			res.SetError(u.ErrCast(), cmds.ErrNormal)
+			return
+		}
+
+		partner, err := peer.IDB58Decode(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		prev, ok := bs.ResetLedger(partner)
+		if !ok {
+			res.SetError(fmt.Errorf("no ledger for peer %s", partner.Pretty()), cmds.ErrClient)
+			return
+		}
+		res.SetOutput(prev)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*decision.Receipt)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			buf := new(bytes.Buffer)
+			fmt.Fprintf(buf, "Reset ledger for %s\n"+
+				"Debt ratio was:\t%f\n"+
+				"Exchanges were:\t%d\n"+
+				"Bytes sent were:\t%d\n"+
+				"Bytes received were:\t%d\n",
+				out.Peer, out.Value, out.Exchanged,
+				out.Sent, out.Recv)
+			return buf, nil
+		},
+	},
+}
+
+// ReprovideOutput is the output of 'ipfs bitswap reprovide'.
+type ReprovideOutput struct {
+	Keys     int
+	Duration time.Duration
+}
+
+var reprovideCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Trigger the reprovider.",
+		ShortDescription: `
+Runs the reprovider right away instead of waiting for the next
+Reprovider.Interval, announcing every block in the blockstore to the routing
+system. Once done, it prints how many keys were announced and how long it
+took. Only one reprovide run can be in progress at a time; if one is already
+running, the command fails instead of starting another.
+`,
+	},
+	Type: ReprovideOutput{},
+	Run: func(req cmds.Request, res cmds.Response) {
+		nd, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if !nd.OnlineMode() || nd.Reprovider == nil {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		st, err := nd.Reprovider.Trigger(req.Context())
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&ReprovideOutput{
+			Keys:     st.Keys,
+			Duration: st.Duration,
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*ReprovideOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			return strings.NewReader(fmt.Sprintf("Reprovided %d keys in %s\n", out.Keys, out.Duration)), nil
+		},
+	},
+}
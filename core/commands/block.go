@@ -10,6 +10,7 @@ import (
 	"github.com/ipfs/go-ipfs/blocks"
 	util "github.com/ipfs/go-ipfs/blocks/blockstore/util"
 	cmds "github.com/ipfs/go-ipfs/commands"
+	pin "github.com/ipfs/go-ipfs/pin"
 
 	mh "gx/ipfs/QmVGtdTZdTFaLsaj2RwdVG8jcjNNcp1DE914DKZ2kHmXHw/go-multihash"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
@@ -19,10 +20,22 @@ import (
 type BlockStat struct {
 	Key  string
 	Size int
+
+	// Pinned and PinnedVia are only set with --verbose. PinnedVia is the
+	// recursive pin an indirectly pinned block is reachable from.
+	Pinned    string `json:",omitempty"`
+	PinnedVia string `json:",omitempty"`
 }
 
 func (bs BlockStat) String() string {
-	return fmt.Sprintf("Key: %s\nSize: %d\n", bs.Key, bs.Size)
+	s := fmt.Sprintf("Key: %s\nSize: %d\n", bs.Key, bs.Size)
+	if bs.Pinned != "" {
+		s += fmt.Sprintf("Pinned: %s\n", bs.Pinned)
+	}
+	if bs.PinnedVia != "" {
+		s += fmt.Sprintf("Pinned via: %s\n", bs.PinnedVia)
+	}
+	return s
 }
 
 var BlockCmd = &cmds.Command{
@@ -53,12 +66,24 @@ on raw IPFS blocks. It outputs the following to stdout:
 	Key  - the base58 encoded multihash
 	Size - the size of the block in bytes
 
+With --verbose, it also outputs how the block is pinned:
+
+	Pinned     - direct, recursive, indirect, internal or not pinned
+	Pinned via - for indirect pins, the recursive pin the block is
+	             reachable from
+
+Finding out whether a block is pinned indirectly walks the dags of the
+recursive pins until the block is found, so it can be slow on large repos.
+Only the first recursive pin found is reported.
 `,
 	},
 
 	Arguments: []cmds.Argument{
 		cmds.StringArg("key", true, false, "The base58 multihash of an existing block to stat.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("verbose", "v", "Also show how the block is pinned.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		b, err := getBlockForKey(req, req.Arguments()[0])
 		if err != nil {
@@ -66,10 +91,32 @@ on raw IPFS blocks. It outputs the following to stdout:
 			return
 		}
 
-		res.SetOutput(&BlockStat{
+		out := &BlockStat{
 			Key:  b.Cid().String(),
 			Size: len(b.RawData()),
-		})
+		}
+
+		verbose, _, _ := req.Option("verbose").Bool()
+		if verbose {
+			n, err := req.InvocContext().GetNode()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			pinned, err := n.Pinning.CheckIfPinned(b.Cid())
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			out.Pinned, _ = pin.PinModeToString(pinned[0].Mode)
+			if pinned[0].Via != nil {
+				out.PinnedVia = pinned[0].Via.String()
+			}
+		}
+
+		res.SetOutput(out)
 	},
 	Type: BlockStat{},
 	Marshalers: cmds.MarshalerMap{
@@ -109,6 +156,20 @@ var blockPutCmd = &cmds.Command{
 		ShortDescription: `
 'ipfs block put' is a plumbing command for storing raw IPFS blocks.
 It reads from stdin, and <key> is a base58 encoded multihash.
+`,
+		LongDescription: `
+'ipfs block put' is a plumbing command for storing raw IPFS blocks.
+It reads from stdin, and <key> is a base58 encoded multihash.
+
+The --format option selects the codec recorded in the resulting cid. It
+accepts 'v0' (the default), 'protobuf' (or 'dag-pb'), 'cbor' (or
+'dag-cbor') and 'raw'. All formats other than 'v0' produce a CIDv1.
+
+The --mhtype and --mhlen options select the multihash function and digest
+length used to hash the block. A --mhlen of -1 uses the function's default
+length; any other value must be positive and no larger than that default.
+CIDv0 can only express sha2-256 multihashes, so choosing another function
+or length with the default format stores the block under a CIDv1 dag-pb cid.
 `,
 	},
 
@@ -150,9 +211,9 @@ It reads from stdin, and <key> is a base58 encoded multihash.
 
 		format, _, _ := req.Option("format").String()
 		switch format {
-		case "cbor":
+		case "cbor", "dag-cbor":
 			pref.Codec = cid.DagCBOR
-		case "protobuf":
+		case "protobuf", "dag-pb":
 			pref.Codec = cid.DagProtobuf
 		case "raw":
 			pref.Codec = cid.Raw
@@ -160,14 +221,14 @@ It reads from stdin, and <key> is a base58 encoded multihash.
 			pref.Version = 0
 			pref.Codec = cid.DagProtobuf
 		default:
-			res.SetError(fmt.Errorf("unrecognized format: %s", format), cmds.ErrNormal)
+			res.SetError(fmt.Errorf("unrecognized format: %s", format), cmds.ErrClient)
 			return
 		}
 
 		mhtype, _, _ := req.Option("mhtype").String()
 		mhtval, ok := mh.Names[mhtype]
 		if !ok {
-			res.SetError(fmt.Errorf("unrecognized multihash function: %s", mhtype), cmds.ErrNormal)
+			res.SetError(fmt.Errorf("unrecognized multihash function: %s", mhtype), cmds.ErrClient)
 			return
 		}
 		pref.MhType = mhtval
@@ -177,8 +238,24 @@ It reads from stdin, and <key> is a base58 encoded multihash.
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		maxlen, err := defaultDigestLength(mhtval)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if mhlen != -1 && (mhlen <= 0 || mhlen > maxlen) {
+			res.SetError(fmt.Errorf("invalid multihash length %d for %s: must be between 1 and %d", mhlen, mhtype, maxlen), cmds.ErrClient)
+			return
+		}
 		pref.MhLength = mhlen
 
+		// CIDv0 is implicitly a full length sha2-256 multihash, anything else
+		// can only be addressed with a CIDv1.
+		if pref.Version == 0 && (mhtval != mh.SHA2_256 || (mhlen != -1 && mhlen != maxlen)) {
+			pref.Version = 1
+		}
+
 		bcid, err := pref.Sum(data)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -212,6 +289,22 @@ It reads from stdin, and <key> is a base58 encoded multihash.
 	Type: BlockStat{},
 }
 
+// defaultDigestLength returns the length of the digest the given multihash
+// function produces when no explicit length is requested.
+func defaultDigestLength(code uint64) (int, error) {
+	sum, err := mh.Sum(nil, code, -1)
+	if err != nil {
+		return 0, err
+	}
+
+	dec, err := mh.Decode(sum)
+	if err != nil {
+		return 0, err
+	}
+
+	return dec.Length, nil
+}
+
 func getBlockForKey(req cmds.Request, skey string) (blocks.Block, error) {
 	if len(skey) == 0 {
 		return nil, fmt.Errorf("zero length cid invalid")
@@ -242,13 +335,22 @@ var blockRmCmd = &cmds.Command{
 		ShortDescription: `
 'ipfs block rm' is a plumbing command for removing raw ipfs blocks.
 It takes a list of base58 encoded multihashs to remove.
+
+Pinned blocks, whether pinned directly or through a recursive pin, are
+skipped and reported, and the command exits with a non-zero status if any
+block could not be removed. Use --quiet to only report failures.
+
+--force has two effects: blocks that do not exist are ignored instead of
+reported, and pinned blocks are removed instead of skipped. Removing pinned
+blocks leaves the pins pointing at missing data. The blocks the pin sets
+themselves are stored in are never removed, not even with --force.
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("hash", true, true, "Bash58 encoded multihash of block(s) to remove."),
 	},
 	Options: []cmds.Option{
-		cmds.BoolOption("force", "f", "Ignore nonexistent blocks.").Default(false),
+		cmds.BoolOption("force", "f", "Ignore nonexistent blocks and remove pinned blocks.").Default(false),
 		cmds.BoolOption("quiet", "q", "Write minimal output.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 
@@ -17,6 +18,16 @@ type BootstrapOutput struct {
 	Peers []string
 }
 
+// BootstrapAddOutput lists the peers that were added, along with how many
+// of them were new to the bootstrap list, how many of the given peers were
+// skipped as duplicates and the size of the resulting list.
+type BootstrapAddOutput struct {
+	BootstrapOutput
+	Added   int
+	Skipped int
+	Total   int
+}
+
 var peerOptionDesc = "A peer to add to the bootstrap list (in the format '<multiaddr>/<peerID>')"
 
 var BootstrapCmd = &cmds.Command{
@@ -42,7 +53,12 @@ var bootstrapAddCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Add peers to the bootstrap list.",
 		ShortDescription: `Outputs a list of peers that were added (that weren't already
-in the bootstrap list).
+in the bootstrap list). A summary of how many peers were new, how many were
+skipped as duplicates and how many the list holds now is written to stderr.
+
+The given peers are appended to the existing bootstrap list. With --default,
+the default bootstrap peers are added along with any given peers. With
+--only, the bootstrap list is replaced by the given peers instead.
 ` + bootstrapSecurityWarning,
 	},
 
@@ -52,6 +68,7 @@ in the bootstrap list).
 
 	Options: []cmds.Option{
 		cmds.BoolOption("default", "Add default bootstrap nodes. (Deprecated, use 'default' subcommand instead)"),
+		cmds.BoolOption("only", "Replace the bootstrap list with the given peers instead of appending to it.").Default(false),
 	},
 	Subcommands: map[string]*cmds.Command{
 		"default": bootstrapAddDefaultCmd,
@@ -64,6 +81,12 @@ in the bootstrap list).
 			return
 		}
 
+		only, _, err := req.Option("only").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		var inputPeers []config.BootstrapPeer
 		if deflt {
 			// parse separately for meaningful, correct error.
@@ -74,15 +97,14 @@ in the bootstrap list).
 			}
 
 			inputPeers = defltPeers
-		} else {
-			parsedPeers, err := config.ParseBootstrapPeers(req.Arguments())
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
-			}
+		}
 
-			inputPeers = parsedPeers
+		parsedPeers, err := config.ParseBootstrapPeers(req.Arguments())
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
 		}
+		inputPeers = append(inputPeers, parsedPeers...)
 
 		if len(inputPeers) == 0 {
 			res.SetError(errors.New("no bootstrap peers to add"), cmds.ErrClient)
@@ -101,29 +123,17 @@ in the bootstrap list).
 			return
 		}
 
-		added, err := bootstrapAdd(r, cfg, inputPeers)
+		out, err := bootstrapAdd(r, cfg, inputPeers, only)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		res.SetOutput(&BootstrapOutput{config.BootstrapPeerStrings(added)})
+		res.SetOutput(out)
 	},
-	Type: BootstrapOutput{},
+	Type: BootstrapAddOutput{},
 	Marshalers: cmds.MarshalerMap{
-		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			v, ok := res.Output().(*BootstrapOutput)
-			if !ok {
-				return nil, u.ErrCast()
-			}
-
-			buf := new(bytes.Buffer)
-			if err := bootstrapWritePeers(buf, "added ", v.Peers); err != nil {
-				return nil, err
-			}
-
-			return buf, nil
-		},
+		cmds.Text: bootstrapAddMarshaler,
 	},
 }
 
@@ -153,29 +163,17 @@ in the bootstrap list).`,
 			return
 		}
 
-		added, err := bootstrapAdd(r, cfg, defltPeers)
+		out, err := bootstrapAdd(r, cfg, defltPeers, false)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		res.SetOutput(&BootstrapOutput{config.BootstrapPeerStrings(added)})
+		res.SetOutput(out)
 	},
-	Type: BootstrapOutput{},
+	Type: BootstrapAddOutput{},
 	Marshalers: cmds.MarshalerMap{
-		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			v, ok := res.Output().(*BootstrapOutput)
-			if !ok {
-				return nil, u.ErrCast()
-			}
-
-			buf := new(bytes.Buffer)
-			if err := bootstrapWritePeers(buf, "added ", v.Peers); err != nil {
-				return nil, err
-			}
-
-			return buf, nil
-		},
+		cmds.Text: bootstrapAddMarshaler,
 	},
 }
 
@@ -333,6 +331,21 @@ func bootstrapMarshaler(res cmds.Response) (io.Reader, error) {
 	return buf, err
 }
 
+func bootstrapAddMarshaler(res cmds.Response) (io.Reader, error) {
+	v, ok := res.Output().(*BootstrapAddOutput)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+
+	buf := new(bytes.Buffer)
+	if err := bootstrapWritePeers(buf, "added ", v.Peers); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(res.Stderr(), "added: %d, skipped duplicates: %d, total: %d\n", v.Added, v.Skipped, v.Total)
+
+	return buf, nil
+}
+
 func bootstrapWritePeers(w io.Writer, prefix string, peers []string) error {
 
 	sort.Stable(sort.StringSlice(peers))
@@ -345,41 +358,65 @@ func bootstrapWritePeers(w io.Writer, prefix string, peers []string) error {
 	return nil
 }
 
-func bootstrapAdd(r repo.Repo, cfg *config.Config, peers []config.BootstrapPeer) ([]config.BootstrapPeer, error) {
+// bootstrapAdd adds peers to the bootstrap list, or replaces the list with
+// them if replace is set. Peers that were already in the list, or given more
+// than once, are counted as skipped.
+func bootstrapAdd(r repo.Repo, cfg *config.Config, peers []config.BootstrapPeer, replace bool) (*BootstrapAddOutput, error) {
 	addedMap := map[string]struct{}{}
 	addedList := make([]config.BootstrapPeer, 0, len(peers))
+	added, skipped := 0, 0
 
 	// re-add cfg bootstrap peers to rm dupes
 	bpeers := cfg.Bootstrap
 	cfg.Bootstrap = nil
 
+	existing := map[string]struct{}{}
+	if !replace {
+		for _, s := range bpeers {
+			existing[s] = struct{}{}
+		}
+	}
+
 	// add new peers
 	for _, peer := range peers {
 		s := peer.String()
 		if _, found := addedMap[s]; found {
+			skipped++
 			continue
 		}
 
 		cfg.Bootstrap = append(cfg.Bootstrap, s)
 		addedList = append(addedList, peer)
 		addedMap[s] = struct{}{}
+		if _, found := existing[s]; found {
+			skipped++
+			continue
+		}
+		added++
 	}
 
 	// add back original peers. in this order so that we output them.
-	for _, s := range bpeers {
-		if _, found := addedMap[s]; found {
-			continue
-		}
+	if !replace {
+		for _, s := range bpeers {
+			if _, found := addedMap[s]; found {
+				continue
+			}
 
-		cfg.Bootstrap = append(cfg.Bootstrap, s)
-		addedMap[s] = struct{}{}
+			cfg.Bootstrap = append(cfg.Bootstrap, s)
+			addedMap[s] = struct{}{}
+		}
 	}
 
 	if err := r.SetConfig(cfg); err != nil {
 		return nil, err
 	}
 
-	return addedList, nil
+	return &BootstrapAddOutput{
+		BootstrapOutput: BootstrapOutput{config.BootstrapPeerStrings(addedList)},
+		Added:           added,
+		Skipped:         skipped,
+		Total:           len(cfg.Bootstrap),
+	}, nil
 }
 
 func bootstrapRemove(r repo.Repo, cfg *config.Config, toRemove []config.BootstrapPeer) ([]config.BootstrapPeer, error) {
@@ -1,26 +1,61 @@
 package commands
 
 import (
+	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
 	coreunix "github.com/ipfs/go-ipfs/core/coreunix"
+	path "github.com/ipfs/go-ipfs/path"
 
 	context "context"
 )
 
+// defaultResolveTimeout bounds the resolution of /ipns/ paths given to
+// 'ipfs cat' and 'ipfs get'.
+const defaultResolveTimeout = "1m"
+
 const progressBarMinSize = 1024 * 1024 * 8 // show progress bar for outputs > 8MiB
 
 var CatCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
-		Tagline:          "Show IPFS object data.",
-		ShortDescription: "Displays the data contained by an IPFS or IPNS object(s) at the given path.",
+		Tagline: "Show IPFS object data.",
+		ShortDescription: `
+Displays the data contained by an IPFS or IPNS object(s) at the given path.
+
+/ipns/ paths, both of IPNS names and of DNSLink domains, are resolved through
+the name system before any data is fetched. Resolution gives up after
+--resolve-timeout. With --verbose, the path each name resolved to is written
+to stderr. Pass '--resolve=false' to only accept /ipfs/ paths and CIDs.
+
+The contents of several paths are written back to back, in the order given.
+All paths are resolved before anything is written, so if one of them cannot
+be read the command fails without output. With --continue-on-error, such
+paths are skipped instead; the command still fails once the others were
+written, listing the paths it skipped.
+
+A progress bar is written to stderr for outputs larger than 8MiB. Pass
+--progress to show it regardless of the size, or '--progress=false' to never
+show it. When the size of the output is not known, only the number of bytes
+written so far is shown. The data on stdout is the same either way.
+`,
 	},
 
 	Arguments: []cmds.Argument{
 		cmds.StringArg("ipfs-path", true, true, "The path to the IPFS object(s) to be outputted.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.IntOption("offset", "o", "Byte offset to begin reading from."),
+		cmds.IntOption("length", "l", "Maximum number of bytes to read."),
+		cmds.BoolOption("resolve", "Resolve /ipns/ paths through the name system.").Default(true),
+		cmds.StringOption("resolve-timeout", "Maximum time to spend resolving each /ipns/ path.").Default(defaultResolveTimeout),
+		cmds.BoolOption("verbose", "v", "Print the path each /ipns/ path resolved to on stderr.").Default(false),
+		cmds.BoolOption("continue-on-error", "Skip paths that cannot be read instead of failing.").Default(false),
+		cmds.BoolOption("progress", "p", "Show a progress bar on stderr. Defaults to showing it for outputs over 8MiB."),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		node, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -35,12 +70,75 @@ var CatCmd = &cmds.Command{
 			}
 		}
 
-		readers, length, err := cat(req.Context(), node, req.Arguments())
+		offset, _, err := req.Option("offset").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if offset < 0 {
+			res.SetError(fmt.Errorf("cannot specify negative offset"), cmds.ErrClient)
+			return
+		}
+
+		max, found, err := req.Option("length").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if max < 0 {
+			res.SetError(fmt.Errorf("cannot specify negative length"), cmds.ErrClient)
+			return
+		}
+		if !found {
+			max = -1
+		}
+
+		resolve, timeout, err := ipnsResolveOptions(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		verbose, _, _ := req.Option("verbose").Bool()
+		continueOnError, _, _ := req.Option("continue-on-error").Bool()
+
+		var skipped []string
+		skip := func(err error) bool {
+			if !continueOnError {
+				return false
+			}
+			skipped = append(skipped, err.Error())
+			return true
+		}
+
+		paths := make([]string, 0, len(req.Arguments()))
+		for _, arg := range req.Arguments() {
+			p, err := resolveIpnsArgs(req.Context(), node, []string{arg}, resolve, timeout)
+			if err != nil {
+				if skip(err) {
+					continue
+				}
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			if verbose && p[0] != arg {
+				res.AddMessage(fmt.Sprintf("resolved %s to %s", arg, p[0]))
+			}
+			paths = append(paths, p[0])
+		}
+
+		readers, length, err := cat(req.Context(), node, paths, int64(offset), int64(max), skip)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
+		if len(skipped) > 0 {
+			// report the skipped paths once everything else was written
+			readers = append(readers, &errReader{fmt.Errorf("skipped %d of %d paths:\n%s",
+				len(skipped), len(req.Arguments()), strings.Join(skipped, "\n"))})
+		}
+
 		/*
 			if err := corerepo.ConditionalGC(req.Context(), node, length); err != nil {
 				res.SetError(err, cmds.ErrNormal)
@@ -54,27 +152,163 @@ var CatCmd = &cmds.Command{
 		res.SetOutput(reader)
 	},
 	PostRun: func(req cmds.Request, res cmds.Response) {
-		if res.Length() < progressBarMinSize {
+		printMessages(res)
+
+		out, ok := res.Output().(io.Reader)
+		if !ok {
 			return
 		}
 
-		bar, reader := progressBarForReader(res.Stderr(), res.Output().(io.Reader), int64(res.Length()))
+		progress, found, _ := req.Option("progress").Bool()
+		if !found {
+			progress = res.Length() >= progressBarMinSize
+		}
+		if !progress {
+			return
+		}
+
+		// a length of 0 makes the bar only count the bytes written
+		bar, reader := progressBarForReader(res.Stderr(), out, int64(res.Length()))
 		bar.Start()
 
 		res.SetOutput(reader)
 	},
 }
 
-func cat(ctx context.Context, node *core.IpfsNode, paths []string) ([]io.Reader, uint64, error) {
+// cat returns readers for the concatenation of the files at the given paths,
+// starting at offset and yielding at most max bytes. A negative max reads
+// until the end of the last file. If a path cannot be opened, it is left out
+// when skip returns true for the error, and cat fails otherwise.
+func cat(ctx context.Context, node *core.IpfsNode, paths []string, offset int64, max int64, skip func(error) bool) ([]io.Reader, uint64, error) {
 	readers := make([]io.Reader, 0, len(paths))
 	length := uint64(0)
+	if max == 0 {
+		return readers, 0, nil
+	}
+
+	total := uint64(0)
 	for _, fpath := range paths {
 		read, err := coreunix.Cat(ctx, node, fpath)
 		if err != nil {
+			if skip(fmt.Errorf("%s: %s", fpath, err)) {
+				continue
+			}
 			return nil, 0, err
 		}
+		total += read.Size()
+
+		// skip whole files that lie before the requested offset without
+		// fetching any of their blocks
+		if offset >= int64(read.Size()) {
+			offset -= int64(read.Size())
+			continue
+		}
+
+		// seeking only fetches the blocks on the path to offset
+		count, err := read.Seek(offset, io.SeekStart)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset = 0
+
+		size := read.Size() - uint64(count)
+		length += size
+		if max > 0 && length >= uint64(max) {
+			var r io.Reader = read
+			if overshoot := length - uint64(max); overshoot != 0 {
+				r = io.LimitReader(read, int64(size-overshoot))
+				length = uint64(max)
+			}
+			readers = append(readers, r)
+			break
+		}
 		readers = append(readers, read)
-		length += uint64(read.Size())
+	}
+
+	if offset > 0 {
+		return nil, 0, fmt.Errorf("offset %d exceeds total size %d", uint64(offset)+total, total)
 	}
 	return readers, length, nil
 }
+
+// errReader is an io.Reader that fails with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// printMessages writes the messages of res to its stderr, one per line.
+func printMessages(res cmds.Response) {
+	for _, m := range res.Messages() {
+		fmt.Fprintln(res.Stderr(), m)
+	}
+}
+
+// ipnsResolveOptions returns the values of the "resolve" and
+// "resolve-timeout" options of req.
+func ipnsResolveOptions(req cmds.Request) (bool, time.Duration, error) {
+	resolve, _, _ := req.Option("resolve").Bool()
+	tstr, _, _ := req.Option("resolve-timeout").String()
+	timeout, err := time.ParseDuration(tstr)
+	if err != nil {
+		return false, 0, fmt.Errorf("invalid resolve-timeout: %s", err)
+	}
+	if timeout <= 0 {
+		return false, 0, fmt.Errorf("resolve-timeout must be positive")
+	}
+	return resolve, timeout, nil
+}
+
+// resolveIpnsArgs resolves the /ipns/ paths among args to the /ipfs/ paths
+// they currently point to. Other paths are returned unchanged. If resolve is
+// false, /ipns/ paths are rejected instead.
+func resolveIpnsArgs(ctx context.Context, n *core.IpfsNode, args []string, resolve bool, timeout time.Duration) ([]string, error) {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "/ipns/") {
+			out[i] = arg
+			continue
+		}
+		if !resolve {
+			return nil, fmt.Errorf("%s is an /ipns/ path, but resolving was disabled with '--resolve=false'", arg)
+		}
+
+		p, err := resolveIpnsPath(ctx, n, path.Path(arg), timeout)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %s", arg, err)
+		}
+		log.Infof("resolved %s to %s", arg, p)
+		out[i] = p.String()
+	}
+	return out, nil
+}
+
+// resolveIpnsPath resolves the name at the start of the /ipns/ path p and
+// joins the result with the remaining segments of p. The lookup is given up
+// after timeout.
+func resolveIpnsPath(ctx context.Context, n *core.IpfsNode, p path.Path, timeout time.Duration) (path.Path, error) {
+	if n.Namesys == nil {
+		return "", core.ErrNoNamesys
+	}
+
+	seg := p.Segments()
+	if len(seg) < 2 || seg[1] == "" {
+		return "", path.ErrNoComponents
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resolved, err := n.Namesys.Resolve(ctx, "/ipns/"+seg[1])
+	if err == context.DeadlineExceeded {
+		return "", fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return path.FromSegments("/", append(resolved.Segments(), seg[2:]...)...)
+}
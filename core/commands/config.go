@@ -9,6 +9,8 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"reflect"
+	"sort"
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
@@ -17,6 +19,7 @@ import (
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
 type ConfigField struct {
@@ -67,6 +70,10 @@ Set the value of the 'Datastore.Path' key:
 			return
 		default:
 		}
+		if isRemotePinKey(key) {
+			res.SetError(fmt.Errorf("cannot show or change remote pinning service keys through API"), cmds.ErrNormal)
+			return
+		}
 
 		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
 		if err != nil {
@@ -100,6 +107,10 @@ Set the value of the 'Datastore.Path' key:
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		if err := scrubConfigField(output); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
 		res.SetOutput(output)
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -129,8 +140,10 @@ Set the value of the 'Datastore.Path' key:
 	Type: ConfigField{},
 	Subcommands: map[string]*cmds.Command{
 		"show":    configShowCmd,
+		"check":   configCheckCmd,
 		"edit":    configEditCmd,
 		"replace": configReplaceCmd,
+		"profile": configProfileCmd,
 	},
 }
 
@@ -168,6 +181,7 @@ included in the output of this command.
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		scrubRemotePinKeys(cfg)
 
 		output, err := config.HumanOutput(cfg)
 		if err != nil {
@@ -220,6 +234,165 @@ func scrubValue(m map[string]interface{}, key []string) error {
 	return nil
 }
 
+type ConfigCheckOutput struct {
+	Key     string `json:",omitempty"`
+	Kind    string `json:",omitempty"`
+	Message string
+}
+
+var configCheckCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check the config file for unknown, deprecated and mistyped keys.",
+		ShortDescription: `
+'ipfs config check' compares the config file with the config schema of this
+version of ipfs, and reports:
+
+  - unknown keys, which are ignored when the config is loaded, such as
+    misspelled keys
+  - deprecated keys, which are no longer used, and their replacements
+  - values of the wrong type, which keep the config from loading
+
+The config file is not modified. The command exits with a non-zero status if
+any problem was found.
+`,
+	},
+
+	Run: func(req cmds.Request, res cmds.Response) {
+		fname, err := config.Filename(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		var cfg map[string]interface{}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			res.SetError(fmt.Errorf("failed to parse config file: %s", err), cmds.ErrNormal)
+			return
+		}
+
+		problems := config.Check(cfg)
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+
+		go func() {
+			defer close(out)
+
+			for _, p := range problems {
+				select {
+				case out <- &ConfigCheckOutput{Key: p.Key, Kind: p.Kind, Message: p.Message}:
+				case <-req.Context().Done():
+					return
+				}
+			}
+
+			if len(problems) > 0 {
+				res.SetError(fmt.Errorf("config check complete, %d problems found.", len(problems)), cmds.ErrNormal)
+				return
+			}
+			select {
+			case out <- &ConfigCheckOutput{Message: "config check complete, no problems found."}:
+			case <-req.Context().Done():
+			}
+		}()
+	},
+	Type: ConfigCheckOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				obj, ok := v.(*ConfigCheckOutput)
+				if !ok {
+					return nil, u.ErrCast()
+				}
+
+				buf := new(bytes.Buffer)
+				if obj.Key != "" {
+					fmt.Fprintf(buf, "%s: %s\n", obj.Key, obj.Message)
+				} else {
+					fmt.Fprintln(buf, obj.Message)
+				}
+				return buf, nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   out,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
+		},
+	},
+}
+
+// scrubRemotePinKeys removes the access keys of the remote pinning services
+// from the config map m.
+func scrubRemotePinKeys(m map[string]interface{}) {
+	pinning, ok := m["Pinning"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	services, ok := pinning["RemoteServices"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, svc := range services {
+		if svcm, ok := svc.(map[string]interface{}); ok {
+			delete(svcm, "Key")
+		}
+	}
+}
+
+// isRemotePinKey reports whether the config key names the access key of a
+// remote pinning service.
+func isRemotePinKey(key string) bool {
+	parts := strings.Split(strings.ToLower(key), ".")
+	return len(parts) == 4 && parts[0] == "pinning" && parts[1] == "remoteservices" && parts[3] == "key"
+}
+
+// scrubConfigField removes the remote pinning service keys from the value
+// of f, if f is one of the sections holding them.
+func scrubConfigField(f *ConfigField) error {
+	parts := strings.Split(strings.ToLower(f.Key), ".")
+	if parts[0] != "pinning" || len(parts) > 3 {
+		return nil
+	}
+
+	// copy the value, so the scrubbing can't leak into the repo's config
+	data, err := json.Marshal(f.Value)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch len(parts) {
+	case 1:
+		m, _ := v.(map[string]interface{})
+		scrubRemotePinKeys(map[string]interface{}{"Pinning": m})
+	case 2:
+		m, _ := v.(map[string]interface{})
+		scrubRemotePinKeys(map[string]interface{}{"Pinning": map[string]interface{}{"RemoteServices": m}})
+	case 3:
+		if m, ok := v.(map[string]interface{}); ok {
+			delete(m, "Key")
+		}
+	}
+	f.Value = v
+	return nil
+}
+
 var configEditCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Open the config file for editing in $EDITOR.",
@@ -249,12 +422,19 @@ var configReplaceCmd = &cmds.Command{
 		ShortDescription: `
 Make sure to back up the config file first if necessary, as this operation
 can't be undone.
+
+The new config is validated before anything is written: it must parse, keep
+the node's Identity.PeerID and use a supported Datastore.Type. Use --dry-run
+to only validate it and list the keys that would change.
 `,
 	},
 
 	Arguments: []cmds.Argument{
 		cmds.FileArg("file", true, false, "The file to use as the new config."),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("dry-run", "Validate the new config and show what would change, without writing it.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
 		if err != nil {
@@ -270,12 +450,207 @@ can't be undone.
 		}
 		defer file.Close()
 
-		err = replaceConfig(r, file)
+		dryRun, _, _ := req.Option("dry-run").Bool()
+		if !dryRun {
+			err = replaceConfig(r, file)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+			}
+			return
+		}
+
+		changes, err := replaceConfigDryRun(r, file)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(&ConfigChanges{changes})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*ConfigChanges)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			if len(v.Changes) == 0 {
+				fmt.Fprintln(buf, "no changes")
+			}
+			for _, c := range v.Changes {
+				fmt.Fprintln(buf, c.String())
+			}
+			return buf, nil
+		},
+	},
+	Type: ConfigChanges{},
+}
+
+// ConfigChanges lists the config keys that 'ipfs config replace --dry-run'
+// would add ("+"), remove ("-") or modify ("~"), with their old and new values.
+type ConfigChanges struct {
+	Changes []ConfigChange
+}
+
+type ConfigChange struct {
+	Type string
+	Key  string
+	Old  interface{} `json:",omitempty"`
+	New  interface{} `json:",omitempty"`
+}
+
+// String formats c as its type and key followed by the values involved, e.g.
+// "~ Discovery.MDNS.Enabled: true -> false".
+func (c ConfigChange) String() string {
+	switch c.Type {
+	case "+":
+		return fmt.Sprintf("+ %s: %s", c.Key, configValueString(c.New))
+	case "-":
+		return fmt.Sprintf("- %s: %s", c.Key, configValueString(c.Old))
+	default:
+		return fmt.Sprintf("%s %s: %s -> %s", c.Type, c.Key, configValueString(c.Old), configValueString(c.New))
+	}
+}
+
+// configValueString formats a config value as JSON on a single line.
+func configValueString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+var configProfileCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Apply profiles to config.",
+	},
+
+	Subcommands: map[string]*cmds.Command{
+		"apply": configProfileApplyCmd,
+	},
+}
+
+var configProfileApplyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Apply profile to config.",
+		ShortDescription: `
+Applies a built-in profile, which adjusts a group of config settings at
+once, and lists the keys that changed with their old and new values. The previous config is backed up to a
+file next to it first. Use --dry-run to only list what would change.
+
+Available profiles:
+
+  server    Disables local network discovery and dialing of private
+            address ranges, and limits the number of connections, for
+            nodes running in a datacenter.
+  lowpower  Disables reproviding and bandwidth metrics, for devices with
+            few resources to spare.
+  test      Listens on random local ports only and removes all bootstrap
+            peers, for use in tests.
+`,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("profile", true, false, "The profile to apply to the config."),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("dry-run", "Show what would change without applying the profile.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		name := req.Arguments()[0]
+		transformer, ok := config.Profiles[name]
+		if !ok {
+			res.SetError(fmt.Errorf("%s is not a profile", name), cmds.ErrClient)
+			return
+		}
+
+		dryRun, _, _ := req.Option("dry-run").Bool()
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		defer r.Close()
+
+		out, err := applyProfile(r, name, transformer, dryRun)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(out)
 	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*ConfigProfileOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			if len(v.Changes) == 0 {
+				fmt.Fprintln(buf, "no changes")
+			}
+			for _, c := range v.Changes {
+				fmt.Fprintln(buf, c.String())
+			}
+			if v.Backup != "" {
+				fmt.Fprintf(buf, "previous config backed up to %s\n", v.Backup)
+			}
+			return buf, nil
+		},
+	},
+	Type: ConfigProfileOutput{},
+}
+
+// ConfigProfileOutput lists the keys changed by a profile and where the
+// previous config was backed up to. Backup is empty on a dry run or when
+// nothing changed.
+type ConfigProfileOutput struct {
+	ConfigChanges
+	Backup string `json:",omitempty"`
+}
+
+func applyProfile(r repo.Repo, name string, transformer config.Transformer, dryRun bool) (*ConfigProfileOutput, error) {
+	cur, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	oldMap, err := config.ToMap(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	// work on a copy, so a failed transformation leaves the config alone
+	cfg, err := config.FromMap(oldMap)
+	if err != nil {
+		return nil, err
+	}
+	if err := transformer(cfg); err != nil {
+		return nil, err
+	}
+
+	newMap, err := config.ToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ConfigProfileOutput{ConfigChanges: ConfigChanges{diffConfigMaps("", oldMap, newMap)}}
+	if dryRun || len(out.Changes) == 0 {
+		return out, nil
+	}
+
+	out.Backup, err = r.BackupConfig("pre-" + name + "-")
+	if err != nil {
+		return nil, fmt.Errorf("could not back up config: %s", err)
+	}
+
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 func getConfig(r repo.Repo, key string) (*ConfigField, error) {
@@ -309,25 +684,135 @@ func editConfig(filename string) error {
 }
 
 func replaceConfig(r repo.Repo, file io.Reader) error {
+	cfg, err := readReplacementConfig(r, file)
+	if err != nil {
+		return err
+	}
+
+	return r.SetConfig(cfg)
+}
+
+func replaceConfigDryRun(r repo.Repo, file io.Reader) ([]ConfigChange, error) {
+	cfg, err := readReplacementConfig(r, file)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	oldMap, err := config.ToMap(cur)
+	if err != nil {
+		return nil, err
+	}
+	newMap, err := config.ToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffConfigMaps("", oldMap, newMap), nil
+}
+
+// readReplacementConfig decodes and validates a config that is about to
+// replace the current one, carrying over the current private key and remote
+// pinning service keys.
+func readReplacementConfig(r repo.Repo, file io.Reader) (*config.Config, error) {
 	var cfg config.Config
 	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
-		return errors.New("failed to decode file as config")
+		return nil, fmt.Errorf("failed to decode file as config: %s", err)
 	}
 	if len(cfg.Identity.PrivKey) != 0 {
-		return errors.New("setting private key with API is not supported")
+		return nil, errors.New("setting private key with API is not supported")
 	}
 
 	keyF, err := getConfig(r, config.PrivKeySelector)
 	if err != nil {
-		return fmt.Errorf("Failed to get PrivKey")
+		return nil, fmt.Errorf("Failed to get PrivKey")
 	}
 
 	pkstr, ok := keyF.Value.(string)
 	if !ok {
-		return fmt.Errorf("private key in config was not a string")
+		return nil, fmt.Errorf("private key in config was not a string")
 	}
 
 	cfg.Identity.PrivKey = pkstr
 
-	return r.SetConfig(&cfg)
+	cur, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	// 'ipfs config show' leaves the service keys out, so keep the current
+	// key of every service the new config doesn't give one for.
+	for name, svc := range cfg.Pinning.RemoteServices {
+		if old, ok := cur.Pinning.RemoteServices[name]; ok && svc.Key == "" {
+			svc.Key = old.Key
+			cfg.Pinning.RemoteServices[name] = svc
+		}
+	}
+	if err := validateReplacementConfig(cur, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %s", err)
+	}
+
+	return &cfg, nil
+}
+
+// validateReplacementConfig checks the fields the node can't start without.
+func validateReplacementConfig(cur, cfg *config.Config) error {
+	if cfg.Identity.PeerID == "" {
+		return errors.New("Identity.PeerID is missing")
+	}
+	if _, err := peer.IDB58Decode(cfg.Identity.PeerID); err != nil {
+		return fmt.Errorf("Identity.PeerID is not a valid peer ID: %s", err)
+	}
+	if cfg.Identity.PeerID != cur.Identity.PeerID {
+		return fmt.Errorf("Identity.PeerID %s does not match the private key of this node (%s)", cfg.Identity.PeerID, cur.Identity.PeerID)
+	}
+
+	switch cfg.Datastore.Type {
+	case "default", "leveldb", "":
+	default:
+		return fmt.Errorf("unknown Datastore.Type: %s", cfg.Datastore.Type)
+	}
+	return nil
+}
+
+// diffConfigMaps lists the keys that differ between two config maps. Nested
+// maps are compared key by key, any other value as a whole.
+func diffConfigMaps(prefix string, a, b map[string]interface{}) []ConfigChange {
+	keys := make(map[string]struct{})
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out []ConfigChange
+	for _, k := range sorted {
+		av, aok := a[k]
+		bv, bok := b[k]
+		switch {
+		case !aok:
+			out = append(out, ConfigChange{Type: "+", Key: prefix + k, New: bv})
+		case !bok:
+			out = append(out, ConfigChange{Type: "-", Key: prefix + k, Old: av})
+		default:
+			am, amok := av.(map[string]interface{})
+			bm, bmok := bv.(map[string]interface{})
+			if amok && bmok {
+				out = append(out, diffConfigMaps(prefix+k+".", am, bm)...)
+			} else if !reflect.DeepEqual(av, bv) {
+				out = append(out, ConfigChange{Type: "~", Key: prefix + k, Old: av, New: bv})
+			}
+		}
+	}
+	return out
 }
@@ -1,12 +1,19 @@
 package dagcmd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"strings"
+	"time"
 
+	car "github.com/ipfs/go-ipfs/car"
 	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
 
 	ipldcbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
@@ -25,8 +32,13 @@ to deprecate and replace the existing 'ipfs object' command moving forward.
 		`,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"put": DagPutCmd,
-		"get": DagGetCmd,
+		"put":     DagPutCmd,
+		"get":     DagGetCmd,
+		"resolve": DagResolveCmd,
+		"stat":    DagStatCmd,
+		"walk":    DagWalkCmd,
+		"export":  DagExportCmd,
+		"import":  DagImportCmd,
 	},
 }
 
@@ -119,12 +131,31 @@ var DagGetCmd = &cmds.Command{
 		Tagline: "Get a dag node from ipfs.",
 		ShortDescription: `
 'ipfs dag get' fetches a dag node from ipfs and prints it out in the specifed format.
+
+By default the node is printed as JSON. With '--output-codec=dag-cbor', the
+canonical serialized bytes of a dag-cbor node are written out as they are
+stored, so that documents can be round-tripped without a lossy conversion.
+
+The node can also be given by a path starting with an /ipns/ name.
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("ref", true, false, "The object to get").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.StringOption("output-codec", "Codec to output the node with: 'json' or 'dag-cbor'.").Default("json"),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
+		codec, _, _ := req.Option("output-codec").String()
+		switch codec {
+		case "json":
+		case "cbor", "dag-cbor":
+			codec = "dag-cbor"
+		default:
+			res.SetError(fmt.Errorf("unrecognized output codec: %s", codec), cmds.ErrClient)
+			return
+		}
+
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -137,12 +168,32 @@ var DagGetCmd = &cmds.Command{
 			return
 		}
 
+		p, err = resolveIpnsPath(req.Context(), n, p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		obj, rem, err := n.Resolver.ResolveToLastNode(req.Context(), p)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
+		if codec == "dag-cbor" {
+			if len(rem) > 0 {
+				res.SetError(fmt.Errorf("cannot output a path within a node as dag-cbor"), cmds.ErrNormal)
+				return
+			}
+			if obj.Cid().Type() != cid.DagCBOR {
+				res.SetError(fmt.Errorf("node %s is not dag-cbor encoded", obj.Cid()), cmds.ErrNormal)
+				return
+			}
+
+			res.SetOutput(bytes.NewReader(obj.RawData()))
+			return
+		}
+
 		var out interface{} = obj
 		if len(rem) > 0 {
 			final, _, err := obj.Resolve(rem)
@@ -157,6 +208,647 @@ var DagGetCmd = &cmds.Command{
 	},
 }
 
+// resolveIpnsPath resolves the /ipns/ name p starts with, if any, so that
+// the rest of p can be followed by the path resolver.
+func resolveIpnsPath(ctx context.Context, n *core.IpfsNode, p path.Path) (path.Path, error) {
+	if !strings.HasPrefix(p.String(), "/ipns/") {
+		return p, nil
+	}
+
+	if !n.OnlineMode() {
+		if err := n.SetupOfflineRouting(); err != nil {
+			return "", err
+		}
+	}
+	return core.ResolveIPNS(ctx, n.Namesys, p)
+}
+
+// ResolveOutput is the output of 'ipfs dag resolve'
+type ResolveOutput struct {
+	Cid     *cid.Cid
+	RemPath string
+}
+
+var DagResolveCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Resolve ipld block.",
+		ShortDescription: `
+'ipfs dag resolve' follows the links along the given path and prints the CID
+of the last block it had to fetch, followed by the rest of the path, which
+points to a value within that block. Nothing is printed after the CID if the
+path ends at a block.
+
+    > ipfs dag resolve /ipfs/<cid>/a/b/c
+    <cid of the block holding b>/c
+
+Paths starting with an /ipns/ name are resolved through the name system
+first, and the rest of the path is followed from there.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("ref", true, false, "The path to resolve").EnableStdin(),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		p, err := path.ParsePath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		p, err = resolveIpnsPath(req.Context(), n, p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		obj, rem, err := n.Resolver.ResolveToLastNode(req.Context(), p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		// make sure the rest of the path exists within the block
+		if len(rem) > 0 {
+			if _, _, err := obj.Resolve(rem); err != nil {
+				res.SetError(fmt.Errorf("could not resolve %s in %s: %s", strings.Join(rem, "/"), obj.Cid(), err), cmds.ErrNormal)
+				return
+			}
+		}
+
+		res.SetOutput(&ResolveOutput{
+			Cid:     obj.Cid(),
+			RemPath: strings.Join(rem, "/"),
+		})
+	},
+	Type: ResolveOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*ResolveOutput)
+			if !ok {
+				return nil, fmt.Errorf("expected a different object in marshaler")
+			}
+
+			p := out.Cid.String()
+			if out.RemPath != "" {
+				p += "/" + out.RemPath
+			}
+			return strings.NewReader(p + "\n"), nil
+		},
+	},
+}
+
+// DagStat is the output of 'ipfs dag stat'
+type DagStat struct {
+	Size      uint64
+	NumBlocks int
+	MaxDepth  int
+	Progress  bool `json:",omitempty"`
+}
+
+var DagStatCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print statistics about a dag.",
+		ShortDescription: `
+'ipfs dag stat' walks the dag below the given root and prints the number of
+unique blocks it is made of, their total size and the length of the
+longest path from the root. Blocks shared between branches are only
+counted once.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("root", true, false, "The root of the dag to walk").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("progress", "p", "Stream progress data.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		p, err := path.ParsePath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		obj, err := n.Resolver.ResolvePath(req.Context(), p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		progress, _, _ := req.Option("progress").Bool()
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+
+		go func() {
+			defer close(out)
+
+			ds := &dagStatWalker{
+				ctx:      req.Context(),
+				dag:      n.DAG,
+				heights:  make(map[string]int),
+				out:      out,
+				progress: progress,
+			}
+
+			height, err := ds.walk(obj)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			ds.stat.MaxDepth = height
+			out <- &ds.stat
+		}()
+	},
+	Type: DagStat{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			outChan, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected a different object in marshaler")
+			}
+
+			progressLine := false
+			marshal := func(v interface{}) (io.Reader, error) {
+				stat, ok := v.(*DagStat)
+				if !ok {
+					return nil, fmt.Errorf("expected a different object in marshaler")
+				}
+
+				if stat.Progress {
+					fmt.Fprintf(res.Stderr(), "\rProcessed %d blocks (%d bytes)", stat.NumBlocks, stat.Size)
+					progressLine = true
+					return bytes.NewReader(nil), nil
+				}
+
+				if progressLine {
+					fmt.Fprintln(res.Stderr())
+				}
+
+				buf := new(bytes.Buffer)
+				fmt.Fprintf(buf, "Size: %d\n", stat.Size)
+				fmt.Fprintf(buf, "NumBlocks: %d\n", stat.NumBlocks)
+				fmt.Fprintf(buf, "MaxDepth: %d\n", stat.MaxDepth)
+				return buf, nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
+		},
+	},
+}
+
+// dagStatProgressInterval is the minimum time between two progress updates
+const dagStatProgressInterval = 500 * time.Millisecond
+
+type dagStatWalker struct {
+	ctx context.Context
+	dag dag.DAGService
+
+	// heights memoizes the height of every block already visited, which
+	// also makes sure that shared blocks are only counted once
+	heights map[string]int
+	stat    DagStat
+
+	out          chan<- interface{}
+	progress     bool
+	lastProgress time.Time
+}
+
+// walk visits the dag below nd and returns its height, that is the number of
+// links on the longest path from nd to a leaf.
+func (w *dagStatWalker) walk(nd node.Node) (int, error) {
+	key := nd.Cid().KeyString()
+	if h, ok := w.heights[key]; ok {
+		return h, nil
+	}
+
+	w.stat.NumBlocks++
+	w.stat.Size += uint64(len(nd.RawData()))
+	if err := w.sendProgress(); err != nil {
+		return 0, err
+	}
+
+	height := 0
+	for _, lnk := range nd.Links() {
+		child, err := w.dag.Get(w.ctx, lnk.Cid)
+		if err != nil {
+			return 0, err
+		}
+
+		h, err := w.walk(child)
+		if err != nil {
+			return 0, err
+		}
+		if h+1 > height {
+			height = h + 1
+		}
+	}
+
+	w.heights[key] = height
+	return height, nil
+}
+
+func (w *dagStatWalker) sendProgress() error {
+	if !w.progress || time.Since(w.lastProgress) < dagStatProgressInterval {
+		return nil
+	}
+	w.lastProgress = time.Now()
+
+	update := w.stat
+	update.Progress = true
+	select {
+	case w.out <- &update:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	}
+}
+
+// DagWalkOutput is a node matched by 'ipfs dag walk'.
+type DagWalkOutput struct {
+	Cid  *cid.Cid
+	Path string
+}
+
+var DagWalkCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Walk a dag and print the nodes matching a selector.",
+		ShortDescription: `
+'ipfs dag walk' follows the links below the given root that match the
+selector given by --selector, and prints the CID of every node whose path
+from the root matches it, as soon as it is found. Only the nodes on matching
+paths are fetched, so parts of a dag can be synced or inspected without
+fetching the whole graph.
+
+The selector is a path glob over link names: each slash separated segment
+matches the name of one link, using shell patterns ('*', '?', '[a-z]'), and
+a '**' segment matches any number of links. Links of dag-pb nodes without a
+name, such as the blocks of a file, are matched by '*'. The default selector
+'**' matches every node.
+
+    > ipfs dag walk --selector='cats/*' <cid>
+    > ipfs dag walk --selector='docs/**/*.md' <cid>
+
+--depth limits how many links deep the walk goes, and the global --timeout
+option bounds how long the whole walk may take. Nodes reachable through
+several matching paths are only printed once.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("root", true, false, "The root of the dag to walk").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("selector", "s", "The path glob selecting the nodes to print.").Default("**"),
+		cmds.IntOption("depth", "d", "The maximum number of links to follow from the root, -1 for no limit.").Default(-1),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		expr, _, _ := req.Option("selector").String()
+		sel, err := parseSelector(expr)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		depth, _, _ := req.Option("depth").Int()
+
+		p, err := path.ParsePath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		obj, err := n.Resolver.ResolvePath(req.Context(), p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+
+		go func() {
+			defer close(out)
+
+			w := &dagWalker{
+				ctx:     req.Context(),
+				dag:     n.DAG,
+				sel:     sel,
+				depth:   depth,
+				visited: make(map[string]int),
+				printed: make(map[string]bool),
+				out:     out,
+			}
+
+			if err := w.walk(obj, "", sel.start(), 0); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+			}
+		}()
+	},
+	Type: DagWalkOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			outChan, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected a different object in marshaler")
+			}
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				out, ok := v.(*DagWalkOutput)
+				if !ok {
+					return nil, fmt.Errorf("expected a different object in marshaler")
+				}
+				return strings.NewReader(out.Cid.String() + "\n"), nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
+		},
+	},
+}
+
+type dagWalker struct {
+	ctx   context.Context
+	dag   dag.DAGService
+	sel   selector
+	depth int
+
+	// visited holds the smallest depth each block was walked at in a given
+	// selector state, printed the blocks already sent out
+	visited map[string]int
+	printed map[string]bool
+
+	out chan<- interface{}
+}
+
+// walk sends nd out if the path p leading to it matches, and continues
+// with the links of nd that paths matching the selector may go through.
+func (w *dagWalker) walk(nd node.Node, p string, st selectorState, depth int) error {
+	key := nd.Cid().KeyString()
+	// a block reached again closer to the root may lead further before
+	// hitting the depth limit, so it has to be walked again
+	if d, ok := w.visited[key+st.key()]; ok && (w.depth < 0 || d <= depth) {
+		return nil
+	}
+	w.visited[key+st.key()] = depth
+
+	if w.sel.matches(st) && !w.printed[key] {
+		w.printed[key] = true
+		select {
+		case w.out <- &DagWalkOutput{Cid: nd.Cid(), Path: p}:
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+	}
+
+	if !w.sel.alive(st) || (w.depth >= 0 && depth >= w.depth) {
+		return nil
+	}
+
+	for _, l := range namedLinks(nd) {
+		next := w.sel.step(st, l.Name)
+		if len(next) == 0 {
+			continue
+		}
+
+		child, err := w.dag.Get(w.ctx, l.Cid)
+		if err != nil {
+			return err
+		}
+
+		cp := l.Name
+		if p != "" {
+			cp = p + "/" + l.Name
+		}
+		if err := w.walk(child, cp, next, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namedLinks returns the links of nd, named after the path leading to them
+// within nd. Links of dag-pb nodes carry their own names; for other formats
+// the names are the paths in the node's tree that hold a link.
+func namedLinks(nd node.Node) []*node.Link {
+	if _, ok := nd.(*dag.ProtoNode); ok {
+		return nd.Links()
+	}
+
+	var links []*node.Link
+	for _, p := range nd.Tree("", -1) {
+		lnk, rest, err := nd.ResolveLink(strings.Split(p, "/"))
+		if err != nil || len(rest) > 0 {
+			continue
+		}
+		links = append(links, &node.Link{Name: p, Size: lnk.Size, Cid: lnk.Cid})
+	}
+	return links
+}
+
+var DagExportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Export a dag as a CAR file.",
+		ShortDescription: `
+'ipfs dag export' writes every block reachable from the given root to stdout
+as a CARv1 archive, with the root as the archive's only root. Blocks shared
+between branches are only written once.
+
+    > ipfs dag export QmRoot > dag.car
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("root", true, false, "The root of the dag to export").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("progress", "p", "Display progress on stderr.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		p, err := path.ParsePath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		obj, err := n.Resolver.ResolvePath(req.Context(), p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		r, w := io.Pipe()
+		go func() {
+			bw := bufio.NewWriter(w)
+			err := car.WriteCar(req.Context(), bw, n.DAG, obj)
+			if err == nil {
+				err = bw.Flush()
+			}
+			w.CloseWithError(err)
+		}()
+		res.SetOutput(r)
+	},
+	PostRun: func(req cmds.Request, res cmds.Response) {
+		progress, _, _ := req.Option("progress").Bool()
+		if !progress || res.Output() == nil {
+			return
+		}
+
+		res.SetOutput(&exportProgressReader{
+			r:   res.Output().(io.Reader),
+			out: res.Stderr(),
+		})
+	},
+}
+
+// exportProgressReader prints the number of bytes read so far to out.
+type exportProgressReader struct {
+	r     io.Reader
+	out   io.Writer
+	bytes int64
+	last  time.Time
+}
+
+func (r *exportProgressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.bytes += int64(n)
+
+	if err != nil || time.Since(r.last) >= dagStatProgressInterval {
+		r.last = time.Now()
+		fmt.Fprintf(r.out, "\rExported %d bytes", r.bytes)
+		if err != nil {
+			fmt.Fprintln(r.out)
+		}
+	}
+	return n, err
+}
+
+// DagImport is the output of 'ipfs dag import'
+type DagImport struct {
+	Roots     []*cid.Cid
+	NumBlocks int
+	Pinned    bool `json:",omitempty"`
+}
+
+var DagImportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Import the blocks of a CAR file.",
+		ShortDescription: `
+'ipfs dag import' reads a CARv1 archive, stores all the blocks it contains
+and prints the archive's roots. Every block is rehashed and rejected if it
+does not match its CID.
+
+The roots are pinned recursively unless '--pin-roots=false' is given, in
+which case the imported blocks are removed by the next 'ipfs repo gc'.
+
+    > ipfs dag import < dag.car
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("path", true, false, "The CAR file to import").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("pin-roots", "Pin the roots of the archive recursively.").Default(true),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		fi, err := req.Files().NextFile()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer fi.Close()
+
+		pinRoots, _, _ := req.Option("pin-roots").Bool()
+
+		defer n.Blockstore.PinLock().Unlock()
+
+		roots, count, err := car.LoadCar(n.Blocks, fi)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out := &DagImport{Roots: roots, NumBlocks: count}
+		if pinRoots {
+			for _, c := range roots {
+				nd, err := n.DAG.Get(req.Context(), c)
+				if err != nil {
+					res.SetError(fmt.Errorf("pinning root %s: %s", c, err), cmds.ErrNormal)
+					return
+				}
+
+				if err := n.Pinning.Pin(req.Context(), nd, true); err != nil {
+					res.SetError(fmt.Errorf("pinning root %s: %s", c, err), cmds.ErrNormal)
+					return
+				}
+			}
+
+			if err := n.Pinning.Flush(); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			out.Pinned = true
+		}
+
+		res.SetOutput(out)
+	},
+	Type: DagImport{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*DagImport)
+			if !ok {
+				return nil, fmt.Errorf("expected a different object in marshaler")
+			}
+
+			buf := new(bytes.Buffer)
+			fmt.Fprintf(buf, "NumBlocks: %d\n", out.NumBlocks)
+			for _, c := range out.Roots {
+				if out.Pinned {
+					fmt.Fprintf(buf, "Root: %s (pinned)\n", c)
+				} else {
+					fmt.Fprintf(buf, "Root: %s\n", c)
+				}
+			}
+			return buf, nil
+		},
+	},
+}
+
 func convertJsonToType(r io.Reader, format string) (node.Node, error) {
 	switch format {
 	case "cbor", "dag-cbor":
@@ -0,0 +1,77 @@
+package dagcmd
+
+import (
+	"context"
+	"testing"
+
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
+)
+
+func TestWalkDiamondDepth(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+
+	// root -a-> mid -x-> x -leaf-> leaf
+	// root -b-> x
+	leaf := dag.NodeWithData([]byte("leaf"))
+	x := dag.NodeWithData([]byte("x"))
+	mid := dag.NodeWithData([]byte("mid"))
+	root := dag.NodeWithData([]byte("root"))
+	if err := x.AddNodeLinkClean("leaf", leaf); err != nil {
+		t.Fatal(err)
+	}
+	if err := mid.AddNodeLinkClean("x", x); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLinkClean("a", mid); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLinkClean("b", x); err != nil {
+		t.Fatal(err)
+	}
+	for _, nd := range []*dag.ProtoNode{leaf, x, mid, root} {
+		if _, err := ds.Add(nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sel, err := parseSelector("**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan interface{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(out)
+		w := &dagWalker{
+			ctx:     ctx,
+			dag:     ds,
+			sel:     sel,
+			depth:   2,
+			visited: make(map[string]int),
+			printed: make(map[string]bool),
+			out:     out,
+		}
+		errs <- w.walk(root, "", sel.start(), 0)
+	}()
+
+	got := make(map[string]string)
+	for v := range out {
+		o := v.(*DagWalkOutput)
+		got[o.Cid.KeyString()] = o.Path
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// x is first reached at depth 2 through mid, where the walk stops, but
+	// leaf is within the limit through b
+	if p, ok := got[leaf.Cid().KeyString()]; !ok || p != "b/leaf" {
+		t.Fatalf("expected leaf to be walked at b/leaf, got %q (found: %t)", p, ok)
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(got))
+	}
+}
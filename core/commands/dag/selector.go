@@ -0,0 +1,103 @@
+package dagcmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// selector is a path glob selecting nodes of a dag by the names of the links
+// leading to them from the root. It is a sequence of slash separated
+// segments; each segment is a pattern as understood by path.Match that
+// matches the name of a single link, except for "**", which matches any
+// number of links.
+type selector []string
+
+func parseSelector(expr string) (selector, error) {
+	expr = strings.Trim(expr, "/")
+	if expr == "" {
+		return selector{}, nil
+	}
+
+	sel := selector(strings.Split(expr, "/"))
+	for _, seg := range sel {
+		if seg == "**" {
+			continue
+		}
+		if strings.Contains(seg, "**") {
+			return nil, fmt.Errorf("invalid selector segment %q: ** must be a whole segment", seg)
+		}
+		if _, err := path.Match(seg, seg); err != nil {
+			return nil, fmt.Errorf("invalid selector segment %q: %s", seg, err)
+		}
+	}
+	return sel, nil
+}
+
+// selectorState is the set of selector segments the path walked so far may
+// continue matching at. len(sel) in the set means the path matches.
+type selectorState []int
+
+// start returns the state for the root of the dag.
+func (sel selector) start() selectorState {
+	return sel.closure([]int{0})
+}
+
+// step returns the state after following a link with the given name, which
+// may hold several path segments.
+func (sel selector) step(st selectorState, name string) selectorState {
+	for _, seg := range strings.Split(name, "/") {
+		var next []int
+		for _, i := range st {
+			if i == len(sel) {
+				continue
+			}
+			if sel[i] == "**" {
+				next = append(next, i)
+			} else if ok, _ := path.Match(sel[i], seg); ok {
+				next = append(next, i+1)
+			}
+		}
+		st = sel.closure(next)
+		if len(st) == 0 {
+			break
+		}
+	}
+	return st
+}
+
+// closure adds the positions reachable by letting a "**" match no links.
+func (sel selector) closure(pos []int) selectorState {
+	set := make(map[int]bool)
+	for _, i := range pos {
+		for !set[i] {
+			set[i] = true
+			if i == len(sel) || sel[i] != "**" {
+				break
+			}
+			i++
+		}
+	}
+
+	st := make(selectorState, 0, len(set))
+	for i := range set {
+		st = append(st, i)
+	}
+	sort.Ints(st)
+	return st
+}
+
+// matches reports whether the path walked to reach st matches the selector.
+func (sel selector) matches(st selectorState) bool {
+	return len(st) > 0 && st[len(st)-1] == len(sel)
+}
+
+// alive reports whether paths continuing from st can match the selector.
+func (sel selector) alive(st selectorState) bool {
+	return len(st) > 0 && st[0] < len(sel)
+}
+
+func (st selectorState) key() string {
+	return fmt.Sprint([]int(st))
+}
@@ -0,0 +1,69 @@
+package dagcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelector(t *testing.T) {
+	cases := []struct {
+		expr    string
+		path    string
+		matches bool
+		alive   bool
+	}{
+		{"", "", true, false},
+		{"**", "", true, true},
+		{"**", "a/b/c", true, true},
+		{"a/*", "a", false, true},
+		{"a/*", "a/b", true, false},
+		{"a/*", "b/c", false, false},
+		{"a/*", "a/b/c", false, false},
+		{"a/**/c", "a/c", true, true},
+		{"a/**/c", "a/b/x/c", true, true},
+		{"a/**/c", "a/b", false, true},
+		{"cats/*/water", "cats/1/water", true, false},
+		{"cats/[0-9]", "cats/0", true, false},
+		{"*", "", false, true},
+	}
+
+	for _, c := range cases {
+		sel, err := parseSelector(c.expr)
+		if err != nil {
+			t.Fatalf("%q: %s", c.expr, err)
+		}
+
+		st := sel.start()
+		if c.path != "" {
+			for _, name := range strings.Split(c.path, "/") {
+				st = sel.step(st, name)
+			}
+		}
+
+		if sel.matches(st) != c.matches {
+			t.Errorf("%q on %q: expected matches to be %t", c.expr, c.path, c.matches)
+		}
+		if sel.alive(st) != c.alive {
+			t.Errorf("%q on %q: expected alive to be %t", c.expr, c.path, c.alive)
+		}
+	}
+}
+
+func TestSelectorMultiSegmentNames(t *testing.T) {
+	sel, err := parseSelector("/cats/*/water/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st := sel.step(sel.start(), "cats/1/water"); !sel.matches(st) {
+		t.Fatal("link names holding several segments should match segment by segment")
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, expr := range []string{"a/b**", "[", "a/[x"} {
+		if _, err := parseSelector(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
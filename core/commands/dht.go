@@ -3,12 +3,17 @@ package commands
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
 
@@ -19,6 +24,8 @@ import (
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	kb "gx/ipfs/QmaQG6fJdzn2532WHoPdVwKqftXr6iCSr5NtWyGi1BHytT/go-libp2p-kbucket"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
 	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
@@ -40,10 +47,30 @@ var DhtCmd = &cmds.Command{
 	},
 }
 
+// DhtQueryEvent is a query event of 'ipfs dht query'. With --verbose, the
+// final closest peers carry their kademlia (XOR) distance to the queried key
+// as hex, and the query step at which they were first returned by another
+// peer. A step of 0 means the peer was already known before the query.
+type DhtQueryEvent struct {
+	ID        string
+	Type      notif.QueryEventType
+	Responses []*pstore.PeerInfo
+	Extra     string
+	Distance  string `json:",omitempty"`
+	Step      int    `json:",omitempty"`
+}
+
 var queryDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:          "Find the closest Peer IDs to a given Peer ID by querying the DHT.",
 		ShortDescription: "Outputs a list of newline-delimited Peer IDs.",
+		LongDescription: `
+Outputs a list of newline-delimited Peer IDs.
+
+With --verbose, every step of the query is printed, followed by the closest
+peers found, each with its XOR distance to the queried key and the query step
+at which it was discovered. Step 0 means the peer was already known locally.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -68,7 +95,10 @@ var queryDhtCmd = &cmds.Command{
 		events := make(chan *notif.QueryEvent)
 		ctx := notif.RegisterForQueryEvents(req.Context(), events)
 
+		verbose, _, _ := req.Option("verbose").Bool()
+
 		k := string(b58.Decode(req.Arguments()[0]))
+		target := kb.ConvertKey(k)
 
 		closestPeers, err := dht.GetClosestPeers(ctx, k)
 		if err != nil {
@@ -91,8 +121,34 @@ var queryDhtCmd = &cmds.Command{
 
 		go func() {
 			defer close(outChan)
+
+			step := 0
+			discovered := make(map[peer.ID]int)
 			for e := range events {
-				outChan <- e
+				out := &DhtQueryEvent{
+					ID:        e.ID.Pretty(),
+					Type:      e.Type,
+					Responses: e.Responses,
+					Extra:     e.Extra,
+				}
+
+				if verbose {
+					switch e.Type {
+					case notif.PeerResponse:
+						step++
+						for _, p := range e.Responses {
+							if _, ok := discovered[p.ID]; !ok {
+								discovered[p.ID] = step
+							}
+						}
+					case notif.FinalPeer:
+						dist := u.XOR(target, kb.ConvertPeerID(e.ID))
+						out.Distance = hex.EncodeToString(dist)
+						out.Step = discovered[e.ID]
+					}
+				}
+
+				outChan <- out
 			}
 		}()
 	},
@@ -112,7 +168,7 @@ var queryDhtCmd = &cmds.Command{
 			}
 
 			marshal := func(v interface{}) (io.Reader, error) {
-				obj, ok := v.(*notif.QueryEvent)
+				obj, ok := v.(*DhtQueryEvent)
 				if !ok {
 					return nil, u.ErrCast()
 				}
@@ -120,7 +176,25 @@ var queryDhtCmd = &cmds.Command{
 				verbose, _, _ := res.Request().Option("v").Bool()
 
 				buf := new(bytes.Buffer)
-				printEvent(obj, buf, verbose, pfm)
+				if obj.Type == notif.FinalPeer {
+					if verbose {
+						fmt.Fprintf(buf, "%s: closest peer %s distance %s step %d\n",
+							time.Now().Format("15:04:05.000"), obj.ID, obj.Distance, obj.Step)
+					}
+					return buf, nil
+				}
+
+				pid, err := peer.IDB58Decode(obj.ID)
+				if err != nil && obj.ID != "" {
+					return nil, err
+				}
+
+				printEvent(&notif.QueryEvent{
+					ID:        pid,
+					Type:      obj.Type,
+					Responses: obj.Responses,
+					Extra:     obj.Extra,
+				}, buf, verbose, pfm)
 				return buf, nil
 			}
 
@@ -131,13 +205,22 @@ var queryDhtCmd = &cmds.Command{
 			}, nil
 		},
 	},
-	Type: notif.QueryEvent{},
+	Type: DhtQueryEvent{},
 }
 
 var findProvidersDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:          "Find peers in the DHT that can provide a specific value, given a key.",
 		ShortDescription: "Outputs a list of newline-delimited provider Peer IDs.",
+		LongDescription: `
+Outputs a list of newline-delimited provider Peer IDs. Each provider is listed
+once, even if several peers return it, and the query stops as soon as
+--num-providers distinct providers were found.
+
+With --verbose, the steps of the query are printed, and every provider is
+followed by the addresses it can be reached at: those in its provider record
+and those already known to the node.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -170,18 +253,19 @@ var findProvidersDhtCmd = &cmds.Command{
 			return
 		}
 
-		outChan := make(chan interface{})
-		res.SetOutput((<-chan interface{})(outChan))
-
-		events := make(chan *notif.QueryEvent)
-		ctx := notif.RegisterForQueryEvents(req.Context(), events)
-
 		c, err := cid.Decode(req.Arguments()[0])
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
+		outChan := make(chan interface{})
+		res.SetOutput((<-chan interface{})(outChan))
+
+		events := make(chan *notif.QueryEvent)
+		ctx, cancel := context.WithCancel(req.Context())
+		ctx = notif.RegisterForQueryEvents(ctx, events)
+
 		pchan := dht.FindProvidersAsync(ctx, c, numProviders)
 		go func() {
 			defer close(outChan)
@@ -192,12 +276,29 @@ var findProvidersDhtCmd = &cmds.Command{
 
 		go func() {
 			defer close(events)
+			defer cancel()
+
+			seen := make(map[peer.ID]struct{})
 			for p := range pchan {
-				np := p
+				if len(seen) >= numProviders {
+					// the query was cancelled below; wait for it to
+					// wind down before closing events.
+					continue
+				}
+				if _, ok := seen[p.ID]; ok {
+					continue
+				}
+				seen[p.ID] = struct{}{}
+
+				np := providerInfo(n.Peerstore, p)
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 					Type:      notif.Provider,
 					Responses: []*pstore.PeerInfo{&np},
 				})
+
+				if len(seen) >= numProviders {
+					cancel()
+				}
 			}
 		}()
 	},
@@ -250,9 +351,31 @@ var findProvidersDhtCmd = &cmds.Command{
 	Type: notif.QueryEvent{},
 }
 
+// providerInfo returns p with the addresses the peerstore knows for it
+// added to those from the provider record.
+func providerInfo(ps pstore.Peerstore, p pstore.PeerInfo) pstore.PeerInfo {
+	addrs := append([]ma.Multiaddr(nil), p.Addrs...)
+	for _, a := range ps.Addrs(p.ID) {
+		if !containsAddr(addrs, a) {
+			addrs = append(addrs, a)
+		}
+	}
+	return pstore.PeerInfo{ID: p.ID, Addrs: addrs}
+}
+
 var provideRefDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Announce to the network that you are providing given values.",
+		ShortDescription: `
+Announces provider records for the given keys, which must be available
+locally. With --recursive, every block reachable from the given keys is
+announced as well. Once done, the number of provider records published and
+the number of peers they were sent to is printed.
+
+This is useful to announce content right away instead of waiting for the
+next reprovide run. Use the global --timeout option to bound how long
+announcing may take.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -304,20 +427,32 @@ var provideRefDhtCmd = &cmds.Command{
 		events := make(chan *notif.QueryEvent)
 		ctx := notif.RegisterForQueryEvents(req.Context(), events)
 
+		var provided int
 		go func() {
 			defer close(outChan)
+
+			peers := make(map[peer.ID]struct{})
 			for e := range events {
+				if e.Type == notif.FinalPeer {
+					peers[e.ID] = struct{}{}
+				}
 				outChan <- e
 			}
+
+			// events is closed once providing is done, so provided is final
+			outChan <- &notif.QueryEvent{
+				Type:  notif.Value,
+				Extra: fmt.Sprintf("provided %d records to %d peers\n", provided, len(peers)),
+			}
 		}()
 
 		go func() {
 			defer close(events)
 			var err error
 			if rec {
-				err = provideKeysRec(ctx, n.Routing, n.DAG, cids)
+				provided, err = provideKeysRec(ctx, n.Routing, n.DAG, cids)
 			} else {
-				err = provideKeys(ctx, n.Routing, cids)
+				provided, err = provideKeys(ctx, n.Routing, cids)
 			}
 			if err != nil {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
@@ -341,6 +476,9 @@ var provideRefDhtCmd = &cmds.Command{
 						fmt.Fprintf(out, "sending provider record to peer %s\n", obj.ID)
 					}
 				},
+				notif.Value: func(obj *notif.QueryEvent, out io.Writer, verbose bool) {
+					fmt.Fprint(out, obj.Extra)
+				},
 			}
 
 			marshal := func(v interface{}) (io.Reader, error) {
@@ -364,24 +502,28 @@ var provideRefDhtCmd = &cmds.Command{
 	Type: notif.QueryEvent{},
 }
 
-func provideKeys(ctx context.Context, r routing.IpfsRouting, cids []*cid.Cid) error {
-	for _, c := range cids {
+// provideKeys announces the given cids and returns how many of them were
+// provided before an error, if any, occurred.
+func provideKeys(ctx context.Context, r routing.IpfsRouting, cids []*cid.Cid) (int, error) {
+	for i, c := range cids {
 		err := r.Provide(ctx, c, true)
 		if err != nil {
-			return err
+			return i, err
 		}
 	}
-	return nil
+	return len(cids), nil
 }
 
-func provideKeysRec(ctx context.Context, r routing.IpfsRouting, dserv dag.DAGService, cids []*cid.Cid) error {
+// provideKeysRec is like provideKeys but also announces all the blocks
+// reachable from the given cids, each one only once.
+func provideKeysRec(ctx context.Context, r routing.IpfsRouting, dserv dag.DAGService, cids []*cid.Cid) (int, error) {
 	provided := cid.NewSet()
 	for _, c := range cids {
 		kset := cid.NewSet()
 
 		err := dag.EnumerateChildrenAsync(ctx, dag.GetLinksDirect(dserv), c, kset.Visit)
 		if err != nil {
-			return err
+			return provided.Len(), err
 		}
 
 		for _, k := range kset.Keys() {
@@ -391,19 +533,33 @@ func provideKeysRec(ctx context.Context, r routing.IpfsRouting, dserv dag.DAGSer
 
 			err = r.Provide(ctx, k, true)
 			if err != nil {
-				return err
+				return provided.Len(), err
 			}
 			provided.Add(k)
 		}
 	}
 
-	return nil
+	return provided.Len(), nil
 }
 
 var findPeerDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
-		Tagline:          "Query the DHT for all of the multiaddresses associated with a Peer ID.",
-		ShortDescription: "Outputs a list of newline-delimited multiaddresses.",
+		Tagline: "Query the DHT for all of the multiaddresses associated with a Peer ID.",
+		ShortDescription: `
+Outputs a list of newline-delimited multiaddresses, each followed by a
+comma-separated list of where it is known from:
+
+  dht        returned by the DHT lookup
+  peerstore  stored in the local peerstore
+  connected  the remote address of an open connection to the peer
+
+The DHT is not queried for peers this node is connected to, so their
+addresses only come from the peerstore and the open connections. The
+peerstore doesn't tell how long it keeps an address, so no expiry times are
+shown.
+
+The command returns as soon as the peer has been found.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -446,7 +602,27 @@ var findPeerDhtCmd = &cmds.Command{
 
 		go func() {
 			defer close(events)
-			pi, err := dht.FindPeer(ctx, pid)
+
+			// FindPeer answers from the peerstore for connected peers,
+			// check for those first so their addresses aren't credited to
+			// the DHT.
+			var dhtAddrs []ma.Multiaddr
+			pi := dht.FindLocal(pid)
+			if pi.ID == "" {
+				var err error
+				pi, err = dht.FindPeer(ctx, pid)
+				if err != nil {
+					notif.PublishQueryEvent(ctx, &notif.QueryEvent{
+						Type:  notif.QueryError,
+						Extra: err.Error(),
+					})
+					return
+				}
+				dhtAddrs = pi.Addrs
+			}
+
+			addrs, sources := findPeerSources(n, pi.ID, dhtAddrs)
+			extra, err := json.Marshal(sources)
 			if err != nil {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 					Type:  notif.QueryError,
@@ -457,7 +633,8 @@ var findPeerDhtCmd = &cmds.Command{
 
 			notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 				Type:      notif.FinalPeer,
-				Responses: []*pstore.PeerInfo{&pi},
+				Responses: []*pstore.PeerInfo{{ID: pi.ID, Addrs: addrs}},
+				Extra:     string(extra),
 			})
 		}()
 	},
@@ -472,9 +649,21 @@ var findPeerDhtCmd = &cmds.Command{
 
 			pfm := pfuncMap{
 				notif.FinalPeer: func(obj *notif.QueryEvent, out io.Writer, verbose bool) {
-					pi := obj.Responses[0]
-					for _, a := range pi.Addrs {
-						fmt.Fprintf(out, "%s\n", a)
+					var sources []PeerAddrSources
+					if obj.Extra != "" {
+						if err := json.Unmarshal([]byte(obj.Extra), &sources); err != nil {
+							log.Debugf("cannot decode address sources: %s", err)
+						}
+					}
+					if len(sources) == 0 {
+						for _, a := range obj.Responses[0].Addrs {
+							fmt.Fprintf(out, "%s\n", a)
+						}
+						return
+					}
+
+					for _, s := range sources {
+						fmt.Fprintf(out, "%s %s\n", s.Addr, strings.Join(s.Sources, ","))
 					}
 				},
 			}
@@ -499,6 +688,54 @@ var findPeerDhtCmd = &cmds.Command{
 	Type: notif.QueryEvent{},
 }
 
+// PeerAddrSources lists where 'dht findpeer' learned about one address of a
+// peer. It is sent JSON encoded in the Extra field of the FinalPeer event.
+type PeerAddrSources struct {
+	Addr    string
+	Sources []string
+}
+
+// findPeerSources merges the addresses the DHT returned for a peer with the
+// ones in the peerstore and those of open connections to it, and returns them
+// along with the sources of each address.
+func findPeerSources(n *core.IpfsNode, p peer.ID, dhtAddrs []ma.Multiaddr) ([]ma.Multiaddr, []PeerAddrSources) {
+	var addrs []ma.Multiaddr
+	var sources []PeerAddrSources
+	index := make(map[string]int)
+
+	add := func(a ma.Multiaddr, source string) {
+		key := a.String()
+		i, ok := index[key]
+		if !ok {
+			i = len(sources)
+			index[key] = i
+			addrs = append(addrs, a)
+			sources = append(sources, PeerAddrSources{Addr: key})
+		}
+
+		for _, s := range sources[i].Sources {
+			if s == source {
+				return
+			}
+		}
+		sources[i].Sources = append(sources[i].Sources, source)
+	}
+
+	for _, a := range dhtAddrs {
+		add(a, "dht")
+	}
+	for _, a := range n.Peerstore.Addrs(p) {
+		add(a, "peerstore")
+	}
+	if n.PeerHost != nil {
+		for _, c := range n.PeerHost.Network().ConnsToPeer(p) {
+			add(c.RemoteMultiaddr(), "connected")
+		}
+	}
+
+	return addrs, sources
+}
+
 var getValueDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Given a key, query the DHT for its best value.",
@@ -510,6 +747,10 @@ this context 'best' means the record that is most desirable. There is no one
 metric for 'best': it depends entirely on the key type. For IPNS, 'best' is
 the record that is both valid and has the highest sequence number (freshest).
 Different key types can specify other 'best' rules.
+
+Only keys in a namespace this node can validate, such as /ipns or /pk, are
+accepted. With '--verbose', the number of peers that returned a value is
+printed along with the best value.
 `,
 	},
 
@@ -544,6 +785,11 @@ Different key types can specify other 'best' rules.
 			return
 		}
 
+		if err := checkDhtNamespace(dht, dhtkey); err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
 		go func() {
 			defer close(outChan)
 			for e := range events {
@@ -553,7 +799,7 @@ Different key types can specify other 'best' rules.
 
 		go func() {
 			defer close(events)
-			val, err := dht.GetValue(ctx, dhtkey)
+			val, from, err := getBestValue(ctx, dht, dhtkey)
 			if err != nil {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 					Type:  notif.QueryError,
@@ -561,8 +807,9 @@ Different key types can specify other 'best' rules.
 				})
 			} else {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
-					Type:  notif.Value,
-					Extra: string(val),
+					Type:      notif.Value,
+					Extra:     string(val),
+					Responses: from,
 				})
 			}
 		}()
@@ -579,7 +826,7 @@ Different key types can specify other 'best' rules.
 			pfm := pfuncMap{
 				notif.Value: func(obj *notif.QueryEvent, out io.Writer, verbose bool) {
 					if verbose {
-						fmt.Fprintf(out, "got value: '%s'\n", obj.Extra)
+						fmt.Fprintf(out, "got value: '%s' (%d peers returned values)\n", obj.Extra, len(obj.Responses))
 					} else {
 						fmt.Fprintln(out, obj.Extra)
 					}
@@ -620,7 +867,8 @@ Keys have two parts: a keytype (foo) and the key name (bar). IPNS uses the
 specifically formatted (protocol buffer).
 
 You may only use keytypes that are supported in your ipfs binary: currently
-this is only /ipns. Unless you have a relatively deep understanding of the
+this is /ipns and /pk. Keys of any other type are rejected before anything is
+written. Unless you have a relatively deep understanding of the
 go-ipfs DHT internals, you likely want to be using 'ipfs name publish' instead
 of this.
 
@@ -662,6 +910,11 @@ NOTE: A value may not exceed 2048 bytes.
 			return
 		}
 
+		if err := checkDhtNamespace(dht, key); err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
 		data := req.Arguments()[1]
 
 		go func() {
@@ -777,6 +1030,53 @@ func printEvent(obj *notif.QueryEvent, out io.Writer, verbose bool, override pfu
 	}
 }
 
+// dhtGetValuesCount is the number of values 'dht get' collects before picking
+// the best one, the same number the DHT itself uses for GetValue.
+const dhtGetValuesCount = 16
+
+// checkDhtNamespace returns an error unless the dht has a validator for the
+// namespace of the escaped key.
+func checkDhtNamespace(dht *ipdht.IpfsDHT, key string) error {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) == 3 && parts[0] == "" {
+		if _, ok := dht.Validator[parts[1]]; ok {
+			return nil
+		}
+	}
+
+	var namespaces []string
+	for ns := range dht.Validator {
+		namespaces = append(namespaces, "/"+ns)
+	}
+	sort.Strings(namespaces)
+	return fmt.Errorf("key is not in a namespace this node can validate, supported namespaces are: %s", strings.Join(namespaces, ", "))
+}
+
+// getBestValue collects values for key from the dht and returns the best one,
+// along with the peers that returned a value.
+func getBestValue(ctx context.Context, dht *ipdht.IpfsDHT, key string) ([]byte, []*pstore.PeerInfo, error) {
+	vals, err := dht.GetValues(ctx, key, dhtGetValuesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(vals) == 0 {
+		return nil, nil, routing.ErrNotFound
+	}
+
+	recs := make([][]byte, len(vals))
+	from := make([]*pstore.PeerInfo, len(vals))
+	for i, v := range vals {
+		recs[i] = v.Val
+		from[i] = &pstore.PeerInfo{ID: v.From}
+	}
+
+	i, err := dht.Selector.BestRecord(key, recs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return recs[i], from, nil
+}
+
 func escapeDhtKey(s string) (string, error) {
 	parts := path.SplitList(s)
 	switch len(parts) {
@@ -789,3 +1089,128 @@ func escapeDhtKey(s string) (string, error) {
 		return "", errors.New("invalid key")
 	}
 }
+
+// DhtStatOutput is the output of 'ipfs stats dht'.
+type DhtStatOutput struct {
+	ID         string
+	KademliaID string
+	Size       int
+	Buckets    []DhtBucket
+}
+
+// DhtBucket is one kbucket of the routing table. Index is the number of
+// leading bits its peers share with the node's own kademlia ID.
+type DhtBucket struct {
+	Index int
+	Size  int
+	Peers []string `json:",omitempty"`
+}
+
+var statDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print statistics about the DHT routing table.",
+		ShortDescription: `
+'ipfs stats dht' prints the node's kademlia ID (the SHA-256 of its peer ID
+that DHT distances are measured from), the number of peers in the routing
+table, and the number of peers in each kbucket. Bucket N holds the peers
+whose kademlia IDs share exactly N leading bits with the node's own.
+
+With --buckets, the peers of each bucket are listed below it. The DHT does
+not record when a peer was last useful, so no timestamps are shown.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("buckets", "b", "List the peers in each bucket.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		dht, ok := n.Routing.(*ipdht.IpfsDHT)
+		if !ok {
+			res.SetError(ErrNotDHT, cmds.ErrNormal)
+			return
+		}
+
+		listPeers, _, _ := req.Option("buckets").Bool()
+		res.SetOutput(dhtStat(n.Identity, dht.RoutingTable().ListPeers(), listPeers))
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*DhtStatOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			fmt.Fprintln(buf, "DHT routing table")
+			fmt.Fprintf(buf, "\tID: %s\n", out.ID)
+			fmt.Fprintf(buf, "\tKademlia ID: %s\n", out.KademliaID)
+			fmt.Fprintf(buf, "\tPeers: %d\n", out.Size)
+			for _, b := range out.Buckets {
+				fmt.Fprintf(buf, "\tBucket %d: %d\n", b.Index, b.Size)
+				for _, p := range b.Peers {
+					fmt.Fprintf(buf, "\t\t%s\n", p)
+				}
+			}
+			return buf, nil
+		},
+	},
+	Type: DhtStatOutput{},
+}
+
+// dhtStat groups peers into kbuckets relative to self. Buckets are listed
+// from 0 up to the last non-empty one, including the empty ones in between.
+func dhtStat(self peer.ID, peers []peer.ID, listPeers bool) *DhtStatOutput {
+	selfKey := kb.ConvertPeerID(self)
+	out := &DhtStatOutput{
+		ID:         self.Pretty(),
+		KademliaID: hex.EncodeToString(selfKey),
+		Buckets:    []DhtBucket{},
+	}
+
+	byBucket := make(map[int][]string)
+	last := -1
+	for _, p := range peers {
+		if p == self {
+			continue
+		}
+		i := commonPrefixLen(selfKey, kb.ConvertPeerID(p))
+		byBucket[i] = append(byBucket[i], p.Pretty())
+		if i > last {
+			last = i
+		}
+		out.Size++
+	}
+
+	for i := 0; i <= last; i++ {
+		b := DhtBucket{Index: i, Size: len(byBucket[i])}
+		if listPeers {
+			b.Peers = byBucket[i]
+			sort.Strings(b.Peers)
+		}
+		out.Buckets = append(out.Buckets, b)
+	}
+	return out
+}
+
+// commonPrefixLen returns the number of leading bits a and b have in common.
+func commonPrefixLen(a, b []byte) int {
+	x := u.XOR(a, b)
+	for i, c := range x {
+		for j := 0; j < 8; j++ {
+			if c&(0x80>>uint(j)) != 0 {
+				return i*8 + j
+			}
+		}
+	}
+	return len(x) * 8
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/ipfs/go-ipfs/namesys"
 	tu "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
 func TestKeyTranslation(t *testing.T) {
@@ -29,3 +31,50 @@ func TestKeyTranslation(t *testing.T) {
 		t.Fatal("keys didnt match!")
 	}
 }
+
+func TestCommonPrefixLen(t *testing.T) {
+	cases := []struct {
+		a, b []byte
+		cpl  int
+	}{
+		{[]byte{0x00, 0x00}, []byte{0x00, 0x00}, 16},
+		{[]byte{0x80, 0x00}, []byte{0x00, 0x00}, 0},
+		{[]byte{0x0f, 0x00}, []byte{0x0e, 0x00}, 7},
+		{[]byte{0xff, 0xff}, []byte{0xff, 0x7f}, 8},
+	}
+	for _, c := range cases {
+		if cpl := commonPrefixLen(c.a, c.b); cpl != c.cpl {
+			t.Errorf("commonPrefixLen(%x, %x) = %d, want %d", c.a, c.b, cpl, c.cpl)
+		}
+	}
+}
+
+func TestDhtStat(t *testing.T) {
+	self := tu.RandPeerIDFatal(t)
+	peers := []peer.ID{self}
+	for i := 0; i < 10; i++ {
+		peers = append(peers, tu.RandPeerIDFatal(t))
+	}
+
+	out := dhtStat(self, peers, true)
+	if out.Size != 10 {
+		t.Fatalf("expected 10 peers, got %d", out.Size)
+	}
+
+	total := 0
+	for i, b := range out.Buckets {
+		if b.Index != i {
+			t.Fatalf("bucket %d has index %d", i, b.Index)
+		}
+		if len(b.Peers) != b.Size {
+			t.Fatalf("bucket %d lists %d peers, but has size %d", i, len(b.Peers), b.Size)
+		}
+		total += b.Size
+	}
+	if total != out.Size {
+		t.Fatalf("buckets hold %d peers, expected %d", total, out.Size)
+	}
+	if last := out.Buckets[len(out.Buckets)-1]; last.Size == 0 {
+		t.Fatal("last bucket is empty")
+	}
+}
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"strings"
 
@@ -40,6 +42,13 @@ The resolver can recursively resolve:
 	dnslink=/ipns/ipfs.io
 	> ipfs dns -r recursive.ipfs.io
 	/ipfs/QmRzTuh2Lpuz7Gr39stNr6mTFdqAghsZec1JoUnfySUzcy
+
+Recursive resolution fails if the chain of links visits the same domain
+twice. Use --verbose to print every link followed along the way:
+
+	> ipfs dns -r -v recursive.ipfs.io
+	recursive.ipfs.io -> /ipns/ipfs.io
+	ipfs.io -> /ipfs/QmRzTuh2Lpuz7Gr39stNr6mTFdqAghsZec1JoUnfySUzcy
 `,
 	},
 
@@ -48,36 +57,86 @@ The resolver can recursively resolve:
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("recursive", "r", "Resolve until the result is not a DNS link.").Default(false),
+		cmds.BoolOption("verbose", "v", "Print each DNS link followed during resolution.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
 		recursive, _, _ := req.Option("recursive").Bool()
+		verbose, _, _ := req.Option("verbose").Bool()
 		name := req.Arguments()[0]
 		resolver := namesys.NewDNSResolver()
 
-		depth := 1
-		if recursive {
-			depth = namesys.DefaultDepthLimit
+		if !recursive {
+			output, err := resolver.ResolveN(req.Context(), name, 1)
+			if err == namesys.ErrResolveFailed {
+				res.SetError(err, cmds.ErrNotFound)
+				return
+			}
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			out := &DNSOutput{ResolvedPath: ResolvedPath{output}}
+			if verbose {
+				out.Links = []*DNSLink{{Domain: strings.SplitN(name, "/", 2)[0], Value: output.String()}}
+			}
+			res.SetOutput(out)
+			return
 		}
-		output, err := resolver.ResolveN(req.Context(), name, depth)
-		if err == namesys.ErrResolveFailed {
+
+		chain, err := namesys.ResolveChain(req.Context(), resolver, name)
+		switch err {
+		case nil:
+		case namesys.ErrResolveFailed:
 			res.SetError(err, cmds.ErrNotFound)
 			return
-		}
-		if err != nil {
+		case namesys.ErrResolveLoop:
+			last := chain[len(chain)-1].Value.String()
+			res.SetError(fmt.Errorf("dnslink loop detected: %s was visited twice", strings.SplitN(strings.TrimPrefix(last, "/ipns/"), "/", 2)[0]), cmds.ErrNormal)
+			return
+		default:
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
-		res.SetOutput(&ResolvedPath{output})
+
+		out := &DNSOutput{ResolvedPath: ResolvedPath{chain[len(chain)-1].Value}}
+		if verbose {
+			for _, l := range chain {
+				out.Links = append(out.Links, &DNSLink{Domain: l.Domain, Value: l.Value.String()})
+			}
+		}
+		res.SetOutput(out)
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			output, ok := res.Output().(*ResolvedPath)
+			output, ok := res.Output().(*DNSOutput)
 			if !ok {
 				return nil, util.ErrCast()
 			}
-			return strings.NewReader(output.Path.String() + "\n"), nil
+
+			if len(output.Links) == 0 {
+				return strings.NewReader(output.Path.String() + "\n"), nil
+			}
+
+			buf := new(bytes.Buffer)
+			for _, l := range output.Links {
+				fmt.Fprintf(buf, "%s -> %s\n", l.Domain, l.Value)
+			}
+			return buf, nil
 		},
 	},
-	Type: ResolvedPath{},
+	Type: DNSOutput{},
+}
+
+// DNSOutput is the output of the dns command. Links is only set with
+// --verbose and lists every link followed, in order.
+type DNSOutput struct {
+	ResolvedPath
+	Links []*DNSLink `json:",omitempty"`
+}
+
+type DNSLink struct {
+	Domain string
+	Value  string
 }
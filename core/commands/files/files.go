@@ -8,17 +8,22 @@ import (
 	"io"
 	"os"
 	gopath "path"
+	"strconv"
 	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
+	chunk "github.com/ipfs/go-ipfs/importer/chunk"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	mfs "github.com/ipfs/go-ipfs/mfs"
 	path "github.com/ipfs/go-ipfs/path"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
+	mod "github.com/ipfs/go-ipfs/unixfs/mod"
 
 	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
@@ -45,15 +50,17 @@ operations.
 		cmds.BoolOption("f", "flush", "Flush target and ancestors after write.").Default(true),
 	},
 	Subcommands: map[string]*cmds.Command{
-		"read":  FilesReadCmd,
-		"write": FilesWriteCmd,
-		"mv":    FilesMvCmd,
-		"cp":    FilesCpCmd,
-		"ls":    FilesLsCmd,
-		"mkdir": FilesMkdirCmd,
-		"stat":  FilesStatCmd,
-		"rm":    FilesRmCmd,
-		"flush": FilesFlushCmd,
+		"read":   FilesReadCmd,
+		"write":  FilesWriteCmd,
+		"mv":     FilesMvCmd,
+		"cp":     FilesCpCmd,
+		"ls":     FilesLsCmd,
+		"mkdir":  FilesMkdirCmd,
+		"stat":   FilesStatCmd,
+		"rm":     FilesRmCmd,
+		"flush":  FilesFlushCmd,
+		"chmod":  FilesChmodCmd,
+		"chtime": FilesChtimeCmd,
 	},
 }
 
@@ -62,6 +69,19 @@ var formatError = errors.New("Format was set by multiple options. Only one forma
 var FilesStatCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Display file status.",
+		ShortDescription: `
+Display the hash, sizes, number of child blocks and type of a file or
+directory.
+
+With '--with-local', the DAG below the node is walked and every block is
+checked against the local blockstore, without fetching anything from the
+network. The size and number of the blocks present locally are reported next
+to the totals. Blocks referenced more than once are checked and counted once,
+in the totals as well, so these can be below the CumulativeSize of a DAG with
+repeated blocks. The walk does not descend into blocks that are missing, so
+the total number of blocks only counts those whose parent is present, and a
+missing block adds the size its parent's link records to the total size.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -77,6 +97,7 @@ ChildBlocks: <childs>
 Type: <type>`),
 		cmds.BoolOption("hash", "Print only hash. Implies '--format=<hash>'. Conflicts with other format options.").Default(false),
 		cmds.BoolOption("size", "Print only size. Implies '--format=<cumulsize>'. Conflicts with other format options.").Default(false),
+		cmds.BoolOption("with-local", "Compute the amount of the DAG that is present locally.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -109,6 +130,22 @@ Type: <type>`),
 			return
 		}
 
+		withLocal, _, _ := req.Option("with-local").Bool()
+		if withLocal {
+			nd, err := fsn.GetNode()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			o.WithLocality = true
+			err = statLocality(req.Context(), node, nd.Cid(), 0, o, cid.NewSet())
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
 		res.SetOutput(o)
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -125,6 +162,9 @@ Type: <type>`),
 			s = strings.Replace(s, "<type>", out.Type, -1)
 
 			fmt.Fprintln(buf, s)
+			if out.WithLocality {
+				fmt.Fprintf(buf, "Local: %d/%d bytes, %d/%d blocks\n", out.SizeLocal, out.SizeTotal, out.LocalBlocks, out.TotalBlocks)
+			}
 			return buf, nil
 		},
 	},
@@ -154,6 +194,45 @@ func statGetFormatOptions(req cmds.Request) (string, error) {
 	}
 }
 
+// statLocality walks the DAG below c and records in o how many of its blocks,
+// and how many bytes of them, are present in the local blockstore, out of how
+// many in total. Missing blocks are not fetched; linkSize, the size recorded
+// in the link to c, is counted for them instead. The walk stops when ctx is
+// cancelled. Blocks in seen are skipped, and visited blocks are added to it.
+func statLocality(ctx context.Context, n *core.IpfsNode, c *cid.Cid, linkSize uint64, o *Object, seen *cid.Set) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !seen.Visit(c) {
+		return nil
+	}
+
+	o.TotalBlocks++
+	has, err := n.Blockstore.Has(c)
+	if err != nil {
+		return err
+	}
+	if !has {
+		o.SizeTotal += linkSize
+		return nil
+	}
+
+	nd, err := n.DAG.Get(ctx, c)
+	if err != nil {
+		return err
+	}
+	o.LocalBlocks++
+	o.SizeLocal += uint64(len(nd.RawData()))
+	o.SizeTotal += uint64(len(nd.RawData()))
+
+	for _, l := range nd.Links() {
+		if err := statLocality(ctx, n, l.Cid, l.Size, o, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func statNode(ds dag.DAGService, fsn mfs.FSNode) (*Object, error) {
 	nd, err := fsn.GetNode()
 	if err != nil {
@@ -204,6 +283,9 @@ var FilesCpCmd = &cmds.Command{
 		cmds.StringArg("source", true, false, "Source object to copy."),
 		cmds.StringArg("dest", true, false, "Destination to copy object to."),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("parents", "p", "Make parent directories of the destination as needed."),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		node, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -212,6 +294,7 @@ var FilesCpCmd = &cmds.Command{
 		}
 
 		flush, _, _ := req.Option("flush").Bool()
+		mkparents, _, _ := req.Option("parents").Bool()
 
 		src, err := checkPath(req.Arguments()[0])
 		if err != nil {
@@ -236,6 +319,14 @@ var FilesCpCmd = &cmds.Command{
 			return
 		}
 
+		if mkparents {
+			err := ensureContainingDirectoryExists(node.FilesRoot, dst)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
 		err = mfs.PutNode(node.FilesRoot, dst, nd)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -282,6 +373,11 @@ type Object struct {
 	CumulativeSize uint64
 	Blocks         int
 	Type           string
+	WithLocality   bool   `json:",omitempty"`
+	LocalBlocks    int    `json:",omitempty"`
+	TotalBlocks    int    `json:",omitempty"`
+	SizeLocal      uint64 `json:",omitempty"`
+	SizeTotal      uint64 `json:",omitempty"`
 }
 
 type FilesLsOutput struct {
@@ -307,6 +403,18 @@ Examples:
     $ ipfs files ls /myfiles/a/b/c/d
     foo
     bar
+
+With '-R', the whole subtree is listed, one entry per line, with paths
+relative to the given directory. Adding '--size' prints the size of each
+entry, where the size of a directory is the total size of the files below
+it. Directories are then listed after their contents, similar to 'du':
+
+    $ ipfs files ls -R --size /myfiles/a
+    b/c/d/foo	12
+    b/c/d/bar	30
+    b/c/d	42
+    b/c	42
+    b	42
 `,
 	},
 	Arguments: []cmds.Argument{
@@ -314,6 +422,8 @@ Examples:
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("l", "Use long listing format."),
+		cmds.BoolOption("R", "recursive", "List the whole subtree."),
+		cmds.BoolOption("size", "Print entry sizes, summing the files below directories. Requires '-R'."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		var arg string
@@ -343,9 +453,30 @@ Examples:
 		}
 
 		long, _, _ := req.Option("l").Bool()
+		recursive, _, _ := req.Option("R").Bool()
+		sizes, _, _ := req.Option("size").Bool()
+
+		if sizes && !recursive {
+			res.SetError(errors.New("the '--size' option requires '-R'"), cmds.ErrClient)
+			return
+		}
 
 		switch fsn := fsn.(type) {
 		case *mfs.Directory:
+			if recursive {
+				out := make(chan interface{})
+				res.SetOutput((<-chan interface{})(out))
+
+				go func() {
+					defer close(out)
+					_, err := walkMfsDir(req.Context(), fsn, "", sizes, out)
+					if err != nil {
+						res.SetError(err, cmds.ErrNormal)
+					}
+				}()
+				return
+			}
+
 			if !long {
 				var output []mfs.NodeListing
 				names, err := fsn.ListNames(req.Context())
@@ -380,23 +511,105 @@ Examples:
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			out := res.Output().(*FilesLsOutput)
-			buf := new(bytes.Buffer)
 			long, _, _ := res.Request().Option("l").Bool()
+			sizes, _, _ := res.Request().Option("size").Bool()
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				out, ok := v.(*FilesLsOutput)
+				if !ok {
+					return nil, fmt.Errorf("unexpected output type: %T", v)
+				}
 
-			for _, o := range out.Entries {
-				if long {
-					fmt.Fprintf(buf, "%s\t%s\t%d\n", o.Name, o.Hash, o.Size)
-				} else {
-					fmt.Fprintf(buf, "%s\n", o.Name)
+				buf := new(bytes.Buffer)
+				for _, o := range out.Entries {
+					if long {
+						fmt.Fprintf(buf, "%s\t%s\t%d\n", o.Name, o.Hash, o.Size)
+					} else if sizes {
+						fmt.Fprintf(buf, "%s\t%d\n", o.Name, o.Size)
+					} else {
+						fmt.Fprintf(buf, "%s\n", o.Name)
+					}
 				}
+				return buf, nil
 			}
-			return buf, nil
+
+			if ch, ok := res.Output().(<-chan interface{}); ok {
+				return &cmds.ChannelMarshaler{
+					Channel:   ch,
+					Marshaler: marshal,
+					Res:       res,
+				}, nil
+			}
+
+			return marshal(res.Output())
 		},
 	},
 	Type: FilesLsOutput{},
 }
 
+// walkMfsDir streams every entry below dir to out, naming them relative to
+// prefix, and returns the total size of the files it found. Only one
+// directory level is held in memory at a time. If sizes is set, directories
+// are sent after their contents with their total size.
+func walkMfsDir(ctx context.Context, dir *mfs.Directory, prefix string, sizes bool, out chan<- interface{}) (int64, error) {
+	entries, err := dir.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	send := func(nl mfs.NodeListing) error {
+		select {
+		case out <- &FilesLsOutput{[]mfs.NodeListing{nl}}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var total int64
+	for _, e := range entries {
+		name := e.Name
+		e.Name = gopath.Join(prefix, name)
+
+		if e.Type != int(mfs.TDir) {
+			total += e.Size
+			if err := send(e); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if !sizes {
+			if err := send(e); err != nil {
+				return 0, err
+			}
+		}
+
+		child, err := dir.Child(name)
+		if err != nil {
+			return 0, err
+		}
+		childDir, ok := child.(*mfs.Directory)
+		if !ok {
+			return 0, fmt.Errorf("%s was not a directory", e.Name)
+		}
+
+		size, err := walkMfsDir(ctx, childDir, e.Name, sizes, out)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+
+		if sizes {
+			e.Size = size
+			if err := send(e); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return total, nil
+}
+
 var FilesReadCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Read a file in a given mfs.",
@@ -404,10 +617,17 @@ var FilesReadCmd = &cmds.Command{
 Read a specified number of bytes from a file at a given offset. By default,
 will read the entire file similar to unix cat.
 
+Reading starts at --offset, which may be at most the size of the file; an
+offset at the end of the file reads nothing. --count limits the number of
+bytes read, and --line-count the number of lines, counting the last line even
+if it does not end in a newline. Given both, reading stops at whichever limit
+is reached first. Only the blocks from the offset on are fetched.
+
 Examples:
 
     $ ipfs files read /test/hello
     hello
+    $ ipfs files read --line-count 10 /test/log
         `,
 	},
 
@@ -417,6 +637,7 @@ Examples:
 	Options: []cmds.Option{
 		cmds.IntOption("offset", "o", "Byte offset to begin reading from."),
 		cmds.IntOption("count", "n", "Maximum number of bytes to read."),
+		cmds.IntOption("line-count", "Maximum number of lines to read."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -457,7 +678,7 @@ Examples:
 			return
 		}
 		if offset < 0 {
-			res.SetError(fmt.Errorf("Cannot specify negative offset."), cmds.ErrNormal)
+			res.SetError(fmt.Errorf("Cannot specify negative offset."), cmds.ErrClient)
 			return
 		}
 
@@ -486,16 +707,53 @@ Examples:
 		}
 		if found {
 			if count < 0 {
-				res.SetError(fmt.Errorf("Cannot specify negative 'count'."), cmds.ErrNormal)
+				res.SetError(fmt.Errorf("Cannot specify negative 'count'."), cmds.ErrClient)
 				return
 			}
 			r = io.LimitReader(r, int64(count))
 		}
 
+		lines, found, err := req.Option("line-count").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if found {
+			if lines < 0 {
+				res.SetError(fmt.Errorf("Cannot specify negative 'line-count'."), cmds.ErrClient)
+				return
+			}
+			r = &lineLimitReader{R: r, N: lines}
+		}
+
 		res.SetOutput(r)
 	},
 }
 
+// lineLimitReader reads from R up to and including the N-th newline.
+type lineLimitReader struct {
+	R io.Reader
+	N int
+}
+
+func (l *lineLimitReader) Read(p []byte) (int, error) {
+	if l.N <= 0 {
+		return 0, io.EOF
+	}
+
+	n, err := l.R.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] != '\n' {
+			continue
+		}
+		l.N--
+		if l.N == 0 {
+			return i + 1, nil
+		}
+	}
+	return n, err
+}
+
 type contextReader interface {
 	CtxReadFull(context.Context, []byte) (int, error)
 }
@@ -519,6 +777,21 @@ Example:
 
     $ ipfs files mv /myfs/a/b/c /myfs/foo/newc
 
+`,
+		LongDescription: `
+Move files around. Just like traditional unix mv.
+
+Example:
+
+    $ ipfs files mv /myfs/a/b/c /myfs/foo/newc
+
+If the destination is an existing directory, files are moved into it, while
+a directory is merged with it: its entries are moved into the destination,
+and subdirectories that exist on both sides are merged in turn. A file that
+would replace an existing file is an error, unless --force is given.
+
+All conflicts are checked before anything is moved, so a failed move leaves
+both paths unchanged.
 `,
 	},
 
@@ -526,6 +799,9 @@ Example:
 		cmds.StringArg("source", true, false, "Source file to move."),
 		cmds.StringArg("dest", true, false, "Destination path for file to be moved to."),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("force", "Overwrite existing files at the destination.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -544,7 +820,112 @@ Example:
 			return
 		}
 
-		err = mfs.Mv(n.FilesRoot, src, dst)
+		force, _, _ := req.Option("force").Bool()
+
+		err = mfs.Mv(n.FilesRoot, src, dst, force)
+		if _, ok := err.(*mfs.ConflictError); ok {
+			res.SetError(fmt.Errorf("%s, use --force to overwrite it", err), cmds.ErrNormal)
+			return
+		}
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	},
+}
+
+var FilesChmodCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Change the unix permissions of a file or directory.",
+		ShortDescription: `
+Store unix permission bits, given in octal, on the unixfs node at the given
+path. 'ipfs get' applies them to the files and directories it writes.
+
+Example:
+
+    $ ipfs files chmod /myfs/bin/run 755
+
+Files stored as raw blocks have no room for metadata and cannot be changed.
+Changing the permissions changes the hash of the node.
+`,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "Path to the file or directory."),
+		cmds.StringArg("mode", true, false, "The permission bits, in octal."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		path, err := checkPath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		mode, err := strconv.ParseUint(req.Arguments()[1], 8, 32)
+		if err != nil || mode > uint64(os.ModePerm) {
+			res.SetError(fmt.Errorf("invalid mode %q, expected octal permission bits like 644", req.Arguments()[1]), cmds.ErrClient)
+			return
+		}
+
+		err = mfs.Chmod(n.FilesRoot, path, os.FileMode(mode))
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	},
+}
+
+var FilesChtimeCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Change the modification time of a file or directory.",
+		ShortDescription: `
+Store a modification time on the unixfs node at the given path. The time is
+given in RFC 3339 format, or as seconds since the unix epoch, and defaults to
+the current time. 'ipfs get' applies it to the files and directories it
+writes.
+
+Example:
+
+    $ ipfs files chtime /myfs/notes.txt 2017-06-01T12:00:00Z
+
+Files stored as raw blocks have no room for metadata and cannot be changed.
+Changing the modification time changes the hash of the node.
+`,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "Path to the file or directory."),
+		cmds.StringArg("mtime", false, false, "The modification time to store."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		path, err := checkPath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		mtime := time.Now()
+		if len(req.Arguments()) > 1 {
+			mtime, err = parseMtime(req.Arguments()[1])
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+		}
+
+		err = mfs.Chtime(n.FilesRoot, path, mtime)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -552,12 +933,28 @@ Example:
 	},
 }
 
+// parseMtime parses a time in RFC 3339 format or in seconds since the unix
+// epoch.
+func parseMtime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or seconds since the epoch", s)
+	}
+	return t, nil
+}
+
 var FilesWriteCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Write to a mutable file in a given filesystem.",
 		ShortDescription: `
 Write data to a file in a given filesystem. This command allows you to specify
 a beginning offset to write to. The entire length of the input will be written.
+An offset past the end of the file fills the gap with zeros, which are stored
+as a few shared blocks no matter how large the gap is.
 
 If the '--create' option is specified, the file will be created if it does not
 exist. Nonexistant intermediate directories will not be created.
@@ -566,6 +963,10 @@ If the '--flush' option is set to false, changes will not be propogated to the
 merkledag root. This can make operations much faster when doing a large number
 of writes to a deeper directory structure.
 
+If the '--atomic' option is specified, the data is written to a copy of the
+file, which replaces the original only once the whole write has succeeded.
+If the write fails, the original file is left untouched.
+
 EXAMPLE:
 
     echo "hello world" | ipfs files write --create /myfs/a/b/file
@@ -587,6 +988,7 @@ stat' on the file or any of its ancestors.
 		cmds.BoolOption("create", "e", "Create the file if it does not exist."),
 		cmds.BoolOption("truncate", "t", "Truncate the file to size zero before writing."),
 		cmds.IntOption("count", "n", "Maximum number of bytes to read."),
+		cmds.BoolOption("atomic", "Write to a copy of the file and only replace it once the write is complete."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		path, err := checkPath(req.Arguments()[0])
@@ -598,6 +1000,7 @@ stat' on the file or any of its ancestors.
 		create, _, _ := req.Option("create").Bool()
 		trunc, _, _ := req.Option("truncate").Bool()
 		flush, _, _ := req.Option("flush").Bool()
+		atomic, _, _ := req.Option("atomic").Bool()
 
 		nd, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -615,61 +1018,55 @@ stat' on the file or any of its ancestors.
 			return
 		}
 
-		fi, err := getFileHandle(nd.FilesRoot, path, create)
+		count, countfound, err := req.Option("count").Int()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		if countfound && count < 0 {
+			res.SetError(fmt.Errorf("cannot have negative byte count"), cmds.ErrNormal)
+			return
+		}
 
-		wfd, err := fi.Open(mfs.OpenWriteOnly, flush)
+		input, err := req.Files().NextFile()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		defer func() {
-			err := wfd.Close()
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-			}
-		}()
+		var r io.Reader = input
+		if countfound {
+			r = io.LimitReader(r, int64(count))
+		}
 
-		if trunc {
-			if err := wfd.Truncate(0); err != nil {
+		if atomic {
+			err := writeFileAtomic(req.Context(), nd, path, r, int64(offset), create, trunc, flush)
+			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
-				return
 			}
-		}
-
-		count, countfound, err := req.Option("count").Int()
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
-		}
-		if countfound && count < 0 {
-			res.SetError(fmt.Errorf("cannot have negative byte count"), cmds.ErrNormal)
 			return
 		}
 
-		_, err = wfd.Seek(int64(offset), io.SeekStart)
+		fi, err := getFileHandle(nd.FilesRoot, path, create)
 		if err != nil {
-			log.Error("seekfail: ", err)
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		input, err := req.Files().NextFile()
+		wfd, err := fi.Open(mfs.OpenWriteOnly, flush)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		var r io.Reader = input
-		if countfound {
-			r = io.LimitReader(r, int64(count))
-		}
+		defer func() {
+			err := wfd.Close()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+			}
+		}()
 
-		n, err := io.Copy(wfd, r)
+		n, err := writeAt(wfd, r, int64(offset), trunc)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -729,13 +1126,22 @@ var FilesFlushCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Flush a given path's data to disk.",
 		ShortDescription: `
-Flush a given path to disk. This is only useful when other commands
-are run with the '--flush=false'.
+Flush a given path to disk and print its hash once the flush has completed.
+This is only useful when other commands are run with the '--flush=false'.
+
+Without a path, or with '--all', the whole mfs is flushed and the resulting
+root hash is printed:
+
+    $ ipfs files flush --all
+    QmcwEpm945YxpK8MXwhKtJ3NuXVkpfgy7StyaEC7UYQX4C
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("path", false, false, "Path to flush. Default: '/'."),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("all", "a", "Flush the whole mfs and print the root hash.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -743,9 +1149,19 @@ are run with the '--flush=false'.
 			return
 		}
 
+		all, _, _ := req.Option("all").Bool()
+
 		path := "/"
 		if len(req.Arguments()) > 0 {
-			path = req.Arguments()[0]
+			if all {
+				res.SetError(fmt.Errorf("cannot give a path together with '--all'"), cmds.ErrClient)
+				return
+			}
+			path, err = checkPath(req.Arguments()[0])
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
 		}
 
 		err = mfs.FlushPath(nd.FilesRoot, path)
@@ -753,7 +1169,40 @@ are run with the '--flush=false'.
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		// look the node up again after the flush, so the hash reflects the
+		// flushed state
+		fsn, err := mfs.Lookup(nd.FilesRoot, path)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		n, err := fsn.GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&FlushOutput{
+			Path: path,
+			Hash: n.Cid().String(),
+		})
 	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out := res.Output().(*FlushOutput)
+			return strings.NewReader(out.Hash + "\n"), nil
+		},
+	},
+	Type: FlushOutput{},
+}
+
+// FlushOutput is the output of 'files flush': the flushed path and its hash
+// after the flush.
+type FlushOutput struct {
+	Path string
+	Hash string
 }
 
 var FilesRmCmd = &cmds.Command{
@@ -860,6 +1309,90 @@ Remove files or directories.
 	},
 }
 
+// fileWriter is implemented by both mfs file descriptors and dag modifiers
+type fileWriter interface {
+	io.Writer
+	io.Seeker
+	Truncate(int64) error
+}
+
+// writeAt copies the contents of r into w starting at offset, optionally
+// truncating w first.
+func writeAt(w fileWriter, r io.Reader, offset int64, trunc bool) (int64, error) {
+	if trunc {
+		if err := w.Truncate(0); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := w.Seek(offset, io.SeekStart)
+	if err != nil {
+		log.Error("seekfail: ", err)
+		return 0, err
+	}
+
+	return io.Copy(w, r)
+}
+
+// writeFileAtomic performs the write on a detached copy of the file at path
+// and swaps the result into the filesystem once it has completed.
+func writeFileAtomic(ctx context.Context, n *core.IpfsNode, path string, r io.Reader, offset int64, create, trunc, flush bool) error {
+	var fi *mfs.File
+	var base node.Node
+
+	target, err := mfs.Lookup(n.FilesRoot, path)
+	switch err {
+	case nil:
+		var ok bool
+		fi, ok = target.(*mfs.File)
+		if !ok {
+			return fmt.Errorf("%s was not a file", path)
+		}
+
+		base, err = fi.GetNode()
+		if err != nil {
+			return err
+		}
+	case os.ErrNotExist:
+		if !create {
+			return err
+		}
+		base = dag.NodeWithData(ft.FilePBData(nil, 0))
+	default:
+		return err
+	}
+
+	dmod, err := mod.NewDagModifier(ctx, base, n.DAG, chunk.DefaultSplitter)
+	if err != nil {
+		return err
+	}
+
+	wrote, err := writeAt(dmod, r, offset, trunc)
+	if err != nil {
+		return err
+	}
+
+	nd, err := dmod.GetNode()
+	if err != nil {
+		return err
+	}
+
+	if fi != nil {
+		err = fi.Replace(nd, flush)
+	} else {
+		err = mfs.PutNode(n.FilesRoot, path, nd)
+		if err == nil && flush {
+			err = mfs.FlushPath(n.FilesRoot, path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("atomically wrote %d bytes to %s", wrote, path)
+	return nil
+}
+
 func getFileHandle(r *mfs.Root, path string, create bool) (*mfs.File, error) {
 
 	target, err := mfs.Lookup(r, path)
@@ -910,6 +1443,17 @@ func getFileHandle(r *mfs.Root, path string, create bool) (*mfs.File, error) {
 	}
 }
 
+// ensureContainingDirectoryExists creates the missing parent directories
+// of the given mfs path.
+func ensureContainingDirectoryExists(r *mfs.Root, p string) error {
+	dirtomake := gopath.Dir(p)
+	if dirtomake == "/" {
+		return nil
+	}
+
+	return mfs.Mkdir(r, dirtomake, true, false)
+}
+
 func checkPath(p string) (string, error) {
 	if len(p) == 0 {
 		return "", fmt.Errorf("Paths must not be empty.")
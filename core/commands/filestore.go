@@ -32,6 +32,10 @@ List objects in the filestore.
 If one or more <obj> is specified only list those specific objects,
 otherwise list all objects.
 
+With --prefix, only objects whose backing file has an absolute path starting
+with the given prefix are listed. A relative prefix is taken relative to the
+filestore root.
+
 The output is:
 
 <hash> <size> <path> <offset>
@@ -42,6 +46,7 @@ The output is:
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("file-order", "sort the results based on the path of the backing file"),
+		cmds.StringOption("prefix", "only list objects whose backing file path starts with the given prefix"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		_, fs, err := getFilestore(req)
@@ -49,10 +54,20 @@ The output is:
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		keep := func(*filestore.ListRes) bool { return true }
+		if prefix, found, _ := req.Option("prefix").String(); found {
+			keep = filestore.PathPrefixFilter(fs, prefix)
+		}
+
 		args := req.Arguments()
 		if len(args) > 0 {
 			out := perKeyActionToChan(args, func(c *cid.Cid) *filestore.ListRes {
-				return filestore.List(fs, c)
+				r := filestore.List(fs, c)
+				if r.ErrorMsg == "" && !keep(r) {
+					return nil
+				}
+				return r
 			}, req.Context())
 			res.SetOutput(out)
 		} else {
@@ -62,7 +77,7 @@ The output is:
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
-			out := listResToChan(next, req.Context())
+			out := listResToChan(filestore.Filter(next, keep), req.Context())
 			res.SetOutput(out)
 		}
 	},
@@ -113,6 +128,16 @@ missing:  <obj> could not be found in the filestore
 ERROR:    internal error, most likely due to a corrupt database
 
 For ERROR entries the error will also be printed to stderr.
+
+With --repair, objects whose backing file is missing or changed are looked
+for below --search-root, which must be inside the filestore root and may be
+given relative to it. A file is considered moved if all of the affected
+objects can be read from it at their original offsets, in which case the
+references are updated to point at the new location. Repair mode adds two
+statuses:
+
+repaired:      the backing file was found at a new location
+unrecoverable: the backing file could not be found below the search root
 `,
 	},
 	Arguments: []cmds.Argument{
@@ -120,6 +145,8 @@ For ERROR entries the error will also be printed to stderr.
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("file-order", "verify the objects based on the order of the backing file"),
+		cmds.BoolOption("repair", "try to relocate moved backing files").Default(false),
+		cmds.StringOption("search-root", "directory to search for moved backing files"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		_, fs, err := getFilestore(req)
@@ -128,6 +155,30 @@ For ERROR entries the error will also be printed to stderr.
 			return
 		}
 		args := req.Arguments()
+
+		repair, _, _ := req.Option("repair").Bool()
+		searchRoot, haveRoot, _ := req.Option("search-root").String()
+		if repair {
+			if !haveRoot {
+				res.SetError(fmt.Errorf("--repair requires --search-root"), cmds.ErrClient)
+				return
+			}
+			if len(args) > 0 {
+				res.SetError(fmt.Errorf("--repair cannot be used with specific objects"), cmds.ErrClient)
+				return
+			}
+			next, err := filestore.RepairAll(fs, searchRoot)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			res.SetOutput(repairResToChan(next, res, req.Context()))
+			return
+		} else if haveRoot {
+			res.SetError(fmt.Errorf("--search-root can only be used with --repair"), cmds.ErrClient)
+			return
+		}
+
 		if len(args) > 0 {
 			out := perKeyActionToChan(args, func(c *cid.Cid) *filestore.ListRes {
 				return filestore.Verify(fs, c)
@@ -158,6 +209,9 @@ For ERROR entries the error will also be printed to stderr.
 				}
 				fmt.Fprintf(res.Stdout(), "%s %s\n", r.Status.Format(), r.FormatLong())
 			}
+			if err := res.Error(); err != nil {
+				return nil, err
+			}
 			return nil, nil
 		},
 	},
@@ -232,6 +286,35 @@ func listResToChan(next func() *filestore.ListRes, ctx context.Context) <-chan i
 	return out
 }
 
+// repairResToChan is like listResToChan, but sets an error on res once all
+// results are sent if any object could not be repaired. The channel is
+// unbuffered so the error is only set after the output is being consumed.
+func repairResToChan(next func() *filestore.ListRes, res cmds.Response, ctx context.Context) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		unrecoverable := 0
+		for {
+			r := next()
+			if r == nil {
+				break
+			}
+			if r.Status == filestore.StatusUnrecoverable {
+				unrecoverable++
+			}
+			select {
+			case out <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if unrecoverable > 0 {
+			res.SetError(fmt.Errorf("%d objects could not be repaired", unrecoverable), cmds.ErrNormal)
+		}
+	}()
+	return out
+}
+
 func perKeyActionToChan(args []string, action func(*cid.Cid) *filestore.ListRes, ctx context.Context) <-chan interface{} {
 	out := make(chan interface{}, 128)
 	go func() {
@@ -246,6 +329,9 @@ func perKeyActionToChan(args []string, action func(*cid.Cid) *filestore.ListRes,
 				continue
 			}
 			r := action(c)
+			if r == nil {
+				continue
+			}
 			select {
 			case out <- r:
 			case <-ctx.Done():
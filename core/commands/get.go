@@ -2,6 +2,7 @@ package commands
 
 import (
 	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +10,8 @@ import (
 	gopath "path"
 	"strings"
 
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 	"gx/ipfs/QmeWjRodbcZFKe5tMN7poEx3izym6osrLSnTLf9UjJZBbs/pb"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
@@ -16,11 +19,15 @@ import (
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
 	tar "github.com/ipfs/go-ipfs/thirdparty/tar"
+	ft "github.com/ipfs/go-ipfs/unixfs"
 	uarchive "github.com/ipfs/go-ipfs/unixfs/archive"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
 )
 
 var ErrInvalidCompressionLevel = errors.New("Compression level must be between 1 and 9")
 
+var ErrConflictingCompression = errors.New("Compression was disabled with '--compression=none' but compression options were given")
+
 var GetCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Download IPFS objects.",
@@ -32,8 +39,30 @@ path can be specified with '--output=<path>' or '-o=<path>'.
 
 To output a TAR archive instead of unpacked files, use '--archive' or '-a'.
 
-To compress the output with GZIP compression, use '--compress' or '-C'. You
-may also specify the level of compression by specifying '-l=<1-9>'.
+To compress the output with GZIP compression, use '--compress' or '-C', or
+equivalently '--compression=gzip'. You may also specify the level of
+compression by specifying '-l=<1-9>'.
+
+Unless an explicit output path is given, '.tar' and '.gz' extensions are
+appended to the output file name as appropriate.
+
+With '--output=-', the output is written to stdout instead: the contents of a
+single file are streamed as they are, and directories are written as a TAR
+archive. '--compress' and '--archive' still apply, so a file can be streamed
+gzipped or wrapped in a TAR archive as well. Nothing else is printed to
+stdout, which makes 'ipfs get -o - <path>' usable in pipelines. Stdout has
+to be asked for with '-o -': without '--output', single files are saved to
+'./<ipfs-path>' like everything else, as existing scripts rely on that. Use
+'ipfs cat' or 'ipfs get -o -' to stream a file.
+
+With '--verify', every block of the requested DAG is rehashed and checked
+against the CID it is referenced by before any output is written. If a block
+does not match, 'ipfs get' fails without writing anything.
+
+/ipns/ paths are resolved through the name system before any data is fetched,
+giving up after --resolve-timeout. The output is still named after the /ipns/
+path; with --verbose, the path it resolved to is written to stderr. Pass
+'--resolve=false' to only accept /ipfs/ paths and CIDs.
 `,
 	},
 
@@ -41,10 +70,15 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 		cmds.StringArg("ipfs-path", true, false, "The path to the IPFS object(s) to be outputted.").EnableStdin(),
 	},
 	Options: []cmds.Option{
-		cmds.StringOption("output", "o", "The path where the output should be stored."),
+		cmds.StringOption("output", "o", "The path where the output should be stored, or '-' for stdout."),
 		cmds.BoolOption("archive", "a", "Output a TAR archive.").Default(false),
 		cmds.BoolOption("compress", "C", "Compress the output with GZIP compression.").Default(false),
+		cmds.StringOption("compression", "The compression to apply to the output: 'gzip' or 'none'."),
 		cmds.IntOption("compression-level", "l", "The level of compression (1-9).").Default(-1),
+		cmds.BoolOption("verify", "Verify the fetched blocks against their CIDs before writing output.").Default(false),
+		cmds.BoolOption("resolve", "Resolve /ipns/ paths through the name system.").Default(true),
+		cmds.StringOption("resolve-timeout", "Maximum time to spend resolving the /ipns/ path.").Default(defaultResolveTimeout),
+		cmds.BoolOption("verbose", "v", "Print the path the /ipns/ path resolved to on stderr.").Default(false),
 	},
 	PreRun: func(req cmds.Request) error {
 		_, err := getCompressOptions(req)
@@ -62,18 +96,56 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 			return
 		}
 
-		node, err := req.InvocContext().GetNode()
+		resolve, timeout, err := ipnsResolveOptions(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		n, err := req.InvocContext().GetNode()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		if !n.OnlineMode() {
+			if err := n.SetupOfflineRouting(); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
 		p := path.Path(req.Arguments()[0])
 		ctx := req.Context()
-		dn, err := core.Resolve(ctx, node.Namesys, node.Resolver, p)
+		resolved, err := resolveIpnsArgs(ctx, n, []string{p.String()}, resolve, timeout)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		rp := path.Path(resolved[0])
+		if verbose, _, _ := req.Option("verbose").Bool(); verbose && rp != p {
+			res.AddMessage(fmt.Sprintf("resolved %s to %s", p, rp))
+		}
+
+		dn, err := core.Resolve(ctx, n.Namesys, n.Resolver, rp)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		verify, _, _ := req.Option("verify").Bool()
+		if verify {
+			c, err := core.ResolveToCid(ctx, n.Namesys, n.Resolver, rp)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			if err := verifyDag(ctx, n.DAG, c, dn, cid.NewSet()); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
 
 		switch dn := dn.(type) {
 		case *dag.ProtoNode:
@@ -92,7 +164,18 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 		}
 
 		archive, _, _ := req.Option("archive").Bool()
-		reader, err := uarchive.DagArchive(ctx, dn, p.String(), node.DAG, archive, cmplvl)
+		outPath, _, _ := req.Option("output").String()
+		if outPath == stdoutPath {
+			reader, err := stdoutReader(ctx, n.DAG, dn, p.String(), archive, cmplvl)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			res.SetOutput(reader)
+			return
+		}
+
+		reader, err := uarchive.DagArchive(ctx, dn, p.String(), n.DAG, archive, cmplvl)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -100,6 +183,8 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 		res.SetOutput(reader)
 	},
 	PostRun: func(req cmds.Request, res cmds.Response) {
+		printMessages(res)
+
 		if res.Output() == nil {
 			return
 		}
@@ -107,7 +192,15 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 		res.SetOutput(nil)
 
 		outPath, _, _ := req.Option("output").String()
-		if len(outPath) == 0 {
+		if outPath == stdoutPath {
+			if _, err := io.Copy(os.Stdout, outReader); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+			}
+			return
+		}
+
+		explicitPath := len(outPath) != 0
+		if !explicitPath {
 			_, outPath = gopath.Split(req.Arguments()[0])
 			outPath = gopath.Clean(outPath)
 		}
@@ -121,11 +214,12 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 		archive, _, _ := req.Option("archive").Bool()
 
 		gw := getWriter{
-			Out:         os.Stdout,
-			Err:         os.Stderr,
-			Archive:     archive,
-			Compression: cmplvl,
-			Size:        int64(res.Length()),
+			Out:          os.Stdout,
+			Err:          os.Stderr,
+			Archive:      archive,
+			Compression:  cmplvl,
+			Size:         int64(res.Length()),
+			ExplicitPath: explicitPath,
 		}
 
 		if err := gw.Write(outReader, outPath); err != nil {
@@ -135,6 +229,56 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 	},
 }
 
+// stdoutPath is the output path that makes 'ipfs get' write to stdout.
+const stdoutPath = "-"
+
+// stdoutReader returns the output of 'ipfs get -o -' for nd: the raw
+// contents of a file, optionally gzipped, or a TAR archive of a directory.
+func stdoutReader(ctx context.Context, ds dag.DAGService, nd node.Node, name string, archive bool, cmplvl int) (io.Reader, error) {
+	isDir := false
+	if pbnd, ok := nd.(*dag.ProtoNode); ok {
+		fsn, err := ft.FromBytes(pbnd.Data())
+		if err != nil {
+			return nil, err
+		}
+		isDir = fsn.GetType() == ft.TDirectory || fsn.GetType() == ft.THAMTShard
+	}
+
+	if isDir || archive || cmplvl != gzip.NoCompression {
+		return uarchive.DagArchive(ctx, nd, name, ds, isDir || archive, cmplvl)
+	}
+	return uio.NewDagReader(ctx, nd, ds)
+}
+
+// verifyDag rehashes nd, which was fetched as c, and all of its descendants,
+// and checks that each block matches the cid it is referenced by. Blocks in
+// seen are skipped.
+func verifyDag(ctx context.Context, ds dag.DAGService, c *cid.Cid, nd node.Node, seen *cid.Set) error {
+	if !seen.Visit(c) {
+		return nil
+	}
+
+	sum, err := c.Prefix().Sum(nd.RawData())
+	if err != nil {
+		return err
+	}
+	if !sum.Equals(c) {
+		return fmt.Errorf("verification failed: block %s hashes to %s", c, sum)
+	}
+
+	for _, l := range nd.Links() {
+		child, err := l.GetNode(ctx, ds)
+		if err != nil {
+			return err
+		}
+
+		if err := verifyDag(ctx, ds, l.Cid, child, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type clearlineReader struct {
 	io.Reader
 	out io.Writer
@@ -178,6 +322,9 @@ type getWriter struct {
 	Archive     bool
 	Compression int
 	Size        int64
+
+	// ExplicitPath disables adjusting the extension of the output file
+	ExplicitPath bool
 }
 
 func (gw *getWriter) Write(r io.Reader, fpath string) error {
@@ -188,18 +335,8 @@ func (gw *getWriter) Write(r io.Reader, fpath string) error {
 }
 
 func (gw *getWriter) writeArchive(r io.Reader, fpath string) error {
-	// adjust file name if tar
-	if gw.Archive {
-		if !strings.HasSuffix(fpath, ".tar") && !strings.HasSuffix(fpath, ".tar.gz") {
-			fpath += ".tar"
-		}
-	}
-
-	// adjust file name if gz
-	if gw.Compression != gzip.NoCompression {
-		if !strings.HasSuffix(fpath, ".gz") {
-			fpath += ".gz"
-		}
+	if !gw.ExplicitPath {
+		fpath = archiveFileName(fpath, gw.Archive, gw.Compression != gzip.NoCompression)
 	}
 
 	// create file
@@ -218,6 +355,25 @@ func (gw *getWriter) writeArchive(r io.Reader, fpath string) error {
 	return err
 }
 
+// archiveFileName appends the '.tar' and '.gz' extensions to fpath as
+// needed for the given archive and compression settings.
+func archiveFileName(fpath string, archive, compressed bool) string {
+	// adjust file name if tar
+	if archive {
+		if !strings.HasSuffix(fpath, ".tar") && !strings.HasSuffix(fpath, ".tar.gz") {
+			fpath += ".tar"
+		}
+	}
+
+	// adjust file name if gz
+	if compressed {
+		if !strings.HasSuffix(fpath, ".gz") {
+			fpath += ".gz"
+		}
+	}
+	return fpath
+}
+
 func (gw *getWriter) writeExtracted(r io.Reader, fpath string) error {
 	fmt.Fprintf(gw.Out, "Saving file(s) to %s\n", fpath)
 	bar := makeProgressBar(gw.Err, gw.Size)
@@ -230,8 +386,27 @@ func (gw *getWriter) writeExtracted(r io.Reader, fpath string) error {
 }
 
 func getCompressOptions(req cmds.Request) (int, error) {
-	cmprs, _, _ := req.Option("compress").Bool()
+	cmprs, cmprsFound, _ := req.Option("compress").Bool()
 	cmplvl, cmplvlFound, _ := req.Option("compression-level").Int()
+
+	compression, found, _ := req.Option("compression").String()
+	if found {
+		switch strings.ToLower(compression) {
+		case "gzip":
+			cmprs = true
+		case "none":
+			if cmprs && cmprsFound {
+				return gzip.NoCompression, ErrConflictingCompression
+			}
+			if cmplvlFound {
+				return gzip.NoCompression, ErrConflictingCompression
+			}
+			cmprs = false
+		default:
+			return gzip.NoCompression, fmt.Errorf("unrecognized compression: %s", compression)
+		}
+	}
+
 	switch {
 	case !cmprs:
 		return gzip.NoCompression, nil
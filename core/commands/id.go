@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -34,8 +35,14 @@ type IdOutput struct {
 	ID              string
 	PublicKey       string
 	Addresses       []string
+	ObservedAddrs   []string
 	AgentVersion    string
 	ProtocolVersion string
+	Protocols       []string `json:",omitempty"`
+
+	// Unknown lists the fields the local peerstore had no value for. It is
+	// only set with --offline.
+	Unknown []string `json:",omitempty"`
 }
 
 var IDCmd = &cmds.Command{
@@ -51,6 +58,19 @@ If no peer is specified, prints out information for local peers.
 <pver>: Protocol version.
 <pubkey>: Public key.
 <addrs>: Addresses (newline delimited).
+<obsaddrs>: Addresses other peers observed us on (newline delimited).
+<protocols>: Protocols the peer supports (newline delimited).
+
+ObservedAddrs lists the addresses remote peers reported seeing our
+connections come from, which is useful to check whether port forwarding or
+NAT traversal is working. It is only known for the local node and is empty
+while offline.
+
+With --offline, the information about a remote peer is taken from the local
+peerstore, without looking the peer up or connecting to it. The peerstore
+only holds what the running node learned about the peer, for example while
+connected to it. Fields without a value are listed under Unknown, and
+printed as 'unknown' with --format.
 
 EXAMPLE:
 
@@ -62,6 +82,7 @@ EXAMPLE:
 	},
 	Options: []cmds.Option{
 		cmds.StringOption("format", "f", "Optional output format."),
+		cmds.BoolOption("offline", "Only use what the local peerstore knows about the peer.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		node, err := req.InvocContext().GetNode()
@@ -91,6 +112,17 @@ EXAMPLE:
 			return
 		}
 
+		offline, _, _ := req.Option("offline").Bool()
+		if offline {
+			output, err := printKnownPeer(node.Peerstore, id)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			res.SetOutput(output)
+			return
+		}
+
 		// TODO handle offline mode with polymorphism instead of conditionals
 		if !node.OnlineMode() {
 			res.SetError(errors.New(offlineIdErrorMessage), cmds.ErrClient)
@@ -126,12 +158,22 @@ EXAMPLE:
 				return nil, err
 			}
 			if found {
+				offline, _, _ := res.Request().Option("offline").Bool()
+				field := func(name, v string) string {
+					if offline && v == "" && containsString(val.Unknown, name) {
+						return "unknown"
+					}
+					return v
+				}
+
 				output := format
 				output = strings.Replace(output, "<id>", val.ID, -1)
-				output = strings.Replace(output, "<aver>", val.AgentVersion, -1)
-				output = strings.Replace(output, "<pver>", val.ProtocolVersion, -1)
-				output = strings.Replace(output, "<pubkey>", val.PublicKey, -1)
-				output = strings.Replace(output, "<addrs>", strings.Join(val.Addresses, "\n"), -1)
+				output = strings.Replace(output, "<aver>", field("AgentVersion", val.AgentVersion), -1)
+				output = strings.Replace(output, "<pver>", field("ProtocolVersion", val.ProtocolVersion), -1)
+				output = strings.Replace(output, "<pubkey>", field("PublicKey", val.PublicKey), -1)
+				output = strings.Replace(output, "<addrs>", field("Addresses", strings.Join(val.Addresses, "\n")), -1)
+				output = strings.Replace(output, "<obsaddrs>", strings.Join(val.ObservedAddrs, "\n"), -1)
+				output = strings.Replace(output, "<protocols>", field("Protocols", strings.Join(val.Protocols, "\n")), -1)
 				output = strings.Replace(output, "\\n", "\n", -1)
 				output = strings.Replace(output, "\\t", "\t", -1)
 				return strings.NewReader(output), nil
@@ -183,6 +225,43 @@ func printPeer(ps pstore.Peerstore, p peer.ID) (interface{}, error) {
 	return info, nil
 }
 
+// printKnownPeer is like printPeer, but also fills in the peer's protocols
+// and records which fields the peerstore had no value for. It fails if the
+// peerstore knows nothing about p.
+func printKnownPeer(ps pstore.Peerstore, p peer.ID) (interface{}, error) {
+	v, err := printPeer(ps, p)
+	if err != nil {
+		return nil, err
+	}
+	info := v.(*IdOutput)
+
+	if protos, err := ps.GetProtocols(p); err == nil {
+		info.Protocols = protos
+	}
+
+	fields := []struct {
+		name  string
+		known bool
+	}{
+		{"PublicKey", info.PublicKey != ""},
+		{"Addresses", len(info.Addresses) > 0},
+		{"AgentVersion", info.AgentVersion != ""},
+		{"ProtocolVersion", info.ProtocolVersion != ""},
+		{"Protocols", len(info.Protocols) > 0},
+	}
+	for _, f := range fields {
+		if !f.known {
+			info.Unknown = append(info.Unknown, f.name)
+		}
+	}
+
+	// every field is unknown
+	if len(info.Unknown) == len(fields) {
+		return nil, fmt.Errorf("peer %s is not in the local peerstore", p.Pretty())
+	}
+	return info, nil
+}
+
 // printing self is special cased as we get values differently.
 func printSelf(node *core.IpfsNode) (interface{}, error) {
 	info := new(IdOutput)
@@ -206,6 +285,12 @@ func printSelf(node *core.IpfsNode) (interface{}, error) {
 			s := a.String() + "/ipfs/" + info.ID
 			info.Addresses = append(info.Addresses, s)
 		}
+
+		if node.Identify != nil {
+			for _, a := range node.Identify.OwnObservedAddrs() {
+				info.ObservedAddrs = append(info.ObservedAddrs, a.String())
+			}
+		}
 	}
 	info.ProtocolVersion = identify.LibP2PVersion
 	info.AgentVersion = identify.ClientVersion
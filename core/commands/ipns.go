@@ -2,8 +2,10 @@ package commands
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	namesys "github.com/ipfs/go-ipfs/namesys"
@@ -45,6 +47,16 @@ Resolve the value of a dnslink:
   > ipfs name resolve ipfs.io
   /ipfs/QmaBvfZooxWkrv7D3r8LS9moNjzD2o525XMZze69hhoxf5
 
+Keep watching a name and print its value every time it changes:
+
+  > ipfs name resolve --stream QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ
+  /ipfs/QmSiTko9JZyabH56y2fussEt1A5oDqsFXB3CkvAqraFryz
+  /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
+With --stream the name is looked up again every --stream-interval. Cached
+entries are reused until their record ttl expires, use --nocache to do a
+full lookup every time. The stream runs until the command is cancelled.
+
 `,
 	},
 
@@ -54,6 +66,8 @@ Resolve the value of a dnslink:
 	Options: []cmds.Option{
 		cmds.BoolOption("recursive", "r", "Resolve until the result is not an IPNS name.").Default(false),
 		cmds.BoolOption("nocache", "n", "Do not use cached entries.").Default(false),
+		cmds.BoolOption("stream", "s", "Keep resolving the name and print each new value.").Default(false),
+		cmds.StringOption("stream-interval", "Time between lookups when streaming.").Default("30s"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -113,6 +127,21 @@ Resolve the value of a dnslink:
 			name = "/ipns/" + name
 		}
 
+		stream, _, _ := req.Option("stream").Bool()
+		var interval time.Duration
+		if stream {
+			istr, _, _ := req.Option("stream-interval").String()
+			interval, err = time.ParseDuration(istr)
+			if err != nil {
+				res.SetError(fmt.Errorf("error parsing stream-interval option: %s", err), cmds.ErrClient)
+				return
+			}
+			if interval <= 0 {
+				res.SetError(errors.New("stream-interval must be positive"), cmds.ErrClient)
+				return
+			}
+		}
+
 		output, err := resolver.ResolveN(req.Context(), name, depth)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -121,15 +150,77 @@ Resolve the value of a dnslink:
 
 		// TODO: better errors (in the case of not finding the name, we get "failed to find any peer in table")
 
-		res.SetOutput(&ResolvedPath{output})
+		if !stream {
+			res.SetOutput(&ResolvedPath{output})
+			return
+		}
+
+		outChan := make(chan interface{})
+		res.SetOutput((<-chan interface{})(outChan))
+
+		go func() {
+			defer close(outChan)
+
+			ctx := req.Context()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			last := output
+			select {
+			case outChan <- &ResolvedPath{last}:
+			case <-ctx.Done():
+				return
+			}
+
+			for {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+
+				p, err := resolver.ResolveN(ctx, name, depth)
+				if err != nil {
+					log.Debugf("name resolve --stream: resolving %s: %s", name, err)
+					continue
+				}
+				if p == last {
+					continue
+				}
+				last = p
+
+				select {
+				case outChan <- &ResolvedPath{p}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			output, ok := res.Output().(*ResolvedPath)
+			if output, ok := res.Output().(*ResolvedPath); ok {
+				return strings.NewReader(output.Path.String() + "\n"), nil
+			}
+
+			outChan, ok := res.Output().(<-chan interface{})
 			if !ok {
 				return nil, u.ErrCast()
 			}
-			return strings.NewReader(output.Path.String() + "\n"), nil
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				output, ok := v.(*ResolvedPath)
+				if !ok {
+					return nil, u.ErrCast()
+				}
+				return strings.NewReader(output.Path.String() + "\n"), nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
 		},
 	},
 	Type: ResolvedPath{},
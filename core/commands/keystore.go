@@ -3,16 +3,25 @@ package commands
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"sort"
 	"strings"
 	"text/tabwriter"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
+	files "github.com/ipfs/go-ipfs/commands/files"
+	core "github.com/ipfs/go-ipfs/core"
+	keystore "github.com/ipfs/go-ipfs/keystore"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	dshelp "github.com/ipfs/go-ipfs/thirdparty/ds-help"
 
 	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
@@ -30,21 +39,42 @@ var KeyCmd = &cmds.Command{
   > ipfs key list
   self
   mykey
+
+'ipfs key export' and 'ipfs key import' move a key between nodes in a
+password protected file. The password is read from a file, or stdin.
+
+  > ipfs key export -o mykey.key mykey password
+  > ipfs key import mykey mykey.key password
+
+'ipfs key sign' and 'ipfs key verify' sign data with a key, and check such
+signatures.
+
+  > echo "hello" | ipfs key sign --key=mykey
+  > echo "hello" | ipfs key verify --key=mykey --signature=<signature>
 		`,
 	},
 	Subcommands: map[string]*cmds.Command{
+		"export": keyExportCmd,
 		"gen":    keyGenCmd,
+		"import": keyImportCmd,
 		"list":   keyListCmd,
 		"rename": keyRenameCmd,
 		"rm":     keyRmCmd,
+		"sign":   keySignCmd,
+		"verify": keyVerifyCmd,
 	},
 }
 
 type KeyOutput struct {
 	Name string
 	Id   string
+	Type string `json:",omitempty"`
+	Size int    `json:",omitempty"`
 }
 
+// minRSAKeySize is the smallest RSA key size 'ipfs key gen' accepts.
+const minRSAKeySize = 2048
+
 type KeyOutputList struct {
 	Keys []KeyOutput
 }
@@ -55,15 +85,25 @@ type KeyRenameOutput struct {
 	Now       string
 	Id        string
 	Overwrite bool
+	Published bool
 }
 
 var keyGenCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Create a new keypair",
+		ShortDescription: `
+'ipfs key gen' creates a new keypair and prints its hash, the peer ID the key
+publishes IPNS names under. With --verbose, the key name, type and size are
+printed as well.
+
+RSA keys must be at least 2048 bits, and default to 2048 bits when no
+--size is given. Ed25519 keys have a fixed size, so --size is ignored.
+`,
 	},
 	Options: []cmds.Option{
 		cmds.StringOption("type", "t", "type of the key to create [rsa, ed25519]"),
 		cmds.IntOption("size", "s", "size of the key to generate"),
+		cmds.BoolOption("verbose", "v", "print the key name, type and size along with its hash").Default(false),
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("name", true, false, "name of key to create"),
@@ -82,7 +122,7 @@ var keyGenCmd = &cmds.Command{
 		}
 
 		if !f {
-			res.SetError(fmt.Errorf("please specify a key type with --type"), cmds.ErrNormal)
+			res.SetError(fmt.Errorf("please specify a key type with --type"), cmds.ErrClient)
 			return
 		}
 
@@ -104,7 +144,10 @@ var keyGenCmd = &cmds.Command{
 		switch typ {
 		case "rsa":
 			if !sizefound {
-				res.SetError(fmt.Errorf("please specify a key size with --size"), cmds.ErrNormal)
+				size = minRSAKeySize
+			}
+			if size < minRSAKeySize {
+				res.SetError(fmt.Errorf("rsa keys must be at least %d bits, got %d", minRSAKeySize, size), cmds.ErrClient)
 				return
 			}
 
@@ -117,6 +160,12 @@ var keyGenCmd = &cmds.Command{
 			sk = priv
 			pk = pub
 		case "ed25519":
+			if sizefound {
+				log.Debugf("key gen: ignoring --size=%d for ed25519 key", size)
+			}
+			// ed25519 public keys are always 256 bits
+			size = 256
+
 			priv, pub, err := ci.GenerateEd25519Key(rand.Reader)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
@@ -126,7 +175,7 @@ var keyGenCmd = &cmds.Command{
 			sk = priv
 			pk = pub
 		default:
-			res.SetError(fmt.Errorf("unrecognized key type: %s", typ), cmds.ErrNormal)
+			res.SetError(fmt.Errorf("unrecognized key type: %s", typ), cmds.ErrClient)
 			return
 		}
 
@@ -145,6 +194,8 @@ var keyGenCmd = &cmds.Command{
 		res.SetOutput(&KeyOutput{
 			Name: name,
 			Id:   pid.Pretty(),
+			Type: typ,
+			Size: size,
 		})
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -154,12 +205,258 @@ var keyGenCmd = &cmds.Command{
 				return nil, fmt.Errorf("expected a KeyOutput as command result")
 			}
 
+			verbose, _, _ := res.Request().Option("verbose").Bool()
+			if verbose {
+				return strings.NewReader(fmt.Sprintf("%s %s %d %s\n", k.Name, k.Type, k.Size, k.Id)), nil
+			}
+
 			return strings.NewReader(k.Id + "\n"), nil
 		},
 	},
 	Type: KeyOutput{},
 }
 
+var keyExportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Export a keypair",
+		ShortDescription: `
+'ipfs key export' writes a key to a file, encrypted with a password, so it
+can be loaded into another node with 'ipfs key import'. The password is read
+from the given file, or from stdin. The key is written to <name>.key unless
+another file is given with --output. Existing files are never overwritten.
+
+  > echo "my password" > password
+  > ipfs key export -o mykey.key mykey password
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "name of key to export"),
+		cmds.FileArg("password-file", true, false, "file holding the password to encrypt the key with").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("output", "o", "The path where the key is to be written."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name := req.Arguments()[0]
+		if name == "self" {
+			res.SetError(fmt.Errorf("cannot export key with name 'self'"), cmds.ErrClient)
+			return
+		}
+
+		password, err := readKeyPassword(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		sk, err := n.Repo.Keystore().Get(name)
+		if err != nil {
+			res.SetError(fmt.Errorf("no key named %s was found", name), cmds.ErrNormal)
+			return
+		}
+
+		data, err := keystore.EncryptKey(sk, password)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(bytes.NewReader(data))
+	},
+	PostRun: func(req cmds.Request, res cmds.Response) {
+		if res.Output() == nil {
+			return
+		}
+		outReader := res.Output().(io.Reader)
+		res.SetOutput(nil)
+
+		name := req.Arguments()[0]
+		outPath, _, _ := req.Option("output").String()
+		if outPath == "" {
+			outPath = name + ".key"
+		}
+
+		f, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(f, outReader); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		fmt.Fprintf(os.Stdout, "exported key %s to %s\n", name, outPath)
+	},
+}
+
+var keyImportCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Import a keypair",
+		ShortDescription: `
+'ipfs key import' loads a key written by 'ipfs key export' into the keystore
+under the given name. The password the key was exported with is read from
+the given file, or from stdin. An existing key with that name is only
+replaced when --force is given.
+
+  > ipfs key import mykey mykey.key password
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "name to import the key as"),
+		cmds.StringArg("key-file", true, false, "file holding the exported key"),
+		cmds.FileArg("password-file", true, false, "file holding the password the key was exported with").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("force", "f", "Allow to overwrite an existing key.").Default(false),
+	},
+	PreRun: func(req cmds.Request) error {
+		// Send the key file ahead of the password, so Run can tell them
+		// apart.
+		kf, err := os.Open(req.Arguments()[1])
+		if err != nil {
+			return err
+		}
+
+		pf, err := req.Files().NextFile()
+		if err != nil {
+			kf.Close()
+			return err
+		}
+
+		req.SetFiles(files.NewSliceFile("", "", []files.File{
+			files.NewReaderFile("", kf.Name(), kf, nil),
+			pf,
+		}))
+		return nil
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		ks := n.Repo.Keystore()
+
+		name := req.Arguments()[0]
+		if name == "self" {
+			res.SetError(fmt.Errorf("cannot overwrite key with name 'self'"), cmds.ErrClient)
+			return
+		}
+
+		file, err := req.Files().NextFile()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer file.Close()
+
+		data, err := ioutil.ReadAll(io.LimitReader(file, 64*1024))
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		password, err := readKeyPassword(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		sk, err := keystore.DecryptKey(data, password)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		old, err := ks.Get(name)
+		switch err {
+		case nil:
+			force, _, _ := req.Option("force").Bool()
+			if !force {
+				res.SetError(fmt.Errorf("key named %s already exists, use --force to overwrite it", name), cmds.ErrClient)
+				return
+			}
+			err = replaceKey(ks, name, old, sk)
+		case keystore.ErrNoSuchKey:
+			err = ks.Put(name, sk)
+		}
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		pid, err := peer.IDFromPrivateKey(sk)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&KeyOutput{
+			Name: name,
+			Id:   pid.Pretty(),
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			k, ok := res.Output().(*KeyOutput)
+			if !ok {
+				return nil, fmt.Errorf("expected a KeyOutput as command result")
+			}
+
+			return strings.NewReader(k.Id + "\n"), nil
+		},
+	},
+	Type: KeyOutput{},
+}
+
+// readKeyPassword reads the password for 'ipfs key export' and 'ipfs key
+// import' from the next file of req. A trailing newline is not part of the
+// password.
+func readKeyPassword(req cmds.Request) ([]byte, error) {
+	file, err := req.Files().NextFile()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimRight(data, "\r\n")
+	if len(data) == 0 {
+		return nil, errors.New("password must not be empty")
+	}
+	return data, nil
+}
+
+// replaceKey replaces the key stored under name, which is old, with sk. The
+// keystore can't overwrite keys, so old is put back if storing sk fails.
+func replaceKey(ks keystore.Keystore, name string, old, sk ci.PrivKey) error {
+	if err := ks.Delete(name); err != nil {
+		return err
+	}
+
+	if err := ks.Put(name, sk); err != nil {
+		if rerr := ks.Put(name, old); rerr != nil {
+			return fmt.Errorf("%s (restoring the previous key failed: %s)", err, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
 var keyListCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "List all local keypairs",
@@ -215,13 +512,19 @@ var keyListCmd = &cmds.Command{
 var keyRenameCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Rename a keypair",
+		ShortDescription: `
+'ipfs key rename' changes the name a key is stored under. The IPNS name of
+the key is its hash and stays the same, but it has to be published with the
+new key name from then on. Renaming a key that has been used to publish an
+IPNS name requires --force, as does overwriting an existing key.
+`,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("name", true, false, "name of key to rename"),
 		cmds.StringArg("newName", true, false, "new name of the key"),
 	},
 	Options: []cmds.Option{
-		cmds.BoolOption("force", "f", "Allow to overwrite an existing key."),
+		cmds.BoolOption("force", "f", "Allow to overwrite an existing key or to rename a key used for publishing."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -259,8 +562,19 @@ var keyRenameCmd = &cmds.Command{
 			return
 		}
 
-		overwrite := false
 		force, _, _ := res.Request().Option("f").Bool()
+
+		published, err := hasPublishedName(n, pid)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if published && !force {
+			res.SetError(fmt.Errorf("key %s has been used to publish /ipns/%s, use --force to rename it anyway", name, pid.Pretty()), cmds.ErrClient)
+			return
+		}
+
+		overwrite := false
 		if force {
 			exist, err := ks.Has(newName)
 			if err != nil {
@@ -295,6 +609,7 @@ var keyRenameCmd = &cmds.Command{
 			Now:       newName,
 			Id:        pid.Pretty(),
 			Overwrite: overwrite,
+			Published: published,
 		})
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -307,9 +622,12 @@ var keyRenameCmd = &cmds.Command{
 			buf := new(bytes.Buffer)
 
 			if k.Overwrite {
-				fmt.Fprintf(buf, "Key %s renamed to %s with overwriting\n", k.Id, k.Now)
+				fmt.Fprintf(buf, "Key %s renamed from %s to %s with overwriting\n", k.Id, k.Was, k.Now)
 			} else {
-				fmt.Fprintf(buf, "Key %s renamed to %s\n", k.Id, k.Now)
+				fmt.Fprintf(buf, "Key %s renamed from %s to %s\n", k.Id, k.Was, k.Now)
+			}
+			if k.Published {
+				fmt.Fprintf(res.Stderr(), "warning: publish /ipns/%s with --key=%s from now on\n", k.Id, k.Now)
 			}
 			return buf, nil
 		},
@@ -376,6 +694,218 @@ var keyRmCmd = &cmds.Command{
 	Type: KeyOutputList{},
 }
 
+// signedMessagePrefix is prepended to the data signed by 'ipfs key sign', so
+// that its signatures can never be passed off as IPNS records or other
+// messages signed with the same key.
+const signedMessagePrefix = "libp2p-key signed message:"
+
+// KeySignOutput is the output of 'ipfs key sign'.
+type KeySignOutput struct {
+	Key       KeyOutput
+	Signature string
+}
+
+// KeyVerifyOutput is the output of 'ipfs key verify'.
+type KeyVerifyOutput struct {
+	Key            KeyOutput
+	SignatureValid bool
+}
+
+var keySignCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Sign data with a keypair",
+		ShortDescription: `
+'ipfs key sign' signs the data read from stdin with the key given by --key,
+and prints the base64 encoded signature. The signature scheme is the one of
+the key type: RSA keys sign with PKCS #1 v1.5 over SHA-256, Ed25519 keys
+with Ed25519. The data is prefixed with "libp2p-key signed message:" before
+signing, so signatures made here are never valid for IPNS records.
+
+  > echo "hello" | ipfs key sign --key=mykey
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("data", true, false, "The data to sign.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("key", "k", "The name of the key to sign with.").Default("self"),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name, _, _ := req.Option("key").String()
+		sk, err := signingKey(n, name)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		data, err := readSignedData(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		sig, err := sk.Sign(data)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		pid, err := peer.IDFromPrivateKey(sk)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&KeySignOutput{
+			Key:       KeyOutput{Name: name, Id: pid.Pretty()},
+			Signature: base64.StdEncoding.EncodeToString(sig),
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*KeySignOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			return strings.NewReader(out.Signature + "\n"), nil
+		},
+	},
+	Type: KeySignOutput{},
+}
+
+var keyVerifyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Verify a signature made with 'ipfs key sign'",
+		ShortDescription: `
+'ipfs key verify' checks that the signature given by --signature was made by
+'ipfs key sign' with the key given by --key over the data read from stdin.
+The command fails if the signature is not valid.
+
+  > echo "hello" | ipfs key verify --key=mykey --signature=<signature>
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.FileArg("data", true, false, "The data the signature was made over.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("key", "k", "The name of the key the data was signed with.").Default("self"),
+		cmds.StringOption("signature", "s", "The base64 encoded signature to verify."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		encoded, _, _ := req.Option("signature").String()
+		if encoded == "" {
+			res.SetError(fmt.Errorf("please specify a signature with --signature"), cmds.ErrClient)
+			return
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			res.SetError(fmt.Errorf("invalid signature: %s", err), cmds.ErrClient)
+			return
+		}
+
+		name, _, _ := req.Option("key").String()
+		sk, err := signingKey(n, name)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		data, err := readSignedData(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		pk := sk.GetPublic()
+		valid, err := pk.Verify(data, sig)
+		if err != nil {
+			// RSA reports invalid signatures as errors.
+			log.Debugf("key verify: %s", err)
+			valid = false
+		}
+
+		if !valid {
+			res.SetError(fmt.Errorf("signature is not valid for key %s", name), cmds.ErrNormal)
+			return
+		}
+
+		pid, err := peer.IDFromPublicKey(pk)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&KeyVerifyOutput{
+			Key:            KeyOutput{Name: name, Id: pid.Pretty()},
+			SignatureValid: valid,
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*KeyVerifyOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			return strings.NewReader(fmt.Sprintf("signature is valid for key %s\n", out.Key.Name)), nil
+		},
+	},
+	Type: KeyVerifyOutput{},
+}
+
+// signingKey returns the private key with the given name, loading the node's
+// own key if the node was not started.
+func signingKey(n *core.IpfsNode, name string) (ci.PrivKey, error) {
+	if name == "self" && n.PrivateKey == nil {
+		if err := n.LoadPrivateKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	sk, err := n.GetKey(name)
+	if err == keystore.ErrNoSuchKey {
+		return nil, fmt.Errorf("no key named %s was found", name)
+	}
+	return sk, err
+}
+
+// readSignedData reads the data argument of 'ipfs key sign' and 'ipfs key
+// verify', with signedMessagePrefix prepended.
+func readSignedData(req cmds.Request) ([]byte, error) {
+	file, err := req.Files().NextFile()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(signedMessagePrefix), data...), nil
+}
+
+// hasPublishedName reports whether an IPNS record for the given key is held
+// in the local datastore, which is the case once it was used to publish.
+func hasPublishedName(n *core.IpfsNode, pid peer.ID) (bool, error) {
+	_, ipnskey := namesys.IpnsKeysForID(pid)
+	return n.Repo.Datastore().Has(dshelp.NewKeyFromBinary([]byte(ipnskey)))
+}
+
 func keyOutputListMarshaler(res cmds.Response) (io.Reader, error) {
 	withId, _, _ := res.Request().Option("l").Bool()
 
@@ -1,10 +1,17 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"sort"
+	"strings"
 
 	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 )
@@ -36,13 +43,25 @@ var logLevelCmd = &cmds.Command{
 		Tagline: "Change the logging level.",
 		ShortDescription: `
 Change the verbosity of one or all subsystems log output. This does not affect the event log.
+`,
+		LongDescription: `
+Change the verbosity of one or all subsystems log output. This does not affect the event log.
+
+Several subsystems can be given as a comma-separated list, and each entry may
+be a wildcard pattern such as '*' or 'core/*'. Names without a wildcard must
+match a subsystem listed by 'ipfs log ls'.
+
+Examples:
+
+    > ipfs log level dht,bitswap debug
+    > ipfs log level '*' warn
 `,
 	},
 
 	Arguments: []cmds.Argument{
 		// TODO use a different keyword for 'all' because all can theoretically
 		// clash with a subsystem name
-		cmds.StringArg("subsystem", true, false, fmt.Sprintf("The subsystem logging identifier. Use '%s' for all subsystems.", logAllKeyword)),
+		cmds.StringArg("subsystem", true, false, fmt.Sprintf("The subsystem logging identifier, a comma-separated list of them, or a wildcard pattern. Use '%s' for all subsystems.", logAllKeyword)),
 		cmds.StringArg("level", true, false, `The log level, with 'debug' the most verbose and 'critical' the least verbose.
 			One of: debug, info, warning, error, critical.
 		`),
@@ -50,18 +69,29 @@ Change the verbosity of one or all subsystems log output. This does not affect t
 	Run: func(req cmds.Request, res cmds.Response) {
 
 		args := req.Arguments()
-		subsystem, level := args[0], args[1]
-
-		if subsystem == logAllKeyword {
-			subsystem = "*"
+		level := args[1]
+		if level == "warn" {
+			level = "warning"
+		}
+		if _, ok := logLevels[level]; !ok {
+			res.SetError(fmt.Errorf("invalid log level: %s", args[1]), cmds.ErrClient)
+			return
 		}
 
-		if err := logging.SetLogLevel(subsystem, level); err != nil {
-			res.SetError(err, cmds.ErrNormal)
+		subsystems, err := matchSubsystems(args[0], logging.GetSubsystems())
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
 			return
 		}
 
-		s := fmt.Sprintf("Changed log level of '%s' to '%s'\n", subsystem, level)
+		for _, subsystem := range subsystems {
+			if err := logging.SetLogLevel(subsystem, level); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		s := fmt.Sprintf("Changed log level of '%s' to '%s'\n", strings.Join(subsystems, "', '"), level)
 		log.Info(s)
 		res.SetOutput(&MessageOutput{s})
 	},
@@ -71,6 +101,65 @@ Change the verbosity of one or all subsystems log output. This does not affect t
 	Type: MessageOutput{},
 }
 
+// matchSubsystems expands a comma-separated list of subsystem names and
+// wildcard patterns into the matching subsystems, in the order given and
+// without duplicates. The 'all' keyword and a bare '*' are passed through
+// as '*' so that loggers created later are affected too.
+func matchSubsystems(arg string, known []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(s string) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+
+	for _, name := range strings.Split(arg, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case name == logAllKeyword || name == "*":
+			add("*")
+		case strings.ContainsAny(name, "*?["):
+			matched := false
+			for _, k := range known {
+				ok, err := path.Match(name, k)
+				if err != nil {
+					return nil, fmt.Errorf("invalid subsystem pattern %q: %s", name, err)
+				}
+				if ok {
+					matched = true
+					add(k)
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("no subsystem matches %q", name)
+			}
+		default:
+			found := false
+			for _, k := range known {
+				if k == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				sorted := append([]string(nil), known...)
+				sort.Strings(sorted)
+				return nil, fmt.Errorf("unknown subsystem %q, valid subsystems are: %s", name, strings.Join(sorted, ", "))
+			}
+			add(name)
+		}
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no subsystem given")
+	}
+	return out, nil
+}
+
 var logLsCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "List the logging subsystems.",
@@ -93,17 +182,135 @@ var logTailCmd = &cmds.Command{
 		Tagline: "Read the event log.",
 		ShortDescription: `
 Outputs event log messages (not other log messages) as they are generated.
+Each event is printed as a JSON object on its own line.
+`,
+		LongDescription: `
+Outputs event log messages (not other log messages) as they are generated.
+Each event is printed as a JSON object on its own line.
+
+Use --subsystem to only show events logged by the given subsystem (see
+'ipfs log ls'). Use --level to only show events whose "level" field is at or
+above the given level. Events without a "level" field, which includes most
+of the events ipfs logs, are always shown.
+
+If the daemon drops the log stream, for example because it could not keep
+up, it is reattached automatically. Interrupting the command detaches it.
 `,
 	},
 
+	Options: []cmds.Option{
+		cmds.StringOption("level", "l", "Only show events at or above this level. One of: debug, info, warning, error, critical.").Default("debug"),
+		cmds.StringOption("subsystem", "s", "Only show events from this subsystem."),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		ctx := req.Context()
-		r, w := io.Pipe()
+
+		lvl, _, _ := req.Option("level").String()
+		minLevel, ok := logLevels[lvl]
+		if !ok {
+			res.SetError(fmt.Errorf("invalid log level: %s", lvl), cmds.ErrClient)
+			return
+		}
+		subsystem, _, _ := req.Option("subsystem").String()
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+
 		go func() {
-			defer w.Close()
-			<-ctx.Done()
+			defer close(out)
+			for {
+				r, w := io.Pipe()
+				logging.WriterGroup.AddWriter(w)
+
+				// Closing the writer makes the log writer group drop it on
+				// the next event, and ends the scan below.
+				detached := make(chan struct{})
+				go func() {
+					select {
+					case <-ctx.Done():
+						w.Close()
+					case <-detached:
+					}
+				}()
+
+				scanner := bufio.NewScanner(r)
+				scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+				for scanner.Scan() {
+					var ev LogEvent
+					if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+						log.Debugf("log tail: skipping malformed event: %s", err)
+						continue
+					}
+					if subsystem != "" && ev["system"] != subsystem {
+						continue
+					}
+					if l, ok := ev.level(); ok && l < minLevel {
+						continue
+					}
+
+					select {
+					case out <- ev:
+					case <-ctx.Done():
+						w.Close()
+					}
+				}
+				close(detached)
+				r.Close()
+
+				if ctx.Err() != nil {
+					return
+				}
+				// the writer group closed our pipe, attach a fresh one
+			}
 		}()
-		logging.WriterGroup.AddWriter(w)
-		res.SetOutput(r)
 	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			outCh, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				return bytes.NewReader(append(b, '\n')), nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outCh,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
+		},
+	},
+	Type: LogEvent{},
+}
+
+var logLevels = map[string]int{
+	"debug":    0,
+	"info":     1,
+	"warning":  2,
+	"error":    3,
+	"critical": 4,
+}
+
+// LogEvent is a single entry of the event log.
+type LogEvent map[string]interface{}
+
+// level returns the level the event was logged at. Events only have one if
+// they were logged with a "level" field; it is not inferred from the rest
+// of the event.
+func (ev LogEvent) level() (int, bool) {
+	s, ok := ev["level"].(string)
+	if !ok {
+		return 0, false
+	}
+	if s == "warn" {
+		s = "warning"
+	}
+	l, ok := logLevels[s]
+	return l, ok
 }
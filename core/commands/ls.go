@@ -2,8 +2,10 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"strings"
 	"text/tabwriter"
 
 	blockservice "github.com/ipfs/go-ipfs/blockservice"
@@ -16,6 +18,7 @@ import (
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 	unixfspb "github.com/ipfs/go-ipfs/unixfs/pb"
 
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
@@ -23,6 +26,10 @@ type LsLink struct {
 	Name, Hash string
 	Size       uint64
 	Type       unixfspb.Data_DataType
+
+	// CumulativeSize is the size of the whole DAG below the link, as
+	// computed from the linked node. It is only set with '--size'.
+	CumulativeSize uint64 `json:",omitempty"`
 }
 
 type LsObject struct {
@@ -43,7 +50,32 @@ the following format:
 
   <link base58 hash> <link size in bytes> <link name>
 
-The JSON output contains type information.
+With '--size', the cumulative size of each entry is taken from the linked
+node itself rather than from the link, and a type column is added:
+
+  <link base58 hash> <cumulative size in bytes> <type> <link name>
+
+The type is 'file', 'dir', 'symlink' or 'metadata'. Raw leaves are shown as
+files, and only in this mode have a JSON Type of 2 (file) rather than -1.
+Determining types and cumulative sizes fetches the linked nodes; with
+'--resolve-type=false' only locally available nodes are looked at, the size
+falls back to the link size and the type of other entries is shown as '-'.
+
+With '--resolve=false', the linked nodes are not looked at at all, and only
+the hash and the name of each entry are printed:
+
+  <link base58 hash> <link name>
+
+With '--stream', each entry is printed as soon as it is enumerated instead of
+once the whole directory was read. Entries come in the order the directory
+yields them, which for sharded directories is not sorted by name, and the
+columns are separated by a single space rather than aligned. Combine
+'--stream' with '--resolve=false' to list directories with tens of thousands
+of entries without fetching any of them.
+
+The JSON output contains type information. With '--stream', it is a sequence
+of objects holding one entry each; when several paths are listed, the entries
+of each path are preceded by an object holding no entries.
 `,
 	},
 
@@ -53,6 +85,9 @@ The JSON output contains type information.
 	Options: []cmds.Option{
 		cmds.BoolOption("headers", "v", "Print table headers (Hash, Size, Name).").Default(false),
 		cmds.BoolOption("resolve-type", "Resolve linked objects to find out their types.").Default(true),
+		cmds.BoolOption("resolve", "Look at linked objects for their type and size.").Default(true),
+		cmds.BoolOption("size", "Print the cumulative size and the type of each entry.").Default(false),
+		cmds.BoolOption("stream", "s", "Print entries as they are enumerated.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
@@ -67,22 +102,50 @@ The JSON output contains type information.
 			return
 		}
 
-		resolve, _, err := req.Option("resolve-type").Bool()
+		resolveType, _, err := req.Option("resolve-type").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		resolve, _, err := req.Option("resolve").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		size, _, err := req.Option("size").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		stream, _, err := req.Option("stream").Bool()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		dserv := nd.DAG
-		if !resolve {
+		if size && !resolve {
+			res.SetError(fmt.Errorf("--size cannot be used with --resolve=false"), cmds.ErrClient)
+			return
+		}
+
+		lo := &lsOptions{
+			dserv:       nd.DAG,
+			resolve:     resolve,
+			resolveType: resolveType,
+			size:        size,
+		}
+		if !resolveType {
 			offlineexch := offline.Exchange(nd.Blockstore)
 			bserv := blockservice.New(nd.Blockstore, offlineexch)
-			dserv = merkledag.NewDAGService(bserv)
+			lo.dserv = merkledag.NewDAGService(bserv)
 		}
 
 		paths := req.Arguments()
 
-		var dagnodes []node.Node
+		var dirs []*uio.Directory
 		for _, fpath := range paths {
 			p, err := path.ParsePath(fpath)
 			if err != nil {
@@ -100,17 +163,56 @@ The JSON output contains type information.
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
-			dagnodes = append(dagnodes, dagnode)
-		}
 
-		output := make([]LsObject, len(req.Arguments()))
-		for i, dagnode := range dagnodes {
 			dir, err := uio.NewDirectoryFromNode(nd.DAG, dagnode)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
+			dirs = append(dirs, dir)
+		}
+
+		if stream {
+			outChan := make(chan interface{})
+			res.SetOutput((<-chan interface{})(outChan))
+
+			go func() {
+				defer close(outChan)
+
+				send := func(obj LsObject) error {
+					select {
+					case outChan <- &LsOutput{[]LsObject{obj}}:
+						return nil
+					case <-req.Context().Done():
+						return req.Context().Err()
+					}
+				}
 
+				for i, dir := range dirs {
+					if len(dirs) > 1 {
+						if err := send(LsObject{Hash: paths[i], Links: []LsLink{}}); err != nil {
+							return
+						}
+					}
+
+					err := dir.ForEachLink(req.Context(), func(link *node.Link) error {
+						l, err := lo.lsLink(req.Context(), link)
+						if err != nil {
+							return err
+						}
+						return send(LsObject{Hash: paths[i], Links: []LsLink{l}})
+					})
+					if err != nil {
+						res.SetError(err, cmds.ErrNormal)
+						return
+					}
+				}
+			}()
+			return
+		}
+
+		output := make([]LsObject, len(dirs))
+		for i, dir := range dirs {
 			links, err := dir.Links(req.Context())
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
@@ -123,32 +225,11 @@ The JSON output contains type information.
 			}
 
 			for j, link := range links {
-				t := unixfspb.Data_DataType(-1)
-
-				linkNode, err := link.GetNode(req.Context(), dserv)
-				if err == merkledag.ErrNotFound && !resolve {
-					// not an error
-					linkNode = nil
-				} else if err != nil {
+				output[i].Links[j], err = lo.lsLink(req.Context(), link)
+				if err != nil {
 					res.SetError(err, cmds.ErrNormal)
 					return
 				}
-
-				if pn, ok := linkNode.(*merkledag.ProtoNode); ok {
-					d, err := unixfs.FromBytes(pn.Data())
-					if err != nil {
-						res.SetError(err, cmds.ErrNormal)
-						return
-					}
-
-					t = d.GetType()
-				}
-				output[i].Links[j] = LsLink{
-					Name: link.Name,
-					Hash: link.Cid.String(),
-					Size: link.Size,
-					Type: t,
-				}
 			}
 		}
 
@@ -156,9 +237,50 @@ The JSON output contains type information.
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-
 			headers, _, _ := res.Request().Option("headers").Bool()
-			output := res.Output().(*LsOutput)
+			size, _, _ := res.Request().Option("size").Bool()
+			resolve, _, _ := res.Request().Option("resolve").Bool()
+
+			if outChan, ok := res.Output().(<-chan interface{}); ok {
+				started, needHeader := false, true
+				marshal := func(v interface{}) (io.Reader, error) {
+					output, ok := v.(*LsOutput)
+					if !ok || len(output.Objects) != 1 {
+						return nil, u.ErrCast()
+					}
+					object := output.Objects[0]
+
+					buf := new(bytes.Buffer)
+					// An object without links starts the listing of one
+					// of several paths.
+					if len(object.Links) == 0 {
+						if started {
+							fmt.Fprintln(buf)
+						}
+						fmt.Fprintf(buf, "%s:\n", object.Hash)
+						started, needHeader = true, true
+						return buf, nil
+					}
+
+					if needHeader && headers {
+						lsWriteHeader(buf, " ", size, resolve)
+					}
+					started, needHeader = true, false
+					lsWriteLink(buf, " ", object.Links[0], size, resolve)
+					return buf, nil
+				}
+
+				return &cmds.ChannelMarshaler{
+					Channel:   outChan,
+					Marshaler: marshal,
+					Res:       res,
+				}, nil
+			}
+
+			output, ok := res.Output().(*LsOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
 			buf := new(bytes.Buffer)
 			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
 			for _, object := range output.Objects {
@@ -166,13 +288,10 @@ The JSON output contains type information.
 					fmt.Fprintf(w, "%s:\n", object.Hash)
 				}
 				if headers {
-					fmt.Fprintln(w, "Hash\tSize\tName")
+					lsWriteHeader(w, "\t", size, resolve)
 				}
 				for _, link := range object.Links {
-					if link.Type == unixfspb.Data_Directory {
-						link.Name += "/"
-					}
-					fmt.Fprintf(w, "%s\t%v\t%s\n", link.Hash, link.Size, link.Name)
+					lsWriteLink(w, "\t", link, size, resolve)
 				}
 				if len(output.Objects) > 1 {
 					fmt.Fprintln(w)
@@ -185,3 +304,112 @@ The JSON output contains type information.
 	},
 	Type: LsOutput{},
 }
+
+// lsOptions says how much 'ipfs ls' looks at the linked nodes.
+type lsOptions struct {
+	dserv merkledag.DAGService
+
+	// resolve is false if linked nodes are not looked at at all.
+	resolve bool
+	// resolveType is false if only linked nodes present locally are
+	// looked at.
+	resolveType bool
+	// size is set if the cumulative size is taken from the linked nodes.
+	size bool
+}
+
+// lsLink builds the entry for link.
+func (lo *lsOptions) lsLink(ctx context.Context, link *node.Link) (LsLink, error) {
+	l := LsLink{
+		Name: link.Name,
+		Hash: link.Cid.String(),
+		Size: link.Size,
+		Type: unixfspb.Data_DataType(-1),
+	}
+	if !lo.resolve {
+		return l, nil
+	}
+
+	linkNode, err := link.GetNode(ctx, lo.dserv)
+	if err == merkledag.ErrNotFound && !lo.resolveType {
+		// not an error
+		linkNode = nil
+	} else if err != nil {
+		return LsLink{}, err
+	}
+
+	switch ln := linkNode.(type) {
+	case *merkledag.ProtoNode:
+		d, err := unixfs.FromBytes(ln.Data())
+		if err != nil {
+			return LsLink{}, err
+		}
+
+		l.Type = d.GetType()
+	case *merkledag.RawNode:
+		// raw leaves have no unixfs type, and have always been listed
+		// with a Type of -1. Keep that, and only call them files in the
+		// --size output.
+		if lo.size {
+			l.Type = unixfspb.Data_File
+		}
+	}
+
+	if lo.size {
+		l.CumulativeSize = link.Size
+		if linkNode != nil {
+			cs, err := linkNode.Size()
+			if err != nil {
+				return LsLink{}, err
+			}
+			l.CumulativeSize = cs
+		}
+	}
+	return l, nil
+}
+
+// lsWriteHeader writes the table headers of 'ipfs ls', with columns
+// separated by sep.
+func lsWriteHeader(w io.Writer, sep string, size, resolve bool) {
+	switch {
+	case size:
+		fmt.Fprintln(w, strings.Join([]string{"Hash", "Size", "Type", "Name"}, sep))
+	case !resolve:
+		fmt.Fprintln(w, strings.Join([]string{"Hash", "Name"}, sep))
+	default:
+		fmt.Fprintln(w, strings.Join([]string{"Hash", "Size", "Name"}, sep))
+	}
+}
+
+// lsWriteLink writes one entry of 'ipfs ls', with columns separated by sep.
+func lsWriteLink(w io.Writer, sep string, link LsLink, size, resolve bool) {
+	if link.Type == unixfspb.Data_Directory {
+		link.Name += "/"
+	}
+
+	switch {
+	case size:
+		fmt.Fprintln(w, strings.Join([]string{link.Hash, fmt.Sprint(link.CumulativeSize), lsTypeName(link.Type), link.Name}, sep))
+	case !resolve:
+		fmt.Fprintln(w, strings.Join([]string{link.Hash, link.Name}, sep))
+	default:
+		fmt.Fprintln(w, strings.Join([]string{link.Hash, fmt.Sprint(link.Size), link.Name}, sep))
+	}
+}
+
+// lsTypeName returns the name of the unixfs type t for the text output of
+// 'ipfs ls --size'.
+func lsTypeName(t unixfspb.Data_DataType) string {
+	switch t {
+	case unixfspb.Data_File, unixfspb.Data_Raw:
+		return "file"
+	case unixfspb.Data_Directory, unixfspb.Data_HAMTShard:
+		return "dir"
+	case unixfspb.Data_Symlink:
+		return "symlink"
+	case unixfspb.Data_Metadata:
+		return "metadata"
+	default:
+		return "-"
+	}
+}
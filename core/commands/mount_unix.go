@@ -68,11 +68,24 @@ baz
 baz
 > cat /ipfs/QmWLdkp93sNxGRjnFHPaYg8tCQ35NBY3XPn6KiETd3Z4WR
 baz
+
+EXPERIMENTAL: with --mfs, the mutable files root (see 'ipfs files') is also
+mounted read-write at the given path. Changes made there go through the same
+MFS root as the 'ipfs files' commands and update it as they are written:
+
+> ipfs mount --mfs=/mfs
+IPFS mounted at: /ipfs
+IPNS mounted at: /ipns
+MFS mounted at: /mfs
+> echo "baz" > /mfs/bar
+> ipfs files read /bar
+baz
 `,
 	},
 	Options: []cmds.Option{
 		cmds.StringOption("ipfs-path", "f", "The path where IPFS should be mounted."),
 		cmds.StringOption("ipns-path", "n", "The path where IPNS should be mounted."),
+		cmds.StringOption("mfs", "Also mount the MFS root read-write at this path. Experimental."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		cfg, err := req.InvocContext().GetConfig()
@@ -112,24 +125,49 @@ baz
 			nsdir = cfg.Mounts.IPNS // NB: be sure to not redeclare!
 		}
 
+		mfsdir, _, err := req.Option("mfs").String()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		err = nodeMount.Mount(node, fsdir, nsdir)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		var output config.Mounts
+		if mfsdir != "" {
+			err = nodeMount.MountMfs(node, mfsdir)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		var output MountOutput
 		output.IPFS = fsdir
 		output.IPNS = nsdir
+		output.MFS = mfsdir
 		res.SetOutput(&output)
 	},
-	Type: config.Mounts{},
+	Type: MountOutput{},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			v := res.Output().(*config.Mounts)
+			v := res.Output().(*MountOutput)
 			s := fmt.Sprintf("IPFS mounted at: %s\n", v.IPFS)
 			s += fmt.Sprintf("IPNS mounted at: %s\n", v.IPNS)
+			if v.MFS != "" {
+				s += fmt.Sprintf("MFS mounted at: %s\n", v.MFS)
+			}
 			return strings.NewReader(s), nil
 		},
 	},
 }
+
+// MountOutput lists the mountpoints set up by 'ipfs mount'. MFS is only set
+// when the experimental MFS mount was requested.
+type MountOutput struct {
+	config.Mounts
+	MFS string `json:",omitempty"`
+}
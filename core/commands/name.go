@@ -5,6 +5,9 @@ import cmds "github.com/ipfs/go-ipfs/commands"
 type IpnsEntry struct {
 	Name  string
 	Value string
+	// Error is set instead of Value when publishing under one of several
+	// keys failed.
+	Error string `json:",omitempty"`
 }
 
 var NameCmd = &cmds.Command{
@@ -7,12 +7,15 @@ import (
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
+	dag "github.com/ipfs/go-ipfs/merkledag"
 	dagutils "github.com/ipfs/go-ipfs/merkledag/utils"
 	path "github.com/ipfs/go-ipfs/path"
 )
 
 type Changes struct {
 	Changes []*dagutils.Change
+	// DataChanged is set when the data of the two compared nodes differs.
+	DataChanged bool `json:",omitempty"`
 }
 
 var ObjectDiffCmd = &cmds.Command{
@@ -40,6 +43,11 @@ Example:
    > OBJ_B=QmcmRptkSPWhptCttgHg27QNDmnV33wAJyUkCnAvqD3eCD
    > ipfs object diff -v $OBJ_A $OBJ_B
    Changed "bar" from QmNgd5cz2jNftnAHBhcRUGdtiaMzb5Rhjqd4etondHHST8 to QmRfFVsjSXkhFxrfWnLpMae2M4GBVsry6VAuYYcji5MiZb.
+
+By default changed links are descended into, producing a diff of the whole
+tree. With --recursive=false only the links of the two objects themselves
+are compared. When the data of the two objects differs, this is reported as
+well.
 `,
 	},
 	Arguments: []cmds.Argument{
@@ -48,6 +56,7 @@ Example:
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("verbose", "v", "Print extra information."),
+		cmds.BoolOption("recursive", "r", "Descend into changed links.").Default(true),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		node, err := req.InvocContext().GetNode()
@@ -85,13 +94,26 @@ Example:
 			return
 		}
 
-		changes, err := dagutils.Diff(ctx, node.DAG, obj_a, obj_b)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
+		out := &Changes{}
+
+		pbA, okA := obj_a.(*dag.ProtoNode)
+		pbB, okB := obj_b.(*dag.ProtoNode)
+		if okA && okB {
+			out.DataChanged = !bytes.Equal(pbA.Data(), pbB.Data())
+		}
+
+		recursive, _, _ := req.Option("recursive").Bool()
+		if recursive {
+			out.Changes, err = dagutils.Diff(ctx, node.DAG, obj_a, obj_b)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		} else {
+			out.Changes = dagutils.DiffLinks(obj_a, obj_b)
 		}
 
-		res.SetOutput(&Changes{changes})
+		res.SetOutput(out)
 	},
 	Type: Changes{},
 	Marshalers: cmds.MarshalerMap{
@@ -99,6 +121,13 @@ Example:
 			verbose, _, _ := res.Request().Option("v").Bool()
 			changes := res.Output().(*Changes)
 			buf := new(bytes.Buffer)
+			if changes.DataChanged {
+				if verbose {
+					fmt.Fprintln(buf, "Object data differs.")
+				} else {
+					fmt.Fprintln(buf, "~ data")
+				}
+			}
 			for _, change := range changes.Changes {
 				if verbose {
 					switch change.Type {
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	gopath "path"
 	"strings"
 	"text/tabwriter"
 
@@ -189,12 +190,23 @@ This command outputs data in the following encodings:
   * "protobuf"
   * "json"
   * "xml"
-(Specified by the "--encoding" or "--enc" flag)`,
+(Specified by the "--encoding" or "--enc" flag)
+
+The "--link-filter" option only outputs the links whose names match the
+given glob pattern, e.g. '*.jpg'. The pattern syntax is that of Go's
+path.Match. The "--no-data" option leaves out the data of the node, which
+is useful together with "--link-filter" on large directories:
+
+  > ipfs object get --link-filter='*.jpg' --no-data --enc=json <key>`,
 	},
 
 	Arguments: []cmds.Argument{
 		cmds.StringArg("key", true, false, "Key of the object to retrieve, in base58-encoded multihash format.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.StringOption("link-filter", "Only output links whose names match this glob pattern."),
+		cmds.BoolOption("no-data", "Do not output the data of the node.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -202,6 +214,15 @@ This command outputs data in the following encodings:
 			return
 		}
 
+		linkFilter, filterLinks, _ := req.Option("link-filter").String()
+		if filterLinks {
+			if _, err := gopath.Match(linkFilter, ""); err != nil {
+				res.SetError(fmt.Errorf("invalid link filter %q: %s", linkFilter, err), cmds.ErrClient)
+				return
+			}
+		}
+		noData, _, _ := req.Option("no-data").Bool()
+
 		fpath := path.Path(req.Arguments()[0])
 
 		object, err := core.Resolve(req.Context(), n.Namesys, n.Resolver, fpath)
@@ -217,16 +238,24 @@ This command outputs data in the following encodings:
 		}
 
 		node := &Node{
-			Links: make([]Link, len(object.Links())),
-			Data:  string(pbo.Data()),
+			Links: make([]Link, 0, len(object.Links())),
+		}
+		if !noData {
+			node.Data = string(pbo.Data())
 		}
 
-		for i, link := range object.Links() {
-			node.Links[i] = Link{
+		for _, link := range object.Links() {
+			if filterLinks {
+				// the pattern was validated above
+				if ok, _ := gopath.Match(linkFilter, link.Name); !ok {
+					continue
+				}
+			}
+			node.Links = append(node.Links, Link{
 				Hash: link.Cid.String(),
 				Name: link.Name,
 				Size: link.Size,
-			}
+			})
 		}
 
 		res.SetOutput(node)
@@ -51,6 +51,9 @@ Example:
 
 	$ echo "hello" | ipfs object patch $HASH append-data
 
+The data is read from stdin when not given as a file argument. Appending no
+data returns the original object's hash unchanged.
+
 NOTE: This does not append data to a file - it modifies the actual raw
 data within an object. Objects have a max size of 1MB and objects larger than
 the limit will not be respected by the network.
@@ -91,13 +94,18 @@ the limit will not be respected by the network.
 			return
 		}
 
-		data, err := ioutil.ReadAll(fi)
+		data, n, err := appendChunks(rtpb.Data(), fi)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		rtpb.SetData(append(rtpb.Data(), data...))
+		if n == 0 {
+			res.SetOutput(&Object{Hash: rtpb.Cid().String()})
+			return
+		}
+
+		rtpb.SetData(data)
 
 		newkey, err := nd.DAG.Add(rtpb)
 		if err != nil {
@@ -113,6 +121,30 @@ the limit will not be respected by the network.
 	},
 }
 
+// appendChunkSize is how much of the appended data is read at a time.
+const appendChunkSize = 64 * 1024
+
+// appendChunks appends what r yields to a copy of data, one chunk at a time,
+// and returns the result along with the number of bytes appended.
+func appendChunks(data []byte, r io.Reader) ([]byte, int64, error) {
+	out := make([]byte, len(data), len(data)+appendChunkSize)
+	copy(out, data)
+
+	var n int64
+	chunk := make([]byte, appendChunkSize)
+	for {
+		read, err := r.Read(chunk)
+		out = append(out, chunk[:read]...)
+		n += int64(read)
+		if err == io.EOF {
+			return out, n, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
 var patchSetDataCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Set the data field of an IPFS object.",
@@ -6,9 +6,11 @@ import (
 	"io"
 	"time"
 
+	blockservice "github.com/ipfs/go-ipfs/blockservice"
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
+	offline "github.com/ipfs/go-ipfs/exchange/offline"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
 	pin "github.com/ipfs/go-ipfs/pin"
@@ -17,6 +19,7 @@ import (
 	context "context"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
 var PinCmd = &cmds.Command{
@@ -28,6 +31,7 @@ var PinCmd = &cmds.Command{
 		"add":    addPinCmd,
 		"rm":     rmPinCmd,
 		"ls":     listPinCmd,
+		"remote": remotePinCmd,
 		"verify": verifyPinCmd,
 		"update": updatePinCmd,
 	},
@@ -40,12 +44,41 @@ type PinOutput struct {
 type AddPinOutput struct {
 	Pins     []string
 	Progress int `json:",omitempty"`
+	// DryRun is only set with --dry-run, in place of Pins.
+	DryRun []PinEstimate `json:",omitempty"`
+}
+
+// PinEstimate is the outcome of 'ipfs pin add --dry-run' for one path.
+type PinEstimate struct {
+	Cid string
+	// LocalBlocks and LocalSize count the blocks of the dag that are
+	// already stored locally.
+	LocalBlocks int
+	LocalSize   uint64
+	// FetchBlocks and FetchSize count the blocks that pinning would have to
+	// fetch. Missing dag-pb subtrees are not walked; they count as one
+	// block, and as the size recorded in the link to them, so FetchBlocks
+	// is only a lower bound when Exact is false.
+	FetchBlocks int
+	FetchSize   uint64
+	Exact       bool
 }
 
 var addPinCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
-		Tagline:          "Pin objects to local storage.",
-		ShortDescription: "Stores an IPFS object(s) from a given path locally to disk.",
+		Tagline: "Pin objects to local storage.",
+		ShortDescription: `
+Stores an IPFS object(s) from a given path locally to disk.
+
+With --dry-run, nothing is pinned. Instead, the dag below each object is
+walked to report how many blocks and bytes are already stored locally, and
+how many would have to be fetched to complete the pin. Subtrees of dag-pb
+nodes that are not stored locally are not fetched; their size is taken from
+the link pointing to them, so the number of blocks to fetch is a lower bound
+and reported as such. Missing blocks of other formats have to be fetched to
+find their links. Blocks fetched by a dry run are not pinned, and can be
+removed by 'ipfs repo gc'.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -54,6 +87,8 @@ var addPinCmd = &cmds.Command{
 	Options: []cmds.Option{
 		cmds.BoolOption("recursive", "r", "Recursively pin the object linked to by the specified object(s).").Default(true),
 		cmds.BoolOption("progress", "Show progress"),
+		cmds.StringOption("name", "A human readable name to attach to the pin(s)."),
+		cmds.BoolOption("dry-run", "Only report how much would have to be fetched, without pinning.").Default(false),
 	},
 	Type: AddPinOutput{},
 	Run: func(req cmds.Request, res cmds.Response) {
@@ -72,6 +107,22 @@ var addPinCmd = &cmds.Command{
 			return
 		}
 		showProgress, _, _ := req.Option("progress").Bool()
+		name, _, _ := req.Option("name").String()
+
+		dryRun, _, _ := req.Option("dry-run").Bool()
+		if dryRun {
+			estimates := make([]PinEstimate, 0, len(req.Arguments()))
+			for _, p := range req.Arguments() {
+				e, err := estimatePin(req.Context(), n, p, recursive)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				estimates = append(estimates, *e)
+			}
+			res.SetOutput(&AddPinOutput{DryRun: estimates})
+			return
+		}
 
 		if !showProgress {
 			added, err := corerepo.Pin(n, req.Context(), req.Arguments(), recursive)
@@ -79,6 +130,10 @@ var addPinCmd = &cmds.Command{
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
+			if err := setPinNames(n, added, name); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
 			res.SetOutput(&AddPinOutput{Pins: cidsToStrings(added)})
 			return
 		}
@@ -94,6 +149,10 @@ var addPinCmd = &cmds.Command{
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
+			if err := setPinNames(n, added, name); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
 			ch <- added
 		}()
 		out := make(chan interface{})
@@ -129,6 +188,9 @@ var addPinCmd = &cmds.Command{
 
 			switch out := res.Output().(type) {
 			case *AddPinOutput:
+				if out.DryRun != nil {
+					return pinEstimatesText(out.DryRun), nil
+				}
 				added = out.Pins
 			case <-chan interface{}:
 				progressLine := false
@@ -170,12 +232,107 @@ var addPinCmd = &cmds.Command{
 	},
 }
 
+// estimatePin walks the dag below fpath like pinning it would, without
+// fetching the missing subtrees of dag-pb nodes.
+func estimatePin(ctx context.Context, n *core.IpfsNode, fpath string, recursive bool) (*PinEstimate, error) {
+	p, err := path.ParsePath(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	offlineDAG := dag.NewDAGService(blockservice.New(n.Blockstore, offline.Exchange(n.Blockstore)))
+	e := &PinEstimate{Exact: true}
+
+	// Resolve locally first, so that a root that has to be fetched is
+	// counted as such.
+	root, err := core.Resolve(ctx, n.Namesys, &path.Resolver{DAG: offlineDAG, ResolveOnce: uio.ResolveUnixfsOnce}, p)
+	rootLocal := err == nil
+	if err != nil {
+		root, err = core.Resolve(ctx, n.Namesys, &path.Resolver{DAG: n.DAG, ResolveOnce: uio.ResolveUnixfsOnce}, p)
+		if err != nil {
+			return nil, fmt.Errorf("pin: %s", err)
+		}
+	}
+	e.Cid = root.Cid().String()
+
+	seen := cid.NewSet()
+	seen.Add(root.Cid())
+	if rootLocal {
+		e.LocalBlocks++
+		e.LocalSize += uint64(len(root.RawData()))
+	} else {
+		e.FetchBlocks++
+		e.FetchSize += uint64(len(root.RawData()))
+	}
+	if !recursive {
+		return e, nil
+	}
+
+	var walk func(nd node.Node) error
+	walk = func(nd node.Node) error {
+		_, isPB := nd.(*dag.ProtoNode)
+		for _, lnk := range nd.Links() {
+			if !seen.Visit(lnk.Cid) {
+				continue
+			}
+
+			has, err := n.Blockstore.Has(lnk.Cid)
+			if err != nil {
+				return err
+			}
+
+			if !has && isPB {
+				e.FetchBlocks++
+				e.FetchSize += lnk.Size
+				e.Exact = false
+				continue
+			}
+
+			child, err := n.DAG.Get(ctx, lnk.Cid)
+			if err != nil {
+				return err
+			}
+			if has {
+				e.LocalBlocks++
+				e.LocalSize += uint64(len(child.RawData()))
+			} else {
+				e.FetchBlocks++
+				e.FetchSize += uint64(len(child.RawData()))
+			}
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func pinEstimatesText(estimates []PinEstimate) io.Reader {
+	buf := new(bytes.Buffer)
+	for _, e := range estimates {
+		atLeast := ""
+		if !e.Exact {
+			atLeast = "at least "
+		}
+		fmt.Fprintf(buf, "%s: would fetch %d bytes in %s%d blocks, %d bytes in %d blocks are local\n",
+			e.Cid, e.FetchSize, atLeast, e.FetchBlocks, e.LocalSize, e.LocalBlocks)
+	}
+	return buf
+}
+
 var rmPinCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Remove pinned objects from local storage.",
 		ShortDescription: `
 Removes the pin from the given object allowing it to be garbage
 collected if needed. (By default, recursively. Use -r=false for direct pins.)
+
+Objects can be given as CIDs, /ipfs/ or /ipns/ paths, or paths in the mutable
+file system (see 'ipfs files'), which are any other paths starting with '/'.
 `,
 	},
 
@@ -251,6 +408,13 @@ With arguments, the command fails if any of the arguments is not a pinned
 object. And if --type=<type> is additionally used, the command will also fail
 if any of the arguments is not of the specified type.
 
+Arguments can be CIDs, /ipfs/ or /ipns/ paths, or paths in the mutable file
+system (see 'ipfs files'), which are any other paths starting with '/'.
+
+With --stream, every pin is written out as soon as it is enumerated instead of
+after the whole pinset has been collected. The order of the output is not
+stable in this mode.
+
 Example:
 	$ echo "hello" | ipfs add -q
 	QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN
@@ -274,6 +438,8 @@ Example:
 	Options: []cmds.Option{
 		cmds.StringOption("type", "t", "The type of pinned keys to list. Can be \"direct\", \"indirect\", \"recursive\", or \"all\".").Default("all"),
 		cmds.BoolOption("quiet", "q", "Write just hashes of objects.").Default(false),
+		cmds.BoolOption("names", "n", "Also write the names attached to pins.").Default(false),
+		cmds.BoolOption("stream", "s", "Write each pin as soon as it is found, in no particular order.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -296,6 +462,48 @@ Example:
 			return
 		}
 
+		names, _, _ := req.Option("names").Bool()
+
+		stream, _, _ := req.Option("stream").Bool()
+		if stream {
+			out := make(chan interface{})
+			res.SetOutput((<-chan interface{})(out))
+
+			go func() {
+				defer close(out)
+
+				ctx := req.Context()
+				emit := func(c *cid.Cid, typeStr string) error {
+					o := &PinLsObject{Cid: c.String(), Type: typeStr}
+					if names {
+						name, err := n.Pinning.Name(c)
+						if err != nil {
+							return err
+						}
+						o.Name = name
+					}
+
+					select {
+					case out <- &PinLsOutput{PinLsObject: o}:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+
+				var err error
+				if len(req.Arguments()) > 0 {
+					err = pinLsKeysStream(req.Arguments(), typeStr, ctx, n, emit)
+				} else {
+					err = pinLsAllStream(typeStr, ctx, n, emit)
+				}
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+				}
+			}()
+			return
+		}
+
 		var keys map[string]RefKeyObject
 
 		if len(req.Arguments()) > 0 {
@@ -306,30 +514,73 @@ Example:
 
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
-		} else {
-			res.SetOutput(&RefKeyList{Keys: keys})
+			return
+		}
+
+		if names {
+			for k, v := range keys {
+				c, err := cid.Decode(k)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				v.Name, err = n.Pinning.Name(c)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				keys[k] = v
+			}
 		}
+
+		res.SetOutput(&PinLsOutput{RefKeyList: &RefKeyList{Keys: keys}})
 	},
-	Type: RefKeyList{},
+	Type: PinLsOutput{},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
 			quiet, _, err := res.Request().Option("quiet").Bool()
 			if err != nil {
 				return nil, err
 			}
+			names, _, _ := res.Request().Option("names").Bool()
 
-			keys, ok := res.Output().(*RefKeyList)
-			if !ok {
-				return nil, u.ErrCast()
-			}
-			out := new(bytes.Buffer)
-			for k, v := range keys.Keys {
+			writePin := func(out io.Writer, k string, v RefKeyObject) {
 				if quiet {
 					fmt.Fprintf(out, "%s\n", k)
+				} else if names {
+					fmt.Fprintf(out, "%s %s %s\n", k, v.Type, v.Name)
 				} else {
 					fmt.Fprintf(out, "%s %s\n", k, v.Type)
 				}
 			}
+
+			if outChan, ok := res.Output().(<-chan interface{}); ok {
+				marshal := func(v interface{}) (io.Reader, error) {
+					o, ok := v.(*PinLsOutput)
+					if !ok || o.PinLsObject == nil {
+						return nil, u.ErrCast()
+					}
+
+					out := new(bytes.Buffer)
+					writePin(out, o.Cid, RefKeyObject{Type: o.Type, Name: o.Name})
+					return out, nil
+				}
+
+				return &cmds.ChannelMarshaler{
+					Channel:   outChan,
+					Marshaler: marshal,
+					Res:       res,
+				}, nil
+			}
+
+			keys, ok := res.Output().(*PinLsOutput)
+			if !ok || keys.RefKeyList == nil {
+				return nil, u.ErrCast()
+			}
+			out := new(bytes.Buffer)
+			for k, v := range keys.Keys {
+				writePin(out, k, v)
+			}
 			return out, nil
 		},
 	},
@@ -342,6 +593,10 @@ var updatePinCmd = &cmds.Command{
 Updates one pin to another, making sure that all objects in the new pin are
 local.  Then removes the old pin. This is an optimized version of adding the
 new pin and removing the old one.
+
+The old pin's dag is used as a hint: subgraphs that the old and the new root
+share are not walked again, so only the blocks that changed are fetched. Use
+'--unpin=false' to keep the old pin as well.
 `,
 	},
 
@@ -395,12 +650,20 @@ new pin and removing the old one.
 			return
 		}
 
+		defer n.Blockstore.PinLock().Unlock()
+
 		err = n.Pinning.Update(req.Context(), fromc, toc, unpin)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
+		err = n.Pinning.Flush()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		res.SetOutput(&PinOutput{Pins: []string{from.String(), to.String()}})
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -420,10 +683,22 @@ new pin and removing the old one.
 var verifyPinCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Verify that recursive pins are complete.",
+		ShortDescription: `
+Checks that every block of each recursively pinned DAG is present locally.
+
+With --repair, missing blocks of broken pins are fetched from the network
+and each pin is reported as 'ok', 'repaired' or 'unrecoverable'. The
+command fails if any pin could not be repaired.
+
+--repair-timeout bounds the repair attempt of each pin. The global
+--timeout option still applies to the command as a whole.
+`,
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("verbose", "Also write the hashes of non-broken pins."),
 		cmds.BoolOption("quiet", "q", "Write just hashes of broken pins."),
+		cmds.BoolOption("repair", "Fetch missing blocks of broken pins from the network."),
+		cmds.StringOption("repair-timeout", "Maximum time to spend repairing each pin.").Default("5m"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -435,17 +710,44 @@ var verifyPinCmd = &cmds.Command{
 		verbose, _, _ := res.Request().Option("verbose").Bool()
 		quiet, _, _ := res.Request().Option("quiet").Bool()
 
+		repair, _, _ := res.Request().Option("repair").Bool()
+		timeoutS, _, _ := res.Request().Option("repair-timeout").String()
+
 		if verbose && quiet {
 			res.SetError(fmt.Errorf("The --verbose and --quiet options can not be used at the same time"), cmds.ErrNormal)
+			return
+		}
+
+		timeout, err := time.ParseDuration(timeoutS)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
 		}
 
 		opts := pinVerifyOpts{
 			explain:   !quiet,
 			includeOk: verbose,
+			repair:    repair,
+			timeout:   timeout,
 		}
-		out := pinVerify(req.Context(), n, opts)
+		results := pinVerify(req.Context(), n, opts)
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
 
-		res.SetOutput(out)
+		go func() {
+			defer close(out)
+			var unrecoverable int
+			for r := range results {
+				if !r.(*PinVerifyRes).Ok {
+					unrecoverable++
+				}
+				out <- r
+			}
+			if repair && unrecoverable > 0 {
+				res.SetError(fmt.Errorf("%d pins could not be repaired", unrecoverable), cmds.ErrNormal)
+			}
+		}()
 	},
 	Type: PinVerifyRes{},
 	Marshalers: cmds.MarshalerMap{
@@ -457,32 +759,54 @@ var verifyPinCmd = &cmds.Command{
 				return nil, u.ErrCast()
 			}
 
-			rdr, wtr := io.Pipe()
-			go func() {
-				defer wtr.Close()
-				for r0 := range outChan {
-					r := r0.(*PinVerifyRes)
-					if quiet && !r.Ok {
-						fmt.Fprintf(wtr, "%s\n", r.Cid)
-					} else if !quiet {
-						r.Format(wtr)
-					}
+			marshal := func(v interface{}) (io.Reader, error) {
+				r, ok := v.(*PinVerifyRes)
+				if !ok {
+					return nil, u.ErrCast()
 				}
-			}()
 
-			return rdr, nil
+				buf := new(bytes.Buffer)
+				if quiet && !r.Ok {
+					fmt.Fprintf(buf, "%s\n", r.Cid)
+				} else if !quiet {
+					r.Format(buf)
+				}
+				return buf, nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
 		},
 	},
 }
 
 type RefKeyObject struct {
 	Type string
+	Name string `json:",omitempty"`
 }
 
 type RefKeyList struct {
 	Keys map[string]RefKeyObject
 }
 
+// PinLsObject is a single pin, as written by 'pin ls --stream'.
+type PinLsObject struct {
+	Cid  string
+	Type string
+	Name string `json:",omitempty"`
+}
+
+// PinLsOutput is the output of 'pin ls'. It holds the whole RefKeyList, or a
+// single PinLsObject when streaming. The fields are pointers so that only the
+// one in use shows up in the JSON output.
+type PinLsOutput struct {
+	*RefKeyList
+	*PinLsObject
+}
+
 func pinLsKeys(args []string, typeStr string, ctx context.Context, n *core.IpfsNode) (map[string]RefKeyObject, error) {
 
 	mode, ok := pin.StringToPinMode(typeStr)
@@ -498,12 +822,7 @@ func pinLsKeys(args []string, typeStr string, ctx context.Context, n *core.IpfsN
 	}
 
 	for _, p := range args {
-		pth, err := path.ParsePath(p)
-		if err != nil {
-			return nil, err
-		}
-
-		c, err := core.ResolveToCid(ctx, n.Namesys, r, pth)
+		c, err := corerepo.ResolvePath(ctx, n, r, p)
 		if err != nil {
 			return nil, err
 		}
@@ -562,6 +881,86 @@ func pinLsAll(typeStr string, ctx context.Context, n *core.IpfsNode) (map[string
 	return keys, nil
 }
 
+// pinLsKeysStream calls emit for every argument, like pinLsKeys.
+func pinLsKeysStream(args []string, typeStr string, ctx context.Context, n *core.IpfsNode, emit func(*cid.Cid, string) error) error {
+	keys, err := pinLsKeys(args, typeStr, ctx, n)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range keys {
+		c, err := cid.Decode(k)
+		if err != nil {
+			return err
+		}
+		if err := emit(c, v.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pinLsAllStream calls emit for every pin of the given type as it is
+// enumerated, without collecting the results first. Like pinLsAll, with type
+// "all" a key is reported once, as recursive over indirect over direct. Only
+// the set of visited blocks is kept in memory.
+func pinLsAllStream(typeStr string, ctx context.Context, n *core.IpfsNode, emit func(*cid.Cid, string) error) error {
+	all := typeStr == "all"
+
+	recursive := n.Pinning.RecursiveKeys()
+	recursiveSet := cid.NewSet()
+	if all {
+		for _, c := range recursive {
+			recursiveSet.Add(c)
+		}
+	}
+
+	if typeStr == "recursive" || all {
+		for _, c := range recursive {
+			if err := emit(c, "recursive"); err != nil {
+				return err
+			}
+		}
+	}
+
+	indirect := cid.NewSet()
+	if typeStr == "indirect" || all {
+		var emitErr error
+		visit := func(c *cid.Cid) bool {
+			if emitErr != nil || !indirect.Visit(c) {
+				return false
+			}
+			if !recursiveSet.Has(c) {
+				emitErr = emit(c, "indirect")
+			}
+			return emitErr == nil
+		}
+
+		for _, k := range recursive {
+			err := dag.EnumerateChildren(ctx, n.DAG.GetLinks, k, visit)
+			if err != nil {
+				return err
+			}
+			if emitErr != nil {
+				return emitErr
+			}
+		}
+	}
+
+	if typeStr == "direct" || all {
+		for _, c := range n.Pinning.DirectKeys() {
+			if recursiveSet.Has(c) || indirect.Has(c) {
+				continue
+			}
+			if err := emit(c, "direct"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // PinVerifyRes is the result returned for each pin checked in "pin verify"
 type PinVerifyRes struct {
 	Cid string
@@ -571,6 +970,8 @@ type PinVerifyRes struct {
 // PinStatus is part of PinVerifyRes, do not use directly
 type PinStatus struct {
 	Ok       bool
+	Repaired bool      `json:",omitempty"`
+	Repair   bool      `json:",omitempty"`
 	BadNodes []BadNode `json:",omitempty"`
 }
 
@@ -583,6 +984,8 @@ type BadNode struct {
 type pinVerifyOpts struct {
 	explain   bool
 	includeOk bool
+	repair    bool
+	timeout   time.Duration
 }
 
 func pinVerify(ctx context.Context, n *core.IpfsNode, opts pinVerifyOpts) <-chan interface{} {
@@ -620,12 +1023,35 @@ func pinVerify(ctx context.Context, n *core.IpfsNode, opts pinVerifyOpts) <-chan
 		return status
 	}
 
+	// repairPin fetches the missing parts of the DAG under root from the
+	// network and checks it again.
+	repairPin := func(root *cid.Cid) PinStatus {
+		rctx, cancel := context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+
+		fetchErr := dag.FetchGraph(rctx, root, n.DAG)
+
+		// forget cached results, some of them may have been repaired
+		visited = make(map[string]PinStatus)
+		status := checkPin(root)
+		status.Repair = true
+		if status.Ok {
+			status.Repaired = true
+		} else if fetchErr != nil && opts.explain {
+			status.BadNodes = append(status.BadNodes, BadNode{Cid: root.String(), Err: fetchErr.Error()})
+		}
+		return status
+	}
+
 	out := make(chan interface{})
 	go func() {
 		defer close(out)
 		for _, cid := range recPins {
 			pinStatus := checkPin(cid)
-			if !pinStatus.Ok || opts.includeOk {
+			if !pinStatus.Ok && opts.repair {
+				pinStatus = repairPin(cid)
+			}
+			if !pinStatus.Ok || pinStatus.Repaired || opts.includeOk {
 				out <- &PinVerifyRes{cid.String(), pinStatus}
 			}
 		}
@@ -636,8 +1062,15 @@ func pinVerify(ctx context.Context, n *core.IpfsNode, opts pinVerifyOpts) <-chan
 
 // Format formats PinVerifyRes
 func (r PinVerifyRes) Format(out io.Writer) {
-	if r.Ok {
+	if r.Repaired {
+		fmt.Fprintf(out, "%s repaired\n", r.Cid)
+	} else if r.Ok {
 		fmt.Fprintf(out, "%s ok\n", r.Cid)
+	} else if r.Repair {
+		fmt.Fprintf(out, "%s unrecoverable\n", r.Cid)
+		for _, e := range r.BadNodes {
+			fmt.Fprintf(out, "  %s: %s\n", e.Cid, e.Err)
+		}
 	} else {
 		fmt.Fprintf(out, "%s broken\n", r.Cid)
 		for _, e := range r.BadNodes {
@@ -646,6 +1079,20 @@ func (r PinVerifyRes) Format(out io.Writer) {
 	}
 }
 
+// setPinNames attaches name to each of the given pins. An empty name leaves
+// the existing names untouched.
+func setPinNames(n *core.IpfsNode, pins []*cid.Cid, name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, c := range pins {
+		if err := n.Pinning.SetName(c, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func cidsToStrings(cs []*cid.Cid) []string {
 	out := make([]string, 0, len(cs))
 	for _, c := range cs {
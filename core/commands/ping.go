@@ -2,8 +2,10 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
 	"strings"
 	"time"
@@ -24,6 +26,63 @@ type PingResult struct {
 	Success bool
 	Time    time.Duration
 	Text    string
+	Summary *PingSummary `json:",omitempty"`
+}
+
+// PingSummary holds the statistics of the pongs received by 'ipfs ping'.
+// Loss is the percentage of sent pings that got no pong.
+type PingSummary struct {
+	Sent     int
+	Received int
+	Loss     float64
+	Min      time.Duration
+	Avg      time.Duration
+	Max      time.Duration
+	StdDev   time.Duration
+}
+
+// pingProbe is a single pong as printed by 'ipfs ping --json'.
+type pingProbe struct {
+	Seq     int
+	Success bool
+	Time    time.Duration
+}
+
+// summarizePings computes the statistics of the given round trip times out
+// of sent pings.
+func summarizePings(sent int, rtts []time.Duration) *PingSummary {
+	sum := &PingSummary{
+		Sent:     sent,
+		Received: len(rtts),
+	}
+	if sent > 0 {
+		sum.Loss = float64(sent-len(rtts)) * 100 / float64(sent)
+	}
+	if len(rtts) == 0 {
+		return sum
+	}
+
+	var total time.Duration
+	sum.Min = rtts[0]
+	for _, t := range rtts {
+		total += t
+		if t < sum.Min {
+			sum.Min = t
+		}
+		if t > sum.Max {
+			sum.Max = t
+		}
+	}
+	sum.Avg = total / time.Duration(len(rtts))
+
+	var sq float64
+	for _, t := range rtts {
+		d := float64(t - sum.Avg)
+		sq += d * d
+	}
+	sum.StdDev = time.Duration(math.Sqrt(sq / float64(len(rtts))))
+
+	return sum
 }
 
 var PingCmd = &cmds.Command{
@@ -33,6 +92,12 @@ var PingCmd = &cmds.Command{
 'ipfs ping' is a tool to test sending data to other nodes. It finds nodes
 via the routing system, sends pings, waits for pongs, and prints out round-
 trip latency information.
+
+With --json, a JSON object is printed for every pong, holding its sequence
+number and round trip time in nanoseconds, followed by a summary object with
+the minimum, average, maximum and standard deviation of the round trip times
+and the percentage of lost pings. The summary covers the pings completed
+before the command was interrupted.
 		`,
 	},
 	Arguments: []cmds.Argument{
@@ -40,6 +105,7 @@ trip latency information.
 	},
 	Options: []cmds.Option{
 		cmds.IntOption("count", "n", "Number of ping messages to send.").Default(10),
+		cmds.BoolOption("json", "Print pongs and the summary as JSON objects.").Default(false),
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
@@ -49,12 +115,34 @@ trip latency information.
 				return nil, u.ErrCast()
 			}
 
+			asJSON, _, _ := res.Request().Option("json").Bool()
+			seq := 0
+
 			marshal := func(v interface{}) (io.Reader, error) {
 				obj, ok := v.(*PingResult)
 				if !ok {
 					return nil, u.ErrCast()
 				}
 
+				if asJSON {
+					var rec interface{}
+					switch {
+					case obj.Summary != nil:
+						rec = obj.Summary
+					case len(obj.Text) == 0:
+						seq++
+						rec = &pingProbe{Seq: seq, Success: obj.Success, Time: obj.Time}
+					default:
+						return strings.NewReader(""), nil
+					}
+
+					b, err := json.Marshal(rec)
+					if err != nil {
+						return nil, err
+					}
+					return bytes.NewReader(append(b, '\n')), nil
+				}
+
 				buf := new(bytes.Buffer)
 				if len(obj.Text) > 0 {
 					buf = bytes.NewBufferString(obj.Text + "\n")
@@ -149,8 +237,12 @@ func pingPeer(ctx context.Context, n *core.IpfsNode, pid peer.ID, numPings int)
 		}
 
 		var done bool
-		var total time.Duration
+		var sent int
+		var rtts []time.Duration
 		for i := 0; i < numPings && !done; i++ {
+			// count the ping before waiting for it, so pings that time
+			// out or fail are counted as lost
+			sent++
 			select {
 			case <-ctx.Done():
 				done = true
@@ -165,14 +257,16 @@ func pingPeer(ctx context.Context, n *core.IpfsNode, pid peer.ID, numPings int)
 					Success: true,
 					Time:    t,
 				}
-				total += t
+				rtts = append(rtts, t)
 				time.Sleep(time.Second)
 			}
 		}
-		averagems := total.Seconds() * 1000 / float64(numPings)
+
+		summary := summarizePings(sent, rtts)
 		outChan <- &PingResult{
 			Success: true,
-			Text:    fmt.Sprintf("Average latency: %.2fms", averagems),
+			Text:    fmt.Sprintf("Average latency: %.2fms", summary.Avg.Seconds()*1000),
+			Summary: summary,
 		}
 	}()
 	return outChan
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizePings(t *testing.T) {
+	rtts := []time.Duration{
+		2 * time.Millisecond,
+		4 * time.Millisecond,
+		4 * time.Millisecond,
+		4 * time.Millisecond,
+		5 * time.Millisecond,
+		5 * time.Millisecond,
+		7 * time.Millisecond,
+		9 * time.Millisecond,
+	}
+
+	sum := summarizePings(10, rtts)
+	if sum.Sent != 10 || sum.Received != 8 || sum.Loss != 20 {
+		t.Fatalf("unexpected counts: %+v", sum)
+	}
+	if sum.Min != 2*time.Millisecond || sum.Max != 9*time.Millisecond {
+		t.Fatalf("unexpected min/max: %+v", sum)
+	}
+	if sum.Avg != 5*time.Millisecond || sum.StdDev != 2*time.Millisecond {
+		t.Fatalf("unexpected avg/stddev: %+v", sum)
+	}
+
+	empty := summarizePings(0, nil)
+	if empty.Loss != 0 || empty.Avg != 0 {
+		t.Fatalf("unexpected summary without pings: %+v", empty)
+	}
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/pprof"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+var diagProfileCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Collect a diagnostic bundle for bug reports.",
+		ShortDescription: `
+'ipfs diag profile' collects diagnostics from the running node into a single
+zip file:
+
+  cpu.pprof       CPU profile taken over --profile-time
+  heap.pprof      heap profile
+  goroutines.txt  stack traces of all goroutines
+  version.json    version information, as printed by 'ipfs version --all'
+  config.json     the config, with the private key and pinning service keys
+                  removed
+
+The zip is written to the path given with --output, by default to
+ipfs-profile-<timestamp>.zip in the current directory.
+`,
+	},
+
+	Options: []cmds.Option{
+		cmds.StringOption("output", "o", "The path where the zip file should be written."),
+		cmds.StringOption("profile-time", "Duration to collect the CPU profile over.").Default("30s"),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		ptime, _, _ := req.Option("profile-time").String()
+		profileTime, err := time.ParseDuration(ptime)
+		if err != nil {
+			res.SetError(fmt.Errorf("invalid profile-time: %s", err), cmds.ErrClient)
+			return
+		}
+		if profileTime <= 0 {
+			res.SetError(fmt.Errorf("profile-time must be positive"), cmds.ErrClient)
+			return
+		}
+
+		cfg, err := redactedConfig(n)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		r, w := io.Pipe()
+		go func() {
+			w.CloseWithError(writeProfile(req.Context(), w, cfg, profileTime))
+		}()
+		res.SetOutput(r)
+	},
+	PostRun: func(req cmds.Request, res cmds.Response) {
+		if res.Output() == nil {
+			return
+		}
+		outReader := res.Output().(io.Reader)
+		res.SetOutput(nil)
+
+		outPath, _, _ := req.Option("output").String()
+		if outPath == "" {
+			outPath = "ipfs-profile-" + time.Now().Format("2006-01-02T15-04-05Z0700") + ".zip"
+		}
+
+		fi, err := os.Create(outPath)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer fi.Close()
+
+		if _, err := io.Copy(fi, outReader); err != nil {
+			os.Remove(outPath)
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		fmt.Fprintf(os.Stdout, "Wrote profiles to: %s\n", outPath)
+	},
+}
+
+// redactedConfig returns the node's config as a map, without the private
+// key and the remote pinning service keys.
+func redactedConfig(n *core.IpfsNode) (map[string]interface{}, error) {
+	c, err := n.Repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := config.ToMap(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := scrubValue(m, []string{config.IdentityTag, config.PrivKeyTag}); err != nil {
+		return nil, err
+	}
+	scrubRemotePinKeys(m)
+	return m, nil
+}
+
+func writeProfile(ctx context.Context, w io.Writer, cfg map[string]interface{}, profileTime time.Duration) error {
+	archive := zip.NewWriter(w)
+
+	f, err := archive.Create("goroutines.txt")
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return err
+	}
+
+	f, err = archive.Create("heap.pprof")
+	if err != nil {
+		return err
+	}
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return err
+	}
+
+	f, err = archive.Create("version.json")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(versionOutput()); err != nil {
+		return err
+	}
+
+	f, err = archive.Create("config.json")
+	if err != nil {
+		return err
+	}
+	out, err := config.HumanOutput(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(out); err != nil {
+		return err
+	}
+
+	// Collect the CPU profile last, so the snapshots above reflect the
+	// node's state at the time the command was invoked.
+	f, err = archive.Create("cpu.pprof")
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		return err
+	}
+	select {
+	case <-time.After(profileTime):
+	case <-ctx.Done():
+		pprof.StopCPUProfile()
+		return ctx.Err()
+	}
+	pprof.StopCPUProfile()
+
+	return archive.Close()
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	path "github.com/ipfs/go-ipfs/path"
 
 	crypto "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
@@ -54,6 +56,15 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
  > ipfs name publish --key=QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
   Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
 
+Publish an <ipfs-path> under several names at once by separating the keys
+with commas. A failure for one key doesn't stop the others from being
+published, but makes the command exit with an error. The lifetime and ttl
+apply to every key:
+
+  > ipfs name publish --key=self,mykey /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  Published to QmSrPmbaUKA3ZodhzPWZnpFgcPMFWF4QsxXbkWfEptTBJd: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
 `,
 	},
 
@@ -67,7 +78,7 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
     This accepts durations such as "300s", "1.5h" or "2h45m". Valid time units are
     "ns", "us" (or "µs"), "ms", "s", "m", "h".`).Default("24h"),
 		cmds.StringOption("ttl", "Time duration this record should be cached for (caution: experimental)."),
-		cmds.StringOption("key", "k", "Name of the key to be used or a valid PeerID, as listed by 'ipfs key list -l'. Default: <<default>>.").Default("self"),
+		cmds.StringOption("key", "k", "Name of the key to be used or a valid PeerID, as listed by 'ipfs key list -l'. Several keys can be given separated by commas. Default: <<default>>.").Default("self"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		log.Debug("begin publish")
@@ -121,11 +132,13 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
 			ctx = context.WithValue(ctx, "ipns-publish-ttl", d)
 		}
 
-		kname, _, _ := req.Option("key").String()
-		k, err := keylookup(n, kname)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
+		kopt, _, _ := req.Option("key").String()
+		knames := strings.Split(kopt, ",")
+		for _, kname := range knames {
+			if kname == "" {
+				res.SetError(errors.New("key names must not be empty"), cmds.ErrClient)
+				return
+			}
 		}
 
 		pth, err := path.ParsePath(pstr)
@@ -134,18 +147,76 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
 			return
 		}
 
-		output, err := publish(ctx, n, k, pth, popts)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
+		if len(knames) == 1 {
+			k, err := keylookup(n, knames[0])
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			output, err := publish(ctx, n, k, pth, popts)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			res.SetOutput(output)
 			return
 		}
-		res.SetOutput(output)
+
+		outChan := make(chan interface{})
+		res.SetOutput((<-chan interface{})(outChan))
+
+		go func() {
+			defer close(outChan)
+			failed := 0
+			for _, kname := range knames {
+				output, err := publishKey(ctx, n, kname, pth, popts)
+				if err != nil {
+					failed++
+					output = &IpnsEntry{Name: kname, Error: err.Error()}
+				}
+
+				select {
+				case outChan <- output:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if failed > 0 {
+				res.SetError(fmt.Errorf("publishing failed for %d keys", failed), cmds.ErrNormal)
+			}
+		}()
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			v := res.Output().(*IpnsEntry)
-			s := fmt.Sprintf("Published to %s: %s\n", v.Name, v.Value)
-			return strings.NewReader(s), nil
+			if v, ok := res.Output().(*IpnsEntry); ok {
+				s := fmt.Sprintf("Published to %s: %s\n", v.Name, v.Value)
+				return strings.NewReader(s), nil
+			}
+
+			outChan, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			marshal := func(o interface{}) (io.Reader, error) {
+				v, ok := o.(*IpnsEntry)
+				if !ok {
+					return nil, u.ErrCast()
+				}
+				if v.Error != "" {
+					fmt.Fprintf(res.Stderr(), "Error publishing to %s: %s\n", v.Name, v.Error)
+					return new(bytes.Buffer), nil
+				}
+				return strings.NewReader(fmt.Sprintf("Published to %s: %s\n", v.Name, v.Value)), nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
 		},
 	},
 	Type: IpnsEntry{},
@@ -183,6 +254,16 @@ func publish(ctx context.Context, n *core.IpfsNode, k crypto.PrivKey, ref path.P
 	}, nil
 }
 
+// publishKey looks up the key named kname and publishes ref under it.
+func publishKey(ctx context.Context, n *core.IpfsNode, kname string, ref path.Path, opts *publishOpts) (*IpnsEntry, error) {
+	k, err := keylookup(n, kname)
+	if err != nil {
+		return nil, err
+	}
+
+	return publish(ctx, n, k, ref, opts)
+}
+
 func keylookup(n *core.IpfsNode, k string) (crypto.PrivKey, error) {
 
 	res, err := n.GetKey(k)
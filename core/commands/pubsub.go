@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +20,7 @@ import (
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
 var PubsubCmd = &cmds.Command{
@@ -63,6 +66,11 @@ To use, the daemon must be run with '--enable-pubsub-experiment'.
 This command outputs data in the following encodings:
   * "json"
 (Specified by the "--encoding" or "--enc" flag)
+
+With --json, each message is printed on its own line as a JSON object with
+the sender peer ID, the topics, the sequence number and the base64 encoded
+payload. With --from, only messages sent by the given comma separated peer
+IDs are delivered.
 `,
 	},
 	Arguments: []cmds.Argument{
@@ -70,6 +78,8 @@ This command outputs data in the following encodings:
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("discover", "try to discover other peers subscribed to the same topic"),
+		cmds.BoolOption("json", "Print each message as a JSON object.").Default(false),
+		cmds.StringOption("from", "Only deliver messages from these comma separated peer IDs."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -89,6 +99,19 @@ This command outputs data in the following encodings:
 			return
 		}
 
+		var from map[peer.ID]bool
+		if fstr, found, _ := req.Option("from").String(); found {
+			from = make(map[peer.ID]bool)
+			for _, pstr := range strings.Split(fstr, ",") {
+				pid, err := peer.IDB58Decode(pstr)
+				if err != nil {
+					res.SetError(fmt.Errorf("invalid peer id %q: %s", pstr, err), cmds.ErrClient)
+					return
+				}
+				from[pid] = true
+			}
+		}
+
 		topic := req.Arguments()[0]
 		sub, err := n.Floodsub.Subscribe(topic)
 		if err != nil {
@@ -114,6 +137,10 @@ This command outputs data in the following encodings:
 					return
 				}
 
+				if from != nil && !from[peer.ID(msg.GetFrom())] {
+					continue
+				}
+
 				out <- msg
 			}
 		}()
@@ -133,9 +160,16 @@ This command outputs data in the following encodings:
 		}
 	},
 	Marshalers: cmds.MarshalerMap{
-		cmds.Text: getPsMsgMarshaler(func(m *floodsub.Message) (io.Reader, error) {
-			return bytes.NewReader(m.Data), nil
-		}),
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			asJSON, _, _ := res.Request().Option("json").Bool()
+			if asJSON {
+				return getPsMsgMarshaler(psMsgJSON)(res)
+			}
+
+			return getPsMsgMarshaler(func(m *floodsub.Message) (io.Reader, error) {
+				return bytes.NewReader(m.Data), nil
+			})(res)
+		},
 		"ndpayload": getPsMsgMarshaler(func(m *floodsub.Message) (io.Reader, error) {
 			m.Data = append(m.Data, '\n')
 			return bytes.NewReader(m.Data), nil
@@ -150,6 +184,33 @@ This command outputs data in the following encodings:
 	Type: floodsub.Message{},
 }
 
+// psMessage is the JSON form of a message printed by 'ipfs pubsub sub --json'.
+type psMessage struct {
+	From   string
+	Topics []string
+	Seqno  uint64
+	Data   []byte
+}
+
+func psMsgJSON(m *floodsub.Message) (io.Reader, error) {
+	var seqno uint64
+	if sn := m.GetSeqno(); len(sn) == 8 {
+		seqno = binary.BigEndian.Uint64(sn)
+	}
+
+	b, err := json.Marshal(&psMessage{
+		From:   peer.ID(m.GetFrom()).Pretty(),
+		Topics: m.GetTopicIDs(),
+		Seqno:  seqno,
+		Data:   m.GetData(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(append(b, '\n')), nil
+}
+
 func connectToPubSubPeers(ctx context.Context, n *core.IpfsNode, cid *cid.Cid) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -201,12 +262,22 @@ func getPsMsgMarshaler(f func(m *floodsub.Message) (io.Reader, error)) func(cmds
 	}
 }
 
+// PubsubPubOutput is the output of 'ipfs pubsub pub'.
+type PubsubPubOutput struct {
+	Topic     string
+	Published int
+}
+
 var PubsubPubCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Publish a message to a given pubsub topic.",
 		ShortDescription: `
 ipfs pubsub pub publishes a message to a specified topic.
 
+If no data is given, the message is read from stdin as is, so it can contain
+arbitrary binary data. With --repeat, the messages are published that many
+times, waiting --interval between each round.
+
 This is an experimental feature. It is not intended in its current state
 to be used in a production environment.
 
@@ -215,7 +286,11 @@ To use, the daemon must be run with '--enable-pubsub-experiment'.
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("topic", true, false, "Topic to publish to."),
-		cmds.StringArg("data", true, true, "Payload of message to publish.").EnableStdin(),
+		cmds.StringArg("data", false, true, "Payload of message to publish.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("repeat", "r", "Number of times to publish the messages.").Default(1),
+		cmds.StringOption("interval", "i", "Time to wait between repeated publishes.").Default("1s"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -235,15 +310,87 @@ To use, the daemon must be run with '--enable-pubsub-experiment'.
 			return
 		}
 
-		topic := req.Arguments()[0]
+		repeat, _, _ := req.Option("repeat").Int()
+		if repeat < 1 {
+			res.SetError(fmt.Errorf("repeat must be at least 1"), cmds.ErrClient)
+			return
+		}
+
+		istr, _, _ := req.Option("interval").String()
+		interval, err := time.ParseDuration(istr)
+		if err != nil {
+			res.SetError(fmt.Errorf("error parsing interval option: %s", err), cmds.ErrClient)
+			return
+		}
+
+		// Use the raw arguments, req.Arguments() would split a message read
+		// from stdin into lines.
+		args := req.StringArguments()
+		topic := args[0]
+
+		var msgs [][]byte
+		for _, data := range args[1:] {
+			msgs = append(msgs, []byte(data))
+		}
+
+		if len(msgs) == 0 {
+			if req.Files() == nil {
+				res.SetError(fmt.Errorf("no message given to publish"), cmds.ErrClient)
+				return
+			}
 
-		for _, data := range req.Arguments()[1:] {
-			if err := n.Floodsub.Publish(topic, []byte(data)); err != nil {
+			fi, err := req.Files().NextFile()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			defer fi.Close()
+
+			data, err := ioutil.ReadAll(fi)
+			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
+			msgs = append(msgs, data)
 		}
+
+		ctx := req.Context()
+		published := 0
+		for i := 0; i < repeat; i++ {
+			if i > 0 {
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					res.SetError(ctx.Err(), cmds.ErrNormal)
+					return
+				}
+			}
+
+			for _, data := range msgs {
+				if err := n.Floodsub.Publish(topic, data); err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				published++
+			}
+		}
+
+		res.SetOutput(&PubsubPubOutput{
+			Topic:     topic,
+			Published: published,
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*PubsubPubOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			return strings.NewReader(fmt.Sprintf("published %d messages to %s\n", out.Published, out.Topic)), nil
+		},
 	},
+	Type: PubsubPubOutput{},
 }
 
 var PubsubLsCmd = &cmds.Command{
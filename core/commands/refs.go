@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
@@ -42,6 +44,24 @@ with the following format:
   <link base58 hash>
 
 NOTE: List all references recursively by using the flag '-r'.
+
+The depth of a recursive listing can be limited with '--max-depth=<n>'.
+A depth of 1 lists only the direct links, like a non-recursive listing,
+and -1 (the default) does not limit the depth.
+
+With '--format=<template>', every link is printed using the template, in
+which these tokens are replaced:
+
+  <src>       the hash of the object the link is in
+  <dst>       the hash of the linked object
+  <linkname>  the name of the link
+
+Any other text in angle brackets, such as <size> or <SRC>, is rejected.
+Literal angle brackets are written as \< and \>, so '\<b\><linkname>\</b\>'
+prints each link name in bold HTML. For example, Graphviz edges can be
+printed with:
+
+  > ipfs refs -r --format='"<src>" -> "<dst>" [label="<linkname>"];' <path>
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
@@ -55,6 +75,7 @@ NOTE: List all references recursively by using the flag '-r'.
 		cmds.BoolOption("edges", "e", "Emit edge format: `<from> -> <to>`.").Default(false),
 		cmds.BoolOption("unique", "u", "Omit duplicate refs from output.").Default(false),
 		cmds.BoolOption("recursive", "r", "Recursively list links of child nodes.").Default(false),
+		cmds.IntOption("max-depth", "Only for recursive refs, limits fetch and listing to the given depth.").Default(-1),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		ctx := req.Context()
@@ -76,12 +97,27 @@ NOTE: List all references recursively by using the flag '-r'.
 			return
 		}
 
+		maxDepth, _, err := req.Option("max-depth").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if maxDepth < -1 {
+			res.SetError(errors.New("max-depth must be -1 (unlimited) or a non-negative number"), cmds.ErrClient)
+			return
+		}
+
 		format, _, err := req.Option("format").String()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
+		if err := checkRefsFormat(format); err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
 		edges, _, err := req.Option("edges").Bool()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -116,6 +152,7 @@ NOTE: List all references recursively by using the flag '-r'.
 				Unique:    unique,
 				PrintFmt:  format,
 				Recursive: recursive,
+				MaxDepth:  maxDepth,
 			}
 
 			for _, o := range objs {
@@ -135,20 +172,52 @@ var RefsLocalCmd = &cmds.Command{
 		Tagline: "List all local references.",
 		ShortDescription: `
 Displays the hashes of all local objects.
+
+The output can be paged through with '--offset' and '--limit'. Hashes are
+listed in blockstore order unless '--sort' is given, which lists them sorted
+by CID. Sorting needs all hashes in memory, while unsorted output is streamed
+from the blockstore:
+
+    > ipfs refs local --sort --offset=1000 --limit=1000
 `,
 	},
-
+	Options: []cmds.Option{
+		cmds.IntOption("offset", "Skip this many refs before listing.").Default(0),
+		cmds.IntOption("limit", "List at most this many refs. 0 means no limit.").Default(0),
+		cmds.BoolOption("sort", "Sort the refs by CID.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		ctx := req.Context()
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		// todo: make async
+		offset, _, err := req.Option("offset").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		limit, _, err := req.Option("limit").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if offset < 0 || limit < 0 {
+			res.SetError(errors.New("offset and limit must not be negative"), cmds.ErrClient)
+			return
+		}
+
+		sorted, _, err := req.Option("sort").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(req.Context())
 		allKeys, err := n.Blockstore.AllKeysChan(ctx)
 		if err != nil {
+			cancel()
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
@@ -158,9 +227,44 @@ Displays the hashes of all local objects.
 
 		go func() {
 			defer close(out)
+			defer cancel()
+
+			i := 0
+			emit := func(r string) bool {
+				i++
+				if i <= offset {
+					return true
+				}
+				if limit > 0 && i > offset+limit {
+					return false
+				}
+
+				select {
+				case out <- &RefWrapper{Ref: r}:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			if !sorted {
+				for k := range allKeys {
+					if !emit(k.String()) {
+						return
+					}
+				}
+				return
+			}
 
+			var all []string
 			for k := range allKeys {
-				out <- &RefWrapper{Ref: k.String()}
+				all = append(all, k.String())
+			}
+			sort.Strings(all)
+			for _, r := range all {
+				if !emit(r) {
+					return
+				}
 			}
 		}()
 	},
@@ -227,29 +331,41 @@ type RefWriter struct {
 	Recursive bool
 	PrintFmt  string
 
+	// MaxDepth limits the depth of recursive listings, -1 means unlimited
+	MaxDepth int
+
 	seen *cid.Set
+
+	// seenDepth records the smallest depth each cid was visited at when
+	// the depth is limited
+	seenDepth map[string]int
 }
 
 // WriteRefs writes refs of the given object to the underlying writer.
 func (rw *RefWriter) WriteRefs(n node.Node) (int, error) {
-	if rw.Recursive {
-		return rw.writeRefsRecursive(n)
+	if rw.Recursive && rw.MaxDepth != 1 {
+		return rw.writeRefsRecursive(n, 0)
 	}
 	return rw.writeRefsSingle(n)
 }
 
-func (rw *RefWriter) writeRefsRecursive(n node.Node) (int, error) {
+func (rw *RefWriter) writeRefsRecursive(n node.Node, depth int) (int, error) {
 	nc := n.Cid()
 
 	var count int
 	for i, ng := range dag.GetDAG(rw.Ctx, rw.DAG, n) {
 		lc := n.Links()[i].Cid
-		if rw.skip(lc) {
-			continue
+		goDeeper, shouldWrite := rw.visit(lc, depth+1)
+
+		if shouldWrite {
+			if err := rw.WriteEdge(nc, lc, n.Links()[i].Name); err != nil {
+				return count, err
+			}
+			count++
 		}
 
-		if err := rw.WriteEdge(nc, lc, n.Links()[i].Name); err != nil {
-			return count, err
+		if !goDeeper {
+			continue
 		}
 
 		nd, err := ng.Get(rw.Ctx)
@@ -257,7 +373,7 @@ func (rw *RefWriter) writeRefsRecursive(n node.Node) (int, error) {
 			return count, err
 		}
 
-		c, err := rw.writeRefsRecursive(nd)
+		c, err := rw.writeRefsRecursive(nd, depth+1)
 		count += c
 		if err != nil {
 			return count, err
@@ -266,6 +382,44 @@ func (rw *RefWriter) writeRefsRecursive(n node.Node) (int, error) {
 	return count, nil
 }
 
+// visit returns whether the children of the cid found at the given depth
+// should be traversed, and whether the cid itself should be written.
+func (rw *RefWriter) visit(c *cid.Cid, depth int) (bool, bool) {
+	atMaxDepth := rw.MaxDepth >= 0 && depth == rw.MaxDepth
+	overMaxDepth := rw.MaxDepth >= 0 && depth > rw.MaxDepth
+
+	// only happens with --max-depth=0, where even the direct links of the
+	// root are too deep
+	if overMaxDepth {
+		return false, false
+	}
+
+	if !rw.Unique {
+		return !atMaxDepth, true
+	}
+
+	if rw.MaxDepth < 0 {
+		// without a depth limit, a cid seen before has already been
+		// fully explored
+		skip := rw.skip(c)
+		return !skip, !skip
+	}
+
+	// with a depth limit, a cid first seen deep in the DAG may have
+	// been cut off, so it must be explored again if it shows up higher.
+	if rw.seenDepth == nil {
+		rw.seenDepth = make(map[string]int)
+	}
+	key := c.KeyString()
+	oldDepth, seen := rw.seenDepth[key]
+	if seen && oldDepth <= depth {
+		return false, false
+	}
+
+	rw.seenDepth[key] = depth
+	return !atMaxDepth, !seen
+}
+
 func (rw *RefWriter) writeRefsSingle(n node.Node) (int, error) {
 	c := n.Cid()
 
@@ -305,6 +459,55 @@ func (rw *RefWriter) skip(c *cid.Cid) bool {
 	return has
 }
 
+// refsFormatTokens are the tokens a RefWriter replaces in its PrintFmt.
+var refsFormatTokens = []string{"<src>", "<dst>", "<linkname>"}
+
+// checkRefsFormat returns an error if format contains anything in angle
+// brackets other than refsFormatTokens.
+func checkRefsFormat(format string) error {
+	_, err := expandRefsFormat(format, "", "", "")
+	return err
+}
+
+// expandRefsFormat replaces the tokens in format with the hashes of the link
+// source and destination and the link name. \< and \> stand for literal angle
+// brackets, an unclosed < is kept as it is, and any other text in angle
+// brackets is an error.
+func expandRefsFormat(format, src, dst, linkname string) (string, error) {
+	var buf bytes.Buffer
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '\\' && i+1 < len(format) && (format[i+1] == '<' || format[i+1] == '>'):
+			i++
+			buf.WriteByte(format[i])
+		case c == '<':
+			end := strings.IndexAny(format[i+1:], "<>")
+			if end < 0 || format[i+1+end] == '<' {
+				buf.WriteByte(c)
+				continue
+			}
+
+			tok := format[i : i+end+2]
+			switch tok {
+			case "<src>":
+				buf.WriteString(src)
+			case "<dst>":
+				buf.WriteString(dst)
+			case "<linkname>":
+				buf.WriteString(linkname)
+			default:
+				return "", fmt.Errorf("unknown token %s in format, available tokens: %s (use \\< and \\> for literal angle brackets)",
+					tok, strings.Join(refsFormatTokens, " "))
+			}
+			i += end + 1
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), nil
+}
+
 // Write one edge
 func (rw *RefWriter) WriteEdge(from, to *cid.Cid, linkname string) error {
 	if rw.Ctx != nil {
@@ -318,10 +521,11 @@ func (rw *RefWriter) WriteEdge(from, to *cid.Cid, linkname string) error {
 	var s string
 	switch {
 	case rw.PrintFmt != "":
-		s = rw.PrintFmt
-		s = strings.Replace(s, "<src>", from.String(), -1)
-		s = strings.Replace(s, "<dst>", to.String(), -1)
-		s = strings.Replace(s, "<linkname>", linkname, -1)
+		var err error
+		s, err = expandRefsFormat(rw.PrintFmt, from.String(), to.String(), linkname)
+		if err != nil {
+			return err
+		}
 	default:
 		s += to.String()
 	}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandRefsFormat(t *testing.T) {
+	cases := map[string]string{
+		"<dst>":                         "D",
+		"<src> -> <dst>":                "S -> D",
+		`"<src>" [label="<linkname>"];`: `"S" [label="L"];`,
+		`\<b\><linkname>\</b\>`:         "<b>L</b>",
+		`\<src\>`:                       "<src>",
+		"a < b":                         "a < b",
+		"<<dst>":                        "<D",
+		`\n<dst>`:                       `\nD`,
+	}
+	for format, expected := range cases {
+		out, err := expandRefsFormat(format, "S", "D", "L")
+		if err != nil {
+			t.Errorf("%q: %s", format, err)
+			continue
+		}
+		if out != expected {
+			t.Errorf("%q: expected %q, got %q", format, expected, out)
+		}
+	}
+
+	for _, format := range []string{"<size>", "<SRC>", "<dest>", "<b><linkname></b>", "a < b > c"} {
+		err := checkRefsFormat(format)
+		if err == nil || !strings.Contains(err.Error(), "unknown token") {
+			t.Errorf("%q: expected an unknown token error, got %v", format, err)
+		}
+	}
+}
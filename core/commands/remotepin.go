@@ -0,0 +1,497 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	path "github.com/ipfs/go-ipfs/path"
+	remote "github.com/ipfs/go-ipfs/pin/remote"
+	config "github.com/ipfs/go-ipfs/repo/config"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+// remotePinPollInterval is how often 'ipfs pin remote add' asks the service
+// about a pin it is waiting for.
+var remotePinPollInterval = time.Second
+
+var errNoRemoteService = errors.New("no remote pinning service given, use --service")
+
+var remotePinCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Pin objects to remote pinning services.",
+		ShortDescription: `
+'ipfs pin remote' asks remote services implementing the IPFS pinning service
+API to pin content, and queries the state of their pins. Services are
+registered with 'ipfs pin remote service add' and then referred to by name:
+
+  > ipfs pin remote service add mysrv https://pin.example.com/api/v1 <key>
+  > ipfs pin remote add --service=mysrv --name=photos QmSomeHash
+  > ipfs pin remote ls --service=mysrv --status=queued,pinning
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add":     remotePinAddCmd,
+		"ls":      remotePinLsCmd,
+		"service": remotePinServiceCmd,
+	},
+}
+
+type RemotePinOutput struct {
+	RequestID string
+	Status    string
+	Cid       string
+	Name      string `json:",omitempty"`
+}
+
+type RemotePinList struct {
+	Pins []RemotePinOutput
+}
+
+var remotePinAddCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Pin an object to a remote pinning service.",
+		ShortDescription: `
+'ipfs pin remote add' asks the service given with --service to pin the object
+at ipfs-path, and waits until the service reports the object as pinned. Use
+--background to return as soon as the service has queued the request.
+
+When the daemon is running, its addresses are passed to the service so it can
+fetch the object from this node.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("ipfs-path", true, false, "Path to the object to be pinned."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("service", "Name of the remote pinning service to use."),
+		cmds.StringOption("name", "A human readable name to attach to the pin."),
+		cmds.BoolOption("background", "Return once the request is queued, without waiting for the pin.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		client, err := remotePinClient(n, req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		p, err := path.ParsePath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		c, err := core.ResolveToCid(req.Context(), n.Namesys, n.Resolver, p)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name, _, _ := req.Option("name").String()
+		pin := remote.Pin{
+			Cid:     c.String(),
+			Name:    name,
+			Origins: nodeOrigins(n),
+		}
+
+		ps, err := client.Add(req.Context(), pin)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if background, _, _ := req.Option("background").Bool(); !background {
+			ps, err = waitForRemotePin(req.Context(), client, ps)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		res.SetOutput(remotePinOutput(ps))
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*RemotePinOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			writeRemotePin(buf, out)
+			return buf, nil
+		},
+	},
+	Type: RemotePinOutput{},
+}
+
+var remotePinLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List pins on a remote pinning service.",
+		ShortDescription: `
+'ipfs pin remote ls' lists the pins on the service given with --service, one
+per line, as "<cid> <status> [<name>]". --cid and --status take comma
+separated lists and restrict the output to matching pins. The statuses are
+queued, pinning, pinned and failed; all of them are listed by default.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("service", "Name of the remote pinning service to use."),
+		cmds.StringOption("cid", "Only list pins of these CIDs (comma separated)."),
+		cmds.StringOption("status", "Only list pins with these statuses (comma separated)."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		client, err := remotePinClient(n, req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		var cids, statuses []string
+		if s, _, _ := req.Option("cid").String(); s != "" {
+			cids = strings.Split(s, ",")
+		}
+		if s, _, _ := req.Option("status").String(); s != "" {
+			statuses = strings.Split(s, ",")
+			for _, st := range statuses {
+				if !containsString(remote.AllStatuses, st) {
+					res.SetError(fmt.Errorf("invalid status %q, must be one of %s", st, strings.Join(remote.AllStatuses, ", ")), cmds.ErrClient)
+					return
+				}
+			}
+		}
+
+		pins, err := client.Ls(req.Context(), cids, statuses)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out := &RemotePinList{Pins: make([]RemotePinOutput, len(pins))}
+		for i := range pins {
+			out.Pins[i] = *remotePinOutput(&pins[i])
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*RemotePinList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			for i := range list.Pins {
+				writeRemotePin(buf, &list.Pins[i])
+			}
+			return buf, nil
+		},
+	},
+	Type: RemotePinList{},
+}
+
+var remotePinServiceCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage remote pinning services.",
+		ShortDescription: `
+'ipfs pin remote service' manages the remote pinning services known to the
+node. Services are stored under "Pinning.RemoteServices" in the ipfs config
+file. Like the private key, their access keys are kept in the config file
+only: they are never printed by 'ipfs pin remote service ls' or by
+'ipfs config show'.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add": remotePinServiceAddCmd,
+		"ls":  remotePinServiceLsCmd,
+		"rm":  remotePinServiceRmCmd,
+	},
+}
+
+type RemotePinService struct {
+	Service  string
+	Endpoint string
+}
+
+type RemotePinServiceList struct {
+	Services []RemotePinService
+}
+
+var remotePinServiceAddCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Add a remote pinning service.",
+		ShortDescription: `
+'ipfs pin remote service add' registers the pinning service API at endpoint
+under the given name. The access key used to authenticate is read from
+key-file, or from stdin if no file is given, so that it does not end up in
+the shell history or the process list. Surrounding whitespace is ignored.
+
+  > ipfs pin remote service add mysrv https://pin.example.com/api/v1 < keyfile
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the service."),
+		cmds.StringArg("endpoint", true, false, "Base URL of the pinning service API."),
+		cmds.FileArg("key-file", true, false, "File holding the access key for the service.").EnableStdin(),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		name, endpoint := req.Arguments()[0], req.Arguments()[1]
+
+		key, err := readRemotePinKey(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if name == "" {
+			res.SetError(errors.New("service name must not be empty"), cmds.ErrClient)
+			return
+		}
+		if key == "" {
+			res.SetError(errors.New("service key must not be empty"), cmds.ErrClient)
+			return
+		}
+		eu, err := url.Parse(endpoint)
+		if err != nil || (eu.Scheme != "http" && eu.Scheme != "https") || eu.Host == "" {
+			res.SetError(fmt.Errorf("invalid endpoint %q, must be an http or https URL", endpoint), cmds.ErrClient)
+			return
+		}
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if _, ok := cfg.Pinning.RemoteServices[name]; ok {
+			res.SetError(fmt.Errorf("remote pinning service %q already exists", name), cmds.ErrNormal)
+			return
+		}
+		if cfg.Pinning.RemoteServices == nil {
+			cfg.Pinning.RemoteServices = make(map[string]config.RemotePinningService)
+		}
+		cfg.Pinning.RemoteServices[name] = config.RemotePinningService{
+			Endpoint: endpoint,
+			Key:      key,
+		}
+
+		if err := r.SetConfig(cfg); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	},
+}
+
+// readRemotePinKey reads the access key given to 'ipfs pin remote service
+// add'.
+func readRemotePinKey(req cmds.Request) (string, error) {
+	file, err := req.Files().NextFile()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+var remotePinServiceLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List remote pinning services.",
+		ShortDescription: `
+'ipfs pin remote service ls' lists the names and endpoints of the remote
+pinning services. Access keys are not shown.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out := &RemotePinServiceList{Services: []RemotePinService{}}
+		for name, svc := range cfg.Pinning.RemoteServices {
+			out.Services = append(out.Services, RemotePinService{
+				Service:  name,
+				Endpoint: svc.Endpoint,
+			})
+		}
+		sort.Sort(remotePinServices(out.Services))
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*RemotePinServiceList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			for _, s := range list.Services {
+				fmt.Fprintf(buf, "%s %s\n", s.Service, s.Endpoint)
+			}
+			return buf, nil
+		},
+	},
+	Type: RemotePinServiceList{},
+}
+
+var remotePinServiceRmCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove a remote pinning service.",
+		ShortDescription: `
+'ipfs pin remote service rm' removes a remote pinning service and its access
+key from the config. Pins on the service are not affected.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the service."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		name := req.Arguments()[0]
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if _, ok := cfg.Pinning.RemoteServices[name]; !ok {
+			res.SetError(fmt.Errorf("no remote pinning service named %q", name), cmds.ErrNormal)
+			return
+		}
+		delete(cfg.Pinning.RemoteServices, name)
+
+		if err := r.SetConfig(cfg); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	},
+}
+
+// remotePinClient returns a client for the service named by the request's
+// --service option.
+func remotePinClient(n *core.IpfsNode, req cmds.Request) (*remote.Client, error) {
+	name, _, _ := req.Option("service").String()
+	if name == "" {
+		return nil, errNoRemoteService
+	}
+
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	svc, ok := cfg.Pinning.RemoteServices[name]
+	if !ok {
+		return nil, fmt.Errorf("no remote pinning service named %q, see 'ipfs pin remote service ls'", name)
+	}
+	return remote.NewClient(svc.Endpoint, svc.Key), nil
+}
+
+// waitForRemotePin polls the service until it is done with ps.
+func waitForRemotePin(ctx context.Context, client *remote.Client, ps *remote.PinStatus) (*remote.PinStatus, error) {
+	ticker := time.NewTicker(remotePinPollInterval)
+	defer ticker.Stop()
+
+	for !ps.Done() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		var err error
+		ps, err = client.Get(ctx, ps.RequestID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ps.Status == remote.StatusFailed {
+		return nil, fmt.Errorf("remote pinning service failed to pin %s", ps.Pin.Cid)
+	}
+	return ps, nil
+}
+
+// nodeOrigins returns the addresses the node can be reached at, or nil
+// when it is offline.
+func nodeOrigins(n *core.IpfsNode) []string {
+	if n.PeerHost == nil {
+		return nil
+	}
+
+	var out []string
+	for _, a := range n.PeerHost.Addrs() {
+		out = append(out, a.String()+"/ipfs/"+n.Identity.Pretty())
+	}
+	return out
+}
+
+func remotePinOutput(ps *remote.PinStatus) *RemotePinOutput {
+	return &RemotePinOutput{
+		RequestID: ps.RequestID,
+		Status:    ps.Status,
+		Cid:       ps.Pin.Cid,
+		Name:      ps.Pin.Name,
+	}
+}
+
+func writeRemotePin(w io.Writer, p *RemotePinOutput) {
+	if p.Name != "" {
+		fmt.Fprintf(w, "%s %s %s\n", p.Cid, p.Status, p.Name)
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", p.Cid, p.Status)
+}
+
+type remotePinServices []RemotePinService
+
+func (s remotePinServices) Len() int           { return len(s) }
+func (s remotePinServices) Less(i, j int) bool { return s[i].Service < s[j].Service }
+func (s remotePinServices) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -2,8 +2,10 @@ package commands
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,10 +14,13 @@ import (
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
 	cmds "github.com/ipfs/go-ipfs/commands"
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 	lockfile "github.com/ipfs/go-ipfs/repo/fsrepo/lock"
+	migrate "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
 
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 )
@@ -38,13 +43,17 @@ var RepoCmd = &cmds.Command{
 		"fsck":    RepoFsckCmd,
 		"version": repoVersionCmd,
 		"verify":  repoVerifyCmd,
+		"migrate": RepoMigrateCmd,
 	},
 }
 
-// GcResult is the result returned by "repo gc" command.
+// GcResult is the result returned by "repo gc" command. The final result
+// of a run carries no Key and reports the totals in Removed and Freed.
 type GcResult struct {
-	Key   *cid.Cid
-	Error string `json:",omitempty"`
+	Key     *cid.Cid
+	Error   string `json:",omitempty"`
+	Removed int    `json:",omitempty"`
+	Freed   uint64 `json:",omitempty"`
 }
 
 var repoGcCmd = &cmds.Command{
@@ -54,11 +63,27 @@ var repoGcCmd = &cmds.Command{
 'ipfs repo gc' is a plumbing command that will sweep the local
 set of stored objects and remove ones that are not pinned in
 order to reclaim hard disk space.
+`,
+		LongDescription: `
+'ipfs repo gc' is a plumbing command that will sweep the local
+set of stored objects and remove ones that are not pinned in
+order to reclaim hard disk space.
+
+Removed objects are reported as they are deleted. The total number of
+blocks removed is written to stderr at the end.
+
+The sweep can be stopped early with --max-removed, which stops after the
+given number of blocks has been removed, or --max-freed, which stops once
+at least the given amount of space (e.g. '500MB') has been freed. Only
+with --max-freed are the sizes of the removed blocks looked up, and the
+space freed included in the total.
 `,
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("quiet", "q", "Write minimal output.").Default(false),
 		cmds.BoolOption("stream-errors", "Stream errors.").Default(false),
+		cmds.IntOption("max-removed", "Stop after removing this many blocks. 0 means no limit.").Default(0),
+		cmds.StringOption("max-freed", "Stop after freeing this much space, e.g. '500MB'."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -69,16 +94,50 @@ order to reclaim hard disk space.
 
 		streamErrors, _, _ := res.Request().Option("stream-errors").Bool()
 
-		gcOutChan := corerepo.GarbageCollectAsync(n, req.Context())
+		var limits gc.Limits
+		limits.MaxRemoved, _, _ = req.Option("max-removed").Int()
+		if limits.MaxRemoved < 0 {
+			res.SetError(fmt.Errorf("max-removed must not be negative"), cmds.ErrClient)
+			return
+		}
+
+		maxFreed, found, _ := req.Option("max-freed").String()
+		if found {
+			limits.MaxFreed, err = humanize.ParseBytes(maxFreed)
+			if err != nil {
+				res.SetError(fmt.Errorf("invalid max-freed %q: %s", maxFreed, err), cmds.ErrClient)
+				return
+			}
+		}
+
+		gcOutChan := corerepo.GarbageCollectAsyncWithLimits(n, req.Context(), limits)
 
 		outChan := make(chan interface{}, cap(gcOutChan))
 		res.SetOutput((<-chan interface{})(outChan))
 
 		go func() {
 			defer close(outChan)
+
+			total := &GcResult{}
+			counted := make(chan gc.Result, cap(gcOutChan))
+			go func() {
+				defer close(counted)
+				for r := range gcOutChan {
+					if r.KeyRemoved != nil {
+						total.Removed++
+						total.Freed += r.Size
+					}
+					select {
+					case counted <- r:
+					case <-req.Context().Done():
+						return
+					}
+				}
+			}()
+
 			if streamErrors {
 				errs := false
-				for res := range gcOutChan {
+				for res := range counted {
 					if res.Error != nil {
 						outChan <- &GcResult{Error: res.Error.Error()}
 						errs = true
@@ -86,16 +145,19 @@ order to reclaim hard disk space.
 						outChan <- &GcResult{Key: res.KeyRemoved}
 					}
 				}
+				outChan <- total
 				if errs {
 					res.SetError(fmt.Errorf("encountered errors during gc run"), cmds.ErrNormal)
 				}
 			} else {
-				err := corerepo.CollectResult(req.Context(), gcOutChan, func(k *cid.Cid) {
+				err := corerepo.CollectResult(req.Context(), counted, func(k *cid.Cid) {
 					outChan <- &GcResult{Key: k}
 				})
 				if err != nil {
 					res.SetError(err, cmds.ErrNormal)
+					return
 				}
+				outChan <- total
 			}
 		}()
 	},
@@ -123,6 +185,18 @@ order to reclaim hard disk space.
 					return nil, nil
 				}
 
+				if obj.Key == nil {
+					if quiet {
+						return nil, nil
+					}
+					if obj.Freed > 0 {
+						fmt.Fprintf(res.Stderr(), "total: %d blocks removed, %s freed\n", obj.Removed, humanize.Bytes(obj.Freed))
+					} else {
+						fmt.Fprintf(res.Stderr(), "total: %d blocks removed\n", obj.Removed)
+					}
+					return nil, nil
+				}
+
 				if quiet {
 					return bytes.NewBufferString(obj.Key.String() + "\n"), nil
 				} else {
@@ -149,6 +223,19 @@ NumObjects      int Number of objects in the local repo.
 RepoPath        string The path to the repo being currently used.
 RepoSize        int Size in bytes that the repo is currently taking.
 Version         string The repo version.
+
+With --size-breakdown, the size is further broken down into:
+SizePinned      int Size in bytes of blocks reachable from pins.
+SizeMFS         int Size in bytes of other blocks reachable from MFS.
+SizeUnpinned    int Size in bytes of all remaining (cached) blocks.
+SizeOther       int Size in bytes of the keystore and config file.
+This walks all pins and the MFS tree, so it takes longer on large repos.
+
+With --latency, the repo is not scanned. Instead, the p50, p95 and p99
+latencies of the most recent datastore get, put, has and delete operations
+are printed. Latencies are only recorded when Datastore.MeasureLatency is
+set in the config, and only by the process that opened the repo, so this
+is mostly useful against a running daemon.
 `,
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
@@ -158,25 +245,67 @@ Version         string The repo version.
 			return
 		}
 
+		latency, _, _ := req.Option("latency").Bool()
+		if latency {
+			r, ok := n.Repo.(*fsrepo.FSRepo)
+			if !ok {
+				res.SetError(errors.New("repo does not support latency measurement"), cmds.ErrNormal)
+				return
+			}
+
+			ops, err := r.DatastoreLatency()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			res.SetOutput(&RepoStatOutput{Ops: ops})
+			return
+		}
+
 		stat, err := corerepo.RepoStat(n, req.Context())
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		res.SetOutput(stat)
+		breakdown, _, _ := req.Option("size-breakdown").Bool()
+		if breakdown {
+			stat.SizeBreakdown, err = corerepo.RepoSizeBreakdown(n, req.Context())
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		res.SetOutput(&RepoStatOutput{Stat: stat})
 	},
 	Options: []cmds.Option{
-		cmds.BoolOption("human", "Output RepoSize in MiB.").Default(false),
+		cmds.BoolOption("human", "Output sizes in MiB.").Default(false),
+		cmds.BoolOption("size-breakdown", "Also break the size down by pinned, MFS, unpinned and other data.").Default(false),
+		cmds.BoolOption("latency", "Print datastore operation latency percentiles instead.").Default(false),
 	},
-	Type: corerepo.Stat{},
+	Type: RepoStatOutput{},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			stat, ok := res.Output().(*corerepo.Stat)
+			out, ok := res.Output().(*RepoStatOutput)
 			if !ok {
 				return nil, u.ErrCast()
 			}
 
+			if out.Stat == nil {
+				buf := new(bytes.Buffer)
+				wtr := tabwriter.NewWriter(buf, 0, 0, 1, ' ', 0)
+				fmt.Fprintln(wtr, "Op\tSamples\tP50\tP95\tP99")
+				for _, op := range []string{"get", "put", "has", "delete"} {
+					l := out.Ops[op]
+					fmt.Fprintf(wtr, "%s\t%d\t%s\t%s\t%s\n", op, l.Samples, l.P50, l.P95, l.P99)
+				}
+				wtr.Flush()
+				return buf, nil
+			}
+
+			stat := out.Stat
 			human, _, err := res.Request().Option("human").Bool()
 			if err != nil {
 				return nil, err
@@ -184,18 +313,23 @@ Version         string The repo version.
 
 			buf := new(bytes.Buffer)
 			wtr := tabwriter.NewWriter(buf, 0, 0, 1, ' ', 0)
-			fmt.Fprintf(wtr, "NumObjects:\t%d\n", stat.NumObjects)
-			sizeInMiB := stat.RepoSize / (1024 * 1024)
-			if human && sizeInMiB > 0 {
-				fmt.Fprintf(wtr, "RepoSize (MiB):\t%d\n", sizeInMiB)
-			} else {
-				fmt.Fprintf(wtr, "RepoSize:\t%d\n", stat.RepoSize)
+			printSize := func(name string, size uint64) {
+				sizeInMiB := size / (1024 * 1024)
+				if human && sizeInMiB > 0 {
+					fmt.Fprintf(wtr, "%s (MiB):\t%d\n", name, sizeInMiB)
+				} else {
+					fmt.Fprintf(wtr, "%s:\t%d\n", name, size)
+				}
 			}
-			maxSizeInMiB := stat.StorageMax / (1024 * 1024)
-			if human && maxSizeInMiB > 0 {
-				fmt.Fprintf(wtr, "StorageMax (MiB):\t%d\n", maxSizeInMiB)
-			} else {
-				fmt.Fprintf(wtr, "StorageMax:\t%d\n", stat.StorageMax)
+
+			fmt.Fprintf(wtr, "NumObjects:\t%d\n", stat.NumObjects)
+			printSize("RepoSize", stat.RepoSize)
+			printSize("StorageMax", stat.StorageMax)
+			if stat.SizeBreakdown != nil {
+				printSize("SizePinned", stat.SizePinned)
+				printSize("SizeMFS", stat.SizeMFS)
+				printSize("SizeUnpinned", stat.SizeUnpinned)
+				printSize("SizeOther", stat.SizeOther)
 			}
 			fmt.Fprintf(wtr, "RepoPath:\t%s\n", stat.RepoPath)
 			fmt.Fprintf(wtr, "Version:\t%s\n", stat.Version)
@@ -206,6 +340,13 @@ Version         string The repo version.
 	},
 }
 
+// RepoStatOutput is the output of 'ipfs repo stat'. It holds the repo stats,
+// or with --latency, the latencies keyed by datastore operation.
+type RepoStatOutput struct {
+	*corerepo.Stat
+	Ops map[string]fsrepo.OpLatency `json:",omitempty"`
+}
+
 var RepoFsckCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Remove repo lockfiles.",
@@ -264,6 +405,15 @@ type VerifyProgress struct {
 var repoVerifyCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Verify all blocks in repo are not corrupted.",
+		ShortDescription: `
+'ipfs repo verify' rehashes every block in the local repo and reports each
+block whose stored data does not match its CID. It does not attempt to
+repair anything. The scan continues past corrupt blocks and exits with a
+non-zero status if any were found.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("progress", "Show the number of blocks processed so far.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
@@ -272,18 +422,20 @@ var repoVerifyCmd = &cmds.Command{
 			return
 		}
 
+		progress, _, _ := req.Option("progress").Bool()
+
+		bs := bstore.NewBlockstore(nd.Repo.Datastore())
+		bs.HashOnRead(true)
+
+		keys, err := bs.AllKeysChan(req.Context())
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		out := make(chan interface{})
 		go func() {
 			defer close(out)
-			bs := bstore.NewBlockstore(nd.Repo.Datastore())
-
-			bs.HashOnRead(true)
-
-			keys, err := bs.AllKeysChan(req.Context())
-			if err != nil {
-				log.Error(err)
-				return
-			}
 
 			var fails int
 			var i int
@@ -296,12 +448,16 @@ var repoVerifyCmd = &cmds.Command{
 					fails++
 				}
 				i++
-				out <- &VerifyProgress{Progress: i}
+				if progress {
+					out <- &VerifyProgress{Progress: i}
+				}
 			}
 			if fails == 0 {
 				out <- &VerifyProgress{Message: "verify complete, all blocks validated."}
 			} else {
-				out <- &VerifyProgress{Message: "verify complete, some blocks were corrupt."}
+				out <- &VerifyProgress{
+					Message: fmt.Sprintf("verify complete, %d of %d blocks were corrupt.", fails, i),
+				}
 			}
 		}()
 
@@ -380,3 +536,182 @@ var repoVersionCmd = &cmds.Command{
 		},
 	},
 }
+
+// MigrateProgress is emitted by 'ipfs repo migrate'. The first object
+// reports the current and target versions, the following ones the backup
+// that was made and each completed migration step.
+type MigrateProgress struct {
+	Current int
+	Target  int
+	DryRun  bool   `json:",omitempty"`
+	Backup  string `json:",omitempty"`
+	Step    int    `json:",omitempty"`
+}
+
+var RepoMigrateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Migrate the repo to a newer version.",
+		ShortDescription: `
+'ipfs repo migrate' upgrades the repo to the version used by this ipfs, or
+to the version given with --to, one version at a time. It uses the
+fs-repo-migrations tool, which is downloaded if none is found in the PATH.
+`,
+		LongDescription: `
+'ipfs repo migrate' upgrades the repo to the version used by this ipfs, or
+to the version given with --to, one version at a time. It uses the
+fs-repo-migrations tool, which is downloaded if none is found in the PATH.
+
+Before migrating, the config and version files are copied into a
+'migration-backup-*' directory inside the repo. With --dry-run, only the
+migration steps that would be run are listed.
+
+This command can only run when no ipfs daemons are running. It keeps the
+repo locked while it migrates, except while fs-repo-migrations runs a step,
+which locks the repo itself.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("to", "The repo version to migrate to. Defaults to the version used by this ipfs."),
+		cmds.BoolOption("dry-run", "Only list the migration steps that would be run.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		configRoot := req.InvocContext().ConfigRoot
+
+		locked, err := fsrepo.LockedByOtherProcess(configRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if locked {
+			res.SetError(errors.New("ipfs daemon is running. please stop it to run this command"), cmds.ErrNormal)
+			return
+		}
+
+		// hold the repo lock until the migration is done, so that no daemon
+		// can start on the repo halfway through
+		lk, err := lockfile.Lock(configRoot)
+		if err != nil {
+			res.SetError(fmt.Errorf("failed to lock the repo: %s", err), cmds.ErrNormal)
+			return
+		}
+		unlock := func() {
+			if lk != nil {
+				lk.Close()
+				lk = nil
+			}
+		}
+
+		rp := migrate.RepoPath(configRoot)
+		current, err := rp.Version()
+		if err != nil {
+			unlock()
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		target, found, _ := req.Option("to").Int()
+		if !found {
+			target = fsrepo.RepoVersion
+		}
+		if target > fsrepo.RepoVersion {
+			unlock()
+			res.SetError(fmt.Errorf("this ipfs only supports repo versions up to %d", fsrepo.RepoVersion), cmds.ErrClient)
+			return
+		}
+		if target < current {
+			unlock()
+			res.SetError(fmt.Errorf("repo is at version %d, migrating down to %d is not supported", current, target), cmds.ErrClient)
+			return
+		}
+
+		dryRun, _, _ := req.Option("dry-run").Bool()
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+
+		go func() {
+			defer close(out)
+			defer unlock()
+
+			out <- &MigrateProgress{Current: current, Target: target, DryRun: dryRun}
+			if current == target {
+				return
+			}
+
+			migrateBin := ""
+			if !dryRun {
+				backup, err := rp.Backup()
+				if err != nil {
+					res.SetError(fmt.Errorf("failed to back up the repo: %s", err), cmds.ErrNormal)
+					return
+				}
+				out <- &MigrateProgress{Current: current, Target: target, Backup: backup}
+
+				migrateBin, err = migrate.FindMigrations(target, ioutil.Discard)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+			}
+
+			for v := current + 1; v <= target; v++ {
+				if !dryRun {
+					// fs-repo-migrations takes the repo lock itself, so it
+					// is handed over for the step and taken back right after
+					unlock()
+					err := migrate.RunMigrationStep(migrateBin, configRoot, v)
+					if err != nil {
+						res.SetError(err, cmds.ErrNormal)
+						return
+					}
+					lk, err = lockfile.Lock(configRoot)
+					if err != nil {
+						lk = nil
+						res.SetError(fmt.Errorf("failed to lock the repo again after migrating to version %d: %s", v, err), cmds.ErrNormal)
+						return
+					}
+				}
+				out <- &MigrateProgress{Current: v - 1, Target: target, DryRun: dryRun, Step: v}
+			}
+		}()
+	},
+	Type: MigrateProgress{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			outChan, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				obj, ok := v.(*MigrateProgress)
+				if !ok {
+					return nil, u.ErrCast()
+				}
+
+				buf := new(bytes.Buffer)
+				switch {
+				case obj.Backup != "":
+					fmt.Fprintf(buf, "Backed up repo files to %s\n", obj.Backup)
+				case obj.Step != 0 && obj.DryRun:
+					fmt.Fprintf(buf, "Would migrate from version %d to %d\n", obj.Current, obj.Step)
+				case obj.Step != 0:
+					fmt.Fprintf(buf, "Migrated from version %d to %d\n", obj.Current, obj.Step)
+				default:
+					fmt.Fprintf(buf, "Current repo version: %d\n", obj.Current)
+					fmt.Fprintf(buf, "Target repo version: %d\n", obj.Target)
+					if obj.Current == obj.Target {
+						fmt.Fprintln(buf, "Repo is up to date, nothing to migrate.")
+					}
+				}
+				return buf, nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
+		},
+	},
+}
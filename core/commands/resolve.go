@@ -1,13 +1,19 @@
 package commands
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	"github.com/ipfs/go-ipfs/core"
 	ns "github.com/ipfs/go-ipfs/namesys"
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
 	path "github.com/ipfs/go-ipfs/path"
+
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 )
 
@@ -15,6 +21,24 @@ type ResolvedPath struct {
 	Path path.Path
 }
 
+// ResolveOutput is the output of 'ipfs resolve' for one of the names given.
+// Record is only set with --verbose when the name is an IPNS name. Error is
+// only set with --continue-on-error, when the name failed to resolve.
+type ResolveOutput struct {
+	ResolvedPath
+	Name   string
+	Record *IpnsRecordInfo `json:",omitempty"`
+	Error  string          `json:",omitempty"`
+}
+
+// IpnsRecordInfo describes the IPNS record a name was resolved from.
+type IpnsRecordInfo struct {
+	Sequence uint64
+	// Validity is the end of life of the record, in RFC3339 format.
+	Validity string
+	TTL      time.Duration
+}
+
 var ResolveCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Resolve the value of names to IPFS.",
@@ -52,14 +76,35 @@ Resolve the value of an IPFS DAG path:
   $ ipfs resolve /ipfs/QmeZy1fGbwgVSrqbfh9fKQrAWgeyRnj7h8fsHS1oy3k99x/beep/boop
   /ipfs/QmYRMjyvAiHKN9UTi8Bzt1HUspmSRD8T8DwxfSMzLgBon1
 
+Show the IPNS record a name was resolved from:
+
+  $ ipfs resolve -v /ipns/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  /ipfs/Qmcqtw8FfrVSBaRmbWwHxt3AuySBhJLcvmFYi3Lbc4xnwj
+  sequence: 3
+  validity: 2017-06-14T15:32:18.362605085Z
+  ttl: 1m0s
+
+Resolve several names at once, one result line per name in the order
+given. Without arguments, names are read from stdin, one per line:
+
+  $ ipfs resolve -r < names.txt
+
+--dht-timeout bounds how long routing lookups may take for each name,
+e.g. '30s'. By default the first name that fails to resolve aborts the
+command. With --continue-on-error, the error is printed in place of the
+result and the other names are still resolved; the command still fails at
+the end if any name did not resolve.
 `,
 	},
 
 	Arguments: []cmds.Argument{
-		cmds.StringArg("name", true, false, "The name to resolve.").EnableStdin(),
+		cmds.StringArg("name", true, true, "The names to resolve.").EnableStdin(),
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("recursive", "r", "Resolve until the result is an IPFS name.").Default(false),
+		cmds.BoolOption("verbose", "v", "Print the sequence number, validity and TTL of the IPNS record.").Default(false),
+		cmds.StringOption("dht-timeout", "Maximum time to spend on routing lookups."),
+		cmds.BoolOption("continue-on-error", "Report names that fail to resolve and go on with the rest.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -77,46 +122,155 @@ Resolve the value of an IPFS DAG path:
 			}
 		}
 
-		name := req.Arguments()[0]
 		recursive, _, _ := req.Option("recursive").Bool()
+		verbose, _, _ := req.Option("verbose").Bool()
+		continueOnError, _, _ := req.Option("continue-on-error").Bool()
 
-		// the case when ipns is resolved step by step
-		if strings.HasPrefix(name, "/ipns/") && !recursive {
-			p, err := n.Namesys.ResolveN(req.Context(), name, 1)
-			// ErrResolveRecursion is fine
-			if err != nil && err != ns.ErrResolveRecursion {
-				res.SetError(err, cmds.ErrNormal)
+		var timeout time.Duration
+		if tstr, found, _ := req.Option("dht-timeout").String(); found {
+			timeout, err = time.ParseDuration(tstr)
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+			if timeout <= 0 {
+				res.SetError(fmt.Errorf("dht-timeout must be positive"), cmds.ErrClient)
 				return
 			}
-			res.SetOutput(&ResolvedPath{p})
-			return
 		}
 
-		// else, ipfs path or ipns with recursive flag
-		p, err := path.ParsePath(name)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
-		}
+		names := req.Arguments()
 
-		node, err := core.Resolve(req.Context(), n.Namesys, n.Resolver, p)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
-		}
+		outChan := make(chan interface{})
+		res.SetOutput((<-chan interface{})(outChan))
+
+		go func() {
+			defer close(outChan)
 
-		c := node.Cid()
+			failed := 0
+			for _, name := range names {
+				ctx := req.Context()
+				var cancel context.CancelFunc = func() {}
+				if timeout > 0 {
+					ctx, cancel = context.WithTimeout(ctx, timeout)
+				}
+				out, err := resolveName(ctx, n, name, recursive, verbose)
+				cancel()
+
+				if err != nil {
+					if !continueOnError {
+						res.SetError(err, cmds.ErrNormal)
+						return
+					}
+					failed++
+					out = &ResolveOutput{Name: name, Error: err.Error()}
+				}
+
+				select {
+				case outChan <- out:
+				case <-req.Context().Done():
+					return
+				}
+			}
 
-		res.SetOutput(&ResolvedPath{path.FromCid(c)})
+			if failed > 0 {
+				res.SetError(fmt.Errorf("failed to resolve %d of %d names", failed, len(names)), cmds.ErrNormal)
+			}
+		}()
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			output, ok := res.Output().(*ResolvedPath)
+			outChan, ok := res.Output().(<-chan interface{})
 			if !ok {
 				return nil, u.ErrCast()
 			}
-			return strings.NewReader(output.Path.String() + "\n"), nil
+
+			marshal := func(v interface{}) (io.Reader, error) {
+				output, ok := v.(*ResolveOutput)
+				if !ok {
+					return nil, u.ErrCast()
+				}
+
+				buf := new(bytes.Buffer)
+				if output.Error != "" {
+					fmt.Fprintf(buf, "error resolving %s: %s\n", output.Name, output.Error)
+					return buf, nil
+				}
+
+				fmt.Fprintln(buf, output.Path.String())
+				if output.Record != nil {
+					fmt.Fprintf(buf, "sequence: %d\n", output.Record.Sequence)
+					fmt.Fprintf(buf, "validity: %s\n", output.Record.Validity)
+					fmt.Fprintf(buf, "ttl: %s\n", output.Record.TTL)
+				}
+				return buf, nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
 		},
 	},
-	Type: ResolvedPath{},
+	Type: ResolveOutput{},
+}
+
+// resolveName resolves a single name given to 'ipfs resolve'. With verbose,
+// the IPNS record the name resolved from is taken from the resolution itself
+// rather than looked up separately.
+func resolveName(ctx context.Context, n *core.IpfsNode, name string, recursive, verbose bool) (*ResolveOutput, error) {
+	out := &ResolveOutput{Name: name}
+
+	if verbose && strings.HasPrefix(name, "/ipns/") {
+		ctx = ns.ContextWithEntryHook(ctx, recordEntryHook(name, out))
+	}
+
+	// the case when ipns is resolved step by step
+	if strings.HasPrefix(name, "/ipns/") && !recursive {
+		p, err := n.Namesys.ResolveN(ctx, name, 1)
+		// ErrResolveRecursion is fine
+		if err != nil && err != ns.ErrResolveRecursion {
+			return nil, err
+		}
+		out.Path = p
+	} else {
+		// else, ipfs path or ipns with recursive flag
+		p, err := path.ParsePath(name)
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := core.Resolve(ctx, n.Namesys, n.Resolver, p)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Path = path.FromCid(node.Cid())
+	}
+
+	return out, nil
+}
+
+// recordEntryHook returns a namesys entry hook that sets out.Record from the
+// IPNS record of the /ipns/ name given. The records of the names it resolves
+// through are ignored, and so the record stays unset for DNS names.
+func recordEntryHook(name string, out *ResolveOutput) func(string, *pb.IpnsEntry) {
+	id := strings.SplitN(strings.TrimPrefix(name, "/ipns/"), "/", 2)[0]
+
+	return func(name string, entry *pb.IpnsEntry) {
+		if name != id || out.Record != nil {
+			return
+		}
+
+		out.Record = &IpnsRecordInfo{
+			Sequence: entry.GetSequence(),
+			Validity: string(entry.GetValidity()),
+		}
+		if entry.Ttl != nil {
+			out.Record.TTL = time.Duration(entry.GetTtl())
+		} else {
+			out.Record.TTL = ns.DefaultResolverCacheTTL
+		}
+	}
 }
@@ -30,6 +30,8 @@ for your IPFS node.`,
 		"bw":      statBwCmd,
 		"repo":    repoStatCmd,
 		"bitswap": bitswapStatCmd,
+		"dht":     statDhtCmd,
+		"provide": statProvideCmd,
 	},
 }
 
@@ -68,6 +70,13 @@ Example:
     TotalOut: 12MB
     RateIn: 0B/s
     RateOut: 0B/s
+
+With --poll, a new snapshot is printed at every --interval. From the second
+snapshot on, DeltaRateIn and DeltaRateOut hold the average rates since the
+previous snapshot, computed from the difference in totals. These can be
+combined with --peer or --proto to watch a single peer or protocol over
+time. With --enc=json, each snapshot is printed as a JSON object on its own
+line.
 `,
 	},
 	Options: []cmds.Option{
@@ -134,6 +143,10 @@ Example:
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		if interval <= 0 {
+			res.SetError(errors.New("interval must be positive"), cmds.ErrClient)
+			return
+		}
 
 		doPoll, _, err := req.Option("poll").Bool()
 		if err != nil {
@@ -146,19 +159,33 @@ Example:
 
 		go func() {
 			defer close(out)
+			var prev *BandwidthOutput
 			for {
+				var stats metrics.Stats
 				if pfound {
-					stats := nd.Reporter.GetBandwidthForPeer(pid)
-					out <- &stats
+					stats = nd.Reporter.GetBandwidthForPeer(pid)
 				} else if tfound {
 					protoId := protocol.ID(tstr)
-					stats := nd.Reporter.GetBandwidthForProtocol(protoId)
-					out <- &stats
+					stats = nd.Reporter.GetBandwidthForProtocol(protoId)
 				} else {
-					totals := nd.Reporter.GetBandwidthTotals()
-					out <- &totals
+					stats = nd.Reporter.GetBandwidthTotals()
 				}
 				if !doPoll {
+					out <- &BandwidthOutput{Stats: stats}
+					return
+				}
+
+				snap := &BandwidthOutput{Stats: stats, time: time.Now()}
+				if prev != nil {
+					secs := snap.time.Sub(prev.time).Seconds()
+					snap.DeltaRateIn = float64(snap.TotalIn-prev.TotalIn) / secs
+					snap.DeltaRateOut = float64(snap.TotalOut-prev.TotalOut) / secs
+				}
+				prev = snap
+
+				select {
+				case out <- snap:
+				case <-req.Context().Done():
 					return
 				}
 				select {
@@ -169,7 +196,7 @@ Example:
 			}
 		}()
 	},
-	Type: metrics.Stats{},
+	Type: BandwidthOutput{},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
 			outCh, ok := res.Output().(<-chan interface{})
@@ -184,16 +211,17 @@ Example:
 
 			first := true
 			marshal := func(v interface{}) (io.Reader, error) {
-				bs, ok := v.(*metrics.Stats)
+				bs, ok := v.(*BandwidthOutput)
 				if !ok {
 					return nil, u.ErrCast()
 				}
+
 				out := new(bytes.Buffer)
 				if !polling {
-					printStats(out, bs)
+					printStats(out, &bs.Stats)
 				} else {
 					if first {
-						fmt.Fprintln(out, "Total Up    Total Down  Rate Up     Rate Down")
+						fmt.Fprintln(out, "Total Up    Total Down  Rate Up     Rate Down   Delta Up    Delta Down")
 						first = false
 					}
 					fmt.Fprint(out, "\r")
@@ -202,6 +230,8 @@ Example:
 					fmt.Fprintf(out, "%8s    ", humanize.Bytes(uint64(bs.TotalIn)))
 					fmt.Fprintf(out, "%8s/s  ", humanize.Bytes(uint64(bs.RateOut)))
 					fmt.Fprintf(out, "%8s/s  ", humanize.Bytes(uint64(bs.RateIn)))
+					fmt.Fprintf(out, "%8s/s  ", humanize.Bytes(uint64(bs.DeltaRateOut)))
+					fmt.Fprintf(out, "%8s/s  ", humanize.Bytes(uint64(bs.DeltaRateIn)))
 				}
 				return out, nil
 
@@ -216,6 +246,17 @@ Example:
 	},
 }
 
+// BandwidthOutput is a snapshot emitted by 'ipfs stats bw', once or, with
+// --poll, at every interval. The delta rates are the average rates since the
+// previous snapshot and are left zero without --poll and for the first one.
+type BandwidthOutput struct {
+	metrics.Stats
+	DeltaRateIn  float64 `json:",omitempty"`
+	DeltaRateOut float64 `json:",omitempty"`
+
+	time time.Time
+}
+
 func printStats(out io.Writer, bs *metrics.Stats) {
 	fmt.Fprintln(out, "Bandwidth")
 	fmt.Fprintf(out, "TotalIn: %s\n", humanize.Bytes(uint64(bs.TotalIn)))
@@ -223,3 +264,106 @@ func printStats(out io.Writer, bs *metrics.Stats) {
 	fmt.Fprintf(out, "RateIn: %s/s\n", humanize.Bytes(uint64(bs.RateIn)))
 	fmt.Fprintf(out, "RateOut: %s/s\n", humanize.Bytes(uint64(bs.RateOut)))
 }
+
+type ProvideStatOutput struct {
+	Strategy     string
+	Interval     time.Duration
+	Running      bool
+	LastRun      time.Time
+	LastKeys     int
+	LastDuration time.Duration
+	LastError    string `json:",omitempty"`
+	NextRun      time.Time
+	NextRunIn    time.Duration
+}
+
+var statProvideCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print reprovider statistics.",
+		ShortDescription: `
+'ipfs stats provide' prints the state of the reprovider, which periodically
+announces the blocks in the blockstore to the routing system:
+
+  Strategy       which blocks are announced
+  Interval       Reprovider.Interval, the time between timed runs
+  Running        whether a run is in progress
+  Last run       when the last finished run started, how many keys it
+                 announced and how long it took
+  Next run       when the next timed run is due
+
+If the last run took longer than the interval, reproviding is falling
+behind: runs start back to back, and records on the routing system may
+expire before they are renewed.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		nd, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if !nd.OnlineMode() || nd.Reprovider == nil {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		st := nd.Reprovider.Status()
+		out := &ProvideStatOutput{
+			Strategy:     st.Strategy,
+			Interval:     st.Interval,
+			Running:      st.Running,
+			LastRun:      st.LastRun,
+			LastKeys:     st.LastStat.Keys,
+			LastDuration: st.LastStat.Duration,
+			NextRun:      st.NextRun,
+		}
+		if st.LastError != nil {
+			out.LastError = st.LastError.Error()
+		}
+		if !st.NextRun.IsZero() {
+			out.NextRunIn = st.NextRun.Sub(time.Now())
+			out.NextRunIn -= out.NextRunIn % time.Second
+			if out.NextRunIn < 0 {
+				out.NextRunIn = 0
+			}
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*ProvideStatOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			fmt.Fprintln(buf, "Reprovider")
+			fmt.Fprintf(buf, "\tStrategy: %s\n", out.Strategy)
+			if out.Interval > 0 {
+				fmt.Fprintf(buf, "\tInterval: %s\n", out.Interval)
+			} else {
+				fmt.Fprintln(buf, "\tInterval: disabled")
+			}
+			fmt.Fprintf(buf, "\tRunning: %t\n", out.Running)
+			if out.LastRun.IsZero() {
+				fmt.Fprintln(buf, "\tLast run: never")
+			} else {
+				fmt.Fprintf(buf, "\tLast run: %s (%d keys in %s)\n", out.LastRun.Format(time.RFC3339), out.LastKeys, out.LastDuration)
+			}
+			if out.LastError != "" {
+				fmt.Fprintf(buf, "\tLast error: %s\n", out.LastError)
+			}
+			if out.NextRun.IsZero() {
+				fmt.Fprintln(buf, "\tNext run: not scheduled")
+			} else {
+				fmt.Fprintf(buf, "\tNext run: in %s (%s)\n", out.NextRunIn, out.NextRun.Format(time.RFC3339))
+			}
+			if out.Interval > 0 && out.LastDuration > out.Interval {
+				fmt.Fprintln(buf, "Warning: the last run took longer than the interval, reproviding is falling behind.")
+			}
+			return buf, nil
+		},
+	},
+	Type: ProvideStatOutput{},
+}
@@ -2,22 +2,32 @@ package commands
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"path"
 	"sort"
+	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
 	repo "github.com/ipfs/go-ipfs/repo"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	"github.com/ipfs/go-ipfs/repo/fsrepo"
 	iaddr "github.com/ipfs/go-ipfs/thirdparty/ipfsaddr"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	p2phost "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
 	swarm "gx/ipfs/QmVkDnNm71vYyY6s6rXwtmyDYis3WkKyrEhMECwT6R12uJ/go-libp2p-swarm"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
 
 	mafilter "gx/ipfs/QmSMZwvs3n4GBikZ7hKzT17c3bk65FmyZo2JqtJ16swqCv/multiaddr-filter"
 	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+	manet "gx/ipfs/Qmf1Gq7N45Rpuw7ev47uWgH6dLPtdnvcMRNPkVBwqjLJg2/go-multiaddr-net"
 )
 
 type stringList struct {
@@ -42,7 +52,10 @@ ipfs peers in the internet.
 		"connect":    swarmConnectCmd,
 		"disconnect": swarmDisconnectCmd,
 		"filters":    swarmFiltersCmd,
+		"limit":      swarmLimitCmd,
+		"peering":    swarmPeeringCmd,
 		"peers":      swarmPeersCmd,
+		"stats":      swarmStatsCmd,
 	},
 }
 
@@ -51,12 +64,30 @@ var swarmPeersCmd = &cmds.Command{
 		Tagline: "List peers with open connections.",
 		ShortDescription: `
 'ipfs swarm peers' lists the set of peers this node is connected to.
+`,
+		LongDescription: `
+'ipfs swarm peers' lists the set of peers this node is connected to.
+
+Extra columns can be requested per connection:
+
+  --latency    the last measured round trip time to the peer, or 'n/a'
+               when no measurement is available yet.
+  --direction  a guess of whether the connection is 'inbound' or
+               'outbound'. libp2p doesn't record which side opened a
+               connection, so it is guessed from the connection addresses,
+               and given as 'unknown' when port reuse makes it ambiguous.
+               In the JSON output it is the DirectionGuess field.
+  --streams    the protocols of the streams open on the connection, one
+               per line below the peer.
+
+--verbose enables all of the above.
 `,
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("verbose", "v", "display all extra information"),
 		cmds.BoolOption("streams", "Also list information about open streams for each peer"),
 		cmds.BoolOption("latency", "Also list information about latency to each peer"),
+		cmds.BoolOption("direction", "Also list a guess of the direction of the connection to each peer"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -75,6 +106,16 @@ var swarmPeersCmd = &cmds.Command{
 		verbose, _, _ := req.Option("verbose").Bool()
 		latency, _, _ := req.Option("latency").Bool()
 		streams, _, _ := req.Option("streams").Bool()
+		direction, _, _ := req.Option("direction").Bool()
+
+		var listenAddrs []ma.Multiaddr
+		if verbose || direction {
+			listenAddrs, err = n.PeerHost.Network().InterfaceListenAddresses()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
 
 		conns := n.PeerHost.Network().Conns()
 
@@ -101,6 +142,9 @@ var swarmPeersCmd = &cmds.Command{
 					ci.Latency = lat.String()
 				}
 			}
+			if verbose || direction {
+				ci.DirectionGuess = guessConnDirection(c, listenAddrs, n.Peerstore.Addrs(pid))
+			}
 			if verbose || streams {
 				strs, err := c.GetStreams()
 				if err != nil {
@@ -132,6 +176,9 @@ var swarmPeersCmd = &cmds.Command{
 				if info.Latency != "" {
 					fmt.Fprintf(buf, " %s", info.Latency)
 				}
+				if info.DirectionGuess != "" {
+					fmt.Fprintf(buf, " %s", info.DirectionGuess)
+				}
 				fmt.Fprintln(buf)
 
 				for _, s := range info.Streams {
@@ -157,8 +204,38 @@ type connInfo struct {
 	Addr    string
 	Peer    string
 	Latency string
-	Muxer   string
-	Streams []streamInfo
+	// DirectionGuess is only a guess, see guessConnDirection.
+	DirectionGuess string
+	Muxer          string
+	Streams        []streamInfo
+}
+
+// guessConnDirection guesses whether c was opened by the remote peer or by
+// us, as the connection doesn't tell.
+// Connections accepted by a listener have one of our listen addresses as
+// their local address and usually an unadvertised remote address, while
+// connections we dialed go to one of the addresses we know for the peer.
+// With port reuse both can hold at once, in which case we don't know.
+func guessConnDirection(c inet.Conn, listenAddrs, peerAddrs []ma.Multiaddr) string {
+	local := containsAddr(listenAddrs, c.LocalMultiaddr())
+	known := containsAddr(peerAddrs, c.RemoteMultiaddr())
+	switch {
+	case local && !known:
+		return "inbound"
+	case !local:
+		return "outbound"
+	default:
+		return "unknown"
+	}
+}
+
+func containsAddr(addrs []ma.Multiaddr, a ma.Multiaddr) bool {
+	for _, b := range addrs {
+		if a.Equal(b) {
+			return true
+		}
+	}
+	return false
 }
 
 func (ci *connInfo) Less(i, j int) bool {
@@ -194,10 +271,14 @@ var swarmAddrsCmd = &cmds.Command{
 		Tagline: "List known addresses. Useful for debugging.",
 		ShortDescription: `
 'ipfs swarm addrs' lists all addresses this node is aware of.
+
+'ipfs swarm addrs local' lists the addresses the node announces, and
+'ipfs swarm addrs listen' the addresses it is bound to.
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"local": swarmAddrsLocalCmd,
+		"listen": swarmAddrsListenCmd,
+		"local":  swarmAddrsLocalCmd,
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -256,11 +337,18 @@ var swarmAddrsLocalCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "List local addresses.",
 		ShortDescription: `
-'ipfs swarm addrs local' lists all local addresses the node is listening on.
+'ipfs swarm addrs local' lists the addresses the node announces to other
+peers: the addresses of its network interfaces it listens on, and addresses
+learned from NAT port mappings and from other peers. The addresses the node
+is bound to are listed by 'ipfs swarm addrs listen'.
+
+--scope restricts the output to public, private, loopback or unspecified
+addresses.
 `,
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("id", "Show peer ID in addresses.").Default(false),
+		cmds.StringOption("scope", "Only list addresses of this scope: public, private, loopback or unspecified."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -275,11 +363,17 @@ var swarmAddrsLocalCmd = &cmds.Command{
 			return
 		}
 
+		scope, _, _ := req.Option("scope").String()
+		if err := checkAddrScope(scope); err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
 		showid, _, _ := req.Option("id").Bool()
 		id := n.Identity.Pretty()
 
 		var addrs []string
-		for _, addr := range n.PeerHost.Addrs() {
+		for _, addr := range filterAddrScope(n.PeerHost.Addrs(), scope) {
 			saddr := addr.String()
 			if showid {
 				saddr = path.Join(saddr, "ipfs", id)
@@ -296,6 +390,142 @@ var swarmAddrsLocalCmd = &cmds.Command{
 	},
 }
 
+var swarmAddrsListenCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List listening addresses.",
+		ShortDescription: `
+'ipfs swarm addrs listen' lists the addresses the node's listeners are bound
+to, as set in Addresses.Swarm. Unspecified addresses such as /ip4/0.0.0.0
+are listed as they are, not expanded to the addresses of the network
+interfaces. They are in the unspecified scope, not in the public one.
+
+--scope restricts the output to public, private, loopback or unspecified
+addresses.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("scope", "Only list addresses of this scope: public, private, loopback or unspecified."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if n.PeerHost == nil {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		scope, _, _ := req.Option("scope").String()
+		if err := checkAddrScope(scope); err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		var addrs []string
+		for _, addr := range filterAddrScope(n.PeerHost.Network().ListenAddresses(), scope) {
+			addrs = append(addrs, addr.String())
+		}
+		sort.Sort(sort.StringSlice(addrs))
+
+		res.SetOutput(&stringList{addrs})
+	},
+	Type: stringList{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: stringListMarshaler,
+	},
+}
+
+// Address scopes accepted by the --scope option of 'ipfs swarm addrs'.
+const (
+	scopePublic      = "public"
+	scopePrivate     = "private"
+	scopeLoopback    = "loopback"
+	scopeUnspecified = "unspecified"
+)
+
+// privateNets are the IP ranges addresses in the private scope belong to.
+var privateNets = parseCIDRs(
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	out := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, ipnet, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		out[i] = ipnet
+	}
+	return out
+}
+
+func checkAddrScope(scope string) error {
+	switch scope {
+	case "", scopePublic, scopePrivate, scopeLoopback, scopeUnspecified:
+		return nil
+	default:
+		return fmt.Errorf("invalid scope %q, must be one of %s, %s, %s or %s", scope, scopePublic, scopePrivate, scopeLoopback, scopeUnspecified)
+	}
+}
+
+// addrScope returns the scope of a. It returns "" if a has no IP address,
+// or one that is neither public nor private, such as a multicast address.
+// Unspecified addresses such as /ip4/0.0.0.0 have a scope of their own, as
+// they stand for all the addresses of the node.
+func addrScope(a ma.Multiaddr) string {
+	switch {
+	case manet.IsIPLoopback(a):
+		return scopeLoopback
+	case manet.IsIPUnspecified(a):
+		return scopeUnspecified
+	}
+
+	v, err := a.ValueForProtocol(ma.P_IP4)
+	if err != nil {
+		v, err = a.ValueForProtocol(ma.P_IP6)
+	}
+	if err != nil {
+		return ""
+	}
+	ip := net.ParseIP(v)
+	if ip == nil || ip.IsMulticast() {
+		return ""
+	}
+
+	for _, ipnet := range privateNets {
+		if ipnet.Contains(ip) {
+			return scopePrivate
+		}
+	}
+	return scopePublic
+}
+
+// filterAddrScope returns the addresses of addrs in the given scope, or all
+// of them if scope is empty.
+func filterAddrScope(addrs []ma.Multiaddr, scope string) []ma.Multiaddr {
+	if scope == "" {
+		return addrs
+	}
+
+	var out []ma.Multiaddr
+	for _, a := range addrs {
+		if addrScope(a) == scope {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 var swarmConnectCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Open connection to a given address.",
@@ -305,11 +535,23 @@ var swarmConnectCmd = &cmds.Command{
 The address format is an IPFS multiaddr:
 
 ipfs swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ
+
+Use --retries to dial a peer again after a failed attempt, and the global
+--timeout option to bound the time spent on all attempts. For example, to
+try a peer up to 3 times within 30 seconds:
+
+ipfs swarm connect --retries=2 --timeout=30s <address>
+
+The failure of every attempt is reported, and the command succeeds as soon
+as one attempt connects.
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("address", true, true, "Address of peer to connect to.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.IntOption("retries", "r", "Number of times to retry a failed dial.").Default(0),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		ctx := req.Context()
 
@@ -321,6 +563,16 @@ ipfs swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3
 
 		addrs := req.Arguments()
 
+		retries, _, err := req.Option("retries").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if retries < 0 {
+			res.SetError(fmt.Errorf("retries must not be negative"), cmds.ErrClient)
+			return
+		}
+
 		if n.PeerHost == nil {
 			res.SetError(errNotOnline, cmds.ErrClient)
 			return
@@ -340,18 +592,20 @@ ipfs swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3
 			return
 		}
 
-		output := make([]string, len(pis))
-		for i, pi := range pis {
-			swrm.Backoff().Clear(pi.ID)
-
-			output[i] = "connect " + pi.ID.Pretty()
+		var output []string
+		for _, pi := range pis {
+			prefix := "connect " + pi.ID.Pretty()
 
-			err := n.PeerHost.Connect(ctx, pi)
+			failures, err := connectRetrying(ctx, n.PeerHost, swrm, pi, retries)
 			if err != nil {
-				res.SetError(fmt.Errorf("%s failure: %s", output[i], err), cmds.ErrNormal)
+				res.SetError(fmt.Errorf("%s failure: %s", prefix, err), cmds.ErrNormal)
 				return
 			}
-			output[i] += " success"
+
+			for _, f := range failures {
+				output = append(output, prefix+" "+f)
+			}
+			output = append(output, prefix+" success")
 		}
 
 		res.SetOutput(&stringList{output})
@@ -362,6 +616,35 @@ ipfs swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3
 	Type: stringList{},
 }
 
+// swarmConnectRetryDelay is how long 'ipfs swarm connect' waits before
+// dialing a peer again after a failed attempt.
+const swarmConnectRetryDelay = time.Second
+
+// connectRetrying dials pi up to retries+1 times and returns the reasons the
+// failed attempts gave. The returned error combines all of them.
+func connectRetrying(ctx context.Context, h p2phost.Host, swrm *swarm.Swarm, pi pstore.PeerInfo, retries int) ([]string, error) {
+	var failures []string
+	for attempt := 1; ; attempt++ {
+		swrm.Backoff().Clear(pi.ID)
+
+		err := h.Connect(ctx, pi)
+		if err == nil {
+			return failures, nil
+		}
+		failures = append(failures, fmt.Sprintf("attempt %d: %s", attempt, err))
+
+		if attempt > retries {
+			return failures, errors.New(strings.Join(failures, "; "))
+		}
+
+		select {
+		case <-time.After(swarmConnectRetryDelay):
+		case <-ctx.Done():
+			return failures, errors.New(strings.Join(failures, "; "))
+		}
+	}
+}
+
 var swarmDisconnectCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Close connection to a given address.",
@@ -371,12 +654,22 @@ format is an IPFS multiaddr:
 
 ipfs swarm disconnect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ
 
+With '--all', every open connection is closed instead. '--idle' only closes
+connections that have no open streams, either among the given addresses or,
+together with '--all', among all connections:
+
+ipfs swarm disconnect --all --idle
+
 The disconnect is not permanent; if ipfs needs to talk to that address later,
 it will reconnect.
 `,
 	},
 	Arguments: []cmds.Argument{
-		cmds.StringArg("address", true, true, "Address of peer to disconnect from.").EnableStdin(),
+		cmds.StringArg("address", false, true, "Address of peer to disconnect from.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("all", "a", "Close all connections.").Default(false),
+		cmds.BoolOption("idle", "Only close connections without open streams.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -386,44 +679,83 @@ it will reconnect.
 		}
 
 		addrs := req.Arguments()
+		all, _, _ := req.Option("all").Bool()
+		idle, _, _ := req.Option("idle").Bool()
 
 		if n.PeerHost == nil {
 			res.SetError(errNotOnline, cmds.ErrClient)
 			return
 		}
 
-		iaddrs, err := parseAddresses(addrs)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
+		if all && len(addrs) > 0 {
+			res.SetError(errors.New("cannot give addresses together with '--all'"), cmds.ErrClient)
+			return
+		}
+		if !all && len(addrs) == 0 {
+			res.SetError(errors.New("no address given, use '--all' to close all connections"), cmds.ErrClient)
 			return
 		}
 
-		output := make([]string, len(iaddrs))
-		for i, addr := range iaddrs {
-			taddr := addr.Transport()
-			output[i] = "disconnect " + addr.ID().Pretty()
-
-			found := false
-			conns := n.PeerHost.Network().ConnsToPeer(addr.ID())
-			for _, conn := range conns {
-				if !conn.RemoteMultiaddr().Equal(taddr) {
-					log.Debug("it's not", conn.RemoteMultiaddr(), taddr)
-					continue
+		var output []string
+		var closed, failed int
+		closeConn := func(conn inet.Conn) string {
+			if idle {
+				strs, err := conn.GetStreams()
+				if err != nil {
+					failed++
+					return " failure: " + err.Error()
 				}
-
-				if err := conn.Close(); err != nil {
-					output[i] += " failure: " + err.Error()
-				} else {
-					output[i] += " success"
+				if len(strs) > 0 {
+					return " skipped: conn has open streams"
 				}
-				found = true
-				break
 			}
 
-			if !found {
-				output[i] += " failure: conn not found"
+			if err := conn.Close(); err != nil {
+				failed++
+				return " failure: " + err.Error()
+			}
+			closed++
+			return " success"
+		}
+
+		if all {
+			for _, conn := range n.PeerHost.Network().Conns() {
+				line := "disconnect " + conn.RemotePeer().Pretty() + " " + conn.RemoteMultiaddr().String()
+				output = append(output, line+closeConn(conn))
+			}
+		} else {
+			iaddrs, err := parseAddresses(addrs)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			for _, addr := range iaddrs {
+				taddr := addr.Transport()
+				line := "disconnect " + addr.ID().Pretty()
+
+				found := false
+				conns := n.PeerHost.Network().ConnsToPeer(addr.ID())
+				for _, conn := range conns {
+					if !conn.RemoteMultiaddr().Equal(taddr) {
+						log.Debug("it's not", conn.RemoteMultiaddr(), taddr)
+						continue
+					}
+
+					line += closeConn(conn)
+					found = true
+					break
+				}
+
+				if !found {
+					failed++
+					line += " failure: conn not found"
+				}
+				output = append(output, line)
 			}
 		}
+
+		output = append(output, fmt.Sprintf("closed %d connection(s), %d failed", closed, failed))
 		res.SetOutput(&stringList{output})
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -493,12 +825,17 @@ Where the above is equivalent to the standard CIDR:
     192.168.0.0/16
 
 Filters default to those specified under the "Swarm.AddrFilters" config key.
+Use --verbose to show whether each filter comes from the config ("config")
+or was only added to the running daemon ("runtime").
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
 		"add": swarmFiltersAddCmd,
 		"rm":  swarmFiltersRmCmd,
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("verbose", "v", "Show where each filter comes from.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -517,6 +854,29 @@ Filters default to those specified under the "Swarm.AddrFilters" config key.
 			return
 		}
 
+		verbose, _, _ := req.Option("verbose").Bool()
+
+		configured := make(map[string]bool)
+		if verbose {
+			cfg, err := n.Repo.Config()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			for _, f := range cfg.Swarm.AddrFilters {
+				mask, err := mafilter.NewMask(f)
+				if err != nil {
+					continue
+				}
+				s, err := mafilter.ConvertIPNet(mask)
+				if err != nil {
+					continue
+				}
+				configured[s] = true
+			}
+		}
+
 		var output []string
 		for _, f := range snet.Filters.Filters() {
 			s, err := mafilter.ConvertIPNet(f)
@@ -524,6 +884,14 @@ Filters default to those specified under the "Swarm.AddrFilters" config key.
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
+
+			if verbose {
+				if configured[s] {
+					s += " config"
+				} else {
+					s += " runtime"
+				}
+			}
 			output = append(output, s)
 		}
 		res.SetOutput(&stringList{output})
@@ -539,13 +907,16 @@ var swarmFiltersAddCmd = &cmds.Command{
 		Tagline: "Add an address filter.",
 		ShortDescription: `
 'ipfs swarm filters add' will add an address filter to the daemons swarm.
-Filters applied this way will not persist daemon reboots, to achieve that,
-add your filters to the ipfs config file.
+The filters are also added to "Swarm.AddrFilters" in the ipfs config file,
+so they persist daemon reboots, unless --no-save is given.
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("address", true, true, "Multiaddr to filter.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("no-save", "Only apply the filters to the running daemon.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -569,6 +940,23 @@ add your filters to the ipfs config file.
 			return
 		}
 
+		noSave, _, _ := req.Option("no-save").Bool()
+
+		for _, arg := range req.Arguments() {
+			mask, err := mafilter.NewMask(arg)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+
+			snet.Filters.AddDialFilter(mask)
+		}
+
+		if noSave {
+			res.SetOutput(&stringList{dedupFilters(req.Arguments())})
+			return
+		}
+
 		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -581,16 +969,6 @@ add your filters to the ipfs config file.
 			return
 		}
 
-		for _, arg := range req.Arguments() {
-			mask, err := mafilter.NewMask(arg)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
-			}
-
-			snet.Filters.AddDialFilter(mask)
-		}
-
 		added, err := filtersAdd(r, cfg, req.Arguments())
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -611,13 +989,17 @@ var swarmFiltersRmCmd = &cmds.Command{
 		Tagline: "Remove an address filter.",
 		ShortDescription: `
 'ipfs swarm filters rm' will remove an address filter from the daemons swarm.
-Filters removed this way will not persist daemon reboots, to achieve that,
-remove your filters from the ipfs config file.
+The filters are also removed from "Swarm.AddrFilters" in the ipfs config
+file, so they don't come back after a daemon reboot, unless --no-save is
+given.
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("address", true, true, "Multiaddr filter to remove.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.BoolOption("no-save", "Only remove the filters from the running daemon.").Default(false),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -636,28 +1018,39 @@ remove your filters from the ipfs config file.
 			return
 		}
 
-		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
-		}
-		defer r.Close()
-		cfg, err := r.Config()
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
-		}
+		noSave, _, _ := req.Option("no-save").Bool()
 
 		if req.Arguments()[0] == "all" || req.Arguments()[0] == "*" {
-			fs := snet.Filters.Filters()
-			for _, f := range fs {
+			var removed []string
+			for _, f := range snet.Filters.Filters() {
 				snet.Filters.Remove(f)
+
+				s, err := mafilter.ConvertIPNet(f)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				removed = append(removed, s)
 			}
 
-			removed, err := filtersRemoveAll(r, cfg)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
+			if !noSave {
+				r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				defer r.Close()
+				cfg, err := r.Config()
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+
+				removed, err = filtersRemoveAll(r, cfg)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
 			}
 
 			res.SetOutput(&stringList{removed})
@@ -675,6 +1068,23 @@ remove your filters from the ipfs config file.
 			snet.Filters.Remove(mask)
 		}
 
+		if noSave {
+			res.SetOutput(&stringList{dedupFilters(req.Arguments())})
+			return
+		}
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		removed, err := filtersRemove(r, cfg, req.Arguments())
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -689,6 +1099,21 @@ remove your filters from the ipfs config file.
 	Type: stringList{},
 }
 
+// dedupFilters returns filters without repeated entries, keeping the
+// order in which they were first given.
+func dedupFilters(filters []string) []string {
+	seen := make(map[string]struct{}, len(filters))
+	out := make([]string, 0, len(filters))
+	for _, f := range filters {
+		if _, found := seen[f]; found {
+			continue
+		}
+		seen[f] = struct{}{}
+		out = append(out, f)
+	}
+	return out
+}
+
 func filtersAdd(r repo.Repo, cfg *config.Config, filters []string) ([]string, error) {
 	addedMap := map[string]struct{}{}
 	addedList := make([]string, 0, len(filters))
@@ -764,3 +1189,546 @@ func filtersRemove(r repo.Repo, cfg *config.Config, toRemoveFilters []string) ([
 
 	return removed, nil
 }
+
+type peeringPeer struct {
+	ID        string
+	Addrs     []string
+	Connected bool
+}
+
+type peeringList struct {
+	Peers []peeringPeer
+}
+
+var swarmPeeringCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the peers this node keeps connections to.",
+		ShortDescription: `
+'ipfs swarm peering' manages the peering set: peers the node always tries to
+stay connected to. Peers in the set are redialed shortly after their
+connection drops, waiting longer each time for peers that keep dropping, and
+periodically for as long as they remain unreachable.
+
+The peering set is stored under "Peering.Peers" in the ipfs config file and
+restored when the daemon starts.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add": swarmPeeringAddCmd,
+		"ls":  swarmPeeringLsCmd,
+		"rm":  swarmPeeringRmCmd,
+	},
+}
+
+var swarmPeeringAddCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Add peers to the peering set.",
+		ShortDescription: `
+'ipfs swarm peering add' adds peers to the peering set and connects to them.
+The addresses are added to "Peering.Peers" in the ipfs config file.
+
+The address format is an IPFS multiaddr:
+
+ipfs swarm peering add /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("address", true, true, "Address of the peer to add.").EnableStdin(),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if n.PeerHost == nil || n.Peering == nil {
+			res.SetError(errNotOnline, cmds.ErrNormal)
+			return
+		}
+
+		pis, err := peersWithAddresses(req.Arguments())
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		added, err := peeringAdd(r, cfg, req.Arguments())
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		for _, pi := range pis {
+			n.Peering.AddPeer(pi)
+		}
+
+		res.SetOutput(&stringList{added})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: stringListMarshaler,
+	},
+	Type: stringList{},
+}
+
+var swarmPeeringLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the peering set.",
+		ShortDescription: `
+'ipfs swarm peering ls' lists the peers in the peering set, whether the node
+is currently connected to each of them, and the addresses used to dial them.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if n.PeerHost == nil || n.Peering == nil {
+			res.SetError(errNotOnline, cmds.ErrNormal)
+			return
+		}
+
+		out := &peeringList{Peers: []peeringPeer{}}
+		for _, st := range n.Peering.ListPeers() {
+			pp := peeringPeer{
+				ID:        st.ID.Pretty(),
+				Connected: st.Connected,
+			}
+			for _, a := range st.Addrs {
+				pp.Addrs = append(pp.Addrs, a.String())
+			}
+			out.Peers = append(out.Peers, pp)
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*peeringList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			for _, p := range list.Peers {
+				state := "not connected"
+				if p.Connected {
+					state = "connected"
+				}
+				fmt.Fprintf(buf, "%s %s\n", p.ID, state)
+				for _, a := range p.Addrs {
+					fmt.Fprintf(buf, "  %s\n", a)
+				}
+			}
+			return buf, nil
+		},
+	},
+	Type: peeringList{},
+}
+
+var swarmPeeringRmCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove peers from the peering set.",
+		ShortDescription: `
+'ipfs swarm peering rm' removes peers from the peering set and all of their
+addresses from "Peering.Peers" in the ipfs config file. Peers can be given by
+peer ID or by address. Open connections to the peers are not closed, but are
+no longer reestablished when they drop. If any of the peers is not in the
+peering set, nothing is removed.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", true, true, "Peer ID or address of the peer to remove.").EnableStdin(),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if n.PeerHost == nil || n.Peering == nil {
+			res.SetError(errNotOnline, cmds.ErrNormal)
+			return
+		}
+
+		ids := make([]peer.ID, len(req.Arguments()))
+		for i, arg := range req.Arguments() {
+			ids[i], err = parsePeeringPeer(arg)
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+		}
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		// check all the peers first, so that an unknown one doesn't leave
+		// the others removed
+		inConfig := peeringConfigIDs(cfg)
+		for _, id := range ids {
+			if !inConfig[id] && !n.Peering.IsPeered(id) {
+				res.SetError(fmt.Errorf("%s: %s", id.Pretty(), core.ErrNotPeered), cmds.ErrClient)
+				return
+			}
+		}
+
+		if err := peeringRemove(r, cfg, ids); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		var removed []string
+		for _, id := range ids {
+			// peers only in the config were not added to the running
+			// service and are not an error
+			n.Peering.RemovePeer(id)
+			removed = append(removed, id.Pretty())
+		}
+
+		res.SetOutput(&stringList{dedupFilters(removed)})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: stringListMarshaler,
+	},
+	Type: stringList{},
+}
+
+// parsePeeringPeer returns the peer ID of s, which is either a peer ID or a
+// peer address.
+func parsePeeringPeer(s string) (peer.ID, error) {
+	if strings.HasPrefix(s, "/") {
+		ia, err := iaddr.ParseString(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid peer address: %s", err)
+		}
+		return ia.ID(), nil
+	}
+
+	id, err := peer.IDB58Decode(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid peer ID %q: %s", s, err)
+	}
+	return id, nil
+}
+
+// peeringAdd adds addrs to the config's peering set, skipping addresses
+// already in it, and returns the addresses that were added.
+func peeringAdd(r repo.Repo, cfg *config.Config, addrs []string) ([]string, error) {
+	existing := make(map[string]struct{}, len(cfg.Peering.Peers))
+	for _, a := range cfg.Peering.Peers {
+		existing[a] = struct{}{}
+	}
+
+	added := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if _, found := existing[a]; found {
+			continue
+		}
+		existing[a] = struct{}{}
+		cfg.Peering.Peers = append(cfg.Peering.Peers, a)
+		added = append(added, a)
+	}
+
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// peeringConfigIDs returns the peers that have addresses in the config's
+// peering set.
+func peeringConfigIDs(cfg *config.Config) map[peer.ID]bool {
+	ids := make(map[peer.ID]bool)
+	for _, a := range cfg.Peering.Peers {
+		bp, err := config.ParseBootstrapPeer(a)
+		if err == nil {
+			ids[bp.ID()] = true
+		}
+	}
+	return ids
+}
+
+// peeringRemove removes all addresses of the given peers from the config's
+// peering set.
+func peeringRemove(r repo.Repo, cfg *config.Config, ids []peer.ID) error {
+	remove := make(map[peer.ID]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	keep := make([]string, 0, len(cfg.Peering.Peers))
+	for _, a := range cfg.Peering.Peers {
+		bp, err := config.ParseBootstrapPeer(a)
+		if err == nil && remove[bp.ID()] {
+			continue
+		}
+		keep = append(keep, a)
+	}
+	cfg.Peering.Peers = keep
+
+	return r.SetConfig(cfg)
+}
+
+var errConnMgrDisabled = errors.New("the connection manager is disabled, see Swarm.ConnMgr in the ipfs config file")
+
+type swarmLimits struct {
+	Connections int
+	LowWater    int
+	HighWater   int
+	GracePeriod string
+	// Persisted is false once the limits were changed with
+	// 'ipfs swarm limit set', as they then differ from the config.
+	Persisted bool
+}
+
+type swarmStats struct {
+	swarmLimits
+	Protected int
+	InGrace   int
+	Trimmed   int
+	LastTrim  string
+}
+
+func newSwarmLimits(st core.ConnMgrStats) swarmLimits {
+	return swarmLimits{
+		Connections: st.Conns,
+		LowWater:    st.LowWater,
+		HighWater:   st.HighWater,
+		GracePeriod: st.GracePeriod.String(),
+		Persisted:   !st.Modified,
+	}
+}
+
+// connMgr returns the connection manager of the node running req.
+func connMgr(req cmds.Request) (*core.ConnManager, error) {
+	n, err := req.InvocContext().GetNode()
+	if err != nil {
+		return nil, err
+	}
+
+	if n.PeerHost == nil {
+		return nil, errNotOnline
+	}
+	if n.ConnMgr == nil {
+		return nil, errConnMgrDisabled
+	}
+	return n.ConnMgr, nil
+}
+
+func writeSwarmLimits(w io.Writer, l swarmLimits) {
+	fmt.Fprintf(w, "Connections: %d\n", l.Connections)
+	fmt.Fprintf(w, "LowWater: %d\n", l.LowWater)
+	fmt.Fprintf(w, "HighWater: %d\n", l.HighWater)
+	fmt.Fprintf(w, "GracePeriod: %s\n", l.GracePeriod)
+}
+
+func writeNotPersisted(w io.Writer, l swarmLimits) {
+	if !l.Persisted {
+		fmt.Fprintln(w, "Limits were set at runtime and are not persisted; the daemon uses Swarm.ConnMgr from the config again when restarted.")
+	}
+}
+
+func swarmLimitsMarshaler(res cmds.Response) (io.Reader, error) {
+	l, ok := res.Output().(*swarmLimits)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+
+	buf := new(bytes.Buffer)
+	writeSwarmLimits(buf, *l)
+	writeNotPersisted(buf, *l)
+	return buf, nil
+}
+
+var swarmLimitCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the limits of the connection manager.",
+		ShortDescription: `
+'ipfs swarm limit' shows the number of open connections next to the
+watermarks of the connection manager. Once there are more connections than
+the high watermark, the connection manager closes connections until only the
+low watermark is left. Connections opened less than the grace period ago,
+and connections to peers of the peering set, are never closed.
+
+The limits are read from "Swarm.ConnMgr" in the ipfs config file when the
+daemon starts, and can be changed at runtime with 'ipfs swarm limit set'.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"set": swarmLimitSetCmd,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		cm, err := connMgr(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		l := newSwarmLimits(cm.Stats())
+		res.SetOutput(&l)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: swarmLimitsMarshaler,
+	},
+	Type: swarmLimits{},
+}
+
+var swarmLimitSetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Change the limits of the connection manager at runtime.",
+		ShortDescription: `
+'ipfs swarm limit set' changes the watermarks and the grace period of the
+connection manager of the running daemon. Limits that are not given keep
+their current value.
+
+The change is NOT persisted: the config file is left untouched, and the
+daemon uses the limits in "Swarm.ConnMgr" again when it restarts. To change
+the limits for good, use 'ipfs config'.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("low", "The number of connections to trim down to."),
+		cmds.IntOption("high", "The number of connections above which connections are closed."),
+		cmds.StringOption("grace", "How long new connections are kept open regardless of the limits, like '30s'."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		cm, err := connMgr(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		limits := cm.Limits()
+		low, lowSet, err := req.Option("low").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+		high, highSet, err := req.Option("high").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+		grace, graceSet, err := req.Option("grace").String()
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+		if !lowSet && !highSet && !graceSet {
+			res.SetError(errors.New("at least one of --low, --high or --grace must be given"), cmds.ErrClient)
+			return
+		}
+
+		if lowSet {
+			limits.LowWater = low
+		}
+		if highSet {
+			limits.HighWater = high
+		}
+		if graceSet {
+			d, err := time.ParseDuration(grace)
+			if err != nil {
+				res.SetError(fmt.Errorf("invalid grace period: %s", err), cmds.ErrClient)
+				return
+			}
+			limits.GracePeriod = d
+		}
+		if limits.LowWater < 0 || limits.HighWater < 0 || limits.GracePeriod < 0 {
+			res.SetError(errors.New("limits must not be negative"), cmds.ErrClient)
+			return
+		}
+
+		if err := cm.SetLimits(limits); err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		l := newSwarmLimits(cm.Stats())
+		res.SetOutput(&l)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: swarmLimitsMarshaler,
+	},
+	Type: swarmLimits{},
+}
+
+var swarmStatsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the state of the connection manager.",
+		ShortDescription: `
+'ipfs swarm stats' shows the limits of the connection manager along with how
+many of the open connections it may not close: those to protected peers,
+which are the peers of the peering set, and those still in their grace
+period. It also shows how many connections it closed since the daemon
+started, and when it last did so.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		cm, err := connMgr(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		st := cm.Stats()
+		out := &swarmStats{
+			swarmLimits: newSwarmLimits(st),
+			Protected:   st.Protected,
+			InGrace:     st.InGrace,
+			Trimmed:     st.Trimmed,
+		}
+		if !st.LastTrim.IsZero() {
+			out.LastTrim = st.LastTrim.Format(time.RFC3339)
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			st, ok := res.Output().(*swarmStats)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			lastTrim := st.LastTrim
+			if lastTrim == "" {
+				lastTrim = "never"
+			}
+
+			buf := new(bytes.Buffer)
+			writeSwarmLimits(buf, st.swarmLimits)
+			fmt.Fprintf(buf, "Protected: %d\n", st.Protected)
+			fmt.Fprintf(buf, "InGrace: %d\n", st.InGrace)
+			fmt.Fprintf(buf, "Trimmed: %d\n", st.Trimmed)
+			fmt.Fprintf(buf, "LastTrim: %s\n", lastTrim)
+			writeNotPersisted(buf, st.swarmLimits)
+			return buf, nil
+		},
+	},
+	Type: swarmStats{},
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"testing"
+
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+)
+
+func TestAddrScope(t *testing.T) {
+	cases := map[string]string{
+		"/ip4/127.0.0.1/tcp/4001":      scopeLoopback,
+		"/ip6/::1/tcp/4001":            scopeLoopback,
+		"/ip4/10.1.2.3/tcp/4001":       scopePrivate,
+		"/ip4/192.168.1.10/udp/4001":   scopePrivate,
+		"/ip4/172.16.5.4/tcp/4001":     scopePrivate,
+		"/ip6/fe80::1/tcp/4001":        scopePrivate,
+		"/ip4/104.131.131.82/tcp/4001": scopePublic,
+		"/ip4/0.0.0.0/tcp/4001":        scopeUnspecified,
+		"/ip6/::/tcp/4001":             scopeUnspecified,
+		"/ip4/224.0.0.251/udp/5353":    "",
+		"/ip6/2604:a880::1/tcp/4001":   scopePublic,
+		"/ip4/172.32.0.1/tcp/4001":     scopePublic,
+	}
+
+	for s, scope := range cases {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := addrScope(a); got != scope {
+			t.Errorf("%s: expected scope %q, got %q", s, scope, got)
+		}
+	}
+}
+
+func TestCheckAddrScope(t *testing.T) {
+	for _, s := range []string{"", scopePublic, scopePrivate, scopeLoopback, scopeUnspecified} {
+		if err := checkAddrScope(s); err != nil {
+			t.Errorf("%q: %s", s, err)
+		}
+	}
+	if err := checkAddrScope("lan"); err == nil {
+		t.Error("expected an error for an unknown scope")
+	}
+}
@@ -17,6 +17,9 @@ var sysDiagCmd = &cmds.Command{
 		Tagline: "Print system diagnostic information.",
 		ShortDescription: `
 Prints out information about your computer to aid in easier debugging.
+This includes the OS and architecture, the Go runtime, memory usage, the
+number of open file descriptors and their limit ("unavailable" where these
+cannot be queried) and the configured datastore type.
 `,
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
@@ -56,6 +59,17 @@ Prints out information about your computer to aid in easier debugging.
 			return
 		}
 
+		fdInfo(info)
+
+		cfg, err := node.Repo.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		info["datastore"] = map[string]interface{}{
+			"type": cfg.Datastore.Type,
+		}
+
 		info["ipfs_version"] = config.CurrentVersionNumber
 		info["ipfs_commit"] = config.CurrentCommit
 		res.SetOutput(info)
@@ -118,10 +132,39 @@ func memInfo(out map[string]interface{}) error {
 
 	m["swap"] = meminf.Swap
 	m["virt"] = meminf.Used
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	m["go_alloc"] = ms.Alloc
+	m["go_sys"] = ms.Sys
+	m["go_heap_inuse"] = ms.HeapInuse
+	m["go_num_gc"] = ms.NumGC
+
 	out["memory"] = m
 	return nil
 }
 
+// fdUsage reports the number of file descriptors open by this process and
+// the soft limit on them. It is set by platforms that can query both.
+var fdUsage func() (open, limit uint64, err error)
+
+func fdInfo(out map[string]interface{}) {
+	fd := map[string]interface{}{
+		"open":  "unavailable",
+		"limit": "unavailable",
+	}
+	if fdUsage != nil {
+		if open, limit, err := fdUsage(); err == nil {
+			fd["open"] = open
+			fd["limit"] = limit
+		} else {
+			log.Debugf("could not query file descriptor usage: %s", err)
+		}
+	}
+
+	out["fds"] = fd
+}
+
 func netInfo(online bool, out map[string]interface{}) error {
 	n := make(map[string]interface{})
 	addrs, err := manet.InterfaceMultiaddrs()
@@ -0,0 +1,27 @@
+// +build freebsd
+
+package commands
+
+import (
+	"io/ioutil"
+	"syscall"
+)
+
+func init() {
+	fdUsage = freebsdFdUsage
+}
+
+func freebsdFdUsage() (uint64, uint64, error) {
+	var rLimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit); err != nil {
+		return 0, 0, err
+	}
+
+	fds, err := ioutil.ReadDir("/dev/fd")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// reading the directory takes up a descriptor of its own
+	return uint64(len(fds) - 1), uint64(rLimit.Cur), nil
+}
@@ -76,12 +76,20 @@ var tarCatCmd = &cmds.Command{
 		Tagline: "Export a tar file from IPFS.",
 		ShortDescription: `
 'ipfs tar cat' will export a tar file from a previously imported one in IPFS.
+
+If a path inside the archive is given with --entry, only the contents of
+that file are written, without fetching the rest of the archive:
+
+    > ipfs tar cat --entry=foo/bar.txt <tar-hash>
 `,
 	},
 
 	Arguments: []cmds.Argument{
 		cmds.StringArg("path", true, false, "ipfs path of archive to export.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmds.StringOption("entry", "Path of a single file inside the archive to output."),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -107,7 +115,18 @@ var tarCatCmd = &cmds.Command{
 			return
 		}
 
-		r, err := tar.ExportTar(req.Context(), rootpb, nd.DAG)
+		entry, _, err := req.Option("entry").String()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		var r io.Reader
+		if entry != "" {
+			r, err = tar.CatEntry(req.Context(), rootpb, nd.DAG, entry)
+		} else {
+			r, err = tar.ExportTar(req.Context(), rootpb, nd.DAG)
+		}
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -12,17 +12,29 @@ import (
 )
 
 type VersionOutput struct {
-	Version string
-	Commit  string
-	Repo    string
-	System  string
-	Golang  string
+	Version   string
+	Commit    string
+	Repo      string
+	System    string
+	Golang    string
+	BuildDate string   `json:",omitempty"`
+	Deps      []string `json:",omitempty"`
 }
 
+// unknownBuildInfo is reported for build information that was not set with
+// ldflags at build time.
+const unknownBuildInfo = "unknown"
+
 var VersionCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
-		Tagline:          "Show ipfs version information.",
-		ShortDescription: "Returns the current version of ipfs and exits.",
+		Tagline: "Show ipfs version information.",
+		ShortDescription: `
+Returns the current version of ipfs and exits.
+
+With '--all', the git commit, build date, repo, system and Go versions and
+the versions of the gx dependencies the binary was built with are shown as
+well. Values that were not recorded at build time are shown as 'unknown'.
+`,
 	},
 
 	Options: []cmds.Option{
@@ -32,13 +44,7 @@ var VersionCmd = &cmds.Command{
 		cmds.BoolOption("all", "Show all version information").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		res.SetOutput(&VersionOutput{
-			Version: config.CurrentVersionNumber,
-			Commit:  config.CurrentCommit,
-			Repo:    fmt.Sprint(fsrepo.RepoVersion),
-			System:  runtime.GOARCH + "/" + runtime.GOOS, //TODO: Precise version here
-			Golang:  runtime.Version(),
-		})
+		res.SetOutput(versionOutput())
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
@@ -76,8 +82,17 @@ var VersionCmd = &cmds.Command{
 			}
 			if all {
 				out := fmt.Sprintf("go-ipfs version: %s-%s\n"+
-					"Repo version: %s\nSystem version: %s\nGolang version: %s\n",
-					v.Version, v.Commit, v.Repo, v.System, v.Golang)
+					"Repo version: %s\nSystem version: %s\nGolang version: %s\n"+
+					"Build date: %s\n",
+					v.Version, v.Commit, v.Repo, v.System, v.Golang, v.BuildDate)
+				if len(v.Deps) == 0 {
+					out += "Dependencies: " + unknownBuildInfo + "\n"
+				} else {
+					out += "Dependencies:\n"
+					for _, d := range v.Deps {
+						out += "  " + d + "\n"
+					}
+				}
 				return strings.NewReader(out), nil
 			}
 
@@ -86,3 +101,25 @@ var VersionCmd = &cmds.Command{
 	},
 	Type: VersionOutput{},
 }
+
+// versionOutput returns the version information of this binary.
+func versionOutput() *VersionOutput {
+	v := &VersionOutput{
+		Version:   config.CurrentVersionNumber,
+		Commit:    config.CurrentCommit,
+		Repo:      fmt.Sprint(fsrepo.RepoVersion),
+		System:    runtime.GOARCH + "/" + runtime.GOOS, //TODO: Precise version here
+		Golang:    runtime.Version(),
+		BuildDate: config.BuildDate,
+	}
+	if v.Commit == "" {
+		v.Commit = unknownBuildInfo
+	}
+	if v.BuildDate == "" {
+		v.BuildDate = unknownBuildInfo
+	}
+	if config.Dependencies != "" {
+		v.Deps = strings.Split(config.Dependencies, ",")
+	}
+	return v
+}
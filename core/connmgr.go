@@ -0,0 +1,269 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	goprocess "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess"
+	periodicproc "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess/periodic"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// ConnMgrPeriod is the interval at which the connection manager checks the
+// number of open connections, besides checking it whenever one is opened.
+var ConnMgrPeriod = 10 * time.Second
+
+// ErrBadWatermarks is returned for a low watermark above the high one.
+var ErrBadWatermarks = errors.New("low watermark must not be above the high watermark")
+
+// ConnMgrLimits are the settings of a ConnManager.
+type ConnMgrLimits struct {
+	LowWater    int
+	HighWater   int
+	GracePeriod time.Duration
+}
+
+// ConnMgrStats is a snapshot of the state of a ConnManager.
+type ConnMgrStats struct {
+	ConnMgrLimits
+
+	// Modified is set once the limits were changed at runtime, so they no
+	// longer match the config.
+	Modified bool
+
+	Conns     int // open connections
+	Protected int // connections to protected peers, which are never closed
+	InGrace   int // connections still in their grace period
+	Trimmed   int // connections closed by the manager since it started
+	LastTrim  time.Time
+}
+
+// ConnManager closes connections once the node has more than HighWater of
+// them, until LowWater are left. Connections to protected peers, and
+// connections opened less than GracePeriod ago, are left alone. Among the
+// others, the most recently opened connections are closed first.
+type ConnManager struct {
+	host      host.Host
+	protected func(peer.ID) bool
+	proc      goprocess.Process
+
+	mu       sync.Mutex
+	limits   ConnMgrLimits
+	modified bool
+	conns    map[inet.Conn]time.Time
+	trimming bool
+	trimmed  int
+	lastTrim time.Time
+}
+
+// NewConnManager starts a ConnManager on h. Connections to peers for which
+// protected returns true are never closed; protected may be nil. Close
+// stops it.
+func NewConnManager(h host.Host, limits ConnMgrLimits, protected func(peer.ID) bool) (*ConnManager, error) {
+	if limits.LowWater > limits.HighWater {
+		return nil, ErrBadWatermarks
+	}
+	if protected == nil {
+		protected = func(peer.ID) bool { return false }
+	}
+
+	cm := &ConnManager{
+		host:      h,
+		protected: protected,
+		limits:    limits,
+		conns:     make(map[inet.Conn]time.Time),
+	}
+
+	now := time.Now()
+	for _, c := range h.Network().Conns() {
+		cm.conns[c] = now
+	}
+
+	cm.proc = periodicproc.Tick(ConnMgrPeriod, func(worker goprocess.Process) {
+		cm.trim()
+	})
+	h.Network().Notify((*connMgrNotifiee)(cm))
+	return cm, nil
+}
+
+// connMgrLimits reads the connection manager settings from cfg, using the
+// defaults for the fields left unset. It returns false if the connection
+// manager is disabled, which it is for configs without a Type, such as
+// those of repos created before there was a connection manager.
+func connMgrLimits(cfg config.ConnMgr) (ConnMgrLimits, bool, error) {
+	switch cfg.Type {
+	case config.ConnMgrBasic:
+	case "", config.ConnMgrNone:
+		return ConnMgrLimits{}, false, nil
+	default:
+		return ConnMgrLimits{}, false, fmt.Errorf("unknown connection manager type %q", cfg.Type)
+	}
+
+	limits := ConnMgrLimits{
+		LowWater:  cfg.LowWater,
+		HighWater: cfg.HighWater,
+	}
+	if limits.LowWater == 0 {
+		limits.LowWater = config.DefaultConnMgrLowWater
+	}
+	if limits.HighWater == 0 {
+		limits.HighWater = config.DefaultConnMgrHighWater
+	}
+
+	grace := cfg.GracePeriod
+	if grace == "" {
+		grace = config.DefaultConnMgrGracePeriod
+	}
+	d, err := time.ParseDuration(grace)
+	if err != nil {
+		return ConnMgrLimits{}, false, fmt.Errorf("failure to parse config setting Swarm.ConnMgr.GracePeriod: %s", err)
+	}
+	limits.GracePeriod = d
+
+	return limits, true, nil
+}
+
+// Limits returns the current settings of the manager.
+func (cm *ConnManager) Limits() ConnMgrLimits {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.limits
+}
+
+// SetLimits changes the settings of the manager. The change only lasts until
+// the node is restarted; the config is not updated.
+func (cm *ConnManager) SetLimits(limits ConnMgrLimits) error {
+	if limits.LowWater > limits.HighWater {
+		return ErrBadWatermarks
+	}
+
+	cm.mu.Lock()
+	cm.limits = limits
+	cm.modified = true
+	cm.mu.Unlock()
+
+	go cm.trim()
+	return nil
+}
+
+// Stats returns the current state of the manager.
+func (cm *ConnManager) Stats() ConnMgrStats {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	st := ConnMgrStats{
+		ConnMgrLimits: cm.limits,
+		Modified:      cm.modified,
+		Conns:         len(cm.conns),
+		Trimmed:       cm.trimmed,
+		LastTrim:      cm.lastTrim,
+	}
+
+	graceEnd := time.Now().Add(-cm.limits.GracePeriod)
+	for c, opened := range cm.conns {
+		switch {
+		case cm.protected(c.RemotePeer()):
+			st.Protected++
+		case opened.After(graceEnd):
+			st.InGrace++
+		}
+	}
+	return st
+}
+
+// Close stops the ConnManager. It does not close any connections.
+func (cm *ConnManager) Close() error {
+	cm.host.Network().StopNotify((*connMgrNotifiee)(cm))
+	return cm.proc.Close()
+}
+
+// trim closes connections if there are more than HighWater of them.
+func (cm *ConnManager) trim() {
+	cm.mu.Lock()
+	if cm.trimming || len(cm.conns) <= cm.limits.HighWater {
+		cm.mu.Unlock()
+		return
+	}
+	cm.trimming = true
+
+	excess := len(cm.conns) - cm.limits.LowWater
+	graceEnd := time.Now().Add(-cm.limits.GracePeriod)
+	var candidates []openedConn
+	for c, opened := range cm.conns {
+		if opened.After(graceEnd) || cm.protected(c.RemotePeer()) {
+			continue
+		}
+		candidates = append(candidates, openedConn{c, opened})
+	}
+	cm.mu.Unlock()
+
+	sort.Sort(newestFirst(candidates))
+	if len(candidates) > excess {
+		candidates = candidates[:excess]
+	}
+
+	closed := 0
+	for _, oc := range candidates {
+		log.Debugf("connmgr: closing connection to %s", oc.conn.RemotePeer())
+		if err := oc.conn.Close(); err != nil {
+			log.Debugf("connmgr: failed to close connection to %s: %s", oc.conn.RemotePeer(), err)
+			continue
+		}
+		closed++
+	}
+
+	cm.mu.Lock()
+	cm.trimming = false
+	cm.trimmed += closed
+	cm.lastTrim = time.Now()
+	cm.mu.Unlock()
+}
+
+type openedConn struct {
+	conn   inet.Conn
+	opened time.Time
+}
+
+type newestFirst []openedConn
+
+func (s newestFirst) Len() int           { return len(s) }
+func (s newestFirst) Less(i, j int) bool { return s[i].opened.After(s[j].opened) }
+func (s newestFirst) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type connMgrNotifiee ConnManager
+
+func (nn *connMgrNotifiee) manager() *ConnManager {
+	return (*ConnManager)(nn)
+}
+
+func (nn *connMgrNotifiee) Connected(n inet.Network, c inet.Conn) {
+	cm := nn.manager()
+	cm.mu.Lock()
+	cm.conns[c] = time.Now()
+	over := len(cm.conns) > cm.limits.HighWater
+	cm.mu.Unlock()
+
+	if over {
+		go cm.trim()
+	}
+}
+
+func (nn *connMgrNotifiee) Disconnected(n inet.Network, c inet.Conn) {
+	cm := nn.manager()
+	cm.mu.Lock()
+	delete(cm.conns, c)
+	cm.mu.Unlock()
+}
+
+func (nn *connMgrNotifiee) OpenedStream(n inet.Network, v inet.Stream) {}
+func (nn *connMgrNotifiee) ClosedStream(n inet.Network, v inet.Stream) {}
+func (nn *connMgrNotifiee) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (nn *connMgrNotifiee) ListenClose(n inet.Network, a ma.Multiaddr) {}
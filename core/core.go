@@ -123,11 +123,14 @@ type IpfsNode struct {
 	// Online
 	PeerHost     p2phost.Host        // the network host (server+client)
 	Bootstrapper io.Closer           // the periodic bootstrapper
+	Peering      *PeeringService     // keeps connections to the peering peers
+	ConnMgr      *ConnManager        // keeps the number of connections in check, nil if disabled
 	Routing      routing.IpfsRouting // the routing system. recommend ipfs-dht
 	Exchange     exchange.Interface  // the block exchange + strategy (bitswap)
 	Namesys      namesys.NameSystem  // the name system, resolves paths to hashes
 	Ping         *ping.PingService
-	Reprovider   *rp.Reprovider // the value reprovider system
+	Identify     *identify.IDService // the identify service, tracks observed addrs
+	Reprovider   *rp.Reprovider      // the value reprovider system
 	IpnsRepub    *ipnsrp.Republisher
 
 	Floodsub *floodsub.PubSub
@@ -146,6 +149,7 @@ type IpfsNode struct {
 type Mounts struct {
 	Ipfs mount.Mount
 	Ipns mount.Mount
+	Mfs  mount.Mount
 }
 
 func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption RoutingOption, hostOption HostOption, do DiscoveryOption, pubsub, mplex bool) error {
@@ -260,9 +264,46 @@ func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption Routin
 		}
 	}
 
+	if err := n.startPeering(cfg); err != nil {
+		return err
+	}
+
+	if err := n.startConnMgr(cfg); err != nil {
+		return err
+	}
+
 	return n.Bootstrap(DefaultBootstrapConfig)
 }
 
+// startPeering starts the peering service with the peers in the config.
+func (n *IpfsNode) startPeering(cfg *config.Config) error {
+	bpeers, err := cfg.PeeringPeers()
+	if err != nil {
+		return fmt.Errorf("incorrectly formatted peering address in config: %s", err)
+	}
+
+	n.Peering = NewPeeringService(n.PeerHost)
+	for _, pi := range toPeerInfos(bpeers) {
+		n.Peering.AddPeer(pi)
+	}
+	return nil
+}
+
+// startConnMgr starts the connection manager configured in the config, if it
+// is enabled. Peers of the peering set are protected from it.
+func (n *IpfsNode) startConnMgr(cfg *config.Config) error {
+	limits, enabled, err := connMgrLimits(cfg.Swarm.ConnMgr)
+	if err != nil || !enabled {
+		return err
+	}
+
+	n.ConnMgr, err = NewConnManager(n.PeerHost, limits, n.Peering.IsPeered)
+	if err != nil {
+		return fmt.Errorf("invalid Swarm.ConnMgr config: %s", err)
+	}
+	return nil
+}
+
 func makeSmuxTransport(mplexExp bool) smux.Transport {
 	mstpt := mssmux.NewBlankTransport()
 
@@ -320,6 +361,14 @@ func (n *IpfsNode) startOnlineServicesWithHost(ctx context.Context, host p2phost
 	// setup diagnostics service
 	n.Ping = ping.NewPingService(host)
 
+	// keep a handle on the identify service before the host gets wrapped,
+	// the routed host doesn't expose it
+	if ih, ok := host.(interface {
+		IDService() *identify.IDService
+	}); ok {
+		n.Identify = ih.IDService()
+	}
+
 	// setup routing service
 	r, err := routingOption(ctx, host, n.Repo.Datastore())
 	if err != nil {
@@ -443,6 +492,9 @@ func (n *IpfsNode) teardown() error {
 	if n.Mounts.Ipns != nil && !n.Mounts.Ipns.IsActive() {
 		closers = append(closers, mount.Closer(n.Mounts.Ipns))
 	}
+	if n.Mounts.Mfs != nil && !n.Mounts.Mfs.IsActive() {
+		closers = append(closers, mount.Closer(n.Mounts.Mfs))
+	}
 
 	if dht, ok := n.Routing.(*dht.IpfsDHT); ok {
 		closers = append(closers, dht.Process())
@@ -456,6 +508,14 @@ func (n *IpfsNode) teardown() error {
 		closers = append(closers, n.Bootstrapper)
 	}
 
+	if n.ConnMgr != nil {
+		closers = append(closers, n.ConnMgr)
+	}
+
+	if n.Peering != nil {
+		closers = append(closers, n.Peering)
+	}
+
 	if n.PeerHost != nil {
 		closers = append(closers, n.PeerHost)
 	}
@@ -146,6 +146,12 @@ func (e *MultiError) Error() string {
 }
 
 func GarbageCollectAsync(n *core.IpfsNode, ctx context.Context) <-chan gc.Result {
+	return GarbageCollectAsyncWithLimits(n, ctx, gc.Limits{})
+}
+
+// GarbageCollectAsyncWithLimits runs a garbage collection that stops once
+// one of the given limits has been reached.
+func GarbageCollectAsyncWithLimits(n *core.IpfsNode, ctx context.Context, limits gc.Limits) <-chan gc.Result {
 	roots, err := BestEffortRoots(n.FilesRoot)
 	if err != nil {
 		out := make(chan gc.Result)
@@ -154,7 +160,7 @@ func GarbageCollectAsync(n *core.IpfsNode, ctx context.Context) <-chan gc.Result
 		return out
 	}
 
-	return gc.GC(ctx, n.Blockstore, n.DAG, n.Pinning, roots)
+	return gc.GCWithLimits(ctx, n.Blockstore, n.DAG, n.Pinning, roots, limits)
 }
 
 func PeriodicGC(ctx context.Context, node *core.IpfsNode) error {
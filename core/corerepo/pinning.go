@@ -16,8 +16,10 @@ package corerepo
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/ipfs/go-ipfs/core"
+	mfs "github.com/ipfs/go-ipfs/mfs"
 	path "github.com/ipfs/go-ipfs/path"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
@@ -76,12 +78,7 @@ func Unpin(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool
 	}
 
 	for _, p := range paths {
-		p, err := path.ParsePath(p)
-		if err != nil {
-			return nil, err
-		}
-
-		k, err := core.ResolveToCid(ctx, n.Namesys, r, p)
+		k, err := ResolvePath(ctx, n, r, p)
 		if err != nil {
 			return nil, err
 		}
@@ -101,3 +98,41 @@ func Unpin(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool
 	}
 	return unpinned, nil
 }
+
+// ResolvePath resolves p to the CID it points to. p is either a CID, an
+// /ipfs/ or /ipns/ path, or any other absolute path, which is looked up in
+// the node's mfs (see 'ipfs files').
+func ResolvePath(ctx context.Context, n *core.IpfsNode, r *path.Resolver, p string) (*cid.Cid, error) {
+	if !isMfsPath(p) {
+		pth, err := path.ParsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %s", p, err)
+		}
+
+		c, err := core.ResolveToCid(ctx, n.Namesys, r, pth)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %s", p, err)
+		}
+		return c, nil
+	}
+
+	if n.FilesRoot == nil {
+		return nil, fmt.Errorf("could not resolve mfs path %s: mfs is not available", p)
+	}
+
+	fsn, err := mfs.Lookup(n.FilesRoot, p)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve mfs path %s: %s", p, err)
+	}
+
+	nd, err := fsn.GetNode()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve mfs path %s: %s", p, err)
+	}
+	return nd.Cid(), nil
+}
+
+func isMfsPath(p string) bool {
+	return strings.HasPrefix(p, "/") &&
+		!strings.HasPrefix(p, "/ipfs/") && !strings.HasPrefix(p, "/ipns/")
+}
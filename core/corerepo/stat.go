@@ -2,12 +2,18 @@ package corerepo
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	context "context"
 	"github.com/ipfs/go-ipfs/core"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
 	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
 type Stat struct {
@@ -16,6 +22,20 @@ type Stat struct {
 	RepoPath   string
 	Version    string
 	StorageMax uint64 // size in bytes
+
+	// SizeBreakdown is only set when asked for, as computing it walks the
+	// pins and MFS.
+	*SizeBreakdown
+}
+
+// SizeBreakdown splits the repo size by category, in bytes. Blocks that are
+// both pinned and referenced from MFS only count towards SizePinned.
+type SizeBreakdown struct {
+	SizePinned   uint64
+	SizeMFS      uint64
+	SizeUnpinned uint64
+	// SizeOther is the size of the keystore and config file.
+	SizeOther uint64
 }
 
 func RepoStat(n *core.IpfsNode, ctx context.Context) (*Stat, error) {
@@ -59,3 +79,108 @@ func RepoStat(n *core.IpfsNode, ctx context.Context) (*Stat, error) {
 		StorageMax: storageMax,
 	}, nil
 }
+
+// RepoSizeBreakdown computes the size of the blocks reachable from the pins,
+// of the other blocks reachable from MFS and of the remaining blocks, along
+// with the size of the keystore and config. The block sizes are looked up
+// without reading the blocks, but all pins and the MFS tree are walked.
+func RepoSizeBreakdown(n *core.IpfsNode, ctx context.Context) (*SizeBreakdown, error) {
+	pinned, mfs, err := reachableSets(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	allKeys, err := n.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sb := &SizeBreakdown{}
+	for k := range allKeys {
+		size, err := n.Blockstore.GetSize(k)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case pinned.Has(k):
+			sb.SizePinned += uint64(size)
+		case mfs.Has(k):
+			sb.SizeMFS += uint64(size)
+		default:
+			sb.SizeUnpinned += uint64(size)
+		}
+	}
+
+	path, err := fsrepo.BestKnownPath()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range []string{"keystore", "config"} {
+		size, err := diskUsage(filepath.Join(path, p))
+		if err != nil {
+			return nil, err
+		}
+		sb.SizeOther += size
+	}
+
+	return sb, nil
+}
+
+// reachableSets returns the set of blocks reachable from the pinset and the
+// set reachable from the MFS root. Missing blocks are skipped, as the stats
+// are only used for reporting.
+func reachableSets(ctx context.Context, n *core.IpfsNode) (pinned, mfs *cid.Set, err error) {
+	ls := n.DAG.GetOfflineLinkService()
+	getLinks := func(ctx context.Context, c *cid.Cid) ([]*node.Link, error) {
+		links, err := ls.GetLinks(ctx, c)
+		if err != nil && err != dag.ErrNotFound {
+			return nil, err
+		}
+		return links, nil
+	}
+
+	pinned = cid.NewSet()
+	if err := gc.Descendants(ctx, getLinks, pinned, n.Pinning.RecursiveKeys()); err != nil {
+		return nil, nil, err
+	}
+	for _, k := range n.Pinning.DirectKeys() {
+		pinned.Add(k)
+	}
+	if err := gc.Descendants(ctx, getLinks, pinned, n.Pinning.InternalPins()); err != nil {
+		return nil, nil, err
+	}
+
+	mfs = cid.NewSet()
+	if n.FilesRoot != nil {
+		roots, err := BestEffortRoots(n.FilesRoot)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := gc.Descendants(ctx, getLinks, mfs, roots); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return pinned, mfs, nil
+}
+
+// diskUsage returns the total size of the regular files at or below path.
+// A missing path has a size of zero.
+func diskUsage(path string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += uint64(fi.Size())
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return size, err
+}
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"os"
 	gopath "path"
+	"sync/atomic"
+	"time"
 
 	bs "github.com/ipfs/go-ipfs/blocks/blockstore"
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
@@ -68,8 +70,27 @@ type AddedObject struct {
 	Name  string
 	Hash  string `json:",omitempty"`
 	Bytes int64  `json:",omitempty"`
+	// To is the mfs path the added root was copied to, if any.
+	To string `json:",omitempty"`
+	// Storage is set for files added with NoCopy, to StorageFilestore or
+	// StorageBlockstore depending on where the file's data ended up.
+	Storage string `json:",omitempty"`
+	// Rate and ETA are set on progress updates: the number of bytes added
+	// per second over the last few seconds, and the time left until all of
+	// the input is added, which is only known after SetTotalSize was called.
+	Rate float64       `json:",omitempty"`
+	ETA  time.Duration `json:",omitempty"`
 }
 
+const (
+	// StorageFilestore marks a file that is referenced through the filestore.
+	StorageFilestore = "filestore"
+	// StorageBlockstore marks a file that was added with NoCopy but could not
+	// be referenced through the filestore, so its data was copied into the
+	// blockstore.
+	StorageBlockstore = "blockstore"
+)
+
 func NewAdder(ctx context.Context, p pin.Pinner, bs bstore.GCBlockstore, ds dag.DAGService) (*Adder, error) {
 	return &Adder{
 		ctx:        ctx,
@@ -107,6 +128,24 @@ type Adder struct {
 	tempRoot   *cid.Cid
 	Prefix     *cid.Prefix
 	liveNodes  uint64
+
+	// PreserveMode and PreserveMtime store the permission bits and the
+	// modification time of added files and directories on their nodes.
+	PreserveMode  bool
+	PreserveMtime bool
+
+	// ShardThreshold, if non-zero, shards directories once they hold more
+	// than this many entries, regardless of the node's sharding config.
+	// It must be set before SetMfsRoot is called.
+	ShardThreshold int
+
+	progress addProgress
+}
+
+// SetTotalSize sets the size of all the input to add, which the ETA of the
+// progress updates is computed against. It may be called while adding.
+func (adder *Adder) SetTotalSize(size int64) {
+	atomic.StoreInt64(&adder.progress.total, size)
 }
 
 func (adder *Adder) mfsRoot() (*mfs.Root, error) {
@@ -120,11 +159,14 @@ func (adder *Adder) mfsRoot() (*mfs.Root, error) {
 	if err != nil {
 		return nil, err
 	}
-	adder.mroot = mr
+	adder.SetMfsRoot(mr)
 	return adder.mroot, nil
 }
 
 func (adder *Adder) SetMfsRoot(r *mfs.Root) {
+	if adder.ShardThreshold > 0 {
+		r.GetValue().(*mfs.Directory).SetShardThreshold(adder.ShardThreshold)
+	}
 	adder.mroot = r
 }
 
@@ -283,7 +325,7 @@ func (adder *Adder) outputDirs(path string, fsn mfs.FSNode) error {
 			return err
 		}
 
-		return outputDagnode(adder.Out, path, nd)
+		return outputDagnode(adder.Out, path, nd, "")
 	default:
 		return fmt.Errorf("unrecognized fsn type: %#v", fsn)
 	}
@@ -374,7 +416,7 @@ func AddWrapped(n *core.IpfsNode, r io.Reader, filename string) (string, node.No
 	return gopath.Join(c.String(), filename), dagnode, nil
 }
 
-func (adder *Adder) addNode(node node.Node, path string) error {
+func (adder *Adder) addNode(node node.Node, path, storage string) error {
 	// patch it into the root
 	if path == "" {
 		path = node.Cid().String()
@@ -400,7 +442,7 @@ func (adder *Adder) addNode(node node.Node, path string) error {
 	}
 
 	if !adder.Silent {
-		return outputDagnode(adder.Out, path, node)
+		return outputDagnode(adder.Out, path, node, storage)
 	}
 	return nil
 }
@@ -456,15 +498,26 @@ func (adder *Adder) addFile(file files.File) error {
 			return err
 		}
 
-		return adder.addNode(dagnode, s.FileName())
+		return adder.addNode(dagnode, s.FileName(), "")
 	}
 
 	// case for regular file
+	// only files read from a known path on disk can be referenced through
+	// the filestore, anything else (e.g. stdin) is copied
+	var storage string
+	if adder.NoCopy {
+		storage = StorageFilestore
+		if fi, ok := file.(files.FileInfo); !ok || fi.AbsPath() == "" {
+			log.Warningf("%s has no path on disk, copying it into the blockstore", file.FileName())
+			storage = StorageBlockstore
+		}
+	}
+
 	// if the progress flag was specified, wrap the file so that we can send
 	// progress updates to the client (over the output channel)
 	var reader io.Reader = file
 	if adder.Progress {
-		rdr := &progressReader{file: file, out: adder.Out}
+		rdr := &progressReader{file: file, out: adder.Out, progress: &adder.progress}
 		if fi, ok := file.(files.FileInfo); ok {
 			reader = &progressReader2{rdr, fi}
 		} else {
@@ -477,8 +530,15 @@ func (adder *Adder) addFile(file files.File) error {
 		return err
 	}
 
+	if stat := fileStat(file); stat != nil && (adder.PreserveMode || adder.PreserveMtime) {
+		dagnode, err = adder.withMetadata(dagnode, stat)
+		if err != nil {
+			return err
+		}
+	}
+
 	// patch it into the root
-	return adder.addNode(dagnode, file.FileName())
+	return adder.addNode(dagnode, file.FileName(), storage)
 }
 
 func (adder *Adder) addDir(dir files.File) error {
@@ -513,9 +573,83 @@ func (adder *Adder) addDir(dir files.File) error {
 		}
 	}
 
+	// set the metadata last, as adding the entries changes the node
+	if stat := fileStat(dir); stat != nil {
+		if adder.PreserveMode {
+			if err := mfs.Chmod(mr, dir.FileName(), stat.Mode()); err != nil {
+				return err
+			}
+		}
+		if adder.PreserveMtime {
+			if err := mfs.Chtime(mr, dir.FileName(), stat.ModTime()); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+// fileStat returns the file info of f, or nil if f carries none.
+func fileStat(f files.File) os.FileInfo {
+	if sf, ok := f.(files.StatFile); ok {
+		return sf.Stat()
+	}
+	return nil
+}
+
+// withMetadata returns a copy of nd with the permission bits and the
+// modification time of stat stored on it, as selected by PreserveMode and
+// PreserveMtime. A raw leaf cannot carry metadata, so it is wrapped in a
+// unixfs file node.
+func (adder *Adder) withMetadata(nd node.Node, stat os.FileInfo) (node.Node, error) {
+	if pi, ok := nd.(*posinfo.FilestoreNode); ok {
+		nd = pi.Node
+	}
+
+	var pbnd *dag.ProtoNode
+	switch nd := nd.(type) {
+	case *dag.ProtoNode:
+		pbnd = nd.Copy().(*dag.ProtoNode)
+	case *dag.RawNode:
+		fsn := &unixfs.FSNode{Type: unixfs.TFile}
+		fsn.AddBlockSize(uint64(len(nd.RawData())))
+		data, err := fsn.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		pbnd = dag.NodeWithData(data)
+		pbnd.SetPrefix(adder.Prefix)
+		if err := pbnd.AddNodeLinkClean("", nd); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cannot store metadata on a node of type %T", nd)
+	}
+
+	data := pbnd.Data()
+	var err error
+	if adder.PreserveMode {
+		data, err = unixfs.SetMode(data, stat.Mode())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if adder.PreserveMtime {
+		data, err = unixfs.SetModTime(data, stat.ModTime())
+		if err != nil {
+			return nil, err
+		}
+	}
+	pbnd.SetData(data)
+
+	if _, err := adder.dagService.Add(pbnd); err != nil {
+		return nil, err
+	}
+	return pbnd, nil
+}
+
 func (adder *Adder) maybePauseForGC() error {
 	if adder.unlocker != nil && adder.blockstore.GCRequested() {
 		err := adder.PinRoot()
@@ -530,7 +664,7 @@ func (adder *Adder) maybePauseForGC() error {
 }
 
 // outputDagnode sends dagnode info over the output channel
-func outputDagnode(out chan interface{}, name string, dn node.Node) error {
+func outputDagnode(out chan interface{}, name string, dn node.Node, storage string) error {
 	if out == nil {
 		return nil
 	}
@@ -541,8 +675,9 @@ func outputDagnode(out chan interface{}, name string, dn node.Node) error {
 	}
 
 	out <- &AddedObject{
-		Hash: o.Hash,
-		Name: name,
+		Hash:    o.Hash,
+		Name:    name,
+		Storage: storage,
 	}
 
 	return nil
@@ -577,6 +712,7 @@ func getOutput(dagnode node.Node) (*Object, error) {
 type progressReader struct {
 	file         files.File
 	out          chan interface{}
+	progress     *addProgress
 	bytes        int64
 	lastProgress int64
 }
@@ -585,17 +721,82 @@ func (i *progressReader) Read(p []byte) (int, error) {
 	n, err := i.file.Read(p)
 
 	i.bytes += int64(n)
+	i.progress.done += int64(n)
 	if i.bytes-i.lastProgress >= progressReaderIncrement || err == io.EOF {
 		i.lastProgress = i.bytes
+		rate, eta := i.progress.estimate(time.Now())
 		i.out <- &AddedObject{
 			Name:  i.file.FileName(),
 			Bytes: i.bytes,
+			Rate:  rate,
+			ETA:   eta,
 		}
 	}
 
 	return n, err
 }
 
+// addProgress tracks the bytes read across all the files of an add.
+type addProgress struct {
+	total int64 // accessed atomically, 0 while unknown
+	done  int64
+	rate  addRate
+}
+
+// estimate records the progress made by now and returns the current rate in
+// bytes per second and the time left, or zero for either if it is not known.
+func (p *addProgress) estimate(now time.Time) (float64, time.Duration) {
+	p.rate.Observe(now, p.done)
+	rate := p.rate.Rate()
+
+	total := atomic.LoadInt64(&p.total)
+	if rate <= 0 || total <= 0 || p.done > total {
+		return rate, 0
+	}
+	return rate, time.Duration(float64(total-p.done) / rate * float64(time.Second))
+}
+
+// addRateWindow is the span of time over which the add throughput is averaged.
+const addRateWindow = 5 * time.Second
+
+type rateSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// addRate keeps a rolling average of the number of bytes added per second.
+type addRate struct {
+	samples []rateSample
+}
+
+// Observe records the total number of bytes processed so far at the given
+// time, discarding samples that fell out of the averaging window.
+func (r *addRate) Observe(now time.Time, total int64) {
+	r.samples = append(r.samples, rateSample{at: now, bytes: total})
+
+	cutoff := now.Add(-addRateWindow)
+	i := 0
+	for i < len(r.samples)-1 && r.samples[i].at.Before(cutoff) {
+		i++
+	}
+	r.samples = r.samples[i:]
+}
+
+// Rate returns the average throughput in bytes per second over the window,
+// or zero if not enough has been observed yet.
+func (r *addRate) Rate() float64 {
+	if len(r.samples) < 2 {
+		return 0
+	}
+	first := r.samples[0]
+	last := r.samples[len(r.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.bytes-first.bytes) / elapsed
+}
+
 type progressReader2 struct {
 	*progressReader
 	files.FileInfo
@@ -267,3 +267,31 @@ func (fi *dummyFileInfo) Mode() os.FileMode  { return 0 }
 func (fi *dummyFileInfo) ModTime() time.Time { return fi.modTime }
 func (fi *dummyFileInfo) IsDir() bool        { return false }
 func (fi *dummyFileInfo) Sys() interface{}   { return nil }
+
+func TestAddProgressEstimate(t *testing.T) {
+	var p addProgress
+	start := time.Now()
+	var rate float64
+	var eta time.Duration
+	for i := 0; i < 10; i++ {
+		p.done = int64(i) * 34 << 20
+		rate, eta = p.estimate(start.Add(time.Duration(i) * time.Second))
+	}
+
+	if len(p.rate.samples) > 6 {
+		t.Fatalf("expected old samples to be dropped, have %d", len(p.rate.samples))
+	}
+	if rate != 34<<20 {
+		t.Fatalf("expected a rate of 34 MiB/s, got %f", rate)
+	}
+	if eta != 0 {
+		t.Fatalf("expected no eta without a total size, got %s", eta)
+	}
+
+	p.total = 20 * 34 << 20
+	p.done = 10 * 34 << 20
+	_, eta = p.estimate(start.Add(10 * time.Second))
+	if eta != 10*time.Second {
+		t.Fatalf("expected an eta of 10s, got %s", eta)
+	}
+}
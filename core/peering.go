@@ -0,0 +1,260 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	goprocess "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess"
+	periodicproc "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess/periodic"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// PeeringPeriod is the interval at which the peering service redials the
+// peers it is not connected to.
+var PeeringPeriod = 30 * time.Second
+
+// peeringConnTimeout bounds a single dial to a peer.
+const peeringConnTimeout = 10 * time.Second
+
+// peeringBackoffBase is how long the peering service waits before redialing a
+// peer whose connection dropped. The wait doubles each time the peer drops
+// again within PeeringPeriod of connecting, up to PeeringPeriod, after which
+// the periodic redial is all that is left.
+const peeringBackoffBase = time.Second
+
+// ErrNotPeered is returned by PeeringService.RemovePeer for peers that were
+// never added.
+var ErrNotPeered = errors.New("peer is not in the peering set")
+
+// PeeringStatus describes one peer of the PeeringService.
+type PeeringStatus struct {
+	ID        peer.ID
+	Addrs     []ma.Multiaddr
+	Connected bool
+}
+
+// PeeringService keeps the node connected to a fixed set of peers. Peers
+// are redialed shortly after their last connection closes, backing off if
+// they keep dropping, and every PeeringPeriod as long as they stay
+// disconnected.
+type PeeringService struct {
+	host host.Host
+	proc goprocess.Process
+
+	mu        sync.Mutex
+	peers     map[peer.ID][]ma.Multiaddr
+	backoff   map[peer.ID]time.Duration
+	connected map[peer.ID]time.Time
+}
+
+// NewPeeringService starts a PeeringService on h. Close stops it.
+func NewPeeringService(h host.Host) *PeeringService {
+	ps := &PeeringService{
+		host:      h,
+		peers:     make(map[peer.ID][]ma.Multiaddr),
+		backoff:   make(map[peer.ID]time.Duration),
+		connected: make(map[peer.ID]time.Time),
+	}
+
+	ps.proc = periodicproc.Tick(PeeringPeriod, func(worker goprocess.Process) {
+		for _, p := range ps.disconnected() {
+			ps.connect(p)
+		}
+	})
+	h.Network().Notify((*peeringNotifiee)(ps))
+	return ps
+}
+
+// AddPeer adds pi to the peering set, or adds its addresses if the peer is
+// already in it, and dials the peer if it is not connected.
+func (ps *PeeringService) AddPeer(pi pstore.PeerInfo) {
+	ps.mu.Lock()
+	addrs := ps.peers[pi.ID]
+	for _, a := range pi.Addrs {
+		if !containsAddr(addrs, a) {
+			addrs = append(addrs, a)
+		}
+	}
+	ps.peers[pi.ID] = addrs
+	ps.mu.Unlock()
+
+	ps.host.Peerstore().AddAddrs(pi.ID, pi.Addrs, pstore.PermanentAddrTTL)
+	if ps.host.Network().Connectedness(pi.ID) != inet.Connected {
+		go ps.connect(pi.ID)
+	}
+}
+
+// RemovePeer removes p from the peering set. Existing connections to the
+// peer are left open.
+func (ps *PeeringService) RemovePeer(p peer.ID) error {
+	ps.mu.Lock()
+	_, ok := ps.peers[p]
+	delete(ps.peers, p)
+	delete(ps.backoff, p)
+	delete(ps.connected, p)
+	ps.mu.Unlock()
+
+	if !ok {
+		return ErrNotPeered
+	}
+	return nil
+}
+
+// ListPeers returns the peers in the peering set, sorted by ID.
+func (ps *PeeringService) ListPeers() []PeeringStatus {
+	ps.mu.Lock()
+	out := make([]PeeringStatus, 0, len(ps.peers))
+	for p, addrs := range ps.peers {
+		out = append(out, PeeringStatus{
+			ID:    p,
+			Addrs: append([]ma.Multiaddr(nil), addrs...),
+		})
+	}
+	ps.mu.Unlock()
+
+	for i := range out {
+		out[i].Connected = ps.host.Network().Connectedness(out[i].ID) == inet.Connected
+	}
+	sort.Sort(peeringStatuses(out))
+	return out
+}
+
+// Close stops the PeeringService. It does not close any connections.
+func (ps *PeeringService) Close() error {
+	ps.host.Network().StopNotify((*peeringNotifiee)(ps))
+	return ps.proc.Close()
+}
+
+// IsPeered returns whether p is in the peering set.
+func (ps *PeeringService) IsPeered(p peer.ID) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	_, ok := ps.peers[p]
+	return ok
+}
+
+// disconnected returns the peers of the peering set the node has no
+// connection to.
+func (ps *PeeringService) disconnected() []peer.ID {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var out []peer.ID
+	for p := range ps.peers {
+		if ps.host.Network().Connectedness(p) != inet.Connected {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// redialDelay returns how long to wait before redialing p, whose last
+// connection just dropped.
+func (ps *PeeringService) redialDelay(p peer.ID) time.Duration {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	d := ps.backoff[p]
+	if since, ok := ps.connected[p]; ok && time.Since(since) >= PeeringPeriod {
+		// the connection held, so this is not a peer that keeps dropping
+		d = 0
+	}
+	delete(ps.connected, p)
+
+	switch {
+	case d == 0:
+		d = peeringBackoffBase
+	case d < PeeringPeriod:
+		d *= 2
+		if d > PeeringPeriod {
+			d = PeeringPeriod
+		}
+	}
+	ps.backoff[p] = d
+	return d
+}
+
+func (ps *PeeringService) connect(p peer.ID) {
+	ps.mu.Lock()
+	addrs, ok := ps.peers[p]
+	ps.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), peeringConnTimeout)
+	defer cancel()
+
+	if err := ps.host.Connect(ctx, pstore.PeerInfo{ID: p, Addrs: addrs}); err != nil {
+		log.Debugf("peering: failed to connect to %s: %s", p, err)
+		return
+	}
+	log.Debugf("peering: connected to %s", p)
+}
+
+type peeringStatuses []PeeringStatus
+
+func (s peeringStatuses) Len() int           { return len(s) }
+func (s peeringStatuses) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s peeringStatuses) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func containsAddr(addrs []ma.Multiaddr, a ma.Multiaddr) bool {
+	for _, b := range addrs {
+		if a.Equal(b) {
+			return true
+		}
+	}
+	return false
+}
+
+type peeringNotifiee PeeringService
+
+func (nn *peeringNotifiee) service() *PeeringService {
+	return (*PeeringService)(nn)
+}
+
+func (nn *peeringNotifiee) Connected(n inet.Network, v inet.Conn) {
+	ps := nn.service()
+	p := v.RemotePeer()
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if _, ok := ps.peers[p]; !ok {
+		return
+	}
+	if _, ok := ps.connected[p]; !ok {
+		ps.connected[p] = time.Now()
+	}
+}
+
+func (nn *peeringNotifiee) Disconnected(n inet.Network, v inet.Conn) {
+	p := v.RemotePeer()
+	go func() {
+		ps := nn.service()
+		if n.Connectedness(p) == inet.Connected || !ps.IsPeered(p) {
+			return
+		}
+
+		select {
+		case <-ps.proc.Closing():
+			return
+		case <-time.After(ps.redialDelay(p)):
+		}
+
+		if n.Connectedness(p) != inet.Connected {
+			ps.connect(p)
+		}
+	}()
+}
+
+func (nn *peeringNotifiee) OpenedStream(n inet.Network, v inet.Stream) {}
+func (nn *peeringNotifiee) ClosedStream(n inet.Network, v inet.Stream) {}
+func (nn *peeringNotifiee) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (nn *peeringNotifiee) ListenClose(n inet.Network, a ma.Multiaddr) {}
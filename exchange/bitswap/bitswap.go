@@ -16,6 +16,7 @@ import (
 	bsmsg "github.com/ipfs/go-ipfs/exchange/bitswap/message"
 	bsnet "github.com/ipfs/go-ipfs/exchange/bitswap/network"
 	notifications "github.com/ipfs/go-ipfs/exchange/bitswap/notifications"
+	wantlist "github.com/ipfs/go-ipfs/exchange/bitswap/wantlist"
 	flags "github.com/ipfs/go-ipfs/flags"
 	"github.com/ipfs/go-ipfs/thirdparty/delay"
 
@@ -222,10 +223,22 @@ func (bs *Bitswap) WantlistForPeer(p peer.ID) []*cid.Cid {
 	return out
 }
 
+// WantlistEntriesForPeer returns the full wantlist entries the given peer
+// has sent us.
+func (bs *Bitswap) WantlistEntriesForPeer(p peer.ID) []*wantlist.Entry {
+	return bs.engine.WantlistForPeer(p)
+}
+
 func (bs *Bitswap) LedgerForPeer(p peer.ID) *decision.Receipt {
 	return bs.engine.LedgerForPeer(p)
 }
 
+// ResetLedger clears the accounting for p, returning the receipt from
+// before the reset. ok is false if there is no ledger for p.
+func (bs *Bitswap) ResetLedger(p peer.ID) (prev *decision.Receipt, ok bool) {
+	return bs.engine.ResetLedger(p)
+}
+
 // GetBlocks returns a channel where the caller may receive blocks that
 // correspond to the provided |keys|. Returns an error if BitSwap is unable to
 // begin this request within the deadline enforced by the context.
@@ -440,6 +453,11 @@ func (bs *Bitswap) GetWantlist() []*cid.Cid {
 	return out
 }
 
+// GetWantlistEntries returns the full entries of our own wantlist.
+func (bs *Bitswap) GetWantlistEntries() []*wantlist.Entry {
+	return bs.wm.wl.Entries()
+}
+
 func (bs *Bitswap) IsOnline() bool {
 	return true
 }
@@ -129,6 +129,32 @@ func (e *Engine) LedgerForPeer(p peer.ID) *Receipt {
 	}
 }
 
+// ResetLedger clears the byte counts and exchange count of the ledger for
+// p, and returns the receipt it had before. The partner's wantlist is kept.
+// ok is false if there is no ledger for p.
+func (e *Engine) ResetLedger(p peer.ID) (prev *Receipt, ok bool) {
+	e.lock.Lock()
+	ledger, ok := e.ledgerMap[p]
+	e.lock.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	ledger.lk.Lock()
+	defer ledger.lk.Unlock()
+
+	prev = &Receipt{
+		Peer:      ledger.Partner.String(),
+		Value:     ledger.Accounting.Value(),
+		Sent:      ledger.Accounting.BytesSent,
+		Recv:      ledger.Accounting.BytesRecv,
+		Exchanged: ledger.ExchangeCount(),
+	}
+	ledger.Accounting = debtRatio{}
+	ledger.exchangeCount = 0
+	return prev, true
+}
+
 func (e *Engine) taskWorker(ctx context.Context) {
 	defer close(e.outbox) // because taskWorker uses the channel exclusively
 	for {
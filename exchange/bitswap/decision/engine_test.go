@@ -66,6 +66,34 @@ func TestConsistentAccounting(t *testing.T) {
 	}
 }
 
+func TestResetLedger(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sender := newEngine(ctx, "Ernie")
+	receiver := newEngine(ctx, "Bert")
+
+	if _, ok := sender.Engine.ResetLedger(receiver.Peer); ok {
+		t.Fatal("reset a ledger that does not exist")
+	}
+
+	m := message.New(false)
+	m.AddBlock(blocks.NewBlock([]byte("this is a message")))
+	sender.Engine.MessageSent(receiver.Peer, m)
+
+	prev, ok := sender.Engine.ResetLedger(receiver.Peer)
+	if !ok {
+		t.Fatal("no ledger to reset")
+	}
+	if prev.Sent == 0 || prev.Exchanged != 1 || prev.Value == 0 {
+		t.Fatalf("reset returned the wrong receipt: %+v", prev)
+	}
+
+	r := sender.Engine.LedgerForPeer(receiver.Peer)
+	if r.Sent != 0 || r.Recv != 0 || r.Exchanged != 0 || r.Value != 0 {
+		t.Fatalf("ledger was not reset: %+v", r)
+	}
+}
+
 func TestPeerIsAddedToPeersWhenMessageReceivedOrSent(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -5,6 +5,7 @@ package wantlist
 import (
 	"sort"
 	"sync"
+	"time"
 
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 )
@@ -23,6 +24,9 @@ type Entry struct {
 	Cid      *cid.Cid
 	Priority int
 
+	// Added is the time the entry first became part of the wantlist.
+	Added time.Time
+
 	RefCnt int
 }
 
@@ -100,6 +104,7 @@ func (w *Wantlist) Add(c *cid.Cid, priority int) bool {
 	w.set[k] = &Entry{
 		Cid:      c,
 		Priority: priority,
+		Added:    time.Now(),
 		RefCnt:   1,
 	}
 
@@ -112,6 +117,9 @@ func (w *Wantlist) AddEntry(e *Entry) bool {
 		ex.RefCnt++
 		return false
 	}
+	if e.Added.IsZero() {
+		e.Added = time.Now()
+	}
 	w.set[k] = e
 	return true
 }
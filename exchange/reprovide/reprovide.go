@@ -2,7 +2,9 @@ package reprovide
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	blocks "github.com/ipfs/go-ipfs/blocks/blockstore"
@@ -13,12 +15,54 @@ import (
 
 var log = logging.Logger("reprovider")
 
+// ErrInProgress is returned by Trigger while another reprovide run is in
+// progress.
+var ErrInProgress = errors.New("a reprovide run is already in progress")
+
+// Stat describes a finished reprovide run.
+type Stat struct {
+	Keys     int           // number of keys announced
+	Duration time.Duration // time the run took
+}
+
+// StrategyAll is the reprovide strategy announcing every block in the
+// blockstore. It is the only strategy the Reprovider implements.
+const StrategyAll = "all"
+
+// Status describes the state of a Reprovider.
+type Status struct {
+	Strategy string
+	// Interval is the time between timed runs, or 0 if the Reprovider
+	// only runs when triggered.
+	Interval time.Duration
+	// Running is set while a run is in progress.
+	Running bool
+	// LastRun is when the last finished run started, or the zero time if
+	// no run finished yet. LastStat and LastError describe that run.
+	LastRun   time.Time
+	LastStat  Stat
+	LastError error
+	// NextRun is when the next timed run is due, or the zero time if none
+	// is scheduled.
+	NextRun time.Time
+}
+
 type Reprovider struct {
 	// The routing system to provide values through
 	rsys routing.ContentRouting
 
 	// The backing store for blocks to be provided
 	bstore blocks.Blockstore
+
+	// running is set while a reprovide run is in progress, the other
+	// fields describe past and scheduled runs
+	runLk    sync.Mutex
+	running  bool
+	interval time.Duration
+	lastRun  time.Time
+	lastStat Stat
+	lastErr  error
+	nextRun  time.Time
 }
 
 func NewReprovider(rsys routing.ContentRouting, bstore blocks.Blockstore) *Reprovider {
@@ -32,6 +76,9 @@ func (rp *Reprovider) ProvideEvery(ctx context.Context, tick time.Duration) {
 	// dont reprovide immediately.
 	// may have just started the daemon and shutting it down immediately.
 	// probability( up another minute | uptime ) increases with uptime.
+	rp.schedule(tick, time.Minute)
+	defer rp.schedule(0, 0)
+
 	after := time.After(time.Minute)
 	for {
 		select {
@@ -42,16 +89,82 @@ func (rp *Reprovider) ProvideEvery(ctx context.Context, tick time.Duration) {
 			if err != nil {
 				log.Debug(err)
 			}
+			rp.schedule(tick, tick)
 			after = time.After(tick)
 		}
 	}
 }
 
+// schedule records that timed runs happen every interval, and that the next
+// one starts after wait. A zero interval means no run is scheduled.
+func (rp *Reprovider) schedule(interval, wait time.Duration) {
+	rp.runLk.Lock()
+	defer rp.runLk.Unlock()
+
+	rp.interval = interval
+	if interval == 0 {
+		rp.nextRun = time.Time{}
+		return
+	}
+	rp.nextRun = time.Now().Add(wait)
+}
+
+// Status returns the state of the Reprovider.
+func (rp *Reprovider) Status() Status {
+	rp.runLk.Lock()
+	defer rp.runLk.Unlock()
+
+	return Status{
+		Strategy:  StrategyAll,
+		Interval:  rp.interval,
+		Running:   rp.running,
+		LastRun:   rp.lastRun,
+		LastStat:  rp.lastStat,
+		LastError: rp.lastErr,
+		NextRun:   rp.nextRun,
+	}
+}
+
+// Reprovide announces all keys in the blockstore, like Trigger.
 func (rp *Reprovider) Reprovide(ctx context.Context) error {
+	_, err := rp.Trigger(ctx)
+	return err
+}
+
+// Trigger announces all keys in the blockstore right away, and returns how
+// many keys were announced and how long it took. It fails with
+// ErrInProgress instead of starting a second run while another one, timed or
+// triggered, is in progress.
+func (rp *Reprovider) Trigger(ctx context.Context) (Stat, error) {
+	rp.runLk.Lock()
+	if rp.running {
+		rp.runLk.Unlock()
+		return Stat{}, ErrInProgress
+	}
+	rp.running = true
+	rp.runLk.Unlock()
+
+	start := time.Now()
+	n, err := rp.reprovide(ctx)
+	st := Stat{Keys: n, Duration: time.Since(start)}
+
+	rp.runLk.Lock()
+	rp.running = false
+	rp.lastRun = start
+	rp.lastStat = st
+	rp.lastErr = err
+	rp.runLk.Unlock()
+
+	return st, err
+}
+
+func (rp *Reprovider) reprovide(ctx context.Context) (int, error) {
 	keychan, err := rp.bstore.AllKeysChan(ctx)
 	if err != nil {
-		return fmt.Errorf("Failed to get key chan from blockstore: %s", err)
+		return 0, fmt.Errorf("Failed to get key chan from blockstore: %s", err)
 	}
+
+	count := 0
 	for c := range keychan {
 		op := func() error {
 			err := rp.rsys.Provide(ctx, c, true)
@@ -66,8 +179,9 @@ func (rp *Reprovider) Reprovide(ctx context.Context) error {
 		err := backoff.Retry(op, backoff.NewExponentialBackOff())
 		if err != nil {
 			log.Debugf("Providing failed after number of retries: %s", err)
-			return err
+			return count, err
 		}
+		count++
 	}
-	return nil
+	return count, nil
 }
@@ -11,6 +11,7 @@ import (
 	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
 	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 
 	. "github.com/ipfs/go-ipfs/exchange/reprovide"
 )
@@ -54,3 +55,76 @@ func TestReprovide(t *testing.T) {
 		t.Fatal("Somehow got the wrong peer back as a provider.")
 	}
 }
+
+// blockingRouting blocks every Provide call until release is closed.
+type blockingRouting struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRouting) Provide(ctx context.Context, c *cid.Cid, brdcst bool) error {
+	select {
+	case r.started <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return nil
+}
+
+func (r *blockingRouting) FindProvidersAsync(ctx context.Context, c *cid.Cid, max int) <-chan pstore.PeerInfo {
+	out := make(chan pstore.PeerInfo)
+	close(out)
+	return out
+}
+
+func TestTriggerInProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bstore := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	bstore.Put(blocks.NewBlock([]byte("this is a test")))
+
+	rsys := &blockingRouting{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	reprov := NewReprovider(rsys, bstore)
+
+	done := make(chan Stat)
+	go func() {
+		st, err := reprov.Trigger(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- st
+	}()
+
+	<-rsys.started
+	if _, err := reprov.Trigger(ctx); err != ErrInProgress {
+		t.Fatalf("expected ErrInProgress, got %v", err)
+	}
+	if !reprov.Status().Running {
+		t.Fatal("expected the status to show a run in progress")
+	}
+
+	close(rsys.release)
+	if st := <-done; st.Keys != 1 {
+		t.Fatalf("expected 1 key to be announced, got %d", st.Keys)
+	}
+
+	st, err := reprov.Trigger(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Keys != 1 {
+		t.Fatalf("expected 1 key to be announced, got %d", st.Keys)
+	}
+
+	status := reprov.Status()
+	if status.Running || status.LastRun.IsZero() || status.LastStat != st || status.LastError != nil {
+		t.Fatalf("unexpected status after a run: %+v", status)
+	}
+	if status.Strategy != StrategyAll || status.Interval != 0 || !status.NextRun.IsZero() {
+		t.Fatalf("expected no timed runs, got %+v", status)
+	}
+}
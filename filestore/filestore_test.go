@@ -5,6 +5,8 @@ import (
 	"context"
 	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ipfs/go-ipfs/blocks/blockstore"
@@ -103,6 +105,25 @@ func TestBasicFilestore(t *testing.T) {
 	}
 }
 
+func TestGetSizeDoesntReadFile(t *testing.T) {
+	dir, fs := newTestFilestore(t)
+
+	fname, cids := randomFileAdd(t, fs, dir, 100)
+	if err := os.Remove(fname); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cids {
+		size, err := fs.GetSize(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != 10 {
+			t.Fatalf("expected size 10, got %d", size)
+		}
+	}
+}
+
 func randomFileAdd(t *testing.T, fs *Filestore, dir string, size int) (string, []*cid.Cid) {
 	buf := make([]byte, size)
 	rand.Read(buf)
@@ -162,3 +183,66 @@ func TestDeletes(t *testing.T) {
 		}
 	}
 }
+
+func TestRepairMovedFile(t *testing.T) {
+	dir, fs := newTestFilestore(t)
+	fname, cids := randomFileAdd(t, fs, dir, 100)
+	lost, lostCids := randomFileAdd(t, fs, dir, 50)
+
+	if err := os.Mkdir(filepath.Join(dir, "moved"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	newName := filepath.Join(dir, "moved", "renamed")
+	if err := os.Rename(fname, newName); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(lost); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Get(cids[0]); err == nil {
+		t.Fatal("expected error reading from moved file")
+	}
+
+	next, err := RepairAll(fs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := make(map[string]Status)
+	for r := next(); r != nil; r = next() {
+		statuses[r.Key.KeyString()] = r.Status
+		if r.Status == StatusRepaired && r.FilePath != "moved/renamed" {
+			t.Fatalf("expected new path moved/renamed, got %s", r.FilePath)
+		}
+	}
+
+	for _, c := range cids {
+		if statuses[c.KeyString()] != StatusRepaired {
+			t.Fatalf("expected %s to be repaired, got %s", c, statuses[c.KeyString()])
+		}
+		if _, err := fs.Get(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range lostCids {
+		if statuses[c.KeyString()] != StatusUnrecoverable {
+			t.Fatalf("expected %s to be unrecoverable, got %s", c, statuses[c.KeyString()])
+		}
+	}
+}
+
+func TestRepairSearchRootOutsideRoot(t *testing.T) {
+	dir, fs := newTestFilestore(t)
+
+	for _, root := range []string{dir + "-other", "..", "../" + filepath.Base(dir) + "-other"} {
+		if _, err := RepairAll(fs, root); err == nil {
+			t.Fatalf("expected error for search root %s outside %s", root, dir)
+		}
+	}
+
+	if _, err := RepairAll(fs, "sub/../sub"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -121,6 +121,17 @@ func (f *FileManager) Get(c *cid.Cid) (blocks.Block, error) {
 	return blocks.NewBlockWithCid(out, c)
 }
 
+// GetSize returns the size of the block with the given Cid, as recorded
+// in its reference, without reading the referenced file.
+func (f *FileManager) GetSize(c *cid.Cid) (int, error) {
+	dobj, err := f.getDataObj(c)
+	if err != nil {
+		return -1, err
+	}
+
+	return int(dobj.GetSize_()), nil
+}
+
 func (f *FileManager) getDataObj(c *cid.Cid) (*pb.DataObj, error) {
 	o, err := f.ds.Get(dshelp.CidToDsKey(c))
 	switch err {
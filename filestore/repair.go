@@ -0,0 +1,183 @@
+package filestore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/ipfs/go-ipfs/filestore/pb"
+	dshelp "github.com/ipfs/go-ipfs/thirdparty/ds-help"
+
+	proto "gx/ipfs/QmT6n4mspWYEya864BhCUJEgyxiRfmiSY9ruQwTUNpRKaM/protobuf/proto"
+)
+
+// RepairAll verifies all blocks in the Filestore's FileManager, like
+// VerifyAll with file ordering, and tries to relocate the backing files of
+// blocks whose file went missing or changed. Candidate files are searched
+// for below searchRoot, which must be inside the filestore root. A relative
+// searchRoot is taken relative to the filestore root.
+//
+// A backing file is only considered relocated if every broken block that
+// referenced it can be read back from the candidate file at its original
+// offset. The references of relocated blocks are updated and reported with
+// StatusRepaired; blocks whose file could not be found are reported with
+// StatusUnrecoverable.
+func RepairAll(fs *Filestore, searchRoot string) (func() *ListRes, error) {
+	f := fs.fm
+
+	if !filepath.IsAbs(searchRoot) {
+		searchRoot = filepath.Join(f.root, searchRoot)
+	}
+	searchRoot = filepath.Clean(searchRoot)
+	if rel, err := filepath.Rel(f.root, searchRoot); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("search root %s is outside the filestore root (%s)", searchRoot, f.root)
+	}
+
+	next, err := listAllFileOrder(fs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*ListRes
+	broken := make(map[string][]*ListRes)
+	for r := next(); r != nil; r = next() {
+		results = append(results, r)
+		if r.Status == StatusFileNotFound || r.Status == StatusFileChanged {
+			broken[r.FilePath] = append(broken[r.FilePath], r)
+		}
+	}
+
+	if len(broken) > 0 {
+		candidates, err := findCandidates(searchRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		for path, rs := range broken {
+			newPath := f.relocate(path, rs, candidates)
+			for _, r := range rs {
+				if newPath == "" {
+					r.Status = StatusUnrecoverable
+					continue
+				}
+
+				if err := f.putDataObj(r, newPath); err != nil {
+					r.Status = StatusOtherError
+					r.ErrorMsg = err.Error()
+					continue
+				}
+				r.Status = StatusRepaired
+				r.ErrorMsg = ""
+				r.FilePath = newPath
+			}
+		}
+	}
+
+	i := 0
+	return func() *ListRes {
+		if i >= len(results) {
+			return nil
+		}
+		i++
+		return results[i-1]
+	}, nil
+}
+
+type candidate struct {
+	path string
+	size uint64
+}
+
+// findCandidates lists all regular files below root.
+func findCandidates(root string) ([]candidate, error) {
+	var out []candidate
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			out = append(out, candidate{p, uint64(fi.Size())})
+		}
+		return nil
+	})
+	return out, err
+}
+
+// relocate returns the new path, relative to the filestore root, of the
+// file that used to live at path, or "" if none of the candidates holds the
+// data of all the given blocks. Candidates with the same base name are
+// tried first.
+func (f *FileManager) relocate(path string, rs []*ListRes, candidates []candidate) string {
+	var need uint64
+	for _, r := range rs {
+		if end := r.Offset + r.Size; end > need {
+			need = end
+		}
+	}
+
+	base := filepath.Base(filepath.FromSlash(path))
+	var sorted []candidate
+	for _, c := range candidates {
+		if filepath.Base(c.path) == base {
+			sorted = append(sorted, c)
+		}
+	}
+	for _, c := range candidates {
+		if filepath.Base(c.path) != base {
+			sorted = append(sorted, c)
+		}
+	}
+
+	for _, c := range sorted {
+		if c.size < need {
+			continue
+		}
+
+		rel, err := filepath.Rel(f.root, c.path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == path {
+			continue
+		}
+
+		if f.matches(rel, rs) {
+			return rel
+		}
+	}
+	return ""
+}
+
+// matches reports whether every block in rs can be read from the file at
+// rel using its recorded offset and size.
+func (f *FileManager) matches(rel string, rs []*ListRes) bool {
+	for _, r := range rs {
+		dobj := &pb.DataObj{
+			FilePath: proto.String(rel),
+			Offset:   proto.Uint64(r.Offset),
+			Size_:    proto.Uint64(r.Size),
+		}
+		if _, err := f.readDataObj(r.Key, dobj); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// putDataObj points the reference for r at the file at rel.
+func (f *FileManager) putDataObj(r *ListRes, rel string) error {
+	dobj := pb.DataObj{
+		FilePath: proto.String(rel),
+		Offset:   proto.Uint64(r.Offset),
+		Size_:    proto.Uint64(r.Size),
+	}
+
+	data, err := proto.Marshal(&dobj)
+	if err != nil {
+		return err
+	}
+
+	return f.ds.Put(dshelp.CidToDsKey(r.Key), data)
+}
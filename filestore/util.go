@@ -2,7 +2,9 @@ package filestore
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/ipfs/go-ipfs/blocks/blockstore"
 	pb "github.com/ipfs/go-ipfs/filestore/pb"
@@ -19,12 +21,14 @@ type Status int32
 
 // These are the supported Status codes.
 const (
-	StatusOk           Status = 0
-	StatusFileError    Status = 10 // Backing File Error
-	StatusFileNotFound Status = 11 // Backing File Not Found
-	StatusFileChanged  Status = 12 // Contents of the file changed
-	StatusOtherError   Status = 20 // Internal Error, likely corrupt entry
-	StatusKeyNotFound  Status = 30
+	StatusOk            Status = 0
+	StatusRepaired      Status = 1  // Backing file was found at a new location
+	StatusFileError     Status = 10 // Backing File Error
+	StatusFileNotFound  Status = 11 // Backing File Not Found
+	StatusFileChanged   Status = 12 // Contents of the file changed
+	StatusUnrecoverable Status = 13 // Backing file could not be relocated
+	StatusOtherError    Status = 20 // Internal Error, likely corrupt entry
+	StatusKeyNotFound   Status = 30
 )
 
 // String provides a human-readable representation for Status codes.
@@ -32,12 +36,16 @@ func (s Status) String() string {
 	switch s {
 	case StatusOk:
 		return "ok"
+	case StatusRepaired:
+		return "repaired"
 	case StatusFileError:
 		return "error"
 	case StatusFileNotFound:
 		return "no-file"
 	case StatusFileChanged:
 		return "changed"
+	case StatusUnrecoverable:
+		return "unrecoverable"
 	case StatusOtherError:
 		return "ERROR"
 	case StatusKeyNotFound:
@@ -116,6 +124,40 @@ func VerifyAll(fs *Filestore, fileOrder bool) (func() *ListRes, error) {
 	return listAll(fs, true)
 }
 
+// PathPrefixFilter returns a function which reports whether the backing
+// file of a ListRes has an absolute path starting with prefix. A relative
+// prefix is taken relative to the filestore root. Entries without a backing
+// file never match.
+func PathPrefixFilter(fs *Filestore, prefix string) func(*ListRes) bool {
+	if !filepath.IsAbs(prefix) {
+		dir := strings.HasSuffix(prefix, "/")
+		prefix = filepath.Join(fs.fm.root, prefix)
+		if dir {
+			prefix += string(filepath.Separator)
+		}
+	}
+	return func(r *ListRes) bool {
+		if r.FilePath == "" {
+			return false
+		}
+		abspath := filepath.Join(fs.fm.root, filepath.FromSlash(r.FilePath))
+		return strings.HasPrefix(abspath, prefix)
+	}
+}
+
+// Filter wraps the given ListRes iterator so that it skips the entries for
+// which keep returns false.
+func Filter(next func() *ListRes, keep func(*ListRes) bool) func() *ListRes {
+	return func() *ListRes {
+		for {
+			r := next()
+			if r == nil || keep(r) {
+				return r
+			}
+		}
+	}
+}
+
 func list(fs *Filestore, verify bool, key *cid.Cid) *ListRes {
 	dobj, err := fs.fm.getDataObj(key)
 	if err != nil {
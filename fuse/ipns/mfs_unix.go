@@ -0,0 +1,68 @@
+// +build linux darwin freebsd netbsd openbsd
+// +build !nofuse
+
+package ipns
+
+import (
+	"errors"
+
+	core "github.com/ipfs/go-ipfs/core"
+	mount "github.com/ipfs/go-ipfs/fuse/mount"
+	mfs "github.com/ipfs/go-ipfs/mfs"
+
+	fs "gx/ipfs/QmaFNtBAXX4nVMQWbUqNysXyhevUj1k4B1y5uS45LC7Vw9/fuse/fs"
+)
+
+// MfsFileSystem is a read-write Fuse Filesystem that exposes the node's
+// mutable files (MFS) root. It goes through the same mfs.Root as the
+// 'ipfs files' commands, so changes made through either are visible in the
+// other.
+//
+// EXPERIMENTAL: this may change or go away.
+type MfsFileSystem struct {
+	Ipfs *core.IpfsNode
+	root *Directory
+}
+
+// NewMfsFileSystem constructs a filesystem over the MFS root of the given
+// node.
+func NewMfsFileSystem(ipfs *core.IpfsNode) (*MfsFileSystem, error) {
+	if ipfs.FilesRoot == nil {
+		return nil, errors.New("node has no mfs root")
+	}
+
+	dir, ok := ipfs.FilesRoot.GetValue().(*mfs.Directory)
+	if !ok {
+		return nil, errors.New("mfs root is not a directory")
+	}
+
+	return &MfsFileSystem{Ipfs: ipfs, root: &Directory{dir: dir}}, nil
+}
+
+// Root returns the MFS root directory.
+func (f *MfsFileSystem) Root() (fs.Node, error) {
+	return f.root, nil
+}
+
+// Destroy flushes pending changes to the MFS root. The root itself is
+// owned by the node and stays open.
+func (f *MfsFileSystem) Destroy() {
+	if err := f.root.dir.Flush(); err != nil {
+		log.Errorf("Error flushing mfs root: %s", err)
+	}
+}
+
+// MountMfs mounts the MFS root of the node at the given location.
+func MountMfs(ipfs *core.IpfsNode, mountpoint string) (mount.Mount, error) {
+	cfg, err := ipfs.Repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err := NewMfsFileSystem(ipfs)
+	if err != nil {
+		return nil, err
+	}
+
+	return mount.NewMount(ipfs.Process(), fsys, mountpoint, cfg.Mounts.FuseAllowOther)
+}
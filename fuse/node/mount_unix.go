@@ -47,21 +47,30 @@ func Mount(node *core.IpfsNode, fsdir, nsdir string) error {
 	return doMount(node, fsdir, nsdir)
 }
 
-func doMount(node *core.IpfsNode, fsdir, nsdir string) error {
-	fmtFuseErr := func(err error, mountpoint string) error {
-		s := err.Error()
-		if strings.Contains(s, fuseNoDirectory) {
-			s = strings.Replace(s, `fusermount: "fusermount:`, "", -1)
-			s = strings.Replace(s, `\n", exit status 1`, "", -1)
-			return errors.New(s)
-		}
-		if s == fuseExitStatus1 {
-			s = fmt.Sprintf("fuse failed to access mountpoint %s", mountpoint)
-			return errors.New(s)
-		}
+// MountMfs mounts the MFS root of the node read-write at mfsdir.
+//
+// EXPERIMENTAL: writes go through the node's MFS root, like the 'ipfs files'
+// commands, but this has seen far less use than the /ipfs and /ipns mounts.
+func MountMfs(node *core.IpfsNode, mfsdir string) error {
+	if node.Mounts.Mfs != nil && node.Mounts.Mfs.IsActive() {
+		node.Mounts.Mfs.Unmount()
+	}
+
+	if err := platformFuseChecks(node); err != nil {
 		return err
 	}
 
+	m, err := ipns.MountMfs(node, mfsdir)
+	if err != nil {
+		log.Errorf("error mounting: %s", err)
+		return fmtFuseErr(err, mfsdir)
+	}
+
+	node.Mounts.Mfs = m
+	return nil
+}
+
+func doMount(node *core.IpfsNode, fsdir, nsdir string) error {
 	// this sync stuff is so that both can be mounted simultaneously.
 	var fsmount mount.Mount
 	var nsmount mount.Mount
@@ -110,3 +119,17 @@ func doMount(node *core.IpfsNode, fsdir, nsdir string) error {
 	node.Mounts.Ipns = nsmount
 	return nil
 }
+
+func fmtFuseErr(err error, mountpoint string) error {
+	s := err.Error()
+	if strings.Contains(s, fuseNoDirectory) {
+		s = strings.Replace(s, `fusermount: "fusermount:`, "", -1)
+		s = strings.Replace(s, `\n", exit status 1`, "", -1)
+		return errors.New(s)
+	}
+	if s == fuseExitStatus1 {
+		s = fmt.Sprintf("fuse failed to access mountpoint %s", mountpoint)
+		return errors.New(s)
+	}
+	return err
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"errors"
+
 	"github.com/ipfs/go-ipfs/core"
 )
 
@@ -9,3 +11,7 @@ func Mount(node *core.IpfsNode, fsdir, nsdir string) error {
 	// currently a no-op, but we don't want to return an error
 	return nil
 }
+
+func MountMfs(node *core.IpfsNode, mfsdir string) error {
+	return errors.New("mounting MFS isn't supported on Windows")
+}
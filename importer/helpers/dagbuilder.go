@@ -26,6 +26,26 @@ type DagBuilderHelper struct {
 	fullPath  string
 	stat      os.FileInfo
 	prefix    *cid.Prefix
+
+	// set for splitters that are a UniformSplitter
+	nextUniform bool
+	uniformLeaf *UnixfsNode
+	subtrees    map[int]*dag.ProtoNode
+}
+
+// UniformSplitter is a chunk.Splitter that yields the same chunk over and
+// over, such as the zeros of a gap in a sparse file, possibly followed by a
+// single different chunk. The leaves built from those chunks, and the full
+// subtrees above them, are identical, so they are only built once.
+type UniformSplitter interface {
+	chunk.Splitter
+
+	// UniformChunks returns how many of the identical chunks are left.
+	UniformChunks() uint64
+
+	// SkipChunks drops the next n identical chunks. n must not be more
+	// than UniformChunks returns.
+	SkipChunks(n uint64)
 }
 
 type DagBuilderParams struct {
@@ -74,6 +94,9 @@ func (db *DagBuilderHelper) prepareNext() {
 		return
 	}
 
+	if us, ok := db.spl.(UniformSplitter); ok {
+		db.nextUniform = us.UniformChunks() > 0
+	}
 	db.nextData, db.recvdErr = db.spl.NextBytes()
 	if db.recvdErr == io.EOF {
 		db.recvdErr = nil
@@ -105,6 +128,51 @@ func (db *DagBuilderHelper) Next() ([]byte, error) {
 	}
 }
 
+// UniformChunks returns how many of the chunks left to build from are the
+// same, or 0 if the splitter isn't a UniformSplitter.
+func (db *DagBuilderHelper) UniformChunks() uint64 {
+	us, ok := db.spl.(UniformSplitter)
+	if !ok {
+		return 0
+	}
+
+	db.prepareNext() // idempotent
+	n := us.UniformChunks()
+	if db.nextData != nil && db.nextUniform {
+		n++
+	}
+	return n
+}
+
+// SkipChunks drops the next n chunks, which must all be among those counted
+// by UniformChunks.
+func (db *DagBuilderHelper) SkipChunks(n uint64) {
+	db.prepareNext() // idempotent
+	if n > 0 && db.nextData != nil {
+		db.nextData = nil
+		n--
+	}
+	if n > 0 {
+		db.spl.(UniformSplitter).SkipChunks(n)
+	}
+}
+
+// CachedSubtree returns the full subtree of the given depth that was built
+// from uniform chunks before, if any.
+func (db *DagBuilderHelper) CachedSubtree(depth int) (*dag.ProtoNode, bool) {
+	nd, ok := db.subtrees[depth]
+	return nd, ok
+}
+
+// CacheSubtree remembers nd as the full subtree of the given depth built from
+// uniform chunks, so that layouts can link it again instead of rebuilding it.
+func (db *DagBuilderHelper) CacheSubtree(depth int, nd *dag.ProtoNode) {
+	if db.subtrees == nil {
+		db.subtrees = make(map[int]*dag.ProtoNode)
+	}
+	db.subtrees[depth] = nd
+}
+
 // GetDagServ returns the dagservice object this Helper is using
 func (db *DagBuilderHelper) GetDagServ() dag.DAGService {
 	return db.dserv
@@ -160,6 +228,33 @@ func (db *DagBuilderHelper) GetNextDataNode() (*UnixfsNode, error) {
 		return nil, nil
 	}
 
+	// nextUniform still describes the chunk Next just returned
+	if !db.nextUniform {
+		return db.newDataNode(data)
+	}
+	if db.uniformLeaf == nil {
+		leaf, err := db.newDataNode(data)
+		if err != nil {
+			return nil, err
+		}
+		if !leaf.raw {
+			// keep the encoded leaf, so it isn't hashed again each time
+			// it is linked
+			dn, err := leaf.GetDagNode()
+			if err != nil {
+				return nil, err
+			}
+			leaf, err = NewUnixfsNodeFromDag(dn.(*dag.ProtoNode))
+			if err != nil {
+				return nil, err
+			}
+		}
+		db.uniformLeaf = leaf
+	}
+	return db.uniformLeaf, nil
+}
+
+func (db *DagBuilderHelper) newDataNode(data []byte) (*UnixfsNode, error) {
 	if len(data) > BlockSizeLimit {
 		return nil, ErrSizeLimitExceeded
 	}
@@ -45,6 +45,9 @@ type UnixfsNode struct {
 	node    *dag.ProtoNode
 	ufmt    *ft.FSNode
 	posInfo *pi.PosInfo
+
+	// clean is set while node still holds the encoding of ufmt
+	clean bool
 }
 
 // NewUnixfsNodeFromDag reconstructs a Unixfs node from a given dag node
@@ -55,8 +58,9 @@ func NewUnixfsNodeFromDag(nd *dag.ProtoNode) (*UnixfsNode, error) {
 	}
 
 	return &UnixfsNode{
-		node: nd,
-		ufmt: mb,
+		node:  nd,
+		ufmt:  mb,
+		clean: true,
 	}, nil
 }
 
@@ -70,6 +74,7 @@ func (n *UnixfsNode) NumChildren() int {
 }
 
 func (n *UnixfsNode) Set(other *UnixfsNode) {
+	n.clean = false
 	n.node = other.node
 	n.raw = other.raw
 	n.rawnode = other.rawnode
@@ -96,6 +101,7 @@ func (n *UnixfsNode) GetChild(ctx context.Context, i int, ds dag.DAGService) (*U
 // the passed in DagBuilderHelper is used to store the child node an
 // pin it locally so it doesnt get lost
 func (n *UnixfsNode) AddChild(child *UnixfsNode, db *DagBuilderHelper) error {
+	n.clean = false
 	n.ufmt.AddBlockSize(child.FileSize())
 
 	childnode, err := child.GetDagNode()
@@ -117,11 +123,13 @@ func (n *UnixfsNode) AddChild(child *UnixfsNode, db *DagBuilderHelper) error {
 
 // Removes the child node at the given index
 func (n *UnixfsNode) RemoveChild(index int, dbh *DagBuilderHelper) {
+	n.clean = false
 	n.ufmt.RemoveBlockSize(index)
 	n.node.SetLinks(append(n.node.Links()[:index], n.node.Links()[index+1:]...))
 }
 
 func (n *UnixfsNode) SetData(data []byte) {
+	n.clean = false
 	n.ufmt.Data = data
 }
 
@@ -160,6 +168,9 @@ func (n *UnixfsNode) getBaseDagNode() (node.Node, error) {
 	if n.raw {
 		return n.rawnode, nil
 	}
+	if n.clean {
+		return n.node, nil
+	}
 
 	data, err := n.ufmt.GetBytes()
 	if err != nil {
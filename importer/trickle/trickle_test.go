@@ -559,3 +559,87 @@ func TestAppendSingleBytesToEmpty(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// uniformSplitter yields n copies of chunk, followed by tail if it isn't
+// empty, and counts the chunks it was asked for.
+type uniformSplitter struct {
+	chunk []byte
+	n     uint64
+	tail  []byte
+	read  int
+}
+
+func (s *uniformSplitter) NextBytes() ([]byte, error) {
+	if s.n > 0 {
+		s.n--
+		s.read++
+		return s.chunk, nil
+	}
+	if len(s.tail) > 0 {
+		tail := s.tail
+		s.tail = nil
+		s.read++
+		return tail, nil
+	}
+	return nil, io.EOF
+}
+
+func (s *uniformSplitter) Reader() io.Reader     { return nil }
+func (s *uniformSplitter) UniformChunks() uint64 { return s.n }
+func (s *uniformSplitter) SkipChunks(n uint64)   { s.n -= n }
+
+func TestAppendUniform(t *testing.T) {
+	ds := mdtest.Mock()
+	ctx := context.Background()
+
+	dbp := &h.DagBuilderParams{
+		Dagserv:  ds,
+		Maxlinks: 4,
+	}
+
+	head := make([]byte, 3000)
+	u.NewTimeSeededRand().Read(head)
+	headnd, err := TrickleLayout(dbp.New(chunk.NewSizeSplitter(bytes.NewReader(head), 500)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nd := headnd.(*merkledag.ProtoNode)
+
+	nchunks := uint64(20000)
+	data := make([]byte, nchunks*10+3)
+
+	spl := &uniformSplitter{chunk: make([]byte, 10), n: nchunks, tail: make([]byte, 3)}
+	uniform, err := TrickleAppend(ctx, nd.Copy(), dbp.New(spl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spl.read > 1000 {
+		t.Fatalf("expected repeated subtrees to be reused, but %d chunks were read", spl.read)
+	}
+
+	plain, err := TrickleAppend(ctx, nd.Copy(), dbp.New(chunk.NewSizeSplitter(bytes.NewReader(data), 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !uniform.Cid().Equals(plain.Cid()) {
+		t.Fatal("appending uniform chunks gave a different dag")
+	}
+
+	err = VerifyTrickleDagStructure(uniform, ds, dbp.Maxlinks, layerRepeat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fread, err := uio.NewDagReader(ctx, uniform, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(fread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := arrComp(out, append(head, data...)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 
 	h "github.com/ipfs/go-ipfs/importer/helpers"
 	dag "github.com/ipfs/go-ipfs/merkledag"
@@ -24,8 +25,8 @@ func TrickleLayout(db *h.DagBuilderHelper) (node.Node, error) {
 	}
 	for level := 1; !db.Done(); level++ {
 		for i := 0; i < layerRepeat && !db.Done(); i++ {
-			next := db.NewUnixfsNode()
-			if err := fillTrickleRec(db, next, level); err != nil {
+			next, err := newTrickleSubtree(db, level)
+			if err != nil {
 				return nil, err
 			}
 			if err := root.AddChild(next, db); err != nil {
@@ -54,8 +55,8 @@ func fillTrickleRec(db *h.DagBuilderHelper, node *h.UnixfsNode, depth int) error
 
 	for i := 1; i < depth && !db.Done(); i++ {
 		for j := 0; j < layerRepeat && !db.Done(); j++ {
-			next := db.NewUnixfsNode()
-			if err := fillTrickleRec(db, next, i); err != nil {
+			next, err := newTrickleSubtree(db, i)
+			if err != nil {
 				return err
 			}
 
@@ -67,6 +68,55 @@ func fillTrickleRec(db *h.DagBuilderHelper, node *h.UnixfsNode, depth int) error
 	return nil
 }
 
+// newTrickleSubtree builds a trickle subtree of the given depth from the data
+// in db. Full subtrees built from the chunks of a UniformSplitter are all the
+// same, so each depth is only built once and then linked in again.
+func newTrickleSubtree(db *h.DagBuilderHelper, depth int) (*h.UnixfsNode, error) {
+	leaves := trickleLeaves(db.Maxlinks(), depth)
+	if db.UniformChunks() < leaves {
+		next := db.NewUnixfsNode()
+		if err := fillTrickleRec(db, next, depth); err != nil {
+			return nil, err
+		}
+		return next, nil
+	}
+
+	if nd, ok := db.CachedSubtree(depth); ok {
+		db.SkipChunks(leaves)
+		return h.NewUnixfsNodeFromDag(nd.Copy().(*dag.ProtoNode))
+	}
+
+	next := db.NewUnixfsNode()
+	if err := fillTrickleRec(db, next, depth); err != nil {
+		return nil, err
+	}
+	nd, err := next.GetDagNode()
+	if err != nil {
+		return nil, err
+	}
+	pbnd, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		return nil, dag.ErrNotProtobuf
+	}
+	db.CacheSubtree(depth, pbnd)
+	return next, nil
+}
+
+// trickleLeaves returns the number of leaves in a full trickle subtree of the
+// given depth, or math.MaxUint64 if there are more.
+func trickleLeaves(maxlinks, depth int) uint64 {
+	leaves := uint64(maxlinks)
+	var below uint64 // leaves of the subtrees of lower depths
+	for i := 1; i < depth; i++ {
+		below += leaves
+		if below > (math.MaxUint64-uint64(maxlinks))/layerRepeat {
+			return math.MaxUint64
+		}
+		leaves = uint64(maxlinks) + layerRepeat*below
+	}
+	return leaves
+}
+
 // TrickleAppend appends the data in `db` to the dag, using the Trickledag format
 func TrickleAppend(ctx context.Context, basen node.Node, db *h.DagBuilderHelper) (out node.Node, err_out error) {
 	base, ok := basen.(*dag.ProtoNode)
@@ -117,8 +167,7 @@ func TrickleAppend(ctx context.Context, basen node.Node, db *h.DagBuilderHelper)
 	// Now, continue filling out tree like normal
 	for i := n; !db.Done(); i++ {
 		for j := 0; j < layerRepeat && !db.Done(); j++ {
-			next := db.NewUnixfsNode()
-			err := fillTrickleRec(db, next, i)
+			next, err := newTrickleSubtree(db, i)
 			if err != nil {
 				return nil, err
 			}
@@ -162,8 +211,7 @@ func appendFillLastChild(ctx context.Context, ufsn *h.UnixfsNode, depth int, lay
 	// Partially filled depth layer
 	if layerFill != 0 {
 		for ; layerFill < layerRepeat && !db.Done(); layerFill++ {
-			next := db.NewUnixfsNode()
-			err := fillTrickleRec(db, next, depth)
+			next, err := newTrickleSubtree(db, depth)
 			if err != nil {
 				return err
 			}
@@ -211,8 +259,8 @@ func trickleAppendRec(ctx context.Context, ufsn *h.UnixfsNode, db *h.DagBuilderH
 	// Now, continue filling out tree like normal
 	for i := n; i < depth && !db.Done(); i++ {
 		for j := 0; j < layerRepeat && !db.Done(); j++ {
-			next := db.NewUnixfsNode()
-			if err := fillTrickleRec(db, next, i); err != nil {
+			next, err := newTrickleSubtree(db, i)
+			if err != nil {
 				return nil, err
 			}
 
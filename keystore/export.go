@@ -0,0 +1,157 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+)
+
+// ExportPEMType is the PEM block type of keys encrypted by EncryptKey.
+const ExportPEMType = "IPFS ENCRYPTED PRIVATE KEY"
+
+// ExportIterations is the number of PBKDF2 iterations EncryptKey uses to
+// derive the encryption key from the password.
+const ExportIterations = 100000
+
+// maxExportIterations bounds the iteration count DecryptKey accepts, so that
+// a crafted file can't keep it busy for ages.
+const maxExportIterations = 10000000
+
+const exportKDF = "PBKDF2-SHA256"
+
+var ErrBadPassword = errors.New("incorrect password for exported key")
+var ErrNotExportedKey = errors.New("file does not contain an exported ipfs key")
+
+// EncryptKey encodes sk as a PEM block, encrypted and authenticated with
+// AES-256-GCM under a key derived from password with PBKDF2-HMAC-SHA256.
+// The salt, nonce and iteration count are stored in the block headers.
+func EncryptKey(sk ci.PrivKey, password []byte) ([]byte, error) {
+	skb, err := sk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := exportCipher(password, salt, ExportIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	block := &pem.Block{
+		Type: ExportPEMType,
+		Headers: map[string]string{
+			"KDF":        exportKDF,
+			"Iterations": strconv.Itoa(ExportIterations),
+			"Salt":       hex.EncodeToString(salt),
+			"Nonce":      hex.EncodeToString(nonce),
+		},
+		Bytes: aead.Seal(nil, nonce, skb, []byte(ExportPEMType)),
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// DecryptKey decodes a key written by EncryptKey. It returns ErrBadPassword
+// if the key can't be decrypted with password, which is also the case if
+// the data was tampered with.
+func DecryptKey(data, password []byte) (ci.PrivKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != ExportPEMType {
+		return nil, ErrNotExportedKey
+	}
+
+	if kdf := block.Headers["KDF"]; kdf != exportKDF {
+		return nil, fmt.Errorf("unsupported key derivation function %q", kdf)
+	}
+
+	iter, err := strconv.Atoi(block.Headers["Iterations"])
+	if err != nil || iter < 1 || iter > maxExportIterations {
+		return nil, fmt.Errorf("invalid iteration count %q", block.Headers["Iterations"])
+	}
+
+	salt, err := hex.DecodeString(block.Headers["Salt"])
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("invalid salt in exported key")
+	}
+
+	nonce, err := hex.DecodeString(block.Headers["Nonce"])
+	if err != nil {
+		return nil, errors.New("invalid nonce in exported key")
+	}
+
+	aead, err := exportCipher(password, salt, iter)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce in exported key")
+	}
+
+	skb, err := aead.Open(nil, nonce, block.Bytes, []byte(ExportPEMType))
+	if err != nil {
+		return nil, ErrBadPassword
+	}
+
+	return ci.UnmarshalPrivateKey(skb)
+}
+
+func exportCipher(password, salt []byte, iter int) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(pbkdf2(sha256.New, password, salt, iter, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
+
+// pbkdf2 derives a key of keyLen bytes from password and salt as described
+// in RFC 2898, using HMAC with h as the pseudorandom function.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		buf[0] = byte(block >> 24)
+		buf[1] = byte(block >> 16)
+		buf[2] = byte(block >> 8)
+		buf[3] = byte(block)
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
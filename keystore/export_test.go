@@ -0,0 +1,63 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// test vectors from RFC 7914, section 11
+	cases := []struct {
+		password, salt string
+		iter           int
+		out            string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+
+	for _, c := range cases {
+		out := hex.EncodeToString(pbkdf2(sha256.New, []byte(c.password), []byte(c.salt), c.iter, 64))
+		if out != c.out {
+			t.Fatalf("pbkdf2(%q, %q, %d) = %s, expected %s", c.password, c.salt, c.iter, out, c.out)
+		}
+	}
+}
+
+func TestEncryptKey(t *testing.T) {
+	sk := privKeyOrFatal(t)
+
+	data, err := EncryptKey(sk, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := DecryptKey(data, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Equals(sk) {
+		t.Fatal("decrypted key doesn't match the original")
+	}
+
+	if _, err := DecryptKey(data, []byte("wrong")); err != ErrBadPassword {
+		t.Fatalf("expected ErrBadPassword for the wrong password, got %v", err)
+	}
+
+	i := bytes.Index(data, []byte("\n\n")) + 4
+	tampered := append([]byte{}, data...)
+	if tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+	if _, err := DecryptKey(tampered, []byte("secret")); err != ErrBadPassword {
+		t.Fatalf("expected ErrBadPassword for tampered data, got %v", err)
+	}
+
+	if _, err := DecryptKey([]byte("not a key"), []byte("secret")); err != ErrNotExportedKey {
+		t.Fatalf("expected ErrNotExportedKey, got %v", err)
+	}
+}
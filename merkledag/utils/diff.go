@@ -164,6 +164,46 @@ func Diff(ctx context.Context, ds dag.DAGService, a, b node.Node) ([]*Change, er
 	return out, nil
 }
 
+// DiffLinks returns the changes between the links of nodes 'a' and 'b',
+// matched by name. Unlike Diff it does not descend into changed links; they
+// are reported as a single Mod change.
+func DiffLinks(a, b node.Node) []*Change {
+	var out []*Change
+
+	for _, lnk := range a.Links() {
+		l, _, err := b.ResolveLink([]string{lnk.Name})
+		if err != nil {
+			out = append(out, &Change{
+				Type:   Remove,
+				Path:   lnk.Name,
+				Before: lnk.Cid,
+			})
+			continue
+		}
+
+		if !l.Cid.Equals(lnk.Cid) {
+			out = append(out, &Change{
+				Type:   Mod,
+				Path:   lnk.Name,
+				Before: lnk.Cid,
+				After:  l.Cid,
+			})
+		}
+	}
+
+	for _, lnk := range b.Links() {
+		if _, _, err := a.ResolveLink([]string{lnk.Name}); err != nil {
+			out = append(out, &Change{
+				Type:  Add,
+				Path:  lnk.Name,
+				After: lnk.Cid,
+			})
+		}
+	}
+
+	return out
+}
+
 type Conflict struct {
 	A *Change
 	B *Change
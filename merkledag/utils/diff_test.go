@@ -0,0 +1,39 @@
+package dagutils
+
+import (
+	"testing"
+)
+
+func TestDiffLinks(t *testing.T) {
+	nds := mkGraph(tg1)
+
+	changes := DiffLinks(nds["a1"], nds["a2"])
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if c := changes[0]; c.Type != Add || c.Path != "bar" || !c.After.Equals(nds["c"].Cid()) {
+		t.Fatalf("unexpected change: %s", c)
+	}
+
+	changes = DiffLinks(nds["a2"], nds["a1"])
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if c := changes[0]; c.Type != Remove || c.Path != "bar" || !c.Before.Equals(nds["c"].Cid()) {
+		t.Fatalf("unexpected change: %s", c)
+	}
+
+	nds = mkGraph(tg3)
+	changes = DiffLinks(nds["a1"], nds["a2"])
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	c := changes[0]
+	if c.Type != Mod || c.Path != "bar" || !c.Before.Equals(nds["c"].Cid()) || !c.After.Equals(nds["d"].Cid()) {
+		t.Fatalf("unexpected change: %s", c)
+	}
+
+	if changes := DiffLinks(nds["a1"], nds["a1"]); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %d", len(changes))
+	}
+}
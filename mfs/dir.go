@@ -38,6 +38,8 @@ type Directory struct {
 	modTime time.Time
 
 	name string
+
+	shardThreshold int
 }
 
 func NewDirectory(ctx context.Context, name string, node node.Node, parent childCloser, dserv dag.DAGService) (*Directory, error) {
@@ -63,6 +65,22 @@ func (d *Directory) SetPrefix(prefix *cid.Prefix) {
 	d.dirbuilder.SetPrefix(prefix)
 }
 
+// SetShardThreshold makes this directory, and the directories created or
+// loaded below it afterwards, switch to HAMT sharding once they hold more
+// than n entries. See uio.Directory.SetShardThreshold.
+func (d *Directory) SetShardThreshold(n int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.setShardThresholdUnsync(n)
+}
+
+// setShardThresholdUnsync is SetShardThreshold without locking, for new
+// directories and operations which already hold the lock.
+func (d *Directory) setShardThresholdUnsync(n int) {
+	d.shardThreshold = n
+	d.dirbuilder.SetShardThreshold(n)
+}
+
 // closeChild updates the child by the given name to the dag node 'nd'
 // and changes its own dag node
 func (d *Directory) closeChild(name string, nd node.Node, sync bool) error {
@@ -153,6 +171,7 @@ func (d *Directory) cacheNode(name string, nd node.Node) (FSNode, error) {
 			if err != nil {
 				return nil, err
 			}
+			ndir.setShardThresholdUnsync(d.shardThreshold)
 
 			d.childDirs[name] = ndir
 			return ndir, nil
@@ -314,6 +333,7 @@ func (d *Directory) Mkdir(name string) (*Directory, error) {
 	if err != nil {
 		return nil, err
 	}
+	dirobj.setShardThresholdUnsync(d.shardThreshold)
 
 	d.childDirs[name] = dirobj
 	return dirobj, nil
@@ -329,6 +349,37 @@ func (d *Directory) Unlink(name string) error {
 	return d.dirbuilder.RemoveChild(d.ctx, name)
 }
 
+// updateData replaces the unixfs data of this directory's node with the
+// result of calling f on it, and updates the parent directory.
+func (d *Directory) updateData(f func([]byte) ([]byte, error)) error {
+	d.lock.Lock()
+	err := func() error {
+		nd, err := d.dirbuilder.GetNode()
+		if err != nil {
+			return err
+		}
+
+		pbnd, ok := nd.(*dag.ProtoNode)
+		if !ok {
+			return dag.ErrNotProtobuf
+		}
+
+		data, err := f(pbnd.Data())
+		if err != nil {
+			return err
+		}
+
+		d.modTime = time.Now()
+		return d.dirbuilder.SetData(data)
+	}()
+	d.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return d.Flush()
+}
+
 func (d *Directory) Flush() error {
 	nd, err := d.GetNode()
 	if err != nil {
@@ -362,6 +413,29 @@ func (d *Directory) AddChild(name string, nd node.Node) error {
 	return nil
 }
 
+// replaceChild links nd under name, replacing any existing entry with a
+// single update of this directory.
+func (d *Directory) replaceChild(name string, nd node.Node) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	_, err := d.dserv.Add(nd)
+	if err != nil {
+		return err
+	}
+
+	delete(d.childDirs, name)
+	delete(d.files, name)
+
+	err = d.dirbuilder.AddChild(d.ctx, name, nd)
+	if err != nil {
+		return err
+	}
+
+	d.modTime = time.Now()
+	return nil
+}
+
 func (d *Directory) sync() error {
 	for name, dir := range d.childDirs {
 		nd, err := dir.GetNode()
@@ -113,6 +113,59 @@ func (fi *File) GetNode() (node.Node, error) {
 	return fi.node, nil
 }
 
+// Replace swaps the contents of this file for the given node and updates
+// the parent directory. It waits for any open descriptor to be closed.
+func (fi *File) Replace(nd node.Node, sync bool) error {
+	fi.desclock.Lock()
+	defer fi.desclock.Unlock()
+
+	_, err := fi.dserv.Add(nd)
+	if err != nil {
+		return err
+	}
+
+	fi.nodelk.Lock()
+	fi.node = nd
+	name := fi.name
+	parent := fi.parent
+	fi.nodelk.Unlock()
+
+	return parent.closeChild(name, nd, sync)
+}
+
+// updateData replaces the unixfs data of this file's root node with the
+// result of calling f on it, and updates the parent directory.
+func (fi *File) updateData(f func([]byte) ([]byte, error)) error {
+	fi.desclock.Lock()
+	defer fi.desclock.Unlock()
+
+	fi.nodelk.Lock()
+	pbnd, ok := fi.node.(*dag.ProtoNode)
+	name := fi.name
+	parent := fi.parent
+	fi.nodelk.Unlock()
+	if !ok {
+		return fmt.Errorf("%s is a raw block, which cannot carry unixfs metadata", name)
+	}
+
+	data, err := f(pbnd.Data())
+	if err != nil {
+		return err
+	}
+
+	nd := pbnd.Copy().(*dag.ProtoNode)
+	nd.SetData(data)
+	if _, err := fi.dserv.Add(nd); err != nil {
+		return err
+	}
+
+	fi.nodelk.Lock()
+	fi.node = nd
+	fi.nodelk.Unlock()
+
+	return parent.closeChild(name, nd, true)
+}
+
 func (fi *File) Flush() error {
 	// open the file in fullsync mode
 	fd, err := fi.Open(OpenWriteOnly, true)
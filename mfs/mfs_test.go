@@ -1106,3 +1106,65 @@ func TestFileDescriptors(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestMvMerge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds, rt := setupRoot(ctx, t)
+
+	rootdir := rt.GetValue().(*Directory)
+
+	a := mkdirP(t, rootdir, "src/a")
+	b := mkdirP(t, rootdir, "dst/a")
+	srcOnly := getRandFile(t, ds, 100)
+	srcShared := getRandFile(t, ds, 200)
+	dstShared := getRandFile(t, ds, 300)
+	dstOnly := getRandFile(t, ds, 400)
+	for name, fi := range map[string]node.Node{"srconly": srcOnly, "shared": srcShared} {
+		if err := a.AddChild(name, fi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, fi := range map[string]node.Node{"shared": dstShared, "dstonly": dstOnly} {
+		if err := b.AddChild(name, fi); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := Mv(rt, "/src", "/dst", false)
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("expected a conflict on /dst/a/shared, got: %v", err)
+	}
+
+	// a failed merge leaves both trees as they were
+	if err := assertDirAtPath(rootdir, "/src/a", []string{"srconly", "shared"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := assertDirAtPath(rootdir, "/dst/a", []string{"shared", "dstonly"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Mv(rt, "/src", "/dst", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := assertDirAtPath(rootdir, "/", []string{"dst"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := assertDirAtPath(rootdir, "/dst/a", []string{"srconly", "shared", "dstonly"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := assertFileAtPath(ds, rootdir, srcShared, "dst/a/shared"); err != nil {
+		t.Fatal(err)
+	}
+	if err := assertFileAtPath(ds, rootdir, dstOnly, "dst/a/dstonly"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Mv(rt, "/dst", "/dst/a/b", false); err == nil {
+		t.Fatal("moving a directory into itself should fail")
+	}
+	if err := Mv(rt, "/dst/a/shared", "/dst/a", false); err == nil {
+		t.Fatal("moving a file onto itself should fail")
+	}
+}
@@ -1,24 +1,48 @@
 package mfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	gopath "path"
 	"strings"
+	"time"
 
+	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
+	ft "github.com/ipfs/go-ipfs/unixfs"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
-// Mv moves the file or directory at 'src' to 'dst'
-func Mv(r *Root, src, dst string) error {
+// ConflictError is returned by Mv when a file would be overwritten without
+// force being set.
+type ConflictError struct {
+	Path string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s already exists", e.Path)
+}
+
+// Mv moves the file or directory at 'src' to 'dst'. If 'dst' ends with a
+// slash, or is an existing directory and 'src' is a file, 'src' is moved
+// into it. Moving a directory onto an existing directory merges the two.
+// Existing files are only overwritten if 'force' is set, otherwise a
+// *ConflictError is returned.
+//
+// All conflicts are checked before the tree is changed. The destination is
+// then updated with a single link change before the source is unlinked, so
+// an interrupted move leaves the data at both paths rather than at neither.
+func Mv(r *Root, src, dst string, force bool) error {
 	srcDir, srcFname := gopath.Split(src)
 
 	var dstDirStr string
 	var filename string
-	if dst[len(dst)-1] == '/' {
+	into := dst[len(dst)-1] == '/'
+	if into {
 		dstDirStr = dst
 		filename = srcFname
 	} else {
@@ -40,6 +64,7 @@ func Mv(r *Root, src, dst string) error {
 	if err != nil {
 		return err
 	}
+	_, srcIsDir := srcObj.(*Directory)
 
 	nd, err := srcObj.GetNode()
 	if err != nil {
@@ -47,20 +72,44 @@ func Mv(r *Root, src, dst string) error {
 	}
 
 	fsn, err := dstDir.Child(filename)
-	if err == nil {
-		switch n := fsn.(type) {
-		case *File:
-			_ = dstDir.Unlink(filename)
-		case *Directory:
-			dstDir = n
-		default:
-			return fmt.Errorf("unexpected type at path: %s", dst)
+	if d, ok := fsn.(*Directory); err == nil && ok && !srcIsDir && !into {
+		// like mv(1), moving a file onto a directory moves it into it
+		dstDir = d
+		dstDirStr = gopath.Join(dstDirStr, filename)
+		filename = srcFname
+		fsn, err = dstDir.Child(filename)
+	}
+
+	src = gopath.Clean(src)
+	target := gopath.Join(dstDirStr, filename)
+	switch {
+	case target == src:
+		return fmt.Errorf("cannot move %s onto itself", src)
+	case strings.HasPrefix(target, src+"/"):
+		return fmt.Errorf("cannot move %s into itself", src)
+	}
+
+	switch err {
+	case os.ErrNotExist:
+	case nil:
+		if strings.HasPrefix(src, target+"/") {
+			return fmt.Errorf("cannot move %s onto its parent %s", src, target)
+		}
+
+		existing, err := fsn.GetNode()
+		if err != nil {
+			return err
 		}
-	} else if err != os.ErrNotExist {
+
+		nd, err = mergeNode(dstDir.ctx, dstDir.dserv, existing, nd, target, force)
+		if err != nil {
+			return err
+		}
+	default:
 		return err
 	}
 
-	err = dstDir.AddChild(filename, nd)
+	err = dstDir.replaceChild(filename, nd)
 	if err != nil {
 		return err
 	}
@@ -68,6 +117,109 @@ func Mv(r *Root, src, dst string) error {
 	return srcDirObj.Unlink(srcFname)
 }
 
+// mergeNode returns the node that results from moving src onto the
+// existing node dst at path. Directories are merged recursively, files are
+// replaced if force is set.
+func mergeNode(ctx context.Context, ds dag.DAGService, dst, src node.Node, path string, force bool) (node.Node, error) {
+	dstIsDir, err := isDirNode(dst)
+	if err != nil {
+		return nil, err
+	}
+	srcIsDir, err := isDirNode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case dstIsDir && srcIsDir:
+		return mergeDirs(ctx, ds, dst, src, path, force)
+	case dstIsDir:
+		return nil, fmt.Errorf("cannot overwrite directory %s with a file", path)
+	case srcIsDir:
+		return nil, fmt.Errorf("cannot overwrite file %s with a directory", path)
+	case !force:
+		return nil, &ConflictError{Path: path}
+	default:
+		return src, nil
+	}
+}
+
+// mergeDirs adds the entries of the directory src to the directory dst and
+// returns the resulting directory node.
+func mergeDirs(ctx context.Context, ds dag.DAGService, dst, src node.Node, path string, force bool) (node.Node, error) {
+	dstDir, err := uio.NewDirectoryFromNode(ds, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	srcDir, err := uio.NewDirectoryFromNode(ds, src)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := srcDir.Links(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lnk := range links {
+		child, err := lnk.GetNode(ctx, ds)
+		if err != nil {
+			return nil, err
+		}
+
+		existing, err := dstDir.Find(ctx, lnk.Name)
+		switch err {
+		case os.ErrNotExist:
+		case nil:
+			child, err = mergeNode(ctx, ds, existing, child, gopath.Join(path, lnk.Name), force)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+
+		err = dstDir.AddChild(ctx, lnk.Name, child)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nd, err := dstDir.GetNode()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ds.Add(nd)
+	if err != nil {
+		return nil, err
+	}
+
+	return nd, nil
+}
+
+// isDirNode reports whether nd is a unixfs directory or shard.
+func isDirNode(nd node.Node) (bool, error) {
+	pbnd, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		// raw nodes are file data
+		return false, nil
+	}
+
+	fsn, err := ft.FromBytes(pbnd.Data())
+	if err != nil {
+		return false, err
+	}
+
+	switch fsn.GetType() {
+	case ft.TDirectory, ft.THAMTShard:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 func lookupDir(r *Root, path string) (*Directory, error) {
 	di, err := Lookup(r, path)
 	if err != nil {
@@ -137,13 +289,16 @@ func Mkdir(r *Root, pth string, mkparents bool, flush bool) error {
 
 		next, ok := fsn.(*Directory)
 		if !ok {
-			return fmt.Errorf("%s was not a directory", path.Join(parts[:i]))
+			return fmt.Errorf("/%s is a file, not a directory", path.Join(parts[:i+1]))
 		}
 		cur = next
 	}
 
 	final, err := cur.Mkdir(parts[len(parts)-1])
 	if err != nil {
+		if mkparents && err == os.ErrExist && final == nil {
+			return fmt.Errorf("/%s is a file, not a directory", path.Join(parts))
+		}
 		if !mkparents || err != os.ErrExist || final == nil {
 			return err
 		}
@@ -211,3 +366,33 @@ func FlushPath(rt *Root, pth string) error {
 	rt.repub.WaitPub()
 	return nil
 }
+
+// Chmod stores the permission bits of mode on the unixfs node at 'path'
+func Chmod(r *Root, path string, mode os.FileMode) error {
+	return updateData(r, path, func(data []byte) ([]byte, error) {
+		return ft.SetMode(data, mode)
+	})
+}
+
+// Chtime stores mtime as the modification time of the unixfs node at 'path'
+func Chtime(r *Root, path string, mtime time.Time) error {
+	return updateData(r, path, func(data []byte) ([]byte, error) {
+		return ft.SetModTime(data, mtime)
+	})
+}
+
+func updateData(r *Root, path string, f func([]byte) ([]byte, error)) error {
+	fsn, err := Lookup(r, path)
+	if err != nil {
+		return err
+	}
+
+	switch fsn := fsn.(type) {
+	case *Directory:
+		return fsn.updateData(f)
+	case *File:
+		return fsn.updateData(f)
+	default:
+		return fmt.Errorf("unrecognized fsnode type %T", fsn)
+	}
+}
@@ -41,6 +41,47 @@ func (r *DNSResolver) ResolveN(ctx context.Context, name string, depth int) (pat
 	return resolve(ctx, r, name, depth, "/ipns/")
 }
 
+// ErrResolveLoop signals that a chain of dnslinks visits a domain twice.
+var ErrResolveLoop = errors.New("Could not resolve name (dnslink loop detected).")
+
+// DNSLink is a single step in a chain of dnslinks: the domain that was
+// looked up and the value its record pointed to.
+type DNSLink struct {
+	Domain string
+	Value  path.Path
+}
+
+// ResolveChain follows the dnslinks starting at the given domain one step
+// at a time until it reaches a value that is not another domain, such as
+// an /ipfs/ path, and returns every step taken. Unlike ResolveN it fails
+// with ErrResolveLoop as soon as a domain is visited twice.
+func ResolveChain(ctx context.Context, r Resolver, name string) ([]DNSLink, error) {
+	var chain []DNSLink
+	visited := make(map[string]bool)
+
+	for len(chain) < DefaultDepthLimit {
+		domain := strings.SplitN(name, "/", 2)[0]
+		if visited[domain] {
+			return chain, ErrResolveLoop
+		}
+		visited[domain] = true
+
+		p, err := r.ResolveN(ctx, name, 1)
+		if err != nil && err != ErrResolveRecursion {
+			return chain, err
+		}
+		chain = append(chain, DNSLink{Domain: domain, Value: p})
+
+		next := strings.TrimPrefix(p.String(), "/ipns/")
+		if next == p.String() || !isd.IsDomain(strings.SplitN(next, "/", 2)[0]) {
+			return chain, nil
+		}
+		name = next
+	}
+
+	return chain, ErrResolveRecursion
+}
+
 type lookupRes struct {
 	path  path.Path
 	error error
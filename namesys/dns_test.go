@@ -1,6 +1,7 @@
 package namesys
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -158,3 +159,39 @@ func TestDNSResolution(t *testing.T) {
 	testResolution(t, r, "double.example.com", DefaultDepthLimit, "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD", nil)
 	testResolution(t, r, "conflict.example.com", DefaultDepthLimit, "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjE", nil)
 }
+
+func TestDNSResolveChain(t *testing.T) {
+	mock := newMockDNS()
+	r := &DNSResolver{lookupTXT: mock.lookupTXT}
+	ctx := context.Background()
+
+	chain, err := ResolveChain(ctx, r, "dns2.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DNSLink{
+		{"dns2.example.com", "/ipns/dns1.example.com"},
+		{"dns1.example.com", "/ipns/ipfs.example.com"},
+		{"ipfs.example.com", "/ipfs/QmY3hE8xgFCjGcz6PHgnvJz5HZi1BaKRfPkn1ghZUcYMjD"},
+	}
+	if len(chain) != len(expected) {
+		t.Fatalf("expected %d steps, got %d: %v", len(expected), len(chain), chain)
+	}
+	for i, l := range chain {
+		if l != expected[i] {
+			t.Fatalf("step %d: expected %v, got %v", i, expected[i], l)
+		}
+	}
+
+	chain, err = ResolveChain(ctx, r, "loop1.example.com")
+	if err != ErrResolveLoop {
+		t.Fatalf("expected ErrResolveLoop, got %v", err)
+	}
+	if len(chain) != 2 {
+		t.Fatalf("expected 2 steps before the loop, got %d: %v", len(chain), chain)
+	}
+
+	if _, err := ResolveChain(ctx, r, "bad.example.com"); err != ErrResolveFailed {
+		t.Fatalf("expected ErrResolveFailed, got %v", err)
+	}
+}
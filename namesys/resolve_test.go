@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
 	path "github.com/ipfs/go-ipfs/path"
 	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
 	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
@@ -50,6 +51,55 @@ func TestRoutingResolve(t *testing.T) {
 	}
 }
 
+func TestRoutingResolveEntryHook(t *testing.T) {
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(context.Background(), testutil.RandIdentityOrFatal(t), dstore)
+
+	resolver := NewRoutingResolver(d, 10)
+	publisher := NewRoutingPublisher(d, dstore)
+
+	privk, pubk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := path.FromString("/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN")
+	err = publisher.Publish(context.Background(), privk, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second resolve is answered from the cache, which keeps the record
+	for i := 0; i < 2; i++ {
+		var names []string
+		var entry *pb.IpnsEntry
+		ctx := ContextWithEntryHook(context.Background(), func(name string, e *pb.IpnsEntry) {
+			names = append(names, name)
+			entry = e
+		})
+
+		res, err := resolver.Resolve(ctx, pid.Pretty())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != h {
+			t.Fatal("Got back incorrect value.")
+		}
+
+		if len(names) != 1 || names[0] != pid.Pretty() {
+			t.Fatalf("expected the hook to be called once for %s, got %v", pid.Pretty(), names)
+		}
+		if string(entry.GetValue()) != h.String() {
+			t.Fatalf("hook got a record for %q", entry.GetValue())
+		}
+	}
+}
+
 func TestPrexistingExpiredRecord(t *testing.T) {
 	dstore := dssync.MutexWrap(ds.NewMapDatastore())
 	d := mockrouting.NewServer().ClientWithDatastore(context.Background(), testutil.RandIdentityOrFatal(t), dstore)
@@ -28,14 +28,30 @@ type routingResolver struct {
 	cache *lru.Cache
 }
 
-func (r *routingResolver) cacheGet(name string) (path.Path, bool) {
+// entryHookKey is the context key of the hook set by ContextWithEntryHook.
+type entryHookKey struct{}
+
+// ContextWithEntryHook returns a context under which the routing resolver
+// calls hook with each IPNS record it resolves a name from, along with the
+// name, a base58 peer ID. Names whose cached value didn't come with its
+// record, such as names published by this node, are looked up again.
+func ContextWithEntryHook(ctx context.Context, hook func(name string, entry *pb.IpnsEntry)) context.Context {
+	return context.WithValue(ctx, entryHookKey{}, hook)
+}
+
+func entryHook(ctx context.Context) func(string, *pb.IpnsEntry) {
+	hook, _ := ctx.Value(entryHookKey{}).(func(string, *pb.IpnsEntry))
+	return hook
+}
+
+func (r *routingResolver) cacheGet(name string) (path.Path, *pb.IpnsEntry, bool) {
 	if r.cache == nil {
-		return "", false
+		return "", nil, false
 	}
 
 	ientry, ok := r.cache.Get(name)
 	if !ok {
-		return "", false
+		return "", nil, false
 	}
 
 	entry, ok := ientry.(cacheEntry)
@@ -45,12 +61,12 @@ func (r *routingResolver) cacheGet(name string) (path.Path, bool) {
 	}
 
 	if time.Now().Before(entry.eol) {
-		return entry.val, true
+		return entry.val, entry.rec, true
 	}
 
 	r.cache.Remove(name)
 
-	return "", false
+	return "", nil, false
 }
 
 func (r *routingResolver) cacheSet(name string, val path.Path, rec *pb.IpnsEntry) {
@@ -75,12 +91,14 @@ func (r *routingResolver) cacheSet(name string, val path.Path, rec *pb.IpnsEntry
 
 	r.cache.Add(name, cacheEntry{
 		val: val,
+		rec: rec,
 		eol: cacheTil,
 	})
 }
 
 type cacheEntry struct {
 	val path.Path
+	rec *pb.IpnsEntry // nil for names added when publishing
 	eol time.Time
 }
 
@@ -118,12 +136,16 @@ func (r *routingResolver) ResolveN(ctx context.Context, name string, depth int)
 // resolve SFS-like names.
 func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Path, error) {
 	log.Debugf("RoutingResolve: '%s'", name)
-	cached, ok := r.cacheGet(name)
-	if ok {
+	name = strings.TrimPrefix(name, "/ipns/")
+	hook := entryHook(ctx)
+	cached, rec, ok := r.cacheGet(name)
+	if ok && (hook == nil || rec != nil) {
+		if hook != nil {
+			hook(name, rec)
+		}
 		return cached, nil
 	}
 
-	name = strings.TrimPrefix(name, "/ipns/")
 	hash, err := mh.FromB58String(name)
 	if err != nil {
 		// name should be a multihash. if it isn't, error out here.
@@ -131,6 +153,39 @@ func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Pa
 		return "", err
 	}
 
+	entry, err := getEntry(ctx, r.routing, hash)
+	if err != nil {
+		return "", err
+	}
+
+	// ok sig checks out. this is a valid name.
+	if hook != nil {
+		hook(name, entry)
+	}
+
+	// check for old style record:
+	valh, err := mh.Cast(entry.GetValue())
+	if err != nil {
+		// Not a multihash, probably a new record
+		p, err := path.ParsePath(string(entry.GetValue()))
+		if err != nil {
+			return "", err
+		}
+
+		r.cacheSet(name, p, entry)
+		return p, nil
+	} else {
+		// Its an old style multihash record
+		log.Warning("Detected old style multihash record")
+		p := path.FromCid(cid.NewCidV0(valh))
+		r.cacheSet(name, p, entry)
+		return p, nil
+	}
+}
+
+// getEntry fetches the IPNS record published under the given peer ID hash
+// from the routing system and checks that it was signed by that peer.
+func getEntry(ctx context.Context, route routing.ValueStore, hash mh.Multihash) (*pb.IpnsEntry, error) {
 	// use the routing system to get the name.
 	// /ipns/<name>
 	h := []byte("/ipns/" + string(hash))
@@ -141,7 +196,7 @@ func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Pa
 	resp := make(chan error, 2)
 	go func() {
 		ipnsKey := string(h)
-		val, err := r.routing.GetValue(ctx, ipnsKey)
+		val, err := route.GetValue(ctx, ipnsKey)
 		if err != nil {
 			log.Warning("RoutingResolve get failed.")
 			resp <- err
@@ -160,7 +215,7 @@ func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Pa
 
 	go func() {
 		// name should be a public key retrievable from ipfs
-		pubk, err := routing.GetPublicKey(r.routing, ctx, hash)
+		pubk, err := routing.GetPublicKey(route, ctx, hash)
 		if err != nil {
 			resp <- err
 			return
@@ -171,37 +226,18 @@ func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Pa
 	}()
 
 	for i := 0; i < 2; i++ {
-		err = <-resp
+		err := <-resp
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
 	// check sig with pk
 	if ok, err := pubkey.Verify(ipnsEntryDataForSig(entry), entry.GetSignature()); err != nil || !ok {
-		return "", fmt.Errorf("Invalid value. Not signed by PrivateKey corresponding to %v", pubkey)
+		return nil, fmt.Errorf("Invalid value. Not signed by PrivateKey corresponding to %v", pubkey)
 	}
 
-	// ok sig checks out. this is a valid name.
-
-	// check for old style record:
-	valh, err := mh.Cast(entry.GetValue())
-	if err != nil {
-		// Not a multihash, probably a new record
-		p, err := path.ParsePath(string(entry.GetValue()))
-		if err != nil {
-			return "", err
-		}
-
-		r.cacheSet(name, p, entry)
-		return p, nil
-	} else {
-		// Its an old style multihash record
-		log.Warning("Detected old style multihash record")
-		p := path.FromCid(cid.NewCidV0(valh))
-		r.cacheSet(name, p, entry)
-		return p, nil
-	}
+	return entry, nil
 }
 
 func checkEOL(e *pb.IpnsEntry) (time.Time, bool) {
@@ -14,12 +14,30 @@ import (
 )
 
 // Result represents an incremental output from a garbage collection
-// run.  It contains either an error, or the cid of a removed object.
+// run.  It contains either an error, or the cid of a removed object. The
+// size of removed objects is only looked up, and set, when the run is
+// limited by Limits.MaxFreed.
 type Result struct {
 	KeyRemoved *cid.Cid
+	Size       uint64
 	Error      error
 }
 
+// Limits bounds how much a garbage collection run removes. Zero values
+// mean no limit.
+type Limits struct {
+	// MaxRemoved stops the sweep once this many blocks have been removed.
+	MaxRemoved int
+	// MaxFreed stops the sweep once at least this many bytes have been
+	// freed.
+	MaxFreed uint64
+}
+
+func (l Limits) reached(removed int, freed uint64) bool {
+	return (l.MaxRemoved > 0 && removed >= l.MaxRemoved) ||
+		(l.MaxFreed > 0 && freed >= l.MaxFreed)
+}
+
 // GC performs a mark and sweep garbage collection of the blocks in the blockstore
 // first, it creates a 'marked' set and adds to it the following:
 // - all recursively pinned blocks, plus all of their descendants (recursively)
@@ -31,6 +49,12 @@ type Result struct {
 // deletes any block that is not found in the marked set.
 //
 func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.Pinner, bestEffortRoots []*cid.Cid) <-chan Result {
+	return GCWithLimits(ctx, bs, ls, pn, bestEffortRoots, Limits{})
+}
+
+// GCWithLimits is like GC, but stops sweeping as soon as one of the given
+// limits is reached.
+func GCWithLimits(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.Pinner, bestEffortRoots []*cid.Cid, limits Limits) <-chan Result {
 	unlocker := bs.GCLock()
 	ls = ls.GetOfflineLinkService()
 
@@ -40,6 +64,10 @@ func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.
 		defer close(output)
 		defer unlocker.Unlock()
 
+		// stopping early at a limit must also stop the key listing
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
 		gcs, err := ColoredSet(ctx, pn, ls, bestEffortRoots, output)
 		if err != nil {
 			output <- Result{Error: err}
@@ -53,6 +81,8 @@ func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.
 		}
 
 		errors := false
+		removed := 0
+		var freed uint64
 
 	loop:
 		for {
@@ -62,6 +92,15 @@ func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.
 					break loop
 				}
 				if !gcs.Has(k) {
+					// reading the block just for its size is costly, so
+					// only do it when the size matters
+					var size uint64
+					if limits.MaxFreed > 0 {
+						if blk, err := bs.Get(k); err == nil {
+							size = uint64(len(blk.RawData()))
+						}
+					}
+
 					err := bs.DeleteBlock(k)
 					if err != nil {
 						errors = true
@@ -70,11 +109,16 @@ func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.
 						// continue as error is non-fatal
 						continue loop
 					}
+					removed++
+					freed += size
 					select {
-					case output <- Result{KeyRemoved: k}:
+					case output <- Result{KeyRemoved: k, Size: size}:
 					case <-ctx.Done():
 						break loop
 					}
+					if limits.reached(removed, freed) {
+						break loop
+					}
 				}
 			case <-ctx.Done():
 				break loop
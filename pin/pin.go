@@ -22,6 +22,9 @@ var log = logging.Logger("pin")
 
 var pinDatastoreKey = ds.NewKey("/local/pins")
 
+// pinNamesDatastoreKey is the prefix under which pin names are stored
+var pinNamesDatastoreKey = ds.NewKey("/local/pinnames")
+
 var emptyKey *cid.Cid
 
 func init() {
@@ -106,6 +109,14 @@ type Pinner interface {
 	// be successful.
 	RemovePinWithMode(*cid.Cid, PinMode)
 
+	// SetName attaches a human readable name to a pin, replacing any
+	// previous one. An empty name removes it.
+	SetName(*cid.Cid, string) error
+
+	// Name returns the name attached to a pin, or the empty string if it
+	// has none.
+	Name(*cid.Cid) (string, error)
+
 	Flush() error
 	DirectKeys() []*cid.Cid
 	RecursiveKeys() []*cid.Cid
@@ -221,18 +232,58 @@ func (p *pinner) Unpin(ctx context.Context, c *cid.Cid, recursive bool) error {
 	case "recursive":
 		if recursive {
 			p.recursePin.Remove(c)
-			return nil
+			return p.removeName(c)
 		} else {
 			return fmt.Errorf("%s is pinned recursively", c)
 		}
 	case "direct":
 		p.directPin.Remove(c)
-		return nil
+		return p.removeName(c)
 	default:
 		return fmt.Errorf("%s is pinned indirectly under %s", c, reason)
 	}
 }
 
+// SetName attaches a name to the given pin
+func (p *pinner) SetName(c *cid.Cid, name string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.recursePin.Has(c) && !p.directPin.Has(c) {
+		return ErrNotPinned
+	}
+	if name == "" {
+		return p.removeName(c)
+	}
+	return p.dstore.Put(pinNamesDatastoreKey.ChildString(c.String()), []byte(name))
+}
+
+// Name returns the name attached to the given pin
+func (p *pinner) Name(c *cid.Cid) (string, error) {
+	v, err := p.dstore.Get(pinNamesDatastoreKey.ChildString(c.String()))
+	switch err {
+	case nil:
+	case ds.ErrNotFound:
+		return "", nil
+	default:
+		return "", err
+	}
+
+	name, ok := v.([]byte)
+	if !ok {
+		return "", fmt.Errorf("pin name for %s was not bytes", c)
+	}
+	return string(name), nil
+}
+
+func (p *pinner) removeName(c *cid.Cid) error {
+	err := p.dstore.Delete(pinNamesDatastoreKey.ChildString(c.String()))
+	if err != nil && err != ds.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
 func (p *pinner) isInternalPin(c *cid.Cid) bool {
 	return p.internalPin.Has(c)
 }
@@ -470,6 +521,18 @@ func (p *pinner) Update(ctx context.Context, from, to *cid.Cid, unpin bool) erro
 	p.recursePin.Add(to)
 	if unpin {
 		p.recursePin.Remove(from)
+
+		// carry the name over to the new pin
+		name, err := p.Name(from)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			if err := p.dstore.Put(pinNamesDatastoreKey.ChildString(to.String()), []byte(name)); err != nil {
+				return err
+			}
+			return p.removeName(from)
+		}
 	}
 	return nil
 }
@@ -400,3 +400,50 @@ func TestPinUpdate(t *testing.T) {
 	assertPinned(t, p, c2, "c2 should be pinned still")
 	assertPinned(t, p, c1, "c1 should be pinned now")
 }
+
+func TestPinNames(t *testing.T) {
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	bserv := bs.New(bstore, offline.Exchange(bstore))
+
+	dserv := mdag.NewDAGService(bserv)
+	p := NewPinner(dstore, dserv, dserv)
+	n1, c1 := randNode()
+	dserv.Add(n1)
+
+	if err := p.SetName(c1, "unpinned"); err != ErrNotPinned {
+		t.Fatal("expected naming an unpinned cid to fail")
+	}
+
+	ctx := context.Background()
+	if err := p.Pin(ctx, n1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.SetName(c1, "backup-2018"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetName(c1, "backup-2019"); err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := p.Name(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "backup-2019" {
+		t.Fatalf("expected pin name to be updated, got %q", name)
+	}
+
+	if err := p.Unpin(ctx, c1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	name, err = p.Name(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "" {
+		t.Fatal("expected name to be removed along with the pin")
+	}
+}
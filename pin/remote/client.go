@@ -0,0 +1,180 @@
+// Package remote implements a client for the IPFS pinning service API, which
+// lets a node ask other services to pin content for it.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pin statuses reported by pinning services.
+const (
+	StatusQueued  = "queued"
+	StatusPinning = "pinning"
+	StatusPinned  = "pinned"
+	StatusFailed  = "failed"
+)
+
+// AllStatuses lists every pin status, in the order pins move through them.
+var AllStatuses = []string{StatusQueued, StatusPinning, StatusPinned, StatusFailed}
+
+// Pin describes the content a pinning service is asked to pin.
+type Pin struct {
+	Cid     string   `json:"cid"`
+	Name    string   `json:"name,omitempty"`
+	Origins []string `json:"origins,omitempty"`
+}
+
+// PinStatus is the state of a pin request on a pinning service.
+type PinStatus struct {
+	RequestID string    `json:"requestid"`
+	Status    string    `json:"status"`
+	Created   time.Time `json:"created"`
+	Pin       Pin       `json:"pin"`
+	Delegates []string  `json:"delegates"`
+}
+
+// Done reports whether the service is done working on the pin, either
+// because it is pinned or because pinning failed.
+func (ps *PinStatus) Done() bool {
+	return ps.Status == StatusPinned || ps.Status == StatusFailed
+}
+
+type pinResults struct {
+	Count   int         `json:"count"`
+	Results []PinStatus `json:"results"`
+}
+
+type apiError struct {
+	Error struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	} `json:"error"`
+}
+
+// Client talks to a single pinning service.
+type Client struct {
+	endpoint string
+	key      string
+
+	// HTTPClient is used to send requests. It defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the service at endpoint, authenticating
+// with key.
+func NewClient(endpoint, key string) *Client {
+	return &Client{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		key:        key,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Add asks the service to pin p.
+func (c *Client) Add(ctx context.Context, p Pin) (*PinStatus, error) {
+	body, err := json.Marshal(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	var ps PinStatus
+	if err := c.do(ctx, "POST", "/pins", bytes.NewReader(body), &ps); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// Get returns the status of the pin request with the given request ID.
+func (c *Client) Get(ctx context.Context, requestID string) (*PinStatus, error) {
+	var ps PinStatus
+	if err := c.do(ctx, "GET", "/pins/"+(&url.URL{Path: requestID}).EscapedPath(), nil, &ps); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
+// lsPageSize is the number of pins requested per page by Ls, the maximum
+// the pinning service API allows.
+const lsPageSize = 1000
+
+// Ls lists the pins on the service. Only pins with one of the given CIDs
+// and statuses are returned; an empty list matches all CIDs, or all
+// statuses. Services return pins a page at a time, newest first; Ls keeps
+// requesting pages created before the last pin it got until it has all of
+// them.
+func (c *Client) Ls(ctx context.Context, cids, statuses []string) ([]PinStatus, error) {
+	if len(statuses) == 0 {
+		statuses = AllStatuses
+	}
+
+	q := url.Values{}
+	q.Set("status", strings.Join(statuses, ","))
+	q.Set("limit", strconv.Itoa(lsPageSize))
+	if len(cids) > 0 {
+		q.Set("cid", strings.Join(cids, ","))
+	}
+
+	var out []PinStatus
+	for {
+		var res pinResults
+		if err := c.do(ctx, "GET", "/pins?"+q.Encode(), nil, &res); err != nil {
+			return nil, err
+		}
+		out = append(out, res.Results...)
+
+		if len(res.Results) == 0 || len(out) >= res.Count {
+			return out, nil
+		}
+		last := res.Results[len(res.Results)-1]
+		q.Set("before", last.Created.Format(time.RFC3339Nano))
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.key)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError builds an error from a failed response, using the reason
+// given by the service if there is one.
+func responseError(resp *http.Response) error {
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var e apiError
+	if err := json.Unmarshal(data, &e); err == nil && e.Error.Reason != "" {
+		if e.Error.Details != "" {
+			return fmt.Errorf("pinning service returned %s: %s: %s", resp.Status, e.Error.Reason, e.Error.Details)
+		}
+		return fmt.Errorf("pinning service returned %s: %s", resp.Status, e.Error.Reason)
+	}
+	return fmt.Errorf("pinning service returned %s", resp.Status)
+}
@@ -0,0 +1,137 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	var pins []PinStatus
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"reason":"UNAUTHORIZED","details":"bad key"}}`))
+			return
+		}
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/pins":
+			var p Pin
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ps := PinStatus{RequestID: "req1", Status: StatusQueued, Pin: p}
+			pins = append(pins, ps)
+			json.NewEncoder(w).Encode(&ps)
+		case r.Method == "GET" && r.URL.Path == "/api/pins":
+			if r.URL.Query().Get("status") != strings.Join(AllStatuses, ",") {
+				t.Errorf("unexpected status filter %q", r.URL.Query().Get("status"))
+			}
+			json.NewEncoder(w).Encode(&pinResults{Count: len(pins), Results: pins})
+		case r.Method == "GET" && r.URL.Path == "/api/pins/req1":
+			json.NewEncoder(w).Encode(&pins[0])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.URL+"/api/", "secret")
+
+	ps, err := c.Add(ctx, Pin{Cid: "QmFoo", Name: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.RequestID != "req1" || ps.Status != StatusQueued || ps.Pin.Cid != "QmFoo" {
+		t.Fatalf("unexpected pin status %+v", ps)
+	}
+	if ps.Done() {
+		t.Fatal("queued pin should not be done")
+	}
+
+	ps, err = c.Get(ctx, "req1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.Pin.Name != "foo" {
+		t.Fatalf("expected pin name foo, got %q", ps.Pin.Name)
+	}
+
+	list, err := c.Ls(ctx, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Pin.Cid != "QmFoo" {
+		t.Fatalf("unexpected pin list %+v", list)
+	}
+
+	if _, err := c.Get(ctx, "nope"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a 404 error, got %v", err)
+	}
+
+	_, err = NewClient(srv.URL+"/api", "wrong").Ls(ctx, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "UNAUTHORIZED: bad key") {
+		t.Fatalf("expected the service's reason in the error, got %v", err)
+	}
+}
+
+func TestClientLsPages(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var pins []PinStatus
+	for i := 0; i < 5; i++ {
+		// newest first, like the services return them
+		pins = append(pins, PinStatus{
+			RequestID: fmt.Sprint(i),
+			Status:    StatusPinned,
+			Created:   start.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page := pins
+		if before := r.URL.Query().Get("before"); before != "" {
+			bt, err := time.Parse(time.RFC3339Nano, before)
+			if err != nil {
+				t.Errorf("invalid before %q: %s", before, err)
+			}
+			page = nil
+			for _, ps := range pins {
+				if ps.Created.Before(bt) {
+					page = append(page, ps)
+				}
+			}
+		}
+		// a service with a lower page limit than asked for
+		if len(page) > 2 {
+			page = page[:2]
+		}
+		json.NewEncoder(w).Encode(&pinResults{Count: len(pins), Results: page})
+	}))
+	defer srv.Close()
+
+	list, err := NewClient(srv.URL, "secret").Ls(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(pins) {
+		t.Fatalf("expected %d pins, got %d", len(pins), len(list))
+	}
+	for i := range list {
+		if list[i].RequestID != pins[i].RequestID {
+			t.Fatalf("pin %d: expected request %s, got %s", i, pins[i].RequestID, list[i].RequestID)
+		}
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests)
+	}
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of problems reported by Check.
+const (
+	// ProblemUnknown is a key that is not part of the config schema, and
+	// is ignored when the config is loaded.
+	ProblemUnknown = "unknown"
+	// ProblemDeprecated is a key that was part of the schema in earlier
+	// versions, but is no longer used.
+	ProblemDeprecated = "deprecated"
+	// ProblemType is a value that does not have the type the schema
+	// expects for its key. Configs with such values fail to load.
+	ProblemType = "type"
+)
+
+// Problem is an issue found in a config by Check.
+type Problem struct {
+	Key     string
+	Kind    string
+	Message string
+}
+
+// deprecatedKeys maps the keys of sections that were dropped from the
+// config to the key replacing them, or to "" if there is no replacement.
+var deprecatedKeys = map[string]string{
+	"Log":     "",
+	"Version": "",
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Check compares the config m, as decoded from its JSON file, with the
+// schema defined by Config. It reports unknown keys, deprecated keys and
+// values of the wrong type, sorted by key. Like encoding/json, it matches
+// keys to fields case-insensitively.
+func Check(m map[string]interface{}) []Problem {
+	var ps []Problem
+	checkStruct(&ps, "", reflect.TypeOf(Config{}), m)
+	sort.Sort(problems(ps))
+	return ps
+}
+
+func checkStruct(ps *[]Problem, prefix string, t reflect.Type, m map[string]interface{}) {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields[strings.ToLower(name)] = f
+	}
+
+	for k, v := range m {
+		key := joinKey(prefix, k)
+
+		if repl, ok := deprecatedKeys[key]; ok {
+			msg := "deprecated key, no longer used"
+			if repl != "" {
+				msg = fmt.Sprintf("deprecated key, use %s instead", repl)
+			}
+			*ps = append(*ps, Problem{Key: key, Kind: ProblemDeprecated, Message: msg})
+			continue
+		}
+
+		f, ok := fields[strings.ToLower(k)]
+		if !ok {
+			*ps = append(*ps, Problem{Key: key, Kind: ProblemUnknown, Message: "unknown key"})
+			continue
+		}
+		checkValue(ps, key, f.Type, v)
+	}
+}
+
+func checkValue(ps *[]Problem, key string, t reflect.Type, v interface{}) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// null leaves the field untouched, and raw messages take any value.
+	if v == nil || t == rawMessageType || t.Kind() == reflect.Interface {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			typeProblem(ps, key, "an object", v)
+			return
+		}
+		checkStruct(ps, key, t, obj)
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			typeProblem(ps, key, "an object", v)
+			return
+		}
+		for k, ev := range obj {
+			checkValue(ps, joinKey(key, k), t.Elem(), ev)
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.([]interface{})
+		if !ok {
+			typeProblem(ps, key, "an array", v)
+			return
+		}
+		for i, ev := range arr {
+			checkValue(ps, fmt.Sprintf("%s[%d]", key, i), t.Elem(), ev)
+		}
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			typeProblem(ps, key, "a string", v)
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			typeProblem(ps, key, "a boolean", v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := v.(float64); !ok || f != math.Trunc(f) {
+			typeProblem(ps, key, "an integer", v)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := v.(float64); !ok || f != math.Trunc(f) || f < 0 {
+			typeProblem(ps, key, "a non-negative integer", v)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			typeProblem(ps, key, "a number", v)
+		}
+	}
+}
+
+func typeProblem(ps *[]Problem, key, expected string, v interface{}) {
+	*ps = append(*ps, Problem{
+		Key:     key,
+		Kind:    ProblemType,
+		Message: fmt.Sprintf("expected %s, got %s", expected, jsonKind(v)),
+	})
+}
+
+// jsonKind describes the JSON type of a decoded value.
+func jsonKind(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "an integer"
+		}
+		return "a number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func joinKey(prefix, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return prefix + "." + k
+}
+
+type problems []Problem
+
+func (p problems) Len() int           { return len(p) }
+func (p problems) Less(i, j int) bool { return p[i].Key < p[j].Key }
+func (p problems) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	var m map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"Identity": {"PeerID": "QmFoo"},
+		"datastore": {"StorageMax": "10GB", "NoSinc": true, "Params": {"any": 1}},
+		"Bootstrap": ["/ip4/1.2.3.4/tcp/4001", 5],
+		"Swarm": {"AddrFilters": "/ip4/10.0.0.0/ipcidr/8"},
+		"Discovery": {"MDNS": {"Enabled": "yes", "Interval": 1.5}},
+		"API": {"HTTPHeaders": {"X-Foo": ["bar"], "X-Bar": "baz"}},
+		"Pinning": {"RemoteServices": {"srv": {"Endpoint": "https://example.com", "Key": null}}},
+		"Log": {"MaxSizeMB": 250}
+	}`), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Problem{
+		{"API.HTTPHeaders.X-Bar", ProblemType, "expected an array, got a string"},
+		{"Bootstrap[1]", ProblemType, "expected a string, got an integer"},
+		{"Discovery.MDNS.Enabled", ProblemType, "expected a boolean, got a string"},
+		{"Discovery.MDNS.Interval", ProblemType, "expected an integer, got a number"},
+		{"Log", ProblemDeprecated, "deprecated key, no longer used"},
+		{"Swarm.AddrFilters", ProblemType, "expected an array, got a string"},
+		{"datastore.NoSinc", ProblemUnknown, "unknown key"},
+	}
+	if got := Check(m); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected problems:\n%v\ngot:\n%v", expected, got)
+	}
+}
+
+func TestCheckClean(t *testing.T) {
+	m, err := ToMap(&Config{Bootstrap: DefaultBootstrapAddresses})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps := Check(m); len(ps) != 0 {
+		t.Fatalf("expected no problems, got %v", ps)
+	}
+}
@@ -26,6 +26,8 @@ type Config struct {
 	SupernodeRouting SupernodeClientConfig // local node's routing servers (if SupernodeRouting enabled)
 	API              API                   // local node's API settings
 	Swarm            SwarmConfig
+	Peering          Peering // peers the node keeps connections to
+	Pinning          Pinning // remote pinning services
 
 	Reprovider   Reprovider
 	Experimental Experiments
@@ -71,6 +71,14 @@ func Init(out io.Writer, nBitsForKeypair int) (*Config, error) {
 		Reprovider: Reprovider{
 			Interval: "12h",
 		},
+		Swarm: SwarmConfig{
+			ConnMgr: ConnMgr{
+				Type:        ConnMgrBasic,
+				LowWater:    DefaultConnMgrLowWater,
+				HighWater:   DefaultConnMgrHighWater,
+				GracePeriod: DefaultConnMgrGracePeriod,
+			},
+		},
 	}
 
 	return conf, nil
@@ -0,0 +1,14 @@
+package config
+
+// Peering holds the peers the node keeps connections to.
+type Peering struct {
+	// Peers are peer addresses of the form /ip4/.../tcp/.../ipfs/<peer id>,
+	// like the Bootstrap addresses. Several addresses of the same peer may
+	// be listed.
+	Peers []string
+}
+
+// PeeringPeers returns the parsed peering addresses.
+func (c *Config) PeeringPeers() ([]BootstrapPeer, error) {
+	return ParseBootstrapPeers(c.Peering.Peers)
+}
@@ -0,0 +1,17 @@
+package config
+
+// Pinning holds the settings for pinning content on other services.
+type Pinning struct {
+	// RemoteServices are the remote pinning services known to the node,
+	// by name.
+	RemoteServices map[string]RemotePinningService `json:",omitempty"`
+}
+
+// RemotePinningService is a service implementing the IPFS pinning service
+// API.
+type RemotePinningService struct {
+	// Endpoint is the base URL of the API, e.g. https://pin.example.com/api/v1.
+	Endpoint string
+	// Key is the secret access token sent with every request.
+	Key string
+}
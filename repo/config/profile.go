@@ -0,0 +1,79 @@
+package config
+
+// Transformer is a function that adjusts a group of config settings.
+type Transformer func(c *Config) error
+
+// Profiles is a map of the built-in config profiles, which can be applied
+// with 'ipfs config profile apply'.
+var Profiles = map[string]Transformer{
+	// server turns off local network discovery, keeps the node from
+	// dialing private address ranges and bounds its connections, for nodes
+	// running in a datacenter.
+	"server": func(c *Config) error {
+		c.Discovery.MDNS.Enabled = false
+		c.Swarm.DisableNatPortMap = true
+		c.Swarm.ConnMgr = ConnMgr{
+			Type:        ConnMgrBasic,
+			LowWater:    DefaultConnMgrLowWater,
+			HighWater:   DefaultConnMgrHighWater,
+			GracePeriod: DefaultConnMgrGracePeriod,
+		}
+		c.Swarm.AddrFilters = appendNonDuplicate(c.Swarm.AddrFilters, []string{
+			"/ip4/10.0.0.0/ipcidr/8",
+			"/ip4/100.64.0.0/ipcidr/10",
+			"/ip4/169.254.0.0/ipcidr/16",
+			"/ip4/172.16.0.0/ipcidr/12",
+			"/ip4/192.0.0.0/ipcidr/24",
+			"/ip4/192.0.0.0/ipcidr/29",
+			"/ip4/192.0.0.8/ipcidr/32",
+			"/ip4/192.0.0.170/ipcidr/32",
+			"/ip4/192.0.0.171/ipcidr/32",
+			"/ip4/192.0.2.0/ipcidr/24",
+			"/ip4/192.168.0.0/ipcidr/16",
+			"/ip4/198.18.0.0/ipcidr/15",
+			"/ip4/198.51.100.0/ipcidr/24",
+			"/ip4/203.0.113.0/ipcidr/24",
+			"/ip4/240.0.0.0/ipcidr/4",
+		}...)
+		return nil
+	},
+
+	// lowpower reduces the background work the node does, for devices
+	// with little CPU, memory or bandwidth to spare.
+	"lowpower": func(c *Config) error {
+		c.Reprovider.Interval = "0"
+		c.Swarm.DisableBandwidthMetrics = true
+		c.Swarm.ConnMgr = ConnMgr{
+			Type:        ConnMgrBasic,
+			LowWater:    20,
+			HighWater:   40,
+			GracePeriod: "1m",
+		}
+		return nil
+	},
+
+	// test makes the node listen on random local ports only and keeps it
+	// from connecting to anything, for use in tests.
+	"test": func(c *Config) error {
+		c.Addresses.API = "/ip4/127.0.0.1/tcp/0"
+		c.Addresses.Gateway = "/ip4/127.0.0.1/tcp/0"
+		c.Addresses.Swarm = []string{"/ip4/127.0.0.1/tcp/0"}
+		c.Bootstrap = []string{}
+		c.Discovery.MDNS.Enabled = false
+		return nil
+	},
+}
+
+func appendNonDuplicate(slice []string, vals ...string) []string {
+	have := make(map[string]bool, len(slice))
+	for _, s := range slice {
+		have[s] = true
+	}
+	for _, v := range vals {
+		if !have[v] {
+			have[v] = true
+			slice = append(slice, v)
+		}
+	}
+	return slice
+}
@@ -4,4 +4,32 @@ type SwarmConfig struct {
 	AddrFilters             []string
 	DisableBandwidthMetrics bool
 	DisableNatPortMap       bool
+
+	ConnMgr ConnMgr
+}
+
+// Connection manager types.
+const (
+	// ConnMgrNone disables the connection manager.
+	ConnMgrNone = "none"
+	// ConnMgrBasic closes connections once there are more than HighWater
+	// of them, until LowWater are left.
+	ConnMgrBasic = "basic"
+)
+
+// Defaults of the connection manager, used for the fields left unset.
+const (
+	DefaultConnMgrLowWater    = 600
+	DefaultConnMgrHighWater   = 900
+	DefaultConnMgrGracePeriod = "20s"
+)
+
+// ConnMgr configures the connection manager, which keeps the number of open
+// connections of the node in check. An empty Type disables it, like "none";
+// Init writes "basic".
+type ConnMgr struct {
+	Type        string
+	LowWater    int
+	HighWater   int
+	GracePeriod string // connections younger than this are never closed
 }
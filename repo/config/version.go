@@ -3,6 +3,13 @@ package config
 // CurrentCommit is the current git commit, this is set as a ldflag in the Makefile
 var CurrentCommit string
 
+// BuildDate is the time the binary was built, this is set as a ldflag in the Makefile
+var BuildDate string
+
+// Dependencies lists the gx dependencies the binary was built with, as comma
+// separated name@version pairs, this is set as a ldflag in the Makefile
+var Dependencies string
+
 // CurrentVersionNumber is the current application's version literal
 const CurrentVersionNumber = "0.4.10-rc1"
 
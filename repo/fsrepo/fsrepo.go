@@ -97,6 +97,8 @@ type FSRepo struct {
 	ds       repo.Datastore
 	keystore keystore.Keystore
 	filemgr  *filestore.FileManager
+	// latency is only set when Datastore.MeasureLatency is enabled
+	latency *latencyDatastore
 }
 
 var _ repo.Repo = (*FSRepo)(nil)
@@ -376,6 +378,11 @@ func (r *FSRepo) openDatastore() error {
 	prefix := "ipfs.fsrepo.datastore"
 	r.ds = measure.New(prefix, r.ds)
 
+	if r.config.Datastore.MeasureLatency {
+		r.latency = newLatencyDatastore(r.ds)
+		r.ds = r.latency
+	}
+
 	return nil
 }
 
@@ -467,6 +474,42 @@ func (r *FSRepo) SetConfig(updated *config.Config) error {
 	return r.setConfigUnsynced(updated)
 }
 
+// BackupConfig copies the current config file to a new file next to it,
+// whose name starts with "config-" and the given prefix, and returns the
+// path of the copy.
+func (r *FSRepo) BackupConfig(prefix string) (string, error) {
+	packageLock.Lock()
+	defer packageLock.Unlock()
+
+	if r.closed {
+		return "", errors.New("repo is closed")
+	}
+
+	filename, err := config.Filename(r.path)
+	if err != nil {
+		return "", err
+	}
+
+	orig, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer orig.Close()
+
+	backup, err := ioutil.TempFile(r.path, "config-"+prefix)
+	if err != nil {
+		return "", err
+	}
+	defer backup.Close()
+
+	if _, err := io.Copy(backup, orig); err != nil {
+		os.Remove(backup.Name())
+		return "", err
+	}
+
+	return backup.Name(), nil
+}
+
 // GetConfigKey retrieves only the value of a particular key.
 func (r *FSRepo) GetConfigKey(key string) (interface{}, error) {
 	packageLock.Lock()
@@ -580,6 +623,21 @@ func (r *FSRepo) Datastore() repo.Datastore {
 	return d
 }
 
+// DatastoreLatency returns latency percentiles of the most recent
+// datastore operations performed through this FSRepo, keyed by operation
+// ("get", "put", "has" and "delete"). It returns ErrLatencyDisabled unless
+// Datastore.MeasureLatency is set in the config.
+func (r *FSRepo) DatastoreLatency() (map[string]OpLatency, error) {
+	packageLock.Lock()
+	l := r.latency
+	packageLock.Unlock()
+
+	if l == nil {
+		return nil, ErrLatencyDisabled
+	}
+	return l.summary(), nil
+}
+
 // GetStorageUsage computes the storage space taken by the repo in bytes
 func (r *FSRepo) GetStorageUsage() (uint64, error) {
 	pth, err := config.PathRoot()
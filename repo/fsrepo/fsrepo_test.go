@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-ipfs/repo/config"
 	"github.com/ipfs/go-ipfs/thirdparty/assert"
@@ -117,3 +118,29 @@ func TestOpenMoreThanOnceInSameProcess(t *testing.T) {
 	assert.Nil(r1.Close(), t)
 	assert.Nil(r2.Close(), t)
 }
+
+func TestLatencyRing(t *testing.T) {
+	var r latencyRing
+	if s := r.summary(); s.Samples != 0 || s.P99 != 0 {
+		t.Fatalf("expected empty summary, got %v", s)
+	}
+
+	// fill past capacity so the oldest samples (all 1h) get overwritten
+	for i := 0; i < latencySamples; i++ {
+		r.record(time.Hour)
+	}
+	for i := 1; i <= latencySamples; i++ {
+		r.record(time.Duration(i) * time.Millisecond)
+	}
+
+	s := r.summary()
+	if s.Samples != latencySamples {
+		t.Fatalf("expected %d samples, got %d", latencySamples, s.Samples)
+	}
+	if s.P50 != 512*time.Millisecond {
+		t.Fatalf("expected p50 of 512ms, got %s", s.P50)
+	}
+	if s.P99 != 1014*time.Millisecond {
+		t.Fatalf("expected p99 of 1014ms, got %s", s.P99)
+	}
+}
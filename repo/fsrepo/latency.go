@@ -0,0 +1,129 @@
+package fsrepo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	repo "github.com/ipfs/go-ipfs/repo"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+)
+
+// latencySamples is the number of recent durations kept per operation.
+const latencySamples = 1024
+
+// ErrLatencyDisabled is returned by DatastoreLatency when the repo was not
+// opened with Datastore.MeasureLatency set in its config.
+var ErrLatencyDisabled = errors.New("datastore latency measurement is disabled (set Datastore.MeasureLatency in the config)")
+
+// OpLatency summarizes the recent durations of one kind of datastore
+// operation.
+type OpLatency struct {
+	Samples int
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+}
+
+// latencyRing keeps the last latencySamples durations recorded.
+type latencyRing struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (r *latencyRing) record(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.samples) < latencySamples {
+		r.samples = append(r.samples, d)
+		return
+	}
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % latencySamples
+}
+
+func (r *latencyRing) summary() OpLatency {
+	r.mu.Lock()
+	sorted := make(durations, len(r.samples))
+	copy(sorted, r.samples)
+	r.mu.Unlock()
+
+	sort.Sort(sorted)
+	return OpLatency{
+		Samples: len(sorted),
+		P50:     sorted.percentile(50),
+		P95:     sorted.percentile(95),
+		P99:     sorted.percentile(99),
+	}
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// percentile returns the nearest-rank percentile p of the sorted durations.
+func (d durations) percentile(p int) time.Duration {
+	if len(d) == 0 {
+		return 0
+	}
+	rank := (p*len(d) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return d[rank-1]
+}
+
+// latencyDatastore records how long Get, Put, Has and Delete take on the
+// wrapped datastore. Queries and batches are passed through untimed.
+type latencyDatastore struct {
+	repo.Datastore
+
+	get, put, has, del latencyRing
+}
+
+func newLatencyDatastore(d repo.Datastore) *latencyDatastore {
+	return &latencyDatastore{Datastore: d}
+}
+
+func (l *latencyDatastore) Get(key ds.Key) (interface{}, error) {
+	start := time.Now()
+	v, err := l.Datastore.Get(key)
+	l.get.record(time.Since(start))
+	return v, err
+}
+
+func (l *latencyDatastore) Put(key ds.Key, value interface{}) error {
+	start := time.Now()
+	err := l.Datastore.Put(key, value)
+	l.put.record(time.Since(start))
+	return err
+}
+
+func (l *latencyDatastore) Has(key ds.Key) (bool, error) {
+	start := time.Now()
+	v, err := l.Datastore.Has(key)
+	l.has.record(time.Since(start))
+	return v, err
+}
+
+func (l *latencyDatastore) Delete(key ds.Key) error {
+	start := time.Now()
+	err := l.Datastore.Delete(key)
+	l.del.record(time.Since(start))
+	return err
+}
+
+func (l *latencyDatastore) summary() map[string]OpLatency {
+	return map[string]OpLatency{
+		"get":    l.get.summary(),
+		"put":    l.put.summary(),
+		"has":    l.has.summary(),
+		"delete": l.del.summary(),
+	}
+}
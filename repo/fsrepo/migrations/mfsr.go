@@ -2,15 +2,20 @@ package mfsr
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 const VersionFile = "version"
 
+// backupFiles are the files copied by Backup before running migrations.
+var backupFiles = []string{"config", VersionFile, "datastore_spec"}
+
 type RepoPath string
 
 func (rp RepoPath) VersionFile() string {
@@ -53,3 +58,47 @@ func (rp RepoPath) WriteVersion(version int) error {
 	fn := rp.VersionFile()
 	return ioutil.WriteFile(fn, []byte(fmt.Sprintf("%d\n", version)), 0644)
 }
+
+// Backup copies the repo's config and version files into a new directory
+// inside the repo, whose name starts with "migration-backup-", and returns
+// the path of that directory. Files that do not exist are skipped.
+func (rp RepoPath) Backup() (string, error) {
+	dir, err := ioutil.TempDir(string(rp), "migration-backup-")
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range backupFiles {
+		err := copyFile(filepath.Join(string(rp), name), filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
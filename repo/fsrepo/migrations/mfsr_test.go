@@ -3,6 +3,7 @@ package mfsr
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -41,3 +42,31 @@ func TestVersion(t *testing.T) {
 
 	assert.Err(rp.CheckVersion(1), t, "Should throw an error for the wrong version.")
 }
+
+func TestBackup(t *testing.T) {
+	rp := testVersionFile("backup", t)
+	defer os.RemoveAll(string(rp))
+
+	assert.Nil(rp.WriteVersion(4), t, "Trouble writing version")
+	err := ioutil.WriteFile(filepath.Join(string(rp), "config"), []byte("{}"), 0600)
+	assert.Nil(err, t, "Trouble writing config")
+
+	dir, err := rp.Backup()
+	assert.Nil(err, t, "Trouble backing up the repo")
+
+	v, err := RepoPath(dir).Version()
+	assert.Nil(err, t, "Trouble reading the backed up version")
+	if v != 4 {
+		t.Fatalf("backed up version is %d, expected 4", v)
+	}
+
+	fi, err := os.Stat(filepath.Join(dir, "config"))
+	assert.Nil(err, t, "Config was not backed up")
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("backed up config has mode %s, expected 0600", fi.Mode().Perm())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "datastore_spec")); !os.IsNotExist(err) {
+		t.Fatalf("missing files should not be backed up: %v", err)
+	}
+}
@@ -35,32 +35,11 @@ func migrationsBinName() string {
 }
 
 func RunMigration(newv int) error {
-	migrateBin := migrationsBinName()
-
 	fmt.Println("  => Looking for suitable fs-repo-migrations binary.")
 
-	var err error
-	migrateBin, err = exec.LookPath(migrateBin)
-	if err == nil {
-		// check to make sure migrations binary supports our target version
-		err = verifyMigrationSupportsVersion(migrateBin, newv)
-	}
-
+	migrateBin, err := FindMigrations(newv, os.Stdout)
 	if err != nil {
-		fmt.Println("  => None found, downloading.")
-
-		loc, err := GetMigrations()
-		if err != nil {
-			fmt.Println("  => Failed to download fs-repo-migrations.")
-			return err
-		}
-
-		err = verifyMigrationSupportsVersion(loc, newv)
-		if err != nil {
-			return fmt.Errorf("no fs-repo-migration binary found for version %d: %s", newv, err)
-		}
-
-		migrateBin = loc
+		return err
 	}
 
 	cmd := exec.Command(migrateBin, "-to", fmt.Sprint(newv), "-y")
@@ -80,6 +59,51 @@ func RunMigration(newv int) error {
 	return nil
 }
 
+// FindMigrations returns the path of an fs-repo-migrations binary that
+// supports migrating to version newv. If no suitable binary is found in the
+// PATH, the latest one is downloaded. Progress messages are written to
+// status.
+func FindMigrations(newv int, status io.Writer) (string, error) {
+	migrateBin, err := exec.LookPath(migrationsBinName())
+	if err == nil {
+		// check to make sure migrations binary supports our target version
+		err = verifyMigrationSupportsVersion(migrateBin, newv)
+	}
+	if err == nil {
+		return migrateBin, nil
+	}
+
+	fmt.Fprintln(status, "  => None found, downloading.")
+
+	loc, err := GetMigrations()
+	if err != nil {
+		fmt.Fprintln(status, "  => Failed to download fs-repo-migrations.")
+		return "", err
+	}
+
+	err = verifyMigrationSupportsVersion(loc, newv)
+	if err != nil {
+		return "", fmt.Errorf("no fs-repo-migration binary found for version %d: %s", newv, err)
+	}
+
+	return loc, nil
+}
+
+// RunMigrationStep uses the fs-repo-migrations binary at migrateBin to
+// migrate the repo at repoPath to version newv. The output of the binary is
+// included in the returned error if the migration fails.
+func RunMigrationStep(migrateBin, repoPath string, newv int) error {
+	cmd := exec.Command(migrateBin, "-to", fmt.Sprint(newv), "-y")
+	cmd.Env = append(os.Environ(), "IPFS_PATH="+repoPath)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("migration to version %d failed: %s\n%s", newv, err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
 func GetMigrations() (string, error) {
 	latest, err := GetLatestVersion(DistPath, migrations)
 	if err != nil {
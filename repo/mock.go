@@ -28,6 +28,10 @@ func (m *Mock) SetConfig(updated *config.Config) error {
 	return nil
 }
 
+func (m *Mock) BackupConfig(prefix string) (string, error) {
+	return "", errTODO
+}
+
 func (m *Mock) SetConfigKey(key string, value interface{}) error {
 	return errTODO
 }
@@ -19,6 +19,7 @@ var (
 type Repo interface {
 	Config() (*config.Config, error)
 	SetConfig(*config.Config) error
+	BackupConfig(prefix string) (string, error)
 
 	SetConfigKey(key string, value interface{}) error
 	GetConfigKey(key string) (interface{}, error)
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -205,6 +206,63 @@ func ExportTar(ctx context.Context, root *dag.ProtoNode, ds dag.DAGService) (io.
 	}, nil
 }
 
+// CatEntry returns a reader for the contents of the regular file at name
+// inside the tarchive rooted at root. Only the nodes along the way to the
+// entry are fetched, other entries of the archive are not loaded.
+func CatEntry(ctx context.Context, root *dag.ProtoNode, ds dag.DAGService, name string) (io.Reader, error) {
+	if string(root.Data()) != "ipfs/tar" {
+		return nil, errors.New("not an IPFS tarchive")
+	}
+
+	name = strings.Trim(name, "/")
+	if name == "" {
+		return nil, errors.New("no entry name given")
+	}
+
+	cur := root
+	for _, elem := range path.SplitList(escapePath(name)) {
+		nd, err := cur.GetLinkedProtoNode(ctx, ds, elem)
+		switch err {
+		case nil:
+		case dag.ErrLinkNotFound:
+			return nil, fmt.Errorf("%s: no such entry in tar archive", name)
+		default:
+			return nil, err
+		}
+		cur = nd
+	}
+
+	// directories that only exist as a prefix of other entries have no
+	// header of their own
+	if len(cur.Data()) == 0 {
+		return nil, fmt.Errorf("%s is a directory", name)
+	}
+
+	h, err := tar.NewReader(bytes.NewReader(cur.Data())).Next()
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid tar header: %s", name, err)
+	}
+	switch h.Typeflag {
+	case tar.TypeReg, tar.TypeRegA:
+	case tar.TypeDir:
+		return nil, fmt.Errorf("%s is a directory", name)
+	default:
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+
+	dataNd, err := cur.GetLinkedProtoNode(ctx, ds, "data")
+	switch err {
+	case nil:
+	case dag.ErrLinkNotFound:
+		// empty files have no data link
+		return bytes.NewReader(nil), nil
+	default:
+		return nil, err
+	}
+
+	return uio.NewDagReader(ctx, dataNd, ds)
+}
+
 type countReader struct {
 	r io.ReadCloser
 	n int
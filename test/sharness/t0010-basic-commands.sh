@@ -38,7 +38,15 @@ test_expect_success "ipfs version --all has all required fields" '
 	grep "go-ipfs version" version_all.txt &&
 	grep "Repo version" version_all.txt &&
 	grep "System version" version_all.txt &&
-	grep "Golang version" version_all.txt
+	grep "Golang version" version_all.txt &&
+	grep "Build date" version_all.txt &&
+	grep "Dependencies" version_all.txt
+'
+
+test_expect_success "ipfs version --enc=json has the build information" '
+	ipfs version --enc=json > version_json.txt &&
+	grep "\"Commit\":\"[^\"]" version_json.txt &&
+	grep "\"BuildDate\":\"[^\"]" version_json.txt
 '
 
 test_expect_success "ipfs help succeeds" '
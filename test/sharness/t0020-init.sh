@@ -122,6 +122,12 @@ test_expect_success "ipfs id agent string contains correct version" '
 	ipfs id -f "<aver>" | grep $(ipfs version -n)
 '
 
+test_expect_success "ipfs id reports no observed addrs while offline" '
+	ipfs id -f "<obsaddrs>" > obsaddrs_out &&
+	test_must_be_empty obsaddrs_out &&
+	ipfs id | grep "\"ObservedAddrs\": null"
+'
+
 test_expect_success "clean up ipfs dir" '
 	rm -rf "$IPFS_PATH"
 '
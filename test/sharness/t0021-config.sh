@@ -151,6 +151,38 @@ test_config_cmd() {
        echo "Error: setting private key with API is not supported" > replace_expected
        test_cmp replace_out replace_expected
   '
+
+  test_expect_success "'ipfs config replace --dry-run' lists changes" '
+       ipfs config show > dry_config &&
+       sed -i"~" -e s/11GB/12GB/ dry_config &&
+       ipfs config replace --dry-run dry_config > dry_out &&
+       echo "~ Datastore.StorageMax: \"11GB\" -> \"12GB\"" > dry_expected &&
+       test_cmp dry_expected dry_out
+  '
+
+  test_expect_success "'ipfs config replace --dry-run' did not write" '
+       grep "\"StorageMax\": \"11GB\"" "$IPFS_PATH/config"
+  '
+
+  test_expect_success "'ipfs config replace' rejects a malformed config" '
+       echo "{\"Identity\": " > bad_config &&
+       test_expect_code 1 ipfs config replace bad_config 2> replace_out &&
+       grep "failed to decode file as config" replace_out
+  '
+
+  test_expect_success "'ipfs config replace' rejects a config without PeerID" '
+       ipfs config show | grep -v "\"PeerID\"" > nopeer_config &&
+       cp "$IPFS_PATH/config" config_before &&
+       test_expect_code 1 ipfs config replace nopeer_config 2> replace_out &&
+       grep "invalid config: Identity.PeerID is missing" replace_out &&
+       test_cmp config_before "$IPFS_PATH/config"
+  '
+
+  test_expect_success "'ipfs config replace' rejects an unknown datastore" '
+       ipfs config show | sed -e "s/\"Type\": \"leveldb\"/\"Type\": \"nosuchds\"/" > badds_config &&
+       test_expect_code 1 ipfs config replace badds_config 2> replace_out &&
+       grep "invalid config: unknown Datastore.Type: nosuchds" replace_out
+  '
 }
 
 test_init_ipfs
@@ -158,6 +190,87 @@ test_init_ipfs
 # should work offline
 test_config_cmd
 
+test_expect_success "'ipfs config profile apply --dry-run' lists changes" '
+  cp "$IPFS_PATH/config" config_before_profile &&
+  ipfs config profile apply --dry-run server > profile_dry &&
+  grep "^~ Discovery.MDNS.Enabled: true -> false$" profile_dry &&
+  grep "^~ Swarm.AddrFilters: .* -> \[\"/ip4/10.0.0.0/ipcidr/8\"," profile_dry &&
+  test_must_fail grep "backed up" profile_dry &&
+  test_cmp config_before_profile "$IPFS_PATH/config"
+'
+
+test_expect_success "'ipfs config profile apply' applies the profile" '
+  ipfs config profile apply server > profile_out &&
+  grep "^~ Discovery.MDNS.Enabled: true -> false$" profile_out &&
+  echo false > mdns_expected &&
+  ipfs config Discovery.MDNS.Enabled > mdns_actual &&
+  test_cmp mdns_expected mdns_actual
+'
+
+test_expect_success "'ipfs config profile apply' backs up the previous config" '
+  BACKUP=$(sed -n "s/^previous config backed up to //p" profile_out) &&
+  test_cmp config_before_profile "$BACKUP"
+'
+
+test_expect_success "applying a profile twice changes nothing" '
+  ipfs config profile apply server > profile_again &&
+  echo "no changes" > profile_again_expected &&
+  test_cmp profile_again_expected profile_again
+'
+
+test_expect_success "'ipfs config profile apply' rejects unknown profiles" '
+  test_must_fail ipfs config profile apply nosuchprofile 2> profile_err &&
+  grep "nosuchprofile is not a profile" profile_err
+'
+
+test_expect_success "restore config" '
+  cp config_before_profile "$IPFS_PATH/config"
+'
+
+test_expect_success "the server profile sets connection manager watermarks" '
+  ipfs config --json Swarm.ConnMgr "{\"Type\": \"none\"}" &&
+  ipfs config profile apply --dry-run server > profile_connmgr &&
+  grep "^~ Swarm.ConnMgr.Type: \"none\" -> \"basic\"$" profile_connmgr &&
+  grep "^~ Swarm.ConnMgr.LowWater: 0 -> 600$" profile_connmgr &&
+  grep "^~ Swarm.ConnMgr.HighWater: 0 -> 900$" profile_connmgr &&
+  grep "^~ Swarm.ConnMgr.GracePeriod: \"\" -> \"20s\"$" profile_connmgr &&
+  cp config_before_profile "$IPFS_PATH/config"
+'
+
+test_expect_success "'ipfs config check' passes on a clean config" '
+  ipfs config check > check_out &&
+  grep "no problems found" check_out
+'
+
+test_expect_success "add unknown and deprecated keys to the config" '
+  cp "$IPFS_PATH/config" config_before_check &&
+  sed -e "s/\"Tour\": {/\"Log\": {}, \"Tuor\": {}, \"Tour\": {\"Lats\": \"\", /" \
+    config_before_check > "$IPFS_PATH/config"
+'
+
+test_expect_success "'ipfs config check' reports the problems" '
+  test_must_fail ipfs config check > check_out 2> check_err &&
+  grep "^Log: deprecated key, no longer used$" check_out &&
+  grep "^Tour.Lats: unknown key$" check_out &&
+  grep "^Tuor: unknown key$" check_out &&
+  grep "3 problems found" check_err
+'
+
+test_expect_success "'ipfs config check --enc=json' fails on problems too" '
+  test_must_fail ipfs config check --enc=json > check_json 2> check_err &&
+  grep "\"Key\":\"Tuor\"" check_json &&
+  grep "3 problems found" check_err
+'
+
+test_expect_success "'ipfs config check' did not modify the config" '
+  ipfs config show > /dev/null &&
+  grep Tuor "$IPFS_PATH/config"
+'
+
+test_expect_success "restore config" '
+  cp config_before_check "$IPFS_PATH/config"
+'
+
 # should work online
 test_launch_ipfs_daemon
 test_config_cmd
@@ -66,6 +66,37 @@ test_expect_success "mount directories can be removed after shutdown" '
 	rmdir ipfs ipns
 '
 
+test_expect_success FUSE "'ipfs mount --mfs' succeeds" '
+  mkdir "$(pwd)/ipfs" "$(pwd)/ipns" "$(pwd)/mfs" &&
+  ipfsi 0 mount -f "$(pwd)/ipfs" -n "$(pwd)/ipns" --mfs "$(pwd)/mfs" >actual
+'
+
+test_expect_success FUSE "'ipfs mount --mfs' output looks good" '
+  echo "IPFS mounted at: $(pwd)/ipfs" >expected &&
+  echo "IPNS mounted at: $(pwd)/ipns" >>expected &&
+  echo "MFS mounted at: $(pwd)/mfs" >>expected &&
+  test_cmp expected actual
+'
+
+test_expect_success FUSE "writes to the mfs mount show up in ipfs files" '
+  echo "mfs mount data" >"$(pwd)/mfs/written" &&
+  ipfsi 0 files read /written >files_read &&
+  echo "mfs mount data" >files_expected &&
+  test_cmp files_expected files_read
+'
+
+test_expect_success FUSE "ipfs files changes show up in the mfs mount" '
+  ipfsi 0 files mkdir /fromfiles &&
+  test -d "$(pwd)/mfs/fromfiles"
+'
+
+test_expect_success "unmount directories" '
+  do_umount "$(pwd)/ipfs" &&
+  do_umount "$(pwd)/ipns" &&
+  do_umount "$(pwd)/mfs" &&
+  rmdir ipfs ipns mfs
+'
+
 test_expect_success 'stop iptb' '
   iptb stop
 '
@@ -46,6 +46,60 @@ test_add_cat_file() {
     	test_cmp expected actual
     '
 
+    test_expect_success "ipfs cat --offset succeeds" '
+    	ipfs cat --offset 6 "$HASH" >actual
+    '
+
+    test_expect_success "ipfs cat --offset output looks good" '
+    	echo "Worlds!" >expected &&
+    	test_cmp expected actual
+    '
+
+    test_expect_success "ipfs cat --offset --length succeeds" '
+    	ipfs cat --offset 6 --length 3 "$HASH" >actual
+    '
+
+    test_expect_success "ipfs cat --offset --length output looks good" '
+    	printf "Wor" >expected &&
+    	test_cmp expected actual
+    '
+
+    test_expect_success "ipfs cat --length=0 outputs nothing" '
+    	ipfs cat --length 0 "$HASH" >actual &&
+    	test_must_be_empty actual
+    '
+
+    test_expect_success "ipfs cat with offset past the end fails" '
+    	test_must_fail ipfs cat --offset 100 "$HASH" 2>cat_err &&
+    	grep "exceeds total size" cat_err
+    '
+
+    test_expect_success "ipfs cat with negative length fails" '
+    	test_must_fail ipfs cat --length=-1 "$HASH"
+    '
+
+    test_expect_success "ipfs cat concatenates several paths in order" '
+    	echo "Second file" >mountdir/second.txt &&
+    	HASH2=$(ipfs add -q mountdir/second.txt) &&
+    	ipfs cat "$HASH2" "$HASH" "$HASH2" >actual &&
+    	cat mountdir/second.txt mountdir/hello.txt mountdir/second.txt >expected &&
+    	test_cmp expected actual
+    '
+
+    test_expect_success "ipfs cat fails without output if a path is bad" '
+    	test_must_fail ipfs cat "$HASH" /ipfs/invalid "$HASH2" >actual 2>cat_err &&
+    	test_must_be_empty actual &&
+    	grep "Error" cat_err
+    '
+
+    test_expect_success "ipfs cat --continue-on-error skips bad paths" '
+    	test_must_fail ipfs cat --continue-on-error "$HASH" /ipfs/invalid "$HASH2" >actual 2>cat_err &&
+    	cat mountdir/hello.txt mountdir/second.txt >expected &&
+    	test_cmp expected actual &&
+    	grep "skipped 1 of 3 paths" cat_err &&
+    	grep "/ipfs/invalid" cat_err
+    '
+
     test_expect_success "ipfs cat /ipfs/file succeeds" '
     	ipfs cat /ipfs/$HASH >actual
     '
@@ -84,6 +138,71 @@ test_add_cat_file() {
         echo "added $HASH .hello.txt" >expected &&
         test_cmp expected actual
     '
+
+    test_expect_success "create a directory with files to ignore" '
+        rm -rf ignoredir &&
+        mkdir -p ignoredir/node_modules ignoredir/sub ignoredir/.git &&
+        echo a >ignoredir/a.txt &&
+        echo b >ignoredir/b.log &&
+        echo keep >ignoredir/keep.log &&
+        echo x >ignoredir/node_modules/x.js &&
+        echo c >ignoredir/sub/c.txt &&
+        echo d >ignoredir/sub/d.log &&
+        echo git >ignoredir/.git/config &&
+        echo hidden >ignoredir/.hidden
+    '
+
+    test_expect_success "ipfs add --ignore skips matching paths" '
+        HASH_IGN=$(ipfs add -r -Q --ignore="node_modules/,*.log,!keep.log" ignoredir) &&
+        ipfs ls $HASH_IGN >ls_out &&
+        grep " a.txt$" ls_out &&
+        grep " keep.log$" ls_out &&
+        grep " sub/$" ls_out &&
+        test_must_fail grep "b.log" ls_out &&
+        test_must_fail grep "node_modules" ls_out &&
+        test_must_fail grep "git\|hidden" ls_out &&
+        ipfs ls $HASH_IGN/sub >ls_out &&
+        grep " c.txt$" ls_out &&
+        test_must_fail grep "d.log" ls_out
+    '
+
+    test_expect_success "ignored paths are not part of the dag" '
+        rm -rf ignoredir_exp &&
+        cp -r ignoredir ignoredir_exp &&
+        rm -r ignoredir_exp/node_modules ignoredir_exp/b.log ignoredir_exp/sub/d.log &&
+        HASH_EXP=$(ipfs add -r -Q ignoredir_exp) &&
+        test "$HASH_IGN" = "$HASH_EXP"
+    '
+
+    test_expect_success "ipfs add --ignore-rules-path reads patterns from a file" '
+        printf "# text files\n*.txt\n" >ignorerules &&
+        HASH_IGN=$(ipfs add -r -Q --ignore-rules-path=ignorerules --ignore="!sub/c.txt" ignoredir) &&
+        ipfs ls $HASH_IGN >ls_out &&
+        test_must_fail grep "a.txt" ls_out &&
+        grep " b.log$" ls_out &&
+        ipfs ls $HASH_IGN/sub >ls_out &&
+        grep " c.txt$" ls_out
+    '
+
+    test_expect_success "ipfs add --hidden works with --ignore" '
+        HASH_IGN=$(ipfs add -r -Q -H --ignore=.git/ ignoredir) &&
+        ipfs ls $HASH_IGN >ls_out &&
+        grep " .hidden$" ls_out &&
+        test_must_fail grep " .git/$" ls_out
+    '
+
+    test_expect_success "ipfs add --ignore takes escaped commas" '
+        echo comma >"ignoredir/a,b.txt" &&
+        HASH_IGN=$(ipfs add -r -Q --ignore="a\\,b.txt,*.log" ignoredir) &&
+        rm "ignoredir/a,b.txt" &&
+        ipfs ls $HASH_IGN >ls_out &&
+        grep " a.txt$" ls_out &&
+        test_must_fail grep "a,b.txt\|b.log" ls_out
+    '
+
+    test_expect_success "ipfs add fails with a missing rules file" '
+        test_must_fail ipfs add -r --ignore-rules-path=nosuchfile ignoredir
+    '
 }
 
 test_add_cat_5MB() {
@@ -117,6 +236,26 @@ test_add_cat_5MB() {
     	test_cmp mountdir/bigfile actual
     '
 
+    test_expect_success "'ipfs cat' shows no progress bar for outputs under 8MiB" '
+    	ipfs cat "$EXP_HASH" >actual 2>progress_err &&
+    	test_must_be_empty progress_err
+    '
+
+    test_expect_success "'ipfs cat --progress' succeeds" '
+    	ipfs cat --progress "$EXP_HASH" >actual 2>progress_err
+    '
+
+    test_expect_success "'ipfs cat --progress' writes progress to stderr only" '
+    	test_cmp mountdir/bigfile actual &&
+    	test -s progress_err
+    '
+
+    test_expect_success "'ipfs cat --progress=false' shows no progress bar" '
+    	ipfs cat --progress=false "$EXP_HASH" >actual 2>progress_err &&
+    	test_must_be_empty progress_err &&
+    	test_cmp mountdir/bigfile actual
+    '
+
     test_expect_success FUSE "cat ipfs/bigfile succeeds" '
     	cat "ipfs/$EXP_HASH" >actual
     '
@@ -187,6 +326,68 @@ test_add_cat_expensive() {
     '
 }
 
+test_add_preserve() {
+	test_expect_success "create files with mode and mtime" '
+		rm -rf pdir pout &&
+		mkdir pdir &&
+		echo "#!/bin/sh" >pdir/run &&
+		chmod 750 pdir/run &&
+		chmod 750 pdir &&
+		TZ=UTC touch -t 201707140240.00 pdir/run pdir mtime_ref
+	'
+
+	test_expect_success "ipfs add ignores mode and mtime by default" '
+		echo "#!/bin/sh" | ipfs add -Q >plain_hash &&
+		ipfs add -Q pdir/run >default_hash &&
+		test_cmp plain_hash default_hash
+	'
+
+	test_expect_success "ipfs add --preserve-mode changes the hash" '
+		ipfs add -Q --preserve-mode pdir/run >mode_hash &&
+		test_must_fail test_cmp plain_hash mode_hash
+	'
+
+	test_expect_success "ipfs add --preserve-mode --preserve-mtime -r succeeds" '
+		ipfs add -Q -r --preserve-mode --preserve-mtime pdir >pdir_hash
+	'
+
+	test_expect_success "ipfs get applies the preserved metadata" '
+		ipfs get -o pout $(cat pdir_hash) &&
+		echo "-rwxr-x---" >mode_expected &&
+		generic_stat pout/run >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		echo "drwxr-x---" >mode_expected &&
+		generic_stat pout >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		test ! pout/run -nt mtime_ref &&
+		test ! mtime_ref -nt pout/run &&
+		test ! pout -nt mtime_ref &&
+		test ! mtime_ref -nt pout
+	'
+
+	test_expect_success "ipfs add --preserve-mtime leaves the mode out" '
+		rm -rf pout &&
+		ipfs get -o pout $(ipfs add -Q --preserve-mtime pdir/run) &&
+		echo "-rw-r--r--" >mode_expected &&
+		generic_stat pout >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		test ! pout -nt mtime_ref &&
+		test ! mtime_ref -nt pout
+	'
+
+	test_expect_success "ipfs add --preserve-mode works with raw leaves" '
+		rm -rf pout &&
+		ipfs get -o pout $(ipfs add -Q --raw-leaves --preserve-mode pdir/run) &&
+		echo "-rwxr-x---" >mode_expected &&
+		generic_stat pout >mode_actual &&
+		test_cmp mode_expected mode_actual
+	'
+
+	test_expect_success "clean up" '
+		rm -rf pdir pout
+	'
+}
+
 test_add_named_pipe() {
     err_prefix=$1
     test_expect_success "useful error message when adding a named pipe" '
@@ -482,6 +683,17 @@ test_expect_success "ipfs add --cid-version=9 fails" '
 	grep -q "unknown CID version" add_out
 '
 
+test_expect_success "ipfs add --hash=unknown fails" '
+	echo "context" > afile.txt &&
+	test_must_fail ipfs add --hash=not-a-hash afile.txt 2>&1 | tee add_out &&
+	grep -q "unrecognized hash function: not-a-hash" add_out
+'
+
+test_expect_success "ipfs add --hash=sha3-512 outputs a CIDv1" '
+	ipfs add -q --hash=sha3-512 afile.txt > sha3_hash &&
+	grep -q "^z" sha3_hash
+'
+
 test_kill_ipfs_daemon
 
 # should work offline
@@ -499,15 +711,53 @@ test_expect_success "ipfs cat file fails" '
     test_must_fail ipfs cat $(cat oh_hash)
 '
 
+test_expect_success "ipfs add --only-hash --cid-version=1 succeeds" '
+    echo "unknown content for only-hash cidv1" | ipfs add --only-hash --cid-version=1 -q > oh_hash_v1
+'
+
+test_expect_success "ipfs add --only-hash --cid-version=1 outputs a CIDv1" '
+    grep -q "^z" oh_hash_v1
+'
+
+test_expect_success "ipfs add --only-hash --cid-version=1 did not write blocks" '
+    test_must_fail ipfs block stat $(cat oh_hash_v1)
+'
+
+test_expect_success "ipfs add --to-files succeeds" '
+    echo "content for to-files" > tf_file &&
+    ipfs add --to-files=/tf/dir/file tf_file > tf_out &&
+    TF_HASH=$(ipfs add -q --only-hash tf_file)
+'
+
+test_expect_success "ipfs add --to-files output looks good" '
+    echo "added $TF_HASH tf_file" > tf_expected &&
+    echo "copied $TF_HASH to /tf/dir/file" >> tf_expected &&
+    test_cmp tf_expected tf_out
+'
+
+test_expect_success "ipfs files read shows the added file" '
+    ipfs files read /tf/dir/file > tf_read &&
+    test_cmp tf_file tf_read
+'
+
+test_expect_success "ipfs add --to-files with --only-hash fails" '
+    test_must_fail ipfs add --only-hash --to-files=/tf/other tf_file 2>tf_err &&
+    grep -- "'"'"'--to-files'"'"' cannot be used with '"'"'--only-hash'"'"'" tf_err
+'
+
 test_add_named_pipe ""
 
 test_add_pwd_is_symlink
 
+test_add_preserve
+
 # Test daemon in offline mode
 test_launch_ipfs_daemon --offline
 
 test_add_cat_file
 
+test_add_preserve
+
 test_kill_ipfs_daemon
 
 test_done
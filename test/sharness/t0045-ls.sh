@@ -88,6 +88,69 @@ test_ls_cmd() {
 		EOF
 		test_cmp expected_ls_headers actual_ls_headers
 	'
+
+	test_expect_success "'ipfs ls --size --headers <dir hash>' succeeds" '
+		ipfs ls --size --headers QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj >actual_ls_size
+	'
+
+	test_expect_success "'ipfs ls --size --headers <dir hash>' output looks good" '
+		cat <<-\EOF >expected_ls_size &&
+			Hash                                           Size Type Name
+			QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss 246  dir  d1/
+			QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy 1143 dir  d2/
+			QmeomffUNfmQy76CQGy9NdmqEnnHU9soCexBnGU3ezPHVH 13   file f1
+			QmNtocSs7MoDkJMc1RkyisCSKvLadujPsfJfSdJ3e1eA1M 13   file f2
+		EOF
+		test_cmp expected_ls_size actual_ls_size
+	'
+
+	test_expect_success "'ipfs ls --size' reports cumulative sizes in JSON" '
+		ipfs ls --size --enc=json QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss >actual_ls_json &&
+		grep "\"CumulativeSize\":139" actual_ls_json &&
+		ipfs ls --enc=json QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss >actual_ls_json &&
+		test_must_fail grep CumulativeSize actual_ls_json
+	'
+
+	test_expect_success "'ipfs ls --stream <two dir hashes>' output looks good" '
+		ipfs ls --stream QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss >actual_ls_stream &&
+		cat <<-\EOF >expected_ls_stream &&
+			QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj:
+			QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss 246 d1/
+			QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy 1143 d2/
+			QmeomffUNfmQy76CQGy9NdmqEnnHU9soCexBnGU3ezPHVH 13 f1
+			QmNtocSs7MoDkJMc1RkyisCSKvLadujPsfJfSdJ3e1eA1M 13 f2
+
+			QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss:
+			QmQNd6ubRXaNG6Prov8o6vk3bn6eWsj9FxLGrAVDUAGkGe 139 128
+			QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN 14 a
+		EOF
+		test_cmp expected_ls_stream actual_ls_stream
+	'
+
+	test_expect_success "'ipfs ls --resolve=false --headers' prints hashes and names" '
+		ipfs ls --resolve=false --headers QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss >actual_ls_noresolve &&
+		cat <<-\EOF >expected_ls_noresolve &&
+			Hash                                           Name
+			QmQNd6ubRXaNG6Prov8o6vk3bn6eWsj9FxLGrAVDUAGkGe 128
+			QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN a
+		EOF
+		test_cmp expected_ls_noresolve actual_ls_noresolve
+	'
+
+	test_expect_success "'ipfs ls --stream --resolve=false' works" '
+		ipfs ls --stream --resolve=false QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj >actual_ls_stream &&
+		cat <<-\EOF >expected_ls_stream &&
+			QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss d1
+			QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy d2
+			QmeomffUNfmQy76CQGy9NdmqEnnHU9soCexBnGU3ezPHVH f1
+			QmNtocSs7MoDkJMc1RkyisCSKvLadujPsfJfSdJ3e1eA1M f2
+		EOF
+		test_cmp expected_ls_stream actual_ls_stream
+	'
+
+	test_expect_success "'ipfs ls --size --resolve=false' fails" '
+		test_must_fail ipfs ls --size --resolve=false QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj
+	'
 }
 
 test_ls_cmd_raw_leaves() {
@@ -99,6 +162,19 @@ test_ls_cmd_raw_leaves() {
 		echo "zb2rhf6GzX4ckKZtjy8yy8iyq1KttCrRyqDedD6xubhY3sw2F 4 foo" > ls-expect
 		test_cmp ls-actual ls-expect
 	'
+
+	test_expect_success "'ipfs ls --size' shows raw leaves as files" '
+		ipfs ls --size QmThNTdtKaVoCVrYmM5EBS6U3S5vfKFue2TxbxxAxRcKKE > ls-actual &&
+		echo "zb2rhf6GzX4ckKZtjy8yy8iyq1KttCrRyqDedD6xubhY3sw2F 4 file foo" > ls-expect &&
+		test_cmp ls-actual ls-expect
+	'
+
+	test_expect_success "'ipfs ls' keeps the JSON type of raw leaves" '
+		ipfs ls --enc=json QmThNTdtKaVoCVrYmM5EBS6U3S5vfKFue2TxbxxAxRcKKE > ls-json &&
+		grep "\"Type\":-1" ls-json &&
+		ipfs ls --size --enc=json QmThNTdtKaVoCVrYmM5EBS6U3S5vfKFue2TxbxxAxRcKKE > ls-json &&
+		grep "\"Type\":2" ls-json
+	'
 }
 
 # should work offline
@@ -141,6 +217,11 @@ test_expect_success "'ipfs ls' fails" '
 	test_must_fail ipfs ls $DIR
 '
 
+test_expect_success "'ipfs ls --resolve=false' does not need the linked blocks" '
+	ipfs ls --resolve=false $DIR > ls_noresolve &&
+	grep "^$FILE file1$" ls_noresolve
+'
+
 test_launch_ipfs_daemon --offline
 
 test_expect_success "'ipfs ls --resolve-type=false' ok" '
@@ -52,6 +52,32 @@ test_expect_success "'ipfs block stat' output looks good" '
   test_cmp expected_stat actual_stat
 '
 
+test_expect_success "'ipfs block stat --verbose' shows unpinned blocks" '
+  ipfs block stat --verbose $HASH >actual_stat_v &&
+  echo "Pinned: not pinned" >>expected_stat &&
+  test_cmp expected_stat actual_stat_v
+'
+
+test_expect_success "'ipfs block stat --verbose' shows direct pins" '
+  ipfs pin add --recursive=false $HASH &&
+  ipfs block stat --verbose $HASH >actual_stat_v &&
+  grep "Pinned: direct" actual_stat_v &&
+  ipfs pin rm --recursive=false $HASH
+'
+
+test_expect_success "'ipfs block stat --verbose' shows recursive and indirect pins" '
+  mkdir -p statdir &&
+  echo "stat me" >statdir/file &&
+  STATDIR=$(ipfs add -r -Q statdir) &&
+  STATFILE=$(ipfs add -Q --pin=false statdir/file) &&
+  ipfs block stat -v $STATDIR >actual_stat_dir &&
+  grep "Pinned: recursive" actual_stat_dir &&
+  ipfs block stat -v $STATFILE >actual_stat_file &&
+  grep "Pinned: indirect" actual_stat_file &&
+  grep "Pinned via: $STATDIR" actual_stat_file &&
+  ipfs pin rm $STATDIR
+'
+
 #
 # "block rm" tests
 #
@@ -169,6 +195,39 @@ test_expect_success "multi-block 'ipfs block rm -q' produces no output" '
   test ! -s block_rm_out
 '
 
+test_expect_success "add and pin a file" '
+  FORCEHASH=$(echo "pinned block" | ipfs add -q) &&
+  ipfs pin ls $FORCEHASH
+'
+
+test_expect_success "'ipfs block rm -q' still reports pinned blocks" '
+  test_must_fail ipfs block rm -q $FORCEHASH > block_rm_out 2> block_rm_err &&
+  test ! -s block_rm_out &&
+  grep -q "$FORCEHASH: pinned: recursive" block_rm_err &&
+  ipfs block stat $FORCEHASH
+'
+
+test_expect_success "'ipfs block rm -f' removes pinned blocks" '
+  ipfs block rm -f $FORCEHASH > block_rm_out &&
+  echo "removed $FORCEHASH" > block_rm_exp &&
+  test_cmp block_rm_exp block_rm_out &&
+  test_must_fail ipfs block stat $FORCEHASH
+'
+
+test_expect_success "clean up pin of removed block" '
+  ipfs pin rm $FORCEHASH
+'
+
+# the empty node is linked from every pin set
+PINSET_EMPTY=QmdfTbBqBPQ7VNxZEYEj14VmRuZBkqFbiwReogJgS1zR1n
+
+test_expect_success "'ipfs block rm -f' keeps the blocks of the pin sets" '
+  test_must_fail ipfs block rm -f $PINSET_EMPTY 2> block_rm_err &&
+  grep -q "$PINSET_EMPTY: block is part of the pin state" block_rm_err &&
+  ipfs block stat $PINSET_EMPTY &&
+  ipfs pin ls --type=recursive
+'
+
 test_expect_success "can set cid format on block put" '
 	HASH=$(ipfs block put --format=protobuf ../t0051-object-data/testPut.pb)
 '
@@ -197,6 +256,34 @@ test_expect_success "can read block with different hash" '
 	echo "foooo" > blk_get_exp &&
 	test_cmp blk_get_exp blk_get_out
 '
+
+test_expect_success "can put a dag-cbor block with blake2b" '
+	echo "{\"data\":\"foo\"}" | ipfs dag put > cbor_hash &&
+	ipfs block get $(cat cbor_hash) > cbor_block &&
+	HASH=$(ipfs block put --format=dag-cbor --mhtype=blake2b-256 < cbor_block)
+'
+
+test_expect_success "dag-cbor block is readable through dag get" '
+	ipfs dag get $HASH > dag_get_out &&
+	ipfs dag get $(cat cbor_hash) > dag_get_exp &&
+	test_cmp dag_get_exp dag_get_out
+'
+
+test_expect_success "non sha2-256 multihash on block put yields a CIDv1" '
+	HASH=$(echo "foooo" | ipfs block put --mhtype=sha3-256) &&
+	echo "$HASH" | grep -q "^z"
+'
+
+test_expect_success "block put rejects an oversized mhlen" '
+	echo "foooo" | test_expect_code 1 ipfs block put --mhtype=sha2-256 --mhlen=33 2> mhlen_err &&
+	grep "invalid multihash length 33" mhlen_err
+'
+
+test_expect_success "block put rejects an unknown format" '
+	echo "foooo" | test_expect_code 1 ipfs block put --format=nope 2> format_err &&
+	grep "unrecognized format: nope" format_err
+'
+
 #
 # Misc tests
 #
@@ -267,6 +267,34 @@ test_object_cmd() {
 		test_must_fail ipfs object patch $EMPTY add-link --create / $FILE
 	'
 
+	test_expect_success "create dir to filter links of" '
+		ONE=$(ipfs object patch $EMPTY add-link a.jpg $FILE) &&
+		TWO=$(ipfs object patch $ONE add-link b.png $FILE) &&
+		FILTERDIR=$(ipfs object patch $TWO add-link c.jpg $FILE)
+	'
+
+	test_expect_success "'ipfs object get --link-filter' succeeds" '
+		ipfs object get --link-filter="*.jpg" $FILTERDIR > filter_out
+	'
+
+	test_expect_success "only matching links are output" '
+		grep "\"Name\":\"a.jpg\"" filter_out &&
+		grep "\"Name\":\"c.jpg\"" filter_out &&
+		test_must_fail grep b.png filter_out &&
+		test_must_fail grep "\"Data\":\"\"" filter_out
+	'
+
+	test_expect_success "'ipfs object get --no-data' leaves out the data" '
+		ipfs object get --link-filter=b.png --no-data $FILTERDIR > nodata_out &&
+		echo "{\"Links\":[{\"Name\":\"b.png\",\"Hash\":\"$FILE\",\"Size\":$(ipfs object stat $FILE | sed -n "s/^CumulativeSize: //p")}],\"Data\":\"\"}" > nodata_exp &&
+		test_cmp nodata_exp nodata_out
+	'
+
+	test_expect_success "'ipfs object get' with a bad link filter fails" '
+		test_must_fail ipfs object get --link-filter="[" $FILTERDIR 2> badfilter_err &&
+		grep "invalid link filter" badfilter_err
+	'
+
 	test_expect_success "patch set-data works" '
 		EMPTY=$(ipfs object new) &&
 		HASH=$(printf "foo" | ipfs object patch $EMPTY set-data)
@@ -287,6 +315,18 @@ test_object_cmd() {
 		ipfs object get $HASH > actual_data_append &&
 		test_cmp exp_data_append actual_data_append
 	'
+
+	test_expect_success "patch append-data with no data returns the same hash" '
+		SAME=$(printf "" | ipfs object patch $HASH append-data) &&
+		test "$SAME" = "$HASH"
+	'
+
+	test_expect_success "patch append-data streams larger data" '
+		random 500000 7 > bigdata &&
+		BIG=$(ipfs object patch $EMPTY append-data < bigdata) &&
+		ipfs object data $BIG > bigdata_actual &&
+		test_cmp bigdata bigdata_actual
+	'
 }
 
 test_object_content_type() {
@@ -77,4 +77,32 @@ test_expect_success "diff looks right" '
 	test_cmp diff_exp diff_out
 '
 
+test_expect_success "non-recursive diff of nested add works" '
+	BAZ_B=$(ipfs resolve /ipfs/$B/baz | cut -d/ -f3) &&
+	BAZ_C=$(ipfs resolve /ipfs/$C/baz | cut -d/ -f3) &&
+	ipfs object diff --recursive=false $B $C > diff_out
+'
+
+test_expect_success "non-recursive diff looks right" '
+	echo "~ $BAZ_B $BAZ_C \"baz\"" > diff_exp &&
+	test_cmp diff_exp diff_out
+'
+
+test_expect_success "diff of objects with different data works" '
+	BAR_C=$(ipfs resolve /ipfs/$C/bar | cut -d/ -f3) &&
+	BAR_D=$(ipfs resolve /ipfs/$D/bar | cut -d/ -f3) &&
+	ipfs object diff -v $BAR_C $BAR_D > diff_out
+'
+
+test_expect_success "diff reports data change" '
+	head -n1 diff_out > diff_first &&
+	echo "Object data differs." > diff_exp &&
+	test_cmp diff_exp diff_first
+'
+
+test_expect_success "directories with the same data do not report a data change" '
+	ipfs object diff $C $D > diff_out &&
+	test_must_fail grep "~ data" diff_out
+'
+
 test_done
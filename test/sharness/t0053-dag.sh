@@ -68,10 +68,97 @@ test_dag_cmd() {
 		test_cmp sub5_exp sub5
 	'
 
+	test_expect_success "can get object as dag-cbor" '
+		ipfs dag get --output-codec=dag-cbor $IPLDHASH > ipld_cbor
+	'
+
+	test_expect_success "dag-cbor output round-trips" '
+		CBORHASH=$(ipfs dag put --input-enc=raw < ipld_cbor) &&
+		test $CBORHASH = $IPLDHASH
+	'
+
+	test_expect_success "unknown output codec fails" '
+		test_must_fail ipfs dag get --output-codec=nope $IPLDHASH
+	'
+
+	test_expect_success "dag-cbor output of a dag-pb node fails" '
+		test_must_fail ipfs dag get --output-codec=dag-cbor $HASH1
+	'
+
+	test_expect_success "dag stat succeeds" '
+		ipfs dag stat $IPLDHASH > dag_stat
+	'
+
+	test_expect_success "dag stat output looks right" '
+		grep "NumBlocks: 4" dag_stat &&
+		grep "MaxDepth: 1" dag_stat
+	'
+
 	test_expect_success "can pin cbor object" '
 		ipfs pin add $EXPHASH
 	'
 
+	test_expect_success "dag resolve prints the last block and the rest of the path" '
+		ipfs dag resolve $IPLDHASH > resolve1 &&
+		ipfs dag resolve $IPLDHASH/sub/beep/1 > resolve2 &&
+		ipfs dag resolve $IPLDHASH/cats/0 > resolve3 &&
+		ipfs dag resolve $IPLDHASH/cats/1/water > resolve4 &&
+		echo "$IPLDHASH" > resolve1_exp &&
+		test_cmp resolve1_exp resolve1 &&
+		echo "$IPLDHASH/sub/beep/1" > resolve2_exp &&
+		test_cmp resolve2_exp resolve2 &&
+		echo "$HASH1" > resolve3_exp &&
+		test_cmp resolve3_exp resolve3 &&
+		echo "$HASH2" > resolve4_exp &&
+		test_cmp resolve4_exp resolve4
+	'
+
+	test_expect_success "dag resolve fails for missing paths" '
+		test_must_fail ipfs dag resolve $IPLDHASH/sub/nope
+	'
+
+	test_expect_success "dag resolve and dag get resolve /ipns/ names" '
+		PEERID=$(ipfs id -f="<id>") &&
+		ipfs name publish "/ipfs/$IPLDHASH" &&
+		ipfs dag resolve "/ipns/$PEERID/sub/beep/1" > resolve_ipns &&
+		test_cmp resolve2_exp resolve_ipns &&
+		ipfs dag resolve "/ipns/$PEERID/cats/0" > resolve_ipns &&
+		test_cmp resolve3_exp resolve_ipns &&
+		ipfs dag get "/ipns/$PEERID/sub/beep/1" > get_ipns &&
+		ipfs dag get "$IPLDHASH/sub/beep/1" > get_ipns_exp &&
+		test_cmp get_ipns_exp get_ipns
+	'
+
+	test_expect_success "dag walk prints every node by default" '
+		ipfs dag walk $IPLDHASH | sort > walk_all &&
+		printf "%s\n" $IPLDHASH $HASH1 $HASH2 $HASH3 | sort > walk_all_exp &&
+		test_cmp walk_all_exp walk_all
+	'
+
+	test_expect_success "dag walk follows only matching links" '
+		ipfs dag walk --selector="cats/*" $IPLDHASH > walk_cats &&
+		echo $HASH1 > walk_cats_exp &&
+		test_cmp walk_cats_exp walk_cats &&
+		ipfs dag walk --selector="cats/*/water" $IPLDHASH > walk_water &&
+		echo $HASH2 > walk_water_exp &&
+		test_cmp walk_water_exp walk_water
+	'
+
+	test_expect_success "dag walk ** matches at any depth" '
+		ipfs dag walk --selector="**/water" $IPLDHASH > walk_water &&
+		test_cmp walk_water_exp walk_water
+	'
+
+	test_expect_success "dag walk respects --depth" '
+		ipfs dag walk --depth=0 $IPLDHASH > walk_depth &&
+		echo $IPLDHASH > walk_depth_exp &&
+		test_cmp walk_depth_exp walk_depth
+	'
+
+	test_expect_success "dag walk rejects invalid selectors" '
+		test_must_fail ipfs dag walk --selector="a/[x" $IPLDHASH
+	'
+
 	test_expect_success "after gc, objects still acessible" '
 		ipfs repo gc > /dev/null &&
 		ipfs refs -r --timeout=2s $EXPHASH > /dev/null
@@ -112,6 +199,53 @@ test_dag_cmd() {
 		test_cmp cat_exp cat_out
 	'
 
+	test_expect_success "dag export succeeds" '
+		ipfs dag export $IPLDHASH > dag.car
+	'
+
+	test_expect_success "dag import of the export succeeds" '
+		ipfs dag import < dag.car > dag_import_out
+	'
+
+	test_expect_success "dag import output looks right" '
+		echo "NumBlocks: 4" > dag_import_exp &&
+		echo "Root: $IPLDHASH (pinned)" >> dag_import_exp &&
+		test_cmp dag_import_exp dag_import_out
+	'
+
+	test_expect_success "dag import pinned the root" '
+		ipfs pin ls --type=recursive $IPLDHASH
+	'
+
+	test_expect_success "dag import rejects a corrupted block" '
+		cp dag.car bad.car &&
+		printf x | dd of=bad.car bs=1 seek=$(($(wc -c < bad.car) - 1)) conv=notrunc 2>/dev/null &&
+		test_must_fail ipfs dag import --pin-roots=false < bad.car 2> dag_import_err &&
+		grep "does not match its data" dag_import_err
+	'
+
+	test_expect_success "dag import rejects input that is not a car" '
+		echo "not a car" | test_must_fail ipfs dag import 2> dag_import_err &&
+		grep "invalid car header" dag_import_err
+	'
+
+	test_expect_success "ipfs add --input=car stores the archive blocks" '
+		ipfs add --input=car dag.car > add_car_out &&
+		echo "added $IPLDHASH dag.car" > add_car_exp &&
+		test_cmp add_car_exp add_car_out
+	'
+
+	test_expect_success "ipfs add --input=car fails on non-car input" '
+		echo "not a car" > not_a_car &&
+		test_must_fail ipfs add --input=car not_a_car 2> add_car_err &&
+		grep "not_a_car is not a valid CAR archive" add_car_err
+	'
+
+	test_expect_success "ipfs add --input=car rejects --only-hash" '
+		test_must_fail ipfs add --input=car --only-hash dag.car 2> add_car_err &&
+		grep "cannot be used with" add_car_err
+	'
+
 	test_expect_success "non-canonical cbor input is normalized" '
 	HASH=$(cat ../t0053-dag-data/non-canon.cbor | ipfs dag put --format=cbor --input-enc=raw) &&
 	test $HASH = "zdpuAmxF8q6iTUtkB3xtEYzmc5Sw762qwQJftt5iW8NTWLtjC" ||
@@ -98,6 +98,16 @@ test_expect_success "output looks good" '
 	grep RateOut statsout >/dev/null
 '
 
+test_expect_success "polling emits one JSON snapshot per line" '
+	test_expect_code 28 curl -m 3 http://localhost:$API_PORT/api/v0/stats/bw\?poll=true\&interval=500ms > statspoll &&
+	test $(grep -c "^{.*\"TotalIn\".*}$" statspoll) -ge 2
+'
+
+test_expect_success "stats bw rejects a non-positive interval" '
+	test_must_fail ipfs stats bw --poll --interval=0s 2> interval_err &&
+	grep "interval must be positive" interval_err
+'
+
 # end same as in t0010
 
 test_expect_success "daemon is still running" '
@@ -49,5 +49,26 @@ test_expect_success "command shows up as inactive" '
 	grep "log/tail" cmd_out3 | grep "false"
 '
 
+test_expect_success "log tail rejects an unknown level" '
+	test_must_fail ipfs log tail --level=loud 2> tail_err &&
+	grep "invalid log level: loud" tail_err
+'
+
+test_expect_success "log level accepts a list of subsystems" '
+	ipfs log level dht,bitswap debug > level_out &&
+	echo "Changed log level of '"'dht', 'bitswap'"' to '"'debug'"'" > level_exp &&
+	test_cmp level_exp level_out
+'
+
+test_expect_success "log level accepts wildcards" '
+	ipfs log level "*" warn > level_all &&
+	grep "Changed log level of '"'*'"' to '"'warning'"'" level_all
+'
+
+test_expect_success "log level rejects unknown subsystems" '
+	test_must_fail ipfs log level dht,nosuchsystem info 2> level_err &&
+	grep "unknown subsystem \"nosuchsystem\", valid subsystems are: .*bitswap" level_err
+'
+
 test_kill_ipfs_daemon
 test_done
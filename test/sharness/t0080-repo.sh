@@ -118,6 +118,31 @@ test_expect_success "'ipfs refs local' no longer shows file" '
 	test_must_fail grep "$PATCH_ROOT" actual8
 '
 
+test_expect_success "'ipfs refs local --sort' lists sorted refs" '
+	ipfs refs local --sort >refs_sorted &&
+	sort actual8 >refs_expected &&
+	test_cmp refs_expected refs_sorted
+'
+
+test_expect_success "'ipfs refs local --offset --limit' pages through sorted refs" '
+	ipfs refs local --sort --limit=2 >refs_page1 &&
+	ipfs refs local --sort --offset=2 --limit=2 >refs_page2 &&
+	head -n 2 refs_sorted >refs_page1_exp &&
+	sed -n 3,4p refs_sorted >refs_page2_exp &&
+	test_cmp refs_page1_exp refs_page1 &&
+	test_cmp refs_page2_exp refs_page2
+'
+
+test_expect_success "'ipfs refs local --limit' limits unsorted output" '
+	ipfs refs local --limit=3 >refs_limited &&
+	test $(wc -l <refs_limited) -eq 3
+'
+
+test_expect_success "'ipfs refs local' rejects a negative offset" '
+	test_must_fail ipfs refs local --offset=-1 2>refs_err &&
+	grep "offset and limit must not be negative" refs_err
+'
+
 test_expect_success "adding multiblock random file succeeds" '
 	random 1000000 >multiblock &&
 	MBLOCKHASH=`ipfs add -q multiblock`
@@ -193,6 +218,50 @@ test_expect_success "'ipfs refs --unique --recursive' is correct" '
 	test_cmp expected line_count || test_fsh cat add_output || test_fsh cat refs_output
 '
 
+test_expect_success "'ipfs refs --recursive --max-depth' limits the depth" '
+	ROOT=$(tail -n1 add_output) &&
+	ipfs refs -r --max-depth=1 $ROOT >refs_depth1 &&
+	ipfs refs $ROOT >refs_single &&
+	test_cmp refs_single refs_depth1 &&
+	ipfs refs -r --max-depth=2 $ROOT >refs_depth2 &&
+	wc -l refs_depth2 | sed "s/^ *//g" >line_count &&
+	echo "4 refs_depth2" >expected &&
+	test_cmp expected line_count &&
+	ipfs refs -r --max-depth=0 $ROOT >refs_depth0 &&
+	test_must_be_empty refs_depth0
+'
+
+test_expect_success "'ipfs refs --max-depth' rejects invalid depths" '
+	test_must_fail ipfs refs -r --max-depth=-2 $ROOT
+'
+
+test_expect_success "'ipfs refs --format' applies the template to each link" '
+	ipfs refs --format="<linkname>" $ROOT >refs_names &&
+	printf "b\nf1\n" >expected &&
+	test_cmp expected refs_names &&
+	ipfs refs --format="\"<src>\" -> \"<dst>\" [label=\"<linkname>\"];" $ROOT >refs_dot &&
+	ipfs refs $ROOT >refs_dst &&
+	sed -e "s/.*\"$ROOT\" -> \"\(.*\)\" \[label=\".*\"\];/\1/" refs_dot >refs_dot_dst &&
+	test_cmp refs_dst refs_dot_dst
+'
+
+test_expect_success "'ipfs refs --format' rejects unknown tokens" '
+	test_must_fail ipfs refs --format="<src> <size>" $ROOT 2>refs_err &&
+	grep "unknown token <size> in format" refs_err &&
+	test_must_fail ipfs refs --format="<SRC>" $ROOT 2>refs_err &&
+	grep "unknown token <SRC> in format" refs_err &&
+	test_must_fail ipfs refs --format="<dest>" $ROOT 2>refs_err &&
+	grep "unknown token <dest> in format" refs_err &&
+	test_must_fail ipfs refs --format="<b><linkname></b>" $ROOT 2>refs_err &&
+	grep "unknown token <b> in format" refs_err
+'
+
+test_expect_success "'ipfs refs --format' prints escaped angle brackets" '
+	ipfs refs --format="\\<b\\><linkname>\\</b\\>" $ROOT >refs_html &&
+	printf "<b>b</b>\n<b>f1</b>\n" >expected &&
+	test_cmp expected refs_html
+'
+
 test_expect_success "'ipfs refs --recursive (bigger)'" '
 	mkdir -p b/c/d/e &&
 	echo "content1" >b/f &&
@@ -234,7 +303,18 @@ test_expect_success "repo stats came out correct" '
   grep "RepoSize" repo-stats &&
   grep "NumObjects" repo-stats &&
   grep "Version" repo-stats &&
-  grep "StorageMax" repo-stats
+  grep "StorageMax" repo-stats &&
+  test_must_fail grep "SizePinned" repo-stats
+'
+
+test_expect_success "repo stats --size-breakdown break down sizes by category" '
+  ipfs repo stat --size-breakdown >repo-stats-breakdown &&
+  grep "SizeMFS" repo-stats-breakdown &&
+  test $(get_field_num "SizePinned" repo-stats-breakdown) -gt 0 &&
+  test $(get_field_num "SizeOther" repo-stats-breakdown) -gt 0 &&
+  echo "unpinned stat data" | ipfs block put >/dev/null &&
+  ipfs repo stat --size-breakdown >repo-stats-unpinned &&
+  test $(get_field_num "SizeUnpinned" repo-stats-unpinned) -ge 19
 '
 
 test_expect_success "'ipfs repo stat' after adding a file" '
@@ -261,6 +341,84 @@ test_expect_success "repo version came out correct" '
 	egrep "^fs-repo@[0-9]+" repo-version-q >/dev/null
 '
 
+test_expect_success "'ipfs repo gc' reports totals" '
+	ipfs repo gc >gc_clean &&
+	echo "gc test block one" | ipfs block put >/dev/null &&
+	ipfs repo gc >gc_totals 2>gc_totals_err &&
+	test $(grep -c "^removed Qm" gc_totals) -eq 1 &&
+	test_must_fail grep "total" gc_totals &&
+	grep "^total: 1 blocks removed$" gc_totals_err
+'
+
+test_expect_success "'ipfs repo gc -q' omits totals" '
+	echo "gc test block two" | ipfs block put >/dev/null &&
+	ipfs repo gc -q >gc_quiet 2>gc_quiet_err &&
+	test $(wc -l < gc_quiet) -eq 1 &&
+	test_must_fail grep "total" gc_quiet_err
+'
+
+test_expect_success "add some unpinned blocks" '
+	for i in 1 2 3 4; do
+		echo "gc limit block $i" | ipfs block put || return 1
+	done >limit_blocks
+'
+
+test_expect_success "'ipfs repo gc --max-removed' stops early" '
+	ipfs repo gc --max-removed=1 >gc_limited 2>gc_limited_err &&
+	test $(grep -c "^removed Qm" gc_limited) -eq 1 &&
+	grep "^total: 1 blocks removed$" gc_limited_err
+'
+
+test_expect_success "'ipfs repo gc --max-freed' stops early" '
+	ipfs repo gc --max-freed=20B >gc_freed 2>gc_freed_err &&
+	test $(grep -c "^removed Qm" gc_freed) -eq 2 &&
+	grep "^total: 2 blocks removed, 34 B freed$" gc_freed_err
+'
+
+test_expect_success "remaining blocks are removed by a full gc" '
+	ipfs repo gc >gc_rest 2>gc_rest_err &&
+	test $(grep -c "^removed Qm" gc_rest) -eq 1
+'
+
+test_expect_success "'ipfs repo gc' rejects bad limits" '
+	test_must_fail ipfs repo gc --max-removed=-1 &&
+	test_must_fail ipfs repo gc --max-freed=lots
+'
+
+test_expect_success "'ipfs repo stat --latency' fails when disabled" '
+  test_must_fail ipfs repo stat --latency 2>latency_err &&
+  grep "latency measurement is disabled" latency_err
+'
+
+test_kill_ipfs_daemon
+
+test_expect_success "enable datastore latency measurement" '
+  ipfs config --json Datastore.MeasureLatency true
+'
+
+test_launch_ipfs_daemon --offline
+
+test_expect_success "'ipfs repo stat --latency' reports percentiles" '
+  echo "latency test block" | ipfs block put >/dev/null &&
+  ipfs repo stat --latency >latency_out &&
+  grep "^Op  *Samples  *P50  *P95  *P99$" latency_out &&
+  grep "^get " latency_out &&
+  grep "^delete " latency_out &&
+  test $(grep "^put " latency_out | awk "{print \$2}") -ge 1
+'
+
+test_expect_success "'ipfs repo stat --latency --enc=json' has only the latencies" '
+  ipfs repo stat --latency --enc=json >latency_json &&
+  grep "\"Ops\":" latency_json &&
+  test_must_fail grep "NumObjects" latency_json
+'
+
+test_expect_success "'ipfs repo stat --enc=json' has no latencies" '
+  ipfs repo stat --enc=json >stat_json &&
+  grep "\"NumObjects\":" stat_json &&
+  test_must_fail grep "\"Ops\"" stat_json
+'
+
 test_kill_ipfs_daemon
 
 test_done
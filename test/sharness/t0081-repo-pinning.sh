@@ -207,6 +207,20 @@ test_expect_success "pin lists look good" '
 	test_pin $HASH_FILE6 indirect
 '
 
+for type in all direct indirect recursive; do
+	test_expect_success "'ipfs pin ls --stream --type=$type' matches 'ipfs pin ls'" '
+		ipfs pin ls --type=$type | sort > pin_ls_exp &&
+		ipfs pin ls --stream --type=$type | sort > pin_ls_stream &&
+		test_cmp pin_ls_exp pin_ls_stream
+	'
+done
+
+test_expect_success "'ipfs pin ls --stream' with arguments works" '
+	ipfs pin ls --stream $HASH_DIR1 $HASH_DIR2 | sort > pin_ls_stream &&
+	ipfs pin ls $HASH_DIR1 $HASH_DIR2 | sort > pin_ls_exp &&
+	test_cmp pin_ls_exp pin_ls_stream
+'
+
 test_expect_success "'ipfs repo gc' succeeds" '
 	ipfs repo gc >gc_out_actual2 &&
 	echo "removed $HASH_FILE3" > gc_out_exp2 &&
@@ -272,6 +286,63 @@ test_expect_success "test add nopin dir" '
 
 '
 
+test_expect_success "create dirs for pin update" '
+	mkdir update_dir &&
+	echo "update file 1" >update_dir/file1 &&
+	UPDATE_OLD=$(ipfs add -q -r update_dir | tail -n1) &&
+	echo "update file 2" >update_dir/file2 &&
+	UPDATE_NEW=$(ipfs add -q -r --pin=false update_dir | tail -n1) &&
+	echo "update file 3" >update_dir/file3 &&
+	UPDATE_NEWER=$(ipfs add -q -r --pin=false update_dir | tail -n1)
+'
+
+test_expect_success "'ipfs pin update' succeeds" '
+	ipfs pin update $UPDATE_OLD $UPDATE_NEW >update_out &&
+	echo "updated $UPDATE_OLD to $UPDATE_NEW" >update_exp &&
+	test_cmp update_exp update_out
+'
+
+test_expect_success "'ipfs pin update' moved the pin" '
+	test_pin_flag $UPDATE_NEW recursive true &&
+	test_pin_flag $UPDATE_OLD recursive false
+'
+
+test_expect_success "'ipfs pin update --unpin=false' keeps the old pin" '
+	ipfs pin update --unpin=false $UPDATE_NEW $UPDATE_NEWER &&
+	test_pin_flag $UPDATE_NEW recursive true &&
+	test_pin_flag $UPDATE_NEWER recursive true
+'
+
+test_expect_success "'ipfs pin update' fails if the old root is not pinned" '
+	test_must_fail ipfs pin update $UPDATE_OLD $UPDATE_NEW 2>update_err &&
+	grep "was not recursively pinned already" update_err
+'
+
+test_expect_success "'ipfs pin ls' resolves /ipfs/ paths" '
+	ipfs pin ls /ipfs/$UPDATE_NEWER/file3 >path_ls_out &&
+	echo "$(ipfs add -q --pin=false update_dir/file3) indirect through $UPDATE_NEWER" >path_ls_exp &&
+	test_cmp path_ls_exp path_ls_out
+'
+
+test_expect_success "'ipfs pin ls' resolves mfs paths" '
+	ipfs files cp /ipfs/$UPDATE_NEWER /pinned_dir &&
+	ipfs pin ls /pinned_dir >mfs_ls_out &&
+	echo "$UPDATE_NEWER recursive" >mfs_ls_exp &&
+	test_cmp mfs_ls_exp mfs_ls_out
+'
+
+test_expect_success "'ipfs pin rm' resolves mfs paths" '
+	ipfs pin rm /pinned_dir >mfs_rm_out &&
+	echo "unpinned $UPDATE_NEWER" >mfs_rm_exp &&
+	test_cmp mfs_rm_exp mfs_rm_out &&
+	test_pin_flag $UPDATE_NEWER recursive false
+'
+
+test_expect_success "'ipfs pin ls' names unresolvable paths" '
+	test_must_fail ipfs pin ls /no_such_dir 2>mfs_ls_err &&
+	grep "could not resolve mfs path /no_such_dir" mfs_ls_err
+'
+
 FICTIONAL_HASH="QmXV4f9v8a56MxWKBhP3ETsz4EaafudU1cKfPaaJnenc48"
 test_launch_ipfs_daemon
 test_expect_success "test unpinning a hash that's not pinned" "
@@ -27,6 +27,18 @@ test_expect_success 'blocks are swapped' '
 	test_must_fail test_cmp noswap swap
 '
 
+test_expect_success 'ipfs get --verify fails on the modified block' '
+	test_must_fail ipfs get --verify $H_BLOCK2 2> get_err &&
+	grep "verification failed: block $H_BLOCK2 hashes to" get_err &&
+	test_must_fail test -e $H_BLOCK2
+'
+
+test_expect_success 'ipfs get --verify succeeds on an intact block' '
+	ipfs get --verify $H_BLOCK1 &&
+	echo "Block 1" > get_exp &&
+	test_cmp get_exp $H_BLOCK1
+'
+
 ipfs config --bool Datastore.HashOnRead true
 
 test_check_bad_blocks() {
@@ -43,6 +55,19 @@ test_check_bad_blocks() {
 
 test_check_bad_blocks
 
+test_expect_success "repo verify reports a summary and no progress by default" '
+	test_expect_code 1 ipfs repo verify > verify_out 2> verify_err &&
+	grep "block $H_BLOCK2 was corrupt" verify_out &&
+	test_must_fail grep "blocks processed" verify_out &&
+	grep "verify complete, 1 of [0-9]* blocks were corrupt" verify_err
+'
+
+test_expect_success "repo verify --progress reports progress" '
+	test_expect_code 1 ipfs repo verify --progress > verify_out &&
+	grep "blocks processed" verify_out &&
+	grep "block $H_BLOCK2 was corrupt" verify_out
+'
+
 test_expect_success "can add and cat a raw-leaf file" '
 	HASH=$(echo "stuff" | ipfs add -q --raw-leaves) &&
 	ipfs cat $HASH > /dev/null
@@ -97,6 +97,82 @@ test_pin_progress() {
 	'
 }
 
+test_pin_dry_run() {
+	EXTRA_ARGS=$1
+
+	test_expect_success "'ipfs add $EXTRA_ARGS --pin=false' 1MB file for dry run" '
+		random 1048576 57 > dryfile &&
+		HASH=`ipfs add $EXTRA_ARGS --pin=false -q dryfile`
+	'
+
+	test_expect_success "'ipfs pin add --dry-run' counts local blocks" '
+		ipfs pin add --dry-run $HASH > dry_run &&
+		grep "^$HASH: would fetch 0 bytes in 0 blocks, [0-9]* bytes in 5 blocks are local$" dry_run
+	'
+
+	test_expect_success "'ipfs pin add --dry-run' does not pin" '
+		test_must_fail ipfs pin ls --type=recursive $HASH
+	'
+
+	test_expect_success "'ipfs pin add --dry-run' estimates missing blocks" '
+		PART=`ipfs refs $HASH | head -1` &&
+		PART_SIZE=`ipfs block stat $PART | sed -n "s/^Size: //p"` &&
+		ipfs block rm $PART &&
+		ipfs pin add --dry-run $HASH > dry_run &&
+		grep "^$HASH: would fetch $PART_SIZE bytes in at least 1 blocks, [0-9]* bytes in 4 blocks are local$" dry_run
+	'
+
+	test_expect_success "'ipfs pin add --dry-run -r=false' only counts the root" '
+		ipfs pin add --dry-run --recursive=false $HASH > dry_run &&
+		grep "^$HASH: would fetch 0 bytes in 0 blocks, [0-9]* bytes in 1 blocks are local$" dry_run
+	'
+}
+
+test_pin_verify() {
+	test_expect_success "'ipfs add' 1MB file for pin verify" '
+		random 1048576 61 > verifyfile &&
+		HASH=`ipfs add -q verifyfile`
+	'
+
+	test_expect_success "'ipfs pin verify --verbose' reports complete pin" '
+		ipfs pin verify --verbose > verify_out &&
+		grep "^$HASH ok$" verify_out
+	'
+
+	test_expect_success "remove a block of the pinned file" '
+		PART=`ipfs refs $HASH | head -1` &&
+		ipfs block rm -f $PART
+	'
+
+	test_expect_success "'ipfs pin verify' reports broken pin" '
+		ipfs pin verify > verify_out &&
+		grep "^$HASH broken$" verify_out &&
+		grep "^  $PART: " verify_out
+	'
+
+	test_expect_success "'ipfs pin verify --quiet' writes hash of broken pin" '
+		ipfs pin verify --quiet > verify_out &&
+		grep "^$HASH$" verify_out
+	'
+
+	test_expect_success "'ipfs pin verify --repair' fails for unrecoverable pin" '
+		test_must_fail ipfs pin verify --repair --repair-timeout=1s > verify_out 2> verify_err &&
+		grep "^$HASH unrecoverable$" verify_out &&
+		grep "1 pins could not be repaired" verify_err
+	'
+
+	test_expect_success "'ipfs pin verify --repair --enc=json' fails for unrecoverable pin" '
+		test_must_fail ipfs pin verify --repair --repair-timeout=1s --enc=json > verify_out &&
+		grep "\"Cid\":\"$HASH\"" verify_out
+	'
+
+	test_expect_success "'ipfs pin verify --repair' succeeds once content is back" '
+		ipfs add -q verifyfile &&
+		ipfs pin verify --repair --verbose > verify_out &&
+		grep "^$HASH ok$" verify_out
+	'
+}
+
 test_init_ipfs
 
 test_pins
@@ -110,6 +186,11 @@ test_pin_dag --raw-leaves
 
 test_pin_progress
 
+test_pin_dry_run
+test_pin_dry_run --raw-leaves
+
+test_pin_verify
+
 test_launch_ipfs_daemon --offline
 
 test_pins
@@ -123,6 +204,11 @@ test_pin_dag --raw-leaves
 
 test_pin_progress
 
+test_pin_dry_run
+test_pin_dry_run --raw-leaves
+
+test_pin_verify
+
 test_kill_ipfs_daemon
 
 test_done
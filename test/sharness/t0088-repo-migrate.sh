@@ -0,0 +1,133 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test ipfs repo migrate"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "setup mock migrations" '
+	mkdir bin &&
+	cat >bin/fs-repo-migrations <<-\EOF &&
+	#!/bin/sh
+	if [ "$1" = "-v" ]; then
+		echo 5
+		exit 0
+	fi
+	echo "$2" >>"$IPFS_PATH"/migrations_run &&
+	echo "$2" >"$IPFS_PATH"/version
+	EOF
+	chmod +x bin/fs-repo-migrations &&
+	export PATH="$(pwd)/bin":$PATH
+'
+
+test_expect_success "'ipfs repo migrate' on an up to date repo succeeds" '
+	ipfs repo migrate >up_to_date_out
+'
+
+test_expect_success "output looks good" '
+	echo "Current repo version: 5" >expected &&
+	echo "Target repo version: 5" >>expected &&
+	echo "Repo is up to date, nothing to migrate." >>expected &&
+	test_cmp expected up_to_date_out
+'
+
+test_expect_success "manually reset repo version to 3" '
+	echo "3" >"$IPFS_PATH"/version
+'
+
+test_expect_success "'ipfs repo migrate --dry-run' succeeds" '
+	ipfs repo migrate --dry-run >dry_run_out
+'
+
+test_expect_success "dry run lists the steps" '
+	echo "Current repo version: 3" >expected &&
+	echo "Target repo version: 5" >>expected &&
+	echo "Would migrate from version 3 to 4" >>expected &&
+	echo "Would migrate from version 4 to 5" >>expected &&
+	test_cmp expected dry_run_out
+'
+
+test_expect_success "dry run did not change the repo" '
+	echo 3 >expected &&
+	test_cmp expected "$IPFS_PATH"/version &&
+	test ! -e "$IPFS_PATH"/migrations_run &&
+	test -z "$(ls -d "$IPFS_PATH"/migration-backup-* 2>/dev/null)"
+'
+
+test_expect_success "'ipfs repo migrate' refuses to migrate down" '
+	test_must_fail ipfs repo migrate --to=2 2>down_err &&
+	grep "migrating down to 2 is not supported" down_err
+'
+
+test_expect_success "'ipfs repo migrate' refuses unsupported versions" '
+	test_must_fail ipfs repo migrate --to=6 2>newer_err &&
+	grep "only supports repo versions up to 5" newer_err
+'
+
+test_expect_success "'ipfs repo migrate --to=4' succeeds" '
+	ipfs repo migrate --to=4 >to_out
+'
+
+test_expect_success "output looks good" '
+	grep "Target repo version: 4" to_out &&
+	grep "Backed up repo files to " to_out &&
+	grep "Migrated from version 3 to 4" to_out &&
+	echo 4 >expected &&
+	test_cmp expected "$IPFS_PATH"/migrations_run
+'
+
+test_expect_success "backup holds the old version and config" '
+	BACKUP=$(sed -n "s/^Backed up repo files to //p" to_out) &&
+	echo 3 >expected &&
+	test_cmp expected "$BACKUP"/version &&
+	test_cmp "$IPFS_PATH"/config "$BACKUP"/config
+'
+
+test_expect_success "'ipfs repo migrate' runs the remaining steps" '
+	ipfs repo migrate >rest_out &&
+	grep "Migrated from version 4 to 5" rest_out &&
+	printf "4\n5\n" >expected &&
+	test_cmp expected "$IPFS_PATH"/migrations_run
+'
+
+test_expect_success "setup failing mock migrations" '
+	mv bin/fs-repo-migrations bin/fs-repo-migrations.ok &&
+	cat >bin/fs-repo-migrations <<-\EOF &&
+	#!/bin/sh
+	if [ "$1" = "-v" ]; then
+		echo 5
+		exit 0
+	fi
+	echo "migration exploded" >&2
+	exit 1
+	EOF
+	chmod +x bin/fs-repo-migrations &&
+	echo 4 >"$IPFS_PATH"/version
+'
+
+test_expect_success "a failed migration fails with any encoding" '
+	test_must_fail ipfs repo migrate >fail_out 2>fail_err &&
+	test_must_fail ipfs repo migrate --enc=json >fail_json_out 2>fail_json_err &&
+	echo 4 >expected &&
+	test_cmp expected "$IPFS_PATH"/version
+'
+
+test_expect_success "restore mock migrations" '
+	mv bin/fs-repo-migrations.ok bin/fs-repo-migrations &&
+	echo 5 >"$IPFS_PATH"/version
+'
+
+test_launch_ipfs_daemon
+
+test_expect_success "'ipfs repo migrate' fails while the daemon is running" '
+	test_must_fail ipfs repo migrate 2>daemon_err &&
+	grep "ipfs daemon is running" daemon_err
+'
+
+test_kill_ipfs_daemon
+
+test_done
@@ -0,0 +1,93 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test ipfs pin remote"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "add remote pinning services" '
+	echo secretkey2 | ipfs pin remote service add srv2 http://127.0.0.1:1/api/v1 &&
+	echo secretkey1 > keyfile1 &&
+	ipfs pin remote service add srv1 https://pin.example.com/api/v1 keyfile1
+'
+
+test_expect_success "service ls lists names and endpoints" '
+	ipfs pin remote service ls > services &&
+	echo "srv1 https://pin.example.com/api/v1" > services_exp &&
+	echo "srv2 http://127.0.0.1:1/api/v1" >> services_exp &&
+	test_cmp services_exp services
+'
+
+test_expect_success "service ls does not print keys" '
+	ipfs pin remote service ls --enc=json > services.json &&
+	test_must_fail grep secretkey services.json
+'
+
+test_expect_success "config show does not print keys" '
+	ipfs config show > config_show &&
+	grep pin.example.com config_show &&
+	test_must_fail grep secretkey config_show
+'
+
+test_expect_success "config does not print or change keys" '
+	test_must_fail ipfs config Pinning.RemoteServices.srv1.Key 2> key_err &&
+	grep "cannot show or change remote pinning service keys" key_err &&
+	test_must_fail ipfs config Pinning.RemoteServices.srv1.key foo &&
+	ipfs config Pinning.RemoteServices > services_cfg &&
+	grep pin.example.com services_cfg &&
+	test_must_fail grep secretkey services_cfg
+'
+
+test_expect_success "config replace keeps the keys" '
+	ipfs config show > replace_cfg &&
+	ipfs config replace replace_cfg &&
+	grep "\"Key\": \"secretkey1\"" "$IPFS_PATH/config" &&
+	grep "\"Key\": \"secretkey2\"" "$IPFS_PATH/config"
+'
+
+test_expect_success "adding an existing service fails" '
+	echo key | test_must_fail ipfs pin remote service add srv1 https://other.example.com 2> dup_err &&
+	grep "already exists" dup_err
+'
+
+test_expect_success "adding a service with an invalid endpoint fails" '
+	echo key | test_must_fail ipfs pin remote service add bad ftp://example.com 2> bad_err &&
+	grep "invalid endpoint" bad_err
+'
+
+test_expect_success "pin remote add requires a service" '
+	HASH=$(echo "remote pin" | ipfs add -q) &&
+	test_must_fail ipfs pin remote add $HASH 2> nosrv_err &&
+	grep "use --service" nosrv_err
+'
+
+test_expect_success "pin remote add fails for unknown services" '
+	test_must_fail ipfs pin remote add --service=nope $HASH 2> unknown_err &&
+	grep "no remote pinning service named" unknown_err
+'
+
+test_expect_success "pin remote add fails when the service is unreachable" '
+	test_must_fail ipfs pin remote add --service=srv2 --background $HASH
+'
+
+test_expect_success "pin remote ls rejects unknown statuses" '
+	test_must_fail ipfs pin remote ls --service=srv2 --status=done 2> status_err &&
+	grep "invalid status" status_err
+'
+
+test_expect_success "service rm removes the service" '
+	ipfs pin remote service rm srv2 &&
+	ipfs pin remote service ls > services &&
+	echo "srv1 https://pin.example.com/api/v1" > services_exp &&
+	test_cmp services_exp services
+'
+
+test_expect_success "removing an unknown service fails" '
+	test_must_fail ipfs pin remote service rm srv2
+'
+
+test_done
@@ -120,6 +120,45 @@ test_get_cmd() {
 		rm -r "$HASH2"
 	'
 
+	test_expect_success "ipfs get -a --compression=gzip succeeds (directory)" '
+		ipfs get "$HASH2" -a --compression=gzip >actual
+	'
+
+	test_expect_success "ipfs get -a --compression=gzip output looks good (directory)" '
+		printf "%s\n\n" "Saving archive to $HASH2.tar.gz" >expected &&
+		test_cmp expected actual &&
+		rm "$HASH2".tar.gz
+	'
+
+	test_expect_success "ipfs get -a -C -o keeps the given path" '
+		ipfs get "$HASH2" -a -C -o out_archive >actual &&
+		tar -zxf out_archive &&
+		test_cmp dir/a "$HASH2"/a &&
+		rm -r "$HASH2" out_archive
+	'
+
+	test_expect_success "ipfs get -C --compression=none fails" '
+		test_must_fail ipfs get "$HASH2" -a -C --compression=none
+	'
+
+	test_expect_success "ipfs get -o - streams a file to stdout" '
+		ipfs get -o - "$HASH" >actual &&
+		test_cmp data actual
+	'
+
+	test_expect_success "ipfs get -o - -C streams a gzipped file" '
+		ipfs get -o - -C "$HASH" | gunzip >actual &&
+		test_cmp data actual
+	'
+
+	test_expect_success "ipfs get -o - writes a directory as tar" '
+		ipfs get -o - "$HASH2" >dir.tar &&
+		tar -xf dir.tar &&
+		test_cmp dir/a "$HASH2"/a &&
+		test_cmp dir/b/c "$HASH2"/b/c &&
+		rm -r "$HASH2" dir.tar
+	'
+
 	test_expect_success "ipfs get ../.. should fail" '
 		echo "Error: invalid 'ipfs ref' path" >expected &&
 		test_must_fail ipfs get ../.. 2>actual &&
@@ -32,6 +32,15 @@ test_expect_success "resolve output looks good" '
 	test_cmp expected2 output
 '
 
+test_expect_success "'ipfs name resolve --stream' prints unchanged values once" '
+	ipfs name resolve --stream --stream-interval=100ms --timeout=2s "$PEERID" >stream_output &&
+	test_cmp expected2 stream_output
+'
+
+test_expect_success "'ipfs name resolve --stream' rejects a bad interval" '
+	test_must_fail ipfs name resolve --stream --stream-interval=0s "$PEERID"
+'
+
 # now test with a path
 
 test_expect_success "'ipfs name publish' succeeds" '
@@ -60,6 +69,56 @@ test_expect_success "ipfs cat on published content succeeds" '
     test_cmp expected actual
 '
 
+test_expect_success "ipfs cat resolves /ipns/ paths with sub-paths" '
+	ipfs name publish "/ipfs/$HASH_WELCOME_DOCS" &&
+	ipfs cat "/ipfs/$HASH_WELCOME_DOCS/help" >expected &&
+	ipfs cat "/ipns/$PEERID/help" >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "ipfs get resolves /ipns/ paths" '
+	ipfs get -o got_help "/ipns/$PEERID/help" &&
+	test_cmp expected got_help
+'
+
+test_expect_success "ipfs cat --verbose prints the resolved path" '
+	ipfs cat --verbose "/ipns/$PEERID/help" >actual 2>cat_err &&
+	test_cmp expected actual &&
+	echo "resolved /ipns/$PEERID/help to /ipfs/$HASH_WELCOME_DOCS/help" >expected_err &&
+	test_cmp expected_err cat_err
+'
+
+test_expect_success "ipfs cat only prints the resolved path with --verbose" '
+	ipfs cat "/ipns/$PEERID/help" >actual 2>cat_err &&
+	test_must_be_empty cat_err
+'
+
+test_expect_success "ipfs get --verbose prints the resolved path" '
+	ipfs get --verbose -o got_help_verbose "/ipns/$PEERID/help" 2>get_err &&
+	test_cmp expected got_help_verbose &&
+	grep "^resolved /ipns/$PEERID/help to /ipfs/$HASH_WELCOME_DOCS/help$" get_err
+'
+
+test_expect_success "ipfs cat --resolve=false rejects /ipns/ paths" '
+	test_must_fail ipfs cat --resolve=false "/ipns/$PEERID/help" 2>cat_err &&
+	grep "resolving was disabled" cat_err
+'
+
+test_expect_success "ipfs get --resolve=false rejects /ipns/ paths" '
+	test_must_fail ipfs get --resolve=false -o nothing "/ipns/$PEERID/help" 2>get_err &&
+	grep "resolving was disabled" get_err &&
+	test ! -e nothing
+'
+
+test_expect_success "ipfs cat --resolve=false still accepts /ipfs/ paths" '
+	ipfs cat --resolve=false "/ipfs/$HASH_WELCOME_DOCS/help" >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "ipfs cat rejects a bad resolve-timeout" '
+	test_must_fail ipfs cat --resolve-timeout=0s "/ipns/$PEERID/help"
+'
+
 # publish with an explicit node ID
 
 test_expect_failure "'ipfs name publish <local-id> <hash>' succeeds" '
@@ -90,4 +149,34 @@ test_expect_success "publish an explicit node ID as key name looks good" '
 	test_cmp expected_node_id_publish actual_node_id_publish
 '
 
+# publish under several keys at once
+
+test_expect_success "'ipfs name publish --key=self,<key>' succeeds" '
+	PEERID=`ipfs id --format="<id>"` &&
+	ipfs name publish --key=self,keyname "/ipfs/$HASH_WELCOME_DOCS" >actual_multi_publish
+'
+
+test_expect_success "publish under several keys looks good" '
+	echo "Published to ${PEERID}: /ipfs/$HASH_WELCOME_DOCS" >expected_multi_publish &&
+	echo "Published to ${NEWID}: /ipfs/$HASH_WELCOME_DOCS" >>expected_multi_publish &&
+	test_cmp expected_multi_publish actual_multi_publish
+'
+
+test_expect_success "publish with one unknown key still publishes the others" '
+	test_must_fail ipfs name publish --key=nosuchkey,keyname "/ipfs/$HASH_WELCOME_DOCS" >actual_multi_publish 2>multi_publish_err &&
+	echo "Published to ${NEWID}: /ipfs/$HASH_WELCOME_DOCS" >expected_multi_publish &&
+	test_cmp expected_multi_publish actual_multi_publish &&
+	grep "Error publishing to nosuchkey" multi_publish_err
+'
+
+test_expect_success "publish with one unknown key fails with --enc=json" '
+	test_must_fail ipfs name publish --enc=json --key=nosuchkey,keyname "/ipfs/$HASH_WELCOME_DOCS" >actual_multi_publish &&
+	grep "\"Name\":\"nosuchkey\"" actual_multi_publish &&
+	grep "\"Name\":\"${NEWID}\"" actual_multi_publish
+'
+
+test_expect_success "publish rejects empty key names" '
+	test_must_fail ipfs name publish --key=self, "/ipfs/$HASH_WELCOME_DOCS"
+'
+
 test_done
@@ -156,6 +156,31 @@ test_bootstrap_cmd() {
 
   test_bootstrap_list_cmd $BP1 $BP2 $BP3 $BP4
 
+  test_expect_success "'ipfs bootstrap add' reports duplicates on stderr" '
+	ipfs bootstrap add "$BP2" "$BP5" "$BP5" >add_dup_actual 2>add_dup_err &&
+	printf "%s\n" "$BP2" "$BP5" | LC_ALL=C sort >add_dup_expected &&
+	test_cmp add_dup_expected add_dup_actual &&
+	echo "added: 1, skipped duplicates: 2, total: 5" >add_dup_err_expected &&
+	test_cmp add_dup_err_expected add_dup_err
+  '
+
+  test_expect_success "'ipfs bootstrap add --only' replaces the list" '
+	ipfs bootstrap add --only "$BP6" "$BP7" >add_only_actual 2>add_only_err &&
+	echo $BP6 >add_only_expected &&
+	echo $BP7 >>add_only_expected &&
+	test_cmp add_only_expected add_only_actual &&
+	echo "added: 2, skipped duplicates: 0, total: 2" >add_only_err_expected &&
+	test_cmp add_only_err_expected add_only_err
+  '
+
+  test_bootstrap_list_cmd $BP6 $BP7
+
+  test_expect_success "restore the list" '
+	ipfs bootstrap add --only <bpeers >/dev/null
+  '
+
+  test_bootstrap_list_cmd $BP1 $BP2 $BP3 $BP4
+
   test_expect_success "'ipfs bootstrap rm' accepts args from stdin" '
 	cat bpeers | ipfs bootstrap rm > rm_stdin_actual
   '
@@ -80,6 +80,53 @@ run_advanced_test() {
 		grep "data sent: 1000256" stat1 > /dev/null
 	'
 
+	test_expect_success "node0 ledger for node1 looks correct" '
+		PEER1=$(iptb get id 1) &&
+		ipfsi 0 bitswap stat --peer="$PEER1" > ledger0 &&
+		test $(wc -l < ledger0) -eq 1 &&
+		read -r P RATIO SENT RECV EXCH WANTS OVERLAP REST < ledger0 &&
+		test "$P" = "$PEER1" &&
+		test "$SENT" -gt 0 &&
+		test -n "$OVERLAP" && test -z "$REST"
+	'
+
+	test_expect_success "node0 --peers lists node1" '
+		ipfsi 0 bitswap stat --peers > ledgers0 &&
+		grep "^$PEER1	" ledgers0 > /dev/null
+	'
+
+	test_expect_success "node0 ledger reset for node1 reports the old ledger" '
+		ipfsi 0 bitswap ledger reset "$PEER1" > reset0 &&
+		grep "^Reset ledger for " reset0 &&
+		grep "^Bytes sent were:	$SENT$" reset0
+	'
+
+	test_expect_success "node0 ledger for node1 is empty after reset" '
+		ipfsi 0 bitswap ledger "$PEER1" > ledger0_reset &&
+		grep "^Exchanges:	0$" ledger0_reset &&
+		grep "^Bytes sent:	0$" ledger0_reset &&
+		grep "^Bytes received:	0$" ledger0_reset
+	'
+
+	test_expect_success "ledger reset fails for peers without a ledger" '
+		test_must_fail ipfsi 0 bitswap ledger reset QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ 2> reset_err &&
+		grep "no ledger for peer" reset_err
+	'
+
+	test_expect_success "'ipfs id --offline' uses the peerstore" '
+		ipfsi 0 id --offline -f="<id>\n<aver>\n<addrs>\n" "$PEER1" > id_out &&
+		head -n1 id_out > id_peer &&
+		echo "$PEER1" > id_exp &&
+		test_cmp id_exp id_peer &&
+		grep "^go-ipfs/" id_out &&
+		grep "^/ip4/" id_out
+	'
+
+	test_expect_success "'ipfs id --offline' fails for unknown peers" '
+		test_must_fail ipfsi 0 id --offline QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ 2> id_err &&
+		grep "is not in the local peerstore" id_err
+	'
+
 	test_expect_success "shut down nodes" '
 		iptb stop
 	'
@@ -28,6 +28,40 @@ test_expect_success 'disconnected: addrs local matches ipfs id' '
 	test_cmp expected actual
 '
 
+test_expect_success 'addrs listen lists the bound addresses' '
+	ipfs swarm addrs listen >actual &&
+	grep "^/ip4/0.0.0.0/tcp/" actual &&
+	test_must_fail grep "/ip4/127.0.0.1" actual
+'
+
+test_expect_success 'addrs local --scope=loopback lists only loopback addresses' '
+	ipfs swarm addrs local --scope=loopback >actual &&
+	grep "^/ip4/127.0.0.1/" actual &&
+	test_must_fail grep -v "^/ip4/127\.\|^/ip6/::1/" actual
+'
+
+test_expect_success 'addrs local --scope=public has no loopback addresses' '
+	ipfs swarm addrs local --scope=public >actual &&
+	test_must_fail grep "^/ip4/127\.\|^/ip6/::1/" actual
+'
+
+test_expect_success 'addrs listen --scope=loopback is empty' '
+	ipfs swarm addrs listen --scope=loopback >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success 'addrs listen --scope=unspecified lists the 0.0.0.0 addresses' '
+	ipfs swarm addrs listen --scope=unspecified >actual &&
+	grep "^/ip4/0.0.0.0/tcp/" actual &&
+	ipfs swarm addrs listen --scope=public >actual &&
+	test_must_fail grep "^/ip4/0.0.0.0/" actual
+'
+
+test_expect_success 'addrs rejects unknown scopes' '
+	test_must_fail ipfs swarm addrs local --scope=lan 2>err &&
+	grep "invalid scope" err
+'
+
 test_expect_success "ipfs id self works" '
 	myid=$(ipfs id -f="<id>") &&
 	ipfs id --timeout=1s $myid > output
@@ -47,6 +81,75 @@ test_expect_success "cant trigger a dial backoff with swarm connect" '
 	test_expect_code 1 grep "backoff" connect_out
 '
 
+test_expect_success "swarm connect --retries reports every failed attempt" '
+	test_expect_code 1 ipfs swarm connect --retries=2 $addr 2> connect_out &&
+	grep "attempt 1:" connect_out &&
+	grep "attempt 2:" connect_out &&
+	grep "attempt 3:" connect_out
+'
+
+test_expect_success "swarm connect rejects negative retries" '
+	test_expect_code 1 ipfs swarm connect --retries=-1 $addr 2> connect_out &&
+	grep "retries must not be negative" connect_out
+'
+
 test_kill_ipfs_daemon
 
+test_expect_success "set up two node testbed" '
+	iptb init -n 2 -p 0 -f --bootstrap=none
+'
+
+startup_cluster 2
+
+test_expect_success "peers output has no extra columns by default" '
+	ipfsi 0 swarm peers > peers_out &&
+	test_must_fail grep " " peers_out
+'
+
+test_expect_success "peers --latency shows a latency column" '
+	ipfsi 0 swarm peers --latency > peers_out &&
+	grep -E "/ipfs/[^ ]+ ([0-9][^ ]*|n/a)$" peers_out
+'
+
+test_expect_success "peers --direction shows a direction column" '
+	ipfsi 0 swarm peers --direction > peers_out &&
+	grep -E "/ipfs/[^ ]+ (inbound|outbound|unknown)$" peers_out
+'
+
+test_expect_success "peers --direction labels the direction as a guess in JSON" '
+	ipfsi 0 swarm peers --direction --enc=json > peers_json &&
+	grep -E "\"DirectionGuess\": ?\"(inbound|outbound|unknown)\"" peers_json
+'
+
+test_expect_success "peers --streams lists streams below the peer" '
+	ipfsi 0 swarm peers --streams > peers_out &&
+	grep "^  " peers_out
+'
+
+test_expect_success "swarm disconnect without an address fails" '
+	test_must_fail ipfsi 0 swarm disconnect 2> disconnect_err &&
+	grep "no address given" disconnect_err
+'
+
+test_expect_success "swarm disconnect --all closes all connections" '
+	ipfsi 0 swarm disconnect --all > disconnect_out &&
+	grep "^disconnect .* success$" disconnect_out &&
+	grep "^closed [1-9][0-9]* connection(s), 0 failed$" disconnect_out
+'
+
+test_expect_success "swarm disconnect --all doesn't read stdin" '
+	echo /ip4/127.0.0.1/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ |
+	ipfsi 0 swarm disconnect --all --idle > disconnect_out &&
+	grep "^closed [0-9]* connection(s), 0 failed$" disconnect_out
+'
+
+test_expect_success "swarm disconnect --all with an address fails" '
+	test_must_fail ipfsi 0 swarm disconnect --all /ip4/127.0.0.1/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ 2> disconnect_err &&
+	grep "cannot give addresses together with" disconnect_err
+'
+
+test_expect_success "shut down nodes" '
+	iptb stop
+'
+
 test_done
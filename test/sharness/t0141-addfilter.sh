@@ -115,6 +115,41 @@ test_launch_ipfs_daemon
 
 test_swarm_filters
 
+test_expect_success "'ipfs swarm filters add --no-save' succeeds" '
+	ipfs swarm filters add --no-save $AF2
+'
+
+test_swarm_filter_cmd $AF2
+
+test_config_swarm_addrfilters_cmd
+
+test_expect_success "'ipfs swarm filters add' succeeds" '
+	ipfs swarm filters add $AF1
+'
+
+test_expect_success "'ipfs swarm filters -v' shows filter sources" '
+	ipfs swarm filters -v > list_actual &&
+	grep "^$AF1 config$" list_actual &&
+	grep "^$AF2 runtime$" list_actual
+'
+
+test_kill_ipfs_daemon
+
+test_launch_ipfs_daemon
+
+# only the saved filter is applied after a restart
+test_swarm_filter_cmd $AF1
+
+test_config_swarm_addrfilters_cmd $AF1
+
+test_expect_success "'ipfs swarm filters rm --no-save' succeeds" '
+	ipfs swarm filters rm --no-save $AF1
+'
+
+test_swarm_filter_cmd
+
+test_config_swarm_addrfilters_cmd $AF1
+
 test_kill_ipfs_daemon
 
 test_done
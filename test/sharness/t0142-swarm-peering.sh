@@ -0,0 +1,108 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test ipfs swarm peering"
+
+. lib/test-lib.sh
+
+test_expect_success "set up testbed" '
+	iptb init -n 2 -p 0 -f --bootstrap=none &&
+	iptb for-each ipfs config --json Discovery.MDNS.Enabled false
+'
+
+test_expect_success "start up nodes" '
+	iptb start
+'
+
+test_expect_success "get node info" '
+	PEER1=$(ipfsi 1 id -f "<id>") &&
+	ADDR1=$(ipfsi 1 id -f "<addrs>" | grep 127.0.0.1 | head -n1) &&
+	test -n "$ADDR1"
+'
+
+test_expect_success "peering set is empty" '
+	ipfsi 0 swarm peering ls > ls_out &&
+	test_must_be_empty ls_out
+'
+
+test_expect_success "'ipfs swarm peering add' succeeds" '
+	ipfsi 0 swarm peering add "$ADDR1" > add_out &&
+	echo "$ADDR1" > add_exp &&
+	test_cmp add_exp add_out
+'
+
+test_expect_success "adding the same address again adds nothing" '
+	ipfsi 0 swarm peering add "$ADDR1" > add_out &&
+	test_must_be_empty add_out
+'
+
+test_expect_success "peering address is saved to the config" '
+	ipfsi 0 config Peering.Peers | grep "$ADDR1"
+'
+
+wait_for_peer() {
+	for i in $(test_seq 1 20); do
+		ipfsi 0 swarm peers | grep "$PEER1" > /dev/null && return 0
+		go-sleep 500ms
+	done
+	return 1
+}
+
+test_expect_success "node 0 connects to the peer" '
+	wait_for_peer
+'
+
+test_expect_success "'ipfs swarm peering ls' shows the peer as connected" '
+	ipfsi 0 swarm peering ls > ls_out &&
+	echo "$PEER1 connected" > ls_exp &&
+	echo "  ${ADDR1%/ipfs/*}" >> ls_exp &&
+	test_cmp ls_exp ls_out
+'
+
+test_expect_success "node 0 reconnects after a disconnect" '
+	ipfsi 0 swarm disconnect "$ADDR1" &&
+	wait_for_peer
+'
+
+test_expect_success "'ipfs swarm peering rm' fails for unknown peers" '
+	test_must_fail ipfsi 0 swarm peering rm QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ 2> rm_err &&
+	grep "is not in the peering set" rm_err
+'
+
+test_expect_success "'ipfs swarm peering rm' fails for invalid peer IDs" '
+	test_must_fail ipfsi 0 swarm peering rm foo
+'
+
+test_expect_success "a failed 'ipfs swarm peering rm' removes nothing" '
+	test_must_fail ipfsi 0 swarm peering rm "$PEER1" QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ &&
+	test_must_fail ipfsi 0 swarm peering rm "$PEER1" foo &&
+	ipfsi 0 swarm peering ls | grep "^$PEER1 " &&
+	ipfsi 0 config Peering.Peers | grep "$ADDR1"
+'
+
+test_expect_success "'ipfs swarm peering rm' succeeds" '
+	ipfsi 0 swarm peering rm "$PEER1" > rm_out &&
+	echo "$PEER1" > rm_exp &&
+	test_cmp rm_exp rm_out
+'
+
+test_expect_success "peering set is empty after rm" '
+	ipfsi 0 swarm peering ls > ls_out &&
+	test_must_be_empty ls_out &&
+	ipfsi 0 config Peering.Peers > cfg_out &&
+	! grep "$PEER1" cfg_out
+'
+
+test_expect_success "node 0 does not reconnect after rm" '
+	ipfsi 0 swarm disconnect "$ADDR1" &&
+	go-sleep 2s &&
+	! ipfsi 0 swarm peers | grep "$PEER1"
+'
+
+test_expect_success "stop nodes" '
+	iptb stop
+'
+
+test_done
@@ -0,0 +1,147 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test ipfs swarm limit and stats"
+
+. lib/test-lib.sh
+
+test_expect_success "set up testbed" '
+	iptb init -n 3 -p 0 -f --bootstrap=none &&
+	iptb for-each ipfs config --json Discovery.MDNS.Enabled false
+'
+
+test_expect_success "'ipfs swarm limit' fails offline" '
+	test_must_fail ipfsi 0 swarm limit 2> limit_err &&
+	grep "online mode" limit_err
+'
+
+test_expect_success "start up nodes" '
+	iptb start
+'
+
+test_expect_success "get node info" '
+	PEER1=$(ipfsi 1 id -f "<id>") &&
+	ADDR1=$(ipfsi 1 id -f "<addrs>" | grep 127.0.0.1 | head -n1) &&
+	test -n "$ADDR1"
+'
+
+test_expect_success "'ipfs swarm limit' shows the config limits" '
+	ipfsi 0 swarm limit > limit_out &&
+	cat > limit_exp <<-EOF &&
+	Connections: 0
+	LowWater: 600
+	HighWater: 900
+	GracePeriod: 20s
+	EOF
+	test_cmp limit_exp limit_out
+'
+
+test_expect_success "'ipfs swarm limit set' needs a limit" '
+	test_must_fail ipfsi 0 swarm limit set 2> set_err &&
+	grep "at least one of" set_err
+'
+
+test_expect_success "'ipfs swarm limit set' rejects a low watermark above the high one" '
+	test_must_fail ipfsi 0 swarm limit set --low=5 --high=2 2> set_err &&
+	grep "low watermark must not be above the high watermark" set_err
+'
+
+test_expect_success "'ipfs swarm limit set' rejects invalid grace periods" '
+	test_must_fail ipfsi 0 swarm limit set --grace=soon
+'
+
+test_expect_success "'ipfs swarm limit set' succeeds" '
+	ipfsi 0 swarm limit set --low=1 --high=1 --grace=0s > set_out &&
+	cat > set_exp <<-EOF &&
+	Connections: 0
+	LowWater: 1
+	HighWater: 1
+	GracePeriod: 0s
+	Limits were set at runtime and are not persisted; the daemon uses Swarm.ConnMgr from the config again when restarted.
+	EOF
+	test_cmp set_exp set_out
+'
+
+test_expect_success "runtime limits are not written to the config" '
+	echo 900 > cfg_exp &&
+	ipfsi 0 config Swarm.ConnMgr.HighWater > cfg_out &&
+	test_cmp cfg_exp cfg_out
+'
+
+wait_for_conns() {
+	for i in $(test_seq 1 20); do
+		test $(ipfsi 0 swarm peers | wc -l) -eq "$1" && return 0
+		go-sleep 500ms
+	done
+	return 1
+}
+
+test_expect_success "connections above the high watermark are closed" '
+	iptb connect 0 1 &&
+	iptb connect 0 2 &&
+	wait_for_conns 1
+'
+
+test_expect_success "'ipfs swarm stats' counts the closed connection" '
+	ipfsi 0 swarm stats > stats_out &&
+	grep "^Connections: 1$" stats_out &&
+	grep "^Trimmed: 1$" stats_out &&
+	grep "^Protected: 0$" stats_out &&
+	! grep "^LastTrim: never$" stats_out &&
+	grep "not persisted" stats_out
+'
+
+test_expect_success "peering peers are protected" '
+	ipfsi 0 swarm disconnect $(ipfsi 0 swarm peers) &&
+	ipfsi 0 swarm peering add "$ADDR1" &&
+	ipfsi 0 swarm limit set --low=0 --high=0 &&
+	iptb connect 0 2 &&
+	wait_for_conns 1 &&
+	ipfsi 0 swarm peers | grep "$PEER1" &&
+	ipfsi 0 swarm stats > stats_out &&
+	grep "^Protected: 1$" stats_out
+'
+
+test_expect_success "'ipfs swarm stats' reports whether the limits are persisted" '
+	ipfsi 0 swarm stats --enc=json > stats_json &&
+	grep "\"Persisted\":false" stats_json
+'
+
+test_expect_success "restart nodes" '
+	iptb stop &&
+	iptb start
+'
+
+test_expect_success "runtime limits are gone after a restart" '
+	ipfsi 0 swarm limit > limit_out &&
+	grep "^HighWater: 900$" limit_out &&
+	! grep "not persisted" limit_out
+'
+
+test_expect_success "disable the connection manager" '
+	iptb stop &&
+	ipfsi 0 config Swarm.ConnMgr.Type none &&
+	iptb start
+'
+
+test_expect_success "'ipfs swarm limit' fails when the connection manager is disabled" '
+	test_must_fail ipfsi 0 swarm limit 2> limit_err &&
+	grep "connection manager is disabled" limit_err &&
+	test_must_fail ipfsi 0 swarm stats
+'
+
+test_expect_success "configs without a connection manager type leave it disabled" '
+	iptb stop &&
+	ipfsi 0 config --json Swarm.ConnMgr "{}" &&
+	iptb start &&
+	test_must_fail ipfsi 0 swarm limit 2> limit_err &&
+	grep "connection manager is disabled" limit_err
+'
+
+test_expect_success "stop nodes" '
+	iptb stop
+'
+
+test_done
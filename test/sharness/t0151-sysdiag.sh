@@ -21,6 +21,14 @@ test_expect_success "output contains some expected keys" '
 	grep "online" output
 '
 
+test_expect_success "output reports limits and datastore" '
+	grep "numcpu" output &&
+	grep "go_alloc" output &&
+	grep "\"fds\"" output &&
+	grep "\"limit\"" output &&
+	grep "\"type\": *\"leveldb\"" output
+'
+
 test_expect_success "uname succeeds" '
 	UOUT=$(uname)
 '
@@ -45,4 +53,18 @@ test_expect_success "output is similar to uname" '
 	esac
 '
 
+test_expect_success "ipfs diag profile writes a zip" '
+	ipfs diag profile --profile-time=1s -o profile.zip > profile_out &&
+	echo "Wrote profiles to: profile.zip" > profile_exp &&
+	test_cmp profile_exp profile_out &&
+	for f in cpu.pprof heap.pprof goroutines.txt version.json config.json; do
+		grep -q "$f" profile.zip || return 1
+	done
+'
+
+test_expect_success "ipfs diag profile rejects bad profile-time" '
+	test_must_fail ipfs diag profile --profile-time=0s 2> profile_err &&
+	grep "profile-time must be positive" profile_err
+'
+
 test_done
@@ -109,6 +109,72 @@ test_resolve_cmd_fail() {
 # should work offline
 test_resolve_cmd
 
+test_expect_success "resolve -v shows ipns record metadata" '
+	ipfs resolve -v -r "/ipns/$id_hash" >actual_verbose &&
+	head -n1 actual_verbose >actual_path &&
+	printf "/ipfs/$c_hash\n" >expected_path &&
+	test_cmp expected_path actual_path &&
+	grep "^sequence: [0-9][0-9]*$" actual_verbose &&
+	grep "^validity: " actual_verbose &&
+	grep "^ttl: " actual_verbose
+'
+
+test_expect_success "resolve -v sequence increases on republish" '
+	seq1=$(grep "^sequence:" actual_verbose | cut -d" " -f2) &&
+	ipfs name publish "/ipfs/$a_hash" &&
+	ipfs resolve -v "/ipns/$id_hash" >actual_verbose2 &&
+	seq2=$(grep "^sequence:" actual_verbose2 | cut -d" " -f2) &&
+	test "$seq2" -gt "$seq1"
+'
+
+test_expect_success "resolve -v leaves metadata empty for /ipfs/ paths" '
+	ipfs resolve -v "/ipfs/$a_hash" >actual_verbose &&
+	printf "/ipfs/$a_hash\n" >expected &&
+	test_cmp expected actual_verbose
+'
+
+test_expect_success "resolve --dht-timeout works" '
+	ipfs resolve --dht-timeout=5s "/ipns/$id_hash" >actual &&
+	printf "/ipfs/$a_hash\n" >expected &&
+	test_cmp expected actual
+'
+
+test_expect_success "resolve rejects invalid --dht-timeout" '
+	test_must_fail ipfs resolve --dht-timeout=-1s "/ipns/$id_hash" &&
+	test_must_fail ipfs resolve --dht-timeout=forever "/ipns/$id_hash"
+'
+
+test_expect_success "resolve takes several names" '
+	ipfs resolve "/ipfs/$a_hash/b" "/ipfs/$a_hash/b/c" >actual &&
+	printf "/ipfs/$b_hash\n/ipfs/$c_hash\n" >expected &&
+	test_cmp expected actual
+'
+
+test_expect_success "resolve reads names from stdin" '
+	printf "/ipfs/$a_hash/b/c\n/ipfs/$a_hash\n" | ipfs resolve >actual &&
+	printf "/ipfs/$c_hash\n/ipfs/$a_hash\n" >expected &&
+	test_cmp expected actual
+'
+
+test_expect_success "resolve stops at the first name that fails" '
+	printf "/ipfs/$a_hash/b\n/ipfs/$a_hash/nope\n/ipfs/$a_hash\n" >names &&
+	test_must_fail ipfs resolve <names >actual &&
+	printf "/ipfs/$b_hash\n" >expected &&
+	test_cmp expected actual
+'
+
+test_expect_success "resolve --continue-on-error reports errors inline" '
+	test_must_fail ipfs resolve --continue-on-error <names >actual 2>resolve_err &&
+	sed -n 1p actual >actual_first &&
+	printf "/ipfs/$b_hash\n" >expected &&
+	test_cmp expected actual_first &&
+	sed -n 2p actual | grep "^error resolving /ipfs/$a_hash/nope: " &&
+	sed -n 3p actual >actual_last &&
+	printf "/ipfs/$a_hash\n" >expected &&
+	test_cmp expected actual_last &&
+	grep "failed to resolve 1 of 3 names" resolve_err
+'
+
 # should work online
 test_launch_ipfs_daemon
 test_resolve_cmd_fail
@@ -19,6 +19,19 @@ test_key_cmd() {
 		edhash=$(ipfs key gen bazed --type=ed25519)
 	'
 
+	test_expect_success "rsa keys below 2048 bits are rejected" '
+		test_must_fail ipfs key gen smallrsa --type=rsa --size=1024 2> gen_err &&
+		grep "rsa keys must be at least 2048 bits" gen_err
+	'
+
+	test_expect_success "verbose key gen reports type and size" '
+		ipfs key gen -v defaultrsa --type=rsa > gen_out &&
+		grep "^defaultrsa rsa 2048 Qm" gen_out &&
+		ipfs key gen -v sizeded --type=ed25519 --size=4096 > gen_out &&
+		grep "^sizeded ed25519 256 " gen_out &&
+		ipfs key rm defaultrsa sizeded
+	'
+
 	test_expect_success "both keys show up in list output" '
 		echo bazed > list_exp &&
 		echo foobarsa >> list_exp &&
@@ -67,6 +80,106 @@ test_key_cmd() {
 		test_must_fail ipfs key rename -f fooed self 2>&1 | tee key_rename_out &&
 		grep -q "Error: cannot overwrite key with name" key_rename_out
 	'
+
+	test_expect_success "key rename output names both keys" '
+		ipfs key gen --type=ed25519 pubkey > pubkey_id &&
+		ipfs key rename pubkey renamed > rename_out &&
+		echo "Key $(cat pubkey_id) renamed from pubkey to renamed" > rename_exp &&
+		test_cmp rename_exp rename_out
+	'
+
+	test_expect_success "key rename refuses keys used for publishing without force" '
+		HASH=$(echo "published" | ipfs add -q) &&
+		ipfs name publish --key=renamed $HASH &&
+		test_must_fail ipfs key rename renamed other 2> rename_err &&
+		grep -q "use --force to rename it anyway" rename_err
+	'
+
+	test_expect_success "key rename -f renames keys used for publishing with a warning" '
+		ipfs key rename -f renamed other 2> rename_err &&
+		grep -q "warning: publish /ipns/$(cat pubkey_id) with --key=other" rename_err &&
+		ipfs key rm other
+	'
+
+	test_expect_success "key export writes an encrypted key file" '
+		echo secret > password &&
+		ipfs key export -o fooed.key fooed < password > export_out &&
+		echo "exported key fooed to fooed.key" > export_exp &&
+		test_cmp export_exp export_out &&
+		grep -q "BEGIN IPFS ENCRYPTED PRIVATE KEY" fooed.key &&
+		grep -q "KDF: PBKDF2-SHA256" fooed.key
+	'
+
+	test_expect_success "key export doesn't overwrite files" '
+		test_must_fail ipfs key export -o fooed.key fooed password
+	'
+
+	test_expect_success "key export requires a password" '
+		test_must_fail ipfs key export -o empty.key fooed < /dev/null &&
+		test_must_fail test -e empty.key
+	'
+
+	test_expect_success "key import loads the key under a new name" '
+		edhash=$(ipfs key list -l | grep "fooed" | cut -d" " -f1) &&
+		ipfs key import imported fooed.key < password > import_out &&
+		echo "$edhash" > import_exp &&
+		test_cmp import_exp import_out
+	'
+
+	test_expect_success "key import refuses to overwrite without force" '
+		test_must_fail ipfs key import imported fooed.key password 2> import_err &&
+		grep -q "already exists" import_err &&
+		ipfs key import -f imported fooed.key password
+	'
+
+	test_expect_success "key import fails with the wrong password" '
+		echo wrong > wrong_password &&
+		test_must_fail ipfs key import other fooed.key wrong_password 2> import_err &&
+		grep -q "incorrect password" import_err
+	'
+
+	test_expect_success "key import -f with the wrong password keeps the old key" '
+		test_must_fail ipfs key import -f imported fooed.key wrong_password &&
+		ipfs key list -l | grep -q "^$edhash imported$"
+	'
+
+	test_expect_success "key sign and verify work with an ed25519 key" '
+		echo "attest this" > sign_data &&
+		ipfs key sign --key=fooed < sign_data > ed_sig &&
+		ipfs key verify --key=fooed --signature="$(cat ed_sig)" < sign_data > verify_out &&
+		echo "signature is valid for key fooed" > verify_exp &&
+		test_cmp verify_exp verify_out
+	'
+
+	test_expect_success "key sign and verify work with an rsa key" '
+		ipfs key sign --key=foobarsa < sign_data > rsa_sig &&
+		ipfs key verify --key=foobarsa --signature="$(cat rsa_sig)" < sign_data
+	'
+
+	test_expect_success "key sign defaults to the self key" '
+		ipfs key sign < sign_data > self_sig &&
+		ipfs key verify --key=self --signature="$(cat self_sig)" < sign_data
+	'
+
+	test_expect_success "key verify fails for other data" '
+		echo "something else" |
+		test_must_fail ipfs key verify --key=fooed --signature="$(cat ed_sig)" 2> verify_err &&
+		grep -q "signature is not valid for key fooed" verify_err
+	'
+
+	test_expect_success "key verify fails for another key" '
+		test_must_fail ipfs key verify --key=imported --signature="$(cat rsa_sig)" < sign_data
+	'
+
+	test_expect_success "key verify --enc=json fails for invalid signatures" '
+		echo "something else" |
+		test_must_fail ipfs key verify --enc=json --key=fooed --signature="$(cat ed_sig)"
+	'
+
+	test_expect_success "key sign fails for unknown keys" '
+		test_must_fail ipfs key sign --key=nope < sign_data 2> sign_err &&
+		grep -q "no key named nope was found" sign_err
+	'
 }
 
 test_key_cmd
@@ -21,14 +21,25 @@ test_expect_success 'peer ids' '
 test_expect_success 'findpeer' '
   ipfsi 1 dht findpeer $PEERID_0 | sort >actual &&
   ipfsi 0 id -f "<addrs>" | cut -d / -f 1-5 | sort >expected &&
-  test_cmp actual expected
+  for addr in $(cat expected); do
+    grep "^$addr " actual || return 1
+  done
+'
+
+test_expect_success 'findpeer annotates every address with its sources' '
+  test_must_fail grep -v -E "^/[^ ]+ (dht|peerstore|connected)(,(dht|peerstore|connected))*$" actual
+'
+
+test_expect_success 'findpeer does not credit the DHT for connected peers' '
+  test_must_fail grep " dht" actual
 '
 
 # ipfs dht put <key> <value>
-test_expect_success 'put' '
-  ipfsi 1 dht put planet pluto | sort >putted &&
-  [ -s putted ] ||
-	test_fsh cat putted
+test_expect_success 'put rejects keys without a validated namespace' '
+  test_must_fail ipfsi 1 dht put planet pluto 2>put_err &&
+  grep "key is not in a namespace this node can validate, supported namespaces are: /ipns, /pk" put_err &&
+  test_must_fail ipfsi 1 dht put /planet/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ pluto 2>put_err &&
+  grep "key is not in a namespace this node can validate" put_err
 '
 
 test_expect_success "add a ref so we can find providers for it" '
@@ -43,14 +54,91 @@ test_expect_success 'findprovs' '
 	test_cmp provs expected
 '
 
+test_expect_success "add the ref on a second node" '
+	ipfsi 2 add -q afile > /dev/null
+'
+
+test_expect_success 'findprovs lists each provider once' '
+	ipfsi 4 dht findprovs $HASH > provs &&
+	sort provs | uniq -d > dups &&
+	test_must_be_empty dups &&
+	grep "$(iptb get id 3)" provs
+'
+
+test_expect_success 'findprovs --num-providers stops early' '
+	ipfsi 4 dht findprovs -n 1 $HASH > provs &&
+	test $(wc -l < provs) -eq 1
+'
+
+test_expect_success 'findprovs --verbose prints provider addresses' '
+	ipfsi 4 dht findprovs -v $HASH > provs_v &&
+	grep -A1 "provider: $(iptb get id 3)$" provs_v | tail -n1 > addr &&
+	grep "^	/" addr
+'
+
+# ipfs stats dht
+test_expect_success 'stats dht reports the routing table' '
+	ipfsi 0 stats dht > dht_stat &&
+	grep "^	ID: $PEERID_0$" dht_stat &&
+	grep -E "^	Kademlia ID: [0-9a-f]{64}$" dht_stat &&
+	grep -E "^	Peers: [1-9][0-9]*$" dht_stat &&
+	grep -E "^	Bucket 0: [0-9]+$" dht_stat
+'
+
+test_expect_success 'stats dht --buckets lists the peers' '
+	ipfsi 0 stats dht --buckets > dht_stat &&
+	grep "^		$(iptb get id 1)$" dht_stat
+'
+
 # ipfs dht get <key>
-test_expect_success 'get' '
-  ipfsi 0 dht put bar foo >actual &&
-  ipfsi 4 dht get -v bar >actual &&
-  egrep "error: record key does not have selectorfunc" actual > /dev//null ||
+test_expect_success 'get rejects keys without a validated namespace' '
+  test_must_fail ipfsi 4 dht get -v bar 2>get_err &&
+  grep "key is not in a namespace this node can validate" get_err
+'
+
+test_expect_success 'get returns a published ipns record' '
+  ipfsi 0 name publish /ipfs/$HASH &&
+  ipfsi 4 dht get -v /ipns/$PEERID_0 >actual &&
+  grep -a "([1-9][0-9]* peers returned values)" actual ||
+	test_fsh cat actual
+'
+
+# ipfs dht provide <key>
+test_expect_success 'provide' '
+  HASH=$(echo "provide me" | ipfsi 1 add -q) &&
+  ipfsi 1 dht provide $HASH >actual &&
+  grep -E "^provided 1 records to [0-9]+ peers$" actual ||
+	test_fsh cat actual
+'
+
+test_expect_success 'provide fails for missing blocks' '
+  test_must_fail ipfsi 2 dht provide $HASH
+'
+
+# ipfs bitswap reprovide
+test_expect_success 'bitswap reprovide announces all keys' '
+  ipfsi 1 bitswap reprovide >actual &&
+  grep -E "^Reprovided [1-9][0-9]* keys in " actual ||
 	test_fsh cat actual
 '
 
+# ipfs stats provide
+test_expect_success 'stats provide shows the last reprovide run' '
+  ipfsi 1 stats provide >actual &&
+  grep "Strategy: all" actual &&
+  grep "Interval: 12h0m0s" actual &&
+  grep "Running: false" actual &&
+  grep -E "Last run: .* \([1-9][0-9]* keys in " actual &&
+  grep "Next run: in " actual ||
+	test_fsh cat actual
+'
+
+test_expect_success 'stats provide --enc=json works' '
+  ipfsi 1 stats provide --enc=json >actual &&
+  grep "\"Strategy\":\"all\"" actual &&
+  grep "\"LastKeys\":[1-9]" actual
+'
+
 # ipfs dht query <peerID>
 ## We query 3 different keys, to statisically lower the chance that the queryer
 ## turns out to be the closest to what a key hashes to.
@@ -64,6 +152,12 @@ test_expect_failure 'query' '
 	test_fsh cat actual
 '
 
+test_expect_success "verbose query annotates closest peers" '
+  ipfsi 3 dht query -v banana >actual &&
+  grep -E "closest peer [^ ]+ distance [0-9a-f]+ step [0-9]+$" actual ||
+	test_fsh cat actual
+'
+
 test_expect_success 'stop iptb' '
   iptb stop
 '
@@ -20,7 +20,6 @@ test_expect_success 'peer ids' '
 # ipfs pubsub sub
 test_expect_success 'pubsub' '
 	echo "testOK" > expected &&
-	touch empty &&
 	mkfifo wait ||
 	test_fsh echo init fail
 
@@ -53,10 +52,42 @@ test_expect_success "publish something" '
 
 test_expect_success "wait until echo > wait executed" '
 	cat wait &&
-	test_cmp pubErr empty &&
+	echo "published 1 messages to testTopic" > pub_exp &&
+	test_cmp pub_exp pubErr &&
 	test_cmp expected actual
 '
 
+test_expect_success "publish from stdin with repeat" '
+	printf "\000binary\377" | ipfsi 1 pubsub pub --repeat=3 --interval=10ms testTopic > pub_out &&
+	echo "published 3 messages to testTopic" > pub_exp &&
+	test_cmp pub_exp pub_out
+'
+
+test_expect_success "publish rejects a repeat below 1" '
+	test_must_fail ipfsi 1 pubsub pub --repeat=0 testTopic "testOK"
+'
+
+test_expect_success "sub rejects invalid --from peer ids" '
+	test_must_fail ipfsi 0 pubsub sub --from=notapeer testTopic 2> sub_err &&
+	grep "invalid peer id" sub_err
+'
+
+test_expect_success "sub --json prints messages from the given peer" '
+	(
+		ipfsi 0 pubsub sub --json --from=$PEERID_2 jsonTopic | if read line; then
+			echo "$line" > json_actual &&
+			echo > wait
+		fi
+	) &
+	sleep 1 &&
+	ipfsi 1 pubsub pub jsonTopic "dropped" &&
+	ipfsi 2 pubsub pub jsonTopic "kept" &&
+	cat wait &&
+	grep "\"From\":\"$PEERID_2\"" json_actual &&
+	grep "\"Topics\":\[\"jsonTopic\"\]" json_actual &&
+	grep "\"Data\":\"a2VwdA==\"" json_actual
+'
+
 test_expect_success 'stop iptb' '
   iptb stop
 '
@@ -34,6 +34,11 @@ test_expect_success "'ipfs tar cat' succeeds" '
 	ipfs tar cat $TAR_HASH > output/out.tar
 '
 
+test_expect_success "'ipfs tar cat' reads the path from stdin" '
+	echo $TAR_HASH | ipfs tar cat > stdin_out.tar &&
+	test_cmp output/out.tar stdin_out.tar
+'
+
 test_expect_success "can extract tar" '
 	tar xf output/out.tar -C output/
 '
@@ -46,4 +51,29 @@ test_expect_success "files look right" '
 	[ -x foo/script ]
 '
 
+test_expect_success "'ipfs tar cat' can output a single entry" '
+	ipfs tar cat --entry=foo/bar/baz $TAR_HASH > baz_out &&
+	test_cmp foo/bar/baz baz_out
+'
+
+test_expect_success "'ipfs tar cat --entry' reads the path from stdin" '
+	echo $TAR_HASH | ipfs tar cat --entry=foo/bar/baz > baz_stdin_out &&
+	test_cmp foo/bar/baz baz_stdin_out
+'
+
+test_expect_success "'ipfs tar cat' fails on a missing entry" '
+	test_must_fail ipfs tar cat --entry=foo/nope $TAR_HASH 2> missing_err &&
+	grep "foo/nope: no such entry in tar archive" missing_err
+'
+
+test_expect_success "'ipfs tar cat' fails on a directory entry" '
+	test_must_fail ipfs tar cat --entry=foo/bar $TAR_HASH 2> dir_err &&
+	grep "foo/bar is a directory" dir_err
+'
+
+test_expect_success "'ipfs tar cat' fails on a symlink entry" '
+	test_must_fail ipfs tar cat --entry=foo/bar/link $TAR_HASH 2> link_err &&
+	grep "foo/bar/link is not a regular file" link_err
+'
+
 test_done
@@ -44,6 +44,60 @@ test_expect_success "'ipfs bitswap wantlist -p' output looks good" '
 	test_must_be_empty wantlist_p_out
 '
 
+test_expect_success "'ipfs bitswap stat --peers' is empty without partners" '
+	ipfs bitswap stat --peers >ledgers_out &&
+	test_must_be_empty ledgers_out
+'
+
+test_expect_success "'ipfs bitswap stat --peer' fails for unknown peer" '
+	test_must_fail ipfs bitswap stat --peer="$PEERID" 2>ledger_err &&
+	grep "no ledger for peer $PEERID" ledger_err
+'
+
+test_expect_success "'ipfs bitswap stat --peer' fails for invalid peer" '
+	test_must_fail ipfs bitswap stat --peer=notapeer
+'
+
+test_expect_success "'ipfs bitswap stat --peer --peers' fails" '
+	test_must_fail ipfs bitswap stat --peer="$PEERID" --peers 2>ledger_err &&
+	grep "mutually exclusive" ledger_err
+'
+
+test_expect_success "start fetching a missing block" '
+	MISSING=QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG &&
+	(ipfs block get --timeout=5s $MISSING >/dev/null 2>&1 &) &&
+	go-sleep 500ms
+'
+
+test_expect_success "'ipfs bitswap wantlist' shows the want" '
+	ipfs bitswap wantlist >wantlist_out &&
+	echo $MISSING >expected &&
+	test_cmp expected wantlist_out
+'
+
+test_expect_success "'ipfs bitswap wantlist --full' shows priority and age" '
+	ipfs bitswap wantlist --full >wantlist_full &&
+	test $(wc -l < wantlist_full) -eq 1 &&
+	read -r C PRIO AGE REST < wantlist_full &&
+	test "$C" = "$MISSING" &&
+	test "$PRIO" -gt 0 &&
+	test -n "$AGE" && test -z "$REST"
+'
+
+test_expect_success "'ipfs bitswap wantlist --full --sort=age' works" '
+	ipfs bitswap wantlist --full --sort=age >wantlist_age &&
+	grep "^$MISSING	" wantlist_age
+'
+
+test_expect_success "'ipfs bitswap wantlist --sort' rejects unknown orders" '
+	test_must_fail ipfs bitswap wantlist --sort=size 2>sort_err &&
+	grep "unknown sort order" sort_err
+'
+
+test_expect_success "wait for the fetch to time out" '
+	go-sleep 5s
+'
+
 test_expect_success "hash was removed from wantlist" '
 	ipfs bitswap wantlist > wantlist_out &&
 	test_must_be_empty wantlist_out
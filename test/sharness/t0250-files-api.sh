@@ -138,6 +138,33 @@ test_files_api() {
 		test_cmp expected actual
 	'
 
+	test_expect_success "stat --with-local reports the whole tree as local" '
+		ipfs files stat --with-local / >stat_local &&
+		head -n5 stat_local >actual &&
+		test_cmp stat actual &&
+		SIZE=$(ipfs files stat --size /) &&
+		grep "^Local: $SIZE/$SIZE bytes, 2/2 blocks$" stat_local
+	'
+
+	test_expect_success "stat --with-local counts repeated blocks once" '
+		DUPHASH=$(echo dup | ipfs add -q) &&
+		ipfs files mkdir /dupdir &&
+		ipfs files cp /ipfs/$DUPHASH /dupdir/a &&
+		ipfs files cp /ipfs/$DUPHASH /dupdir/b &&
+		ipfs files stat --with-local /dupdir >stat_dup &&
+		CUMULSIZE=$(ipfs files stat --size /dupdir) &&
+		ipfs files rm -r /dupdir &&
+		grep "^Local: [0-9]*/[0-9]* bytes, 2/2 blocks$" stat_dup &&
+		LOCAL=$(sed -n "s|^Local: \([0-9]*\)/.*|\1|p" stat_dup) &&
+		TOTAL=$(sed -n "s|^Local: [0-9]*/\([0-9]*\) .*|\1|p" stat_dup) &&
+		test "$LOCAL" -eq "$TOTAL" &&
+		test "$TOTAL" -lt "$CUMULSIZE"
+	'
+
+	test_expect_success "stat without --with-local has no locality" '
+		test_must_fail grep "^Local:" stat
+	'
+
 	test_expect_success "check root hash" '
 		ipfs files stat --hash / > roothash
 	'
@@ -298,8 +325,47 @@ test_files_api() {
 		test_expect_code 1 ipfs files read --offset 5 /cats/file1
 	'
 
+	test_expect_success "reading at the end of the file prints nothing" '
+		ipfs files read --offset 4 /cats/file1 > output &&
+		test_must_be_empty output
+	'
+
+	test_expect_success "offset past end of file error is clear" '
+		test_must_fail ipfs files read --offset 5 /cats/file1 2> read_err &&
+		grep "Offset was past end of file (5 > 4)" read_err
+	'
+
 	test_expect_success "cannot read negative count bytes" '
-		test_expect_code 1 ipfs read --count -1 /cats/file1
+		test_expect_code 1 ipfs files read --count -1 /cats/file1
+	'
+
+	test_expect_success "cannot read negative line count" '
+		test_expect_code 1 ipfs files read --line-count -1 /cats/file1
+	'
+
+	test_expect_success "read with line count works" '
+		printf "one\ntwo\nthree" | ipfs files write --create /lines &&
+		ipfs files read --line-count 2 /lines > output &&
+		printf "one\ntwo\n" > expected &&
+		test_cmp expected output
+	'
+
+	test_expect_success "line count includes an unterminated last line" '
+		ipfs files read --line-count 5 /lines > output &&
+		printf "one\ntwo\nthree" > expected &&
+		test_cmp expected output
+	'
+
+	test_expect_success "line count combines with offset and count" '
+		ipfs files read --offset 4 --line-count 1 /lines > output &&
+		printf "two\n" > expected &&
+		test_cmp expected output &&
+		ipfs files read --line-count 2 --count 6 /lines > output &&
+		printf "one\ntw" > expected &&
+		test_cmp expected output &&
+		ipfs files read --line-count 0 /lines > output &&
+		test_must_be_empty output &&
+		ipfs files rm /lines
 	'
 
 	test_expect_success "reading zero bytes prints nothing" '
@@ -377,6 +443,32 @@ test_files_api() {
 		ipfs files rm /fun
 	'
 
+	test_expect_success "write far past end works" '
+		echo blah | ipfs files write --create --offset 1073741824 /sparse
+	'
+
+	test_expect_success "sparse file has the right size" '
+		ipfs files stat --format="<size>" /sparse > sparse_size &&
+		echo 1073741829 > sparse_size_expected &&
+		test_cmp sparse_size_expected sparse_size
+	'
+
+	test_expect_success "sparse file gap is not stored" '
+		SPARSE_HASH=$(ipfs files stat --hash /sparse) &&
+		ipfs refs -r -u $SPARSE_HASH > sparse_refs &&
+		test $(wc -l < sparse_refs) -lt 10
+	'
+
+	test_expect_success "sparse file gap reads as zeros" '
+		ipfs files read --offset 1073741820 /sparse > sparse_tail &&
+		printf "\000\000\000\000blah\n" > sparse_tail_expected &&
+		test_cmp sparse_tail_expected sparse_tail
+	'
+
+	test_expect_success "cleanup sparse file" '
+		ipfs files rm /sparse
+	'
+
 	test_expect_success "cannot write to directory" '
 		ipfs files stat --hash /cats > dirhash &&
 		test_expect_code 1 ipfs files write /cats < output
@@ -416,7 +508,27 @@ test_files_api() {
 	'
 
 	test_expect_success "flush root succeeds" '
-		ipfs files flush /
+		ipfs files flush / > flush_out
+	'
+
+	test_expect_success "flush root prints the root hash" '
+		ipfs files stat --hash / > flush_exp &&
+		test_cmp flush_exp flush_out
+	'
+
+	test_expect_success "flush --all prints the root hash" '
+		ipfs files flush --all > flush_all_out &&
+		test_cmp flush_exp flush_all_out
+	'
+
+	test_expect_success "flush without a path prints the root hash" '
+		ipfs files flush > flush_nopath_out &&
+		test_cmp flush_exp flush_nopath_out
+	'
+
+	test_expect_success "flush --all with a path fails" '
+		test_must_fail ipfs files flush --all /cats 2> flush_err &&
+		grep "cannot give a path together with '"'"'--all'"'"'" flush_err
 	'
 
 	# test mv
@@ -429,6 +541,66 @@ test_files_api() {
 		verify_dir_contents /cats/this
 	'
 
+	test_expect_success "set up dirs to merge" '
+		ipfs files mkdir -p /mvsrc/sub &&
+		ipfs files mkdir -p /mvdst/sub &&
+		echo src-shared | ipfs files write --create /mvsrc/sub/shared &&
+		echo src-only | ipfs files write --create /mvsrc/sub/srconly &&
+		echo dst-shared | ipfs files write --create /mvdst/sub/shared &&
+		echo dst-only | ipfs files write --create /mvdst/dstonly
+	'
+
+	test_expect_success "mv onto an existing file fails without --force" '
+		test_must_fail ipfs files mv /mvsrc/sub/shared /mvdst/sub/shared 2> mv_err &&
+		grep "/mvdst/sub/shared already exists, use --force to overwrite it" mv_err
+	'
+
+	test_expect_success "merging dirs with a conflict fails" '
+		test_must_fail ipfs files mv /mvsrc /mvdst 2> mv_err &&
+		grep "/mvdst/sub/shared already exists" mv_err
+	'
+
+	test_expect_success "failed merge left both dirs alone" '
+		verify_dir_contents /mvsrc/sub shared srconly &&
+		verify_dir_contents /mvdst/sub shared &&
+		verify_dir_contents /mvdst dstonly sub
+	'
+
+	test_expect_success "merging dirs with --force succeeds" '
+		ipfs files mv --force /mvsrc /mvdst
+	'
+
+	test_expect_success "merge worked" '
+		test_must_fail ipfs files stat /mvsrc &&
+		verify_dir_contents /mvdst dstonly sub &&
+		verify_dir_contents /mvdst/sub shared srconly &&
+		echo src-shared > shared_exp &&
+		ipfs files read /mvdst/sub/shared > shared_out &&
+		test_cmp shared_exp shared_out
+	'
+
+	test_expect_success "mv of a file onto a dir moves it into the dir" '
+		ipfs files mv /mvdst/dstonly /mvdst/sub &&
+		verify_dir_contents /mvdst sub &&
+		verify_dir_contents /mvdst/sub dstonly shared srconly
+	'
+
+	test_expect_success "mv of a dir onto a file fails" '
+		echo file | ipfs files write --create /mvfile &&
+		test_must_fail ipfs files mv --force /mvdst /mvfile 2> mv_err &&
+		grep "cannot overwrite file /mvfile with a directory" mv_err
+	'
+
+	test_expect_success "mv of a dir into itself fails" '
+		test_must_fail ipfs files mv /mvdst /mvdst/sub/inner 2> mv_err &&
+		grep "cannot move /mvdst into itself" mv_err
+	'
+
+	test_expect_success "cleanup merged dirs" '
+		ipfs files rm -r /mvdst &&
+		ipfs files rm /mvfile
+	'
+
 	test_expect_success "cleanup, remove 'cats'" '
 		ipfs files rm -r /cats
 	'
@@ -452,8 +624,37 @@ test_files_api() {
 		test_cmp file_out file_exp
 	'
 
+	test_expect_success "atomic truncate and write over that file" '
+		echo "atomic fish" | ipfs files write --atomic --truncate /cats
+	'
+
+	test_expect_success "atomic output looks good" '
+		ipfs files read /cats > file_out &&
+		echo "atomic fish" > file_exp &&
+		test_cmp file_out file_exp
+	'
+
+	test_expect_success "atomic write with offset works" '
+		echo "tuna" | ipfs files write --atomic --offset 7 /cats &&
+		ipfs files read /cats > file_out &&
+		echo "atomic tuna" > file_exp &&
+		test_cmp file_out file_exp
+	'
+
+	test_expect_success "atomic write can create a file" '
+		echo "new" | ipfs files write --atomic --create /atomic_new &&
+		ipfs files read /atomic_new > file_out &&
+		echo "new" > file_exp &&
+		test_cmp file_out file_exp
+	'
+
+	test_expect_success "atomic write does not create without --create" '
+		test_must_fail ipfs files write --atomic /atomic_missing < file_exp
+	'
+
 	test_expect_success "cleanup" '
-		ipfs files rm /cats
+		ipfs files rm /cats &&
+		ipfs files rm /atomic_new
 	'
 
 	# test flush flags
@@ -517,14 +718,136 @@ test_files_api() {
 		ipfs files ls /adir | grep foobar
 	'
 
+	test_expect_success "copy a file into missing parents fails without -p" '
+		test_must_fail ipfs files cp /foobar /bdir/nested/foobar
+	'
+
+	test_expect_success "copy a file into missing parents with -p" '
+		ipfs files cp -p /foobar /bdir/nested/foobar
+	'
+
+	verify_dir_contents /bdir/nested foobar
+
+	test_expect_success "copy with -p into existing parents works" '
+		ipfs files cp -p /foobar /bdir/nested/foobar2
+	'
+
+	verify_dir_contents /bdir/nested foobar foobar2
+
+	test_expect_success "ls -R lists the whole subtree" '
+		ipfs files ls -R /bdir > ls_out &&
+		printf "nested\nnested/foobar\nnested/foobar2\n" > ls_exp &&
+		test_cmp ls_exp ls_out
+	'
+
+	test_expect_success "ls -R --size sums directory sizes" '
+		ipfs files ls -R --size /bdir > ls_out &&
+		printf "nested/foobar\t5\nnested/foobar2\t5\nnested\t10\n" > ls_exp &&
+		test_cmp ls_exp ls_out
+	'
+
+	test_expect_success "ls --size without -R fails" '
+		test_must_fail ipfs files ls --size /bdir
+	'
+
+	test_expect_success "copy with -p does not clobber existing file" '
+		test_must_fail ipfs files cp -p /adir/foobar /bdir/nested/foobar
+	'
+
+	test_expect_success "copy with -p under a file reports it" '
+		test_must_fail ipfs files cp -p /foobar /foobar/sub/file 2> cp_err &&
+		grep "/foobar is a file, not a directory" cp_err
+	'
+
 	test_expect_success "clean up" '
 		ipfs files rm -r /foobar &&
-		ipfs files rm -r /adir
+		ipfs files rm -r /adir &&
+		ipfs files rm -r /bdir
+	'
+}
+
+test_files_metadata() {
+	test_expect_success "set up a directory with a file" '
+		ipfs files mkdir /meta &&
+		echo "#!/bin/sh" | ipfs files write --create /meta/run &&
+		ipfs files stat --hash /meta/run >run_hash_before
+	'
+
+	test_expect_success "files chmod succeeds" '
+		ipfs files chmod /meta/run 755 &&
+		ipfs files chmod /meta 750
+	'
+
+	test_expect_success "files chmod changes the hash" '
+		ipfs files stat --hash /meta/run >run_hash_after &&
+		test_must_fail test_cmp run_hash_before run_hash_after
+	'
+
+	test_expect_success "files chmod rejects bad modes" '
+		test_must_fail ipfs files chmod /meta/run 9 &&
+		test_must_fail ipfs files chmod /meta/run 1777 &&
+		test_must_fail ipfs files chmod /meta/run rwx
+	'
+
+	test_expect_success "files chtime succeeds" '
+		ipfs files chtime /meta/run 1500000000 &&
+		ipfs files chtime /meta 2017-07-14T02:40:00Z
+	'
+
+	test_expect_success "files chtime rejects bad times" '
+		test_must_fail ipfs files chtime /meta/run yesterday
+	'
+
+	test_expect_success "metadata survives writing to the file" '
+		echo "exit 0" | ipfs files write --offset 10 /meta/run &&
+		ipfs files read /meta/run >run_content &&
+		printf "#!/bin/sh\nexit 0\n" >run_expected &&
+		test_cmp run_expected run_content
+	'
+
+	test_expect_success "ipfs get applies the stored metadata" '
+		ipfs get -o meta_out "$(ipfs files stat --hash /meta)" &&
+		echo "-rwxr-xr-x" >mode_expected &&
+		generic_stat meta_out/run >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		echo "drwxr-x---" >mode_expected &&
+		generic_stat meta_out >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		TZ=UTC touch -t 201707140240.00 mtime_ref &&
+		test ! meta_out/run -nt mtime_ref &&
+		test ! mtime_ref -nt meta_out/run &&
+		test ! meta_out -nt mtime_ref &&
+		test ! mtime_ref -nt meta_out
+	'
+
+	test_expect_success "ipfs get leaves metadata that is not stored to the umask" '
+		echo "plain" | ipfs files write --create /meta/plain &&
+		(umask 027 && ipfs get -o meta_out/plain "$(ipfs files stat --hash /meta/plain)") &&
+		echo "-rw-r-----" >mode_expected &&
+		generic_stat meta_out/plain >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		test meta_out/plain -nt mtime_ref
+	'
+
+	test_expect_success "ipfs get into an existing directory leaves it as it is" '
+		mkdir -m 0700 meta_into &&
+		ipfs get -o meta_into "$(ipfs files stat --hash /meta)" &&
+		echo "drwx------" >mode_expected &&
+		generic_stat meta_into >mode_actual &&
+		test_cmp mode_expected mode_actual &&
+		test meta_into -nt mtime_ref
+	'
+
+	test_expect_success "clean up" '
+		chmod -R u+w meta_out meta_into &&
+		rm -rf meta_out meta_into &&
+		ipfs files rm -r /meta
 	'
 }
 
 # test offline and online
 test_files_api
+test_files_metadata
 
 test_expect_success "clean up objects from previous test run" '
 	ipfs repo gc
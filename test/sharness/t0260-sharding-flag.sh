@@ -25,9 +25,42 @@ test_add_large_dir() {
 	'
 }
 
+test_add_deterministic() {
+	exphash="$1"
+	test_expect_success "ipfs add --deterministic on very large directory succeeds" '
+		ipfs add -r -q --deterministic testdata | tail -n1 > detdir_out &&
+		echo "$exphash" > detdir_exp &&
+		test_cmp detdir_exp detdir_out
+	'
+
+	test_expect_success "ipfs add --deterministic on small directory is not sharded" '
+		ipfs add -r -Q --deterministic smalldata > detsmall_out &&
+		echo "$SMALL" > detsmall_exp &&
+		test_cmp detsmall_exp detsmall_out
+	'
+}
+
+test_expect_success "set up small test data" '
+	mkdir smalldata &&
+	echo a > smalldata/a &&
+	echo b > smalldata/b
+'
+
 test_init_ipfs
 
+test_expect_success "add small directory" '
+	SMALL=$(ipfs add -r -Q smalldata)
+'
+
+test_expect_success "ipfs add --deterministic rejects layout options" '
+	test_must_fail ipfs add -r --deterministic --trickle smalldata 2> det_err &&
+	grep "cannot be used with .--deterministic" det_err &&
+	test_must_fail ipfs add -r --deterministic --cid-version=0 smalldata &&
+	test_must_fail ipfs add -r --deterministic --preserve-mode smalldata
+'
+
 UNSHARDED="QmavrTrQG4VhoJmantURAYuw3bowq3E2WcvP36NRQDAC1N"
+SHARDED="QmSCJD1KYLhVVHqBK3YyXuoEqHt7vggyJhzoFYbT8v1XYL"
 test_add_large_dir "$UNSHARDED"
 
 test_launch_ipfs_daemon
@@ -36,19 +69,28 @@ test_add_large_dir "$UNSHARDED"
 
 test_kill_ipfs_daemon
 
+# the deterministic layout shards directories above a fixed size, whatever
+# the sharding config says
+test_add_deterministic "$SHARDED"
+
 test_expect_success "enable sharding" '
 	ipfs config --json Experimental.ShardingEnabled true
 '
 
-SHARDED="QmSCJD1KYLhVVHqBK3YyXuoEqHt7vggyJhzoFYbT8v1XYL"
 test_add_large_dir "$SHARDED"
 
 test_launch_ipfs_daemon
 
 test_add_large_dir "$SHARDED"
 
+test_add_deterministic "$SHARDED"
+
 test_kill_ipfs_daemon
 
+test_expect_success "small directory is sharded without --deterministic" '
+	test "$(ipfs add -r -Q smalldata)" != "$SMALL"
+'
+
 test_expect_success "sharded and unsharded output look the same" '
 	ipfs ls "$SHARDED" | sort > sharded_out &&
 	ipfs ls "$UNSHARDED" | sort > unsharded_out &&
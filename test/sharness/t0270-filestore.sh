@@ -59,6 +59,18 @@ test_filestore_adds() {
 	'
 
 	assert_repo_size_greater_than 1000000
+
+	test_expect_success "nocopy add reports a summary" '
+		ipfs add --raw-leaves --nocopy -r somedir >/dev/null 2> nocopy_err &&
+		grep "nocopy: [0-9]* file(s) referenced in the filestore, 0 copied into the blockstore" nocopy_err &&
+		test_must_fail grep "warning" nocopy_err
+	'
+
+	test_expect_success "nocopy add of piped data warns" '
+		echo "piped nocopy data" | ipfs add --raw-leaves --nocopy 2> nocopy_err &&
+		grep "cannot be referenced in the filestore, its data was copied into the blockstore" nocopy_err &&
+		grep "nocopy: 0 file(s) referenced in the filestore, 1 copied into the blockstore" nocopy_err
+	'
 }
 
 init_ipfs_filestore() {
@@ -82,6 +82,23 @@ test_filestore_adds() {
 		test_cmp ls_expect_file_order ls_actual
 	'
 
+	test_expect_success "'ipfs filestore ls --prefix' filters by backing file" '
+		ipfs filestore ls --file-order --prefix=somedir/file3 > ls_prefix_actual &&
+		grep somedir/file3 ls_expect_file_order > ls_prefix_expect &&
+		test_cmp ls_prefix_expect ls_prefix_actual
+	'
+
+	test_expect_success "'ipfs filestore ls --prefix' accepts absolute paths" '
+		ipfs filestore ls --prefix="$(pwd)/somedir/file1" > ls_prefix_actual &&
+		grep somedir/file1 ls_expect_file_order > ls_prefix_expect &&
+		test_cmp ls_prefix_expect ls_prefix_actual
+	'
+
+	test_expect_success "'ipfs filestore ls --prefix' skips non-matching objects" '
+		ipfs filestore ls --prefix=otherdir/ $FILE1_HASH > ls_prefix_actual &&
+		test_must_be_empty ls_prefix_actual
+	'
+
 	test_expect_success "'ipfs filestore ls HASH' works" '
 		ipfs filestore ls $FILE1_HASH > ls_actual &&
 		grep -q somedir/file1 ls_actual
@@ -153,6 +170,55 @@ test_filestore_verify() {
         test_init_dataset
 }
 
+test_filestore_repair() {
+	test_filestore_state
+
+	test_expect_success "move a file to another directory" '
+		mkdir -p otherdir &&
+		mv somedir/file1 otherdir/file1
+	'
+
+	test_expect_success "'ipfs filestore verify --repair' requires --search-root" '
+		test_must_fail ipfs filestore verify --repair
+	'
+
+	test_expect_success "'ipfs filestore verify --repair' relocates the file" '
+		ipfs filestore verify --repair --search-root=otherdir > repair_actual &&
+		grep repaired repair_actual | grep -q otherdir/file1
+	'
+
+	test_expect_success "block can be read from the new location" '
+		ipfs cat $FILE1_HASH > file1.data &&
+		test_cmp otherdir/file1 file1.data
+	'
+
+	test_expect_success "remove a file" '
+		mv somedir/file2 file2.bk
+	'
+
+	test_expect_success "'ipfs filestore verify --repair' reports removed file as unrecoverable" '
+		test_must_fail ipfs filestore verify --repair --search-root=somedir > repair_actual &&
+		grep unrecoverable repair_actual | grep -q somedir/file2
+	'
+
+	test_expect_success "'ipfs filestore verify --repair --enc=json' fails for removed file" '
+		test_must_fail ipfs filestore verify --repair --search-root=somedir --enc=json > repair_actual &&
+		grep -q somedir/file2 repair_actual
+	'
+
+	test_expect_success "'ipfs filestore verify --repair' rejects search root next to the filestore root" '
+		test_must_fail ipfs filestore verify --repair --search-root="$(pwd)-other" 2> repair_err &&
+		grep -q "outside the filestore root" repair_err
+	'
+
+	test_expect_success "restore the original layout" '
+		mv file2.bk somedir/file2 &&
+		mv otherdir/file1 somedir/file1 &&
+		ipfs filestore verify --repair --search-root=somedir > repair_actual &&
+		grep repaired repair_actual | grep -q somedir/file1
+	'
+}
+
 test_filestore_dups() {
 	# make sure the filestore is in a clean state
 	test_filestore_state
@@ -175,6 +241,8 @@ test_filestore_adds
 
 test_filestore_verify
 
+test_filestore_repair
+
 test_filestore_dups
 
 #
@@ -191,6 +259,8 @@ test_filestore_adds
 
 test_filestore_verify
 
+test_filestore_repair
+
 test_filestore_dups
 
 test_kill_ipfs_daemon
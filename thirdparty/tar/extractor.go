@@ -10,6 +10,15 @@ import (
 	"strings"
 )
 
+// Names of the PAX records flagging that the mode, or the modification time,
+// of an entry was stored with the data rather than being a default. Only
+// flagged metadata is applied when extracting. They are written as extended
+// attributes, the only kind of PAX record the tar package lets us add.
+const (
+	PAXModeKey  = "ipfs.unixfs.mode"
+	PAXMtimeKey = "ipfs.unixfs.mtime"
+)
+
 type Extractor struct {
 	Path     string
 	Progress func(int64) int64
@@ -32,6 +41,8 @@ func (te *Extractor) Extract(reader io.Reader) error {
 	}
 
 	// files come recursively in order (i == 0 is root directory)
+	var dirs []*tar.Header
+	var rootDir *tar.Header
 	for i := 0; ; i++ {
 		header, err := tarReader.Next()
 		if err != nil && err != io.EOF {
@@ -46,6 +57,10 @@ func (te *Extractor) Extract(reader io.Reader) error {
 			if err := te.extractDir(header, i); err != nil {
 				return err
 			}
+			if i == 0 {
+				rootDir = header
+			}
+			dirs = append(dirs, header)
 		case tar.TypeReg:
 			if err := te.extractFile(header, tarReader, i, rootExists, rootIsDir); err != nil {
 				return err
@@ -58,6 +73,34 @@ func (te *Extractor) Extract(reader io.Reader) error {
 			return fmt.Errorf("unrecognized tar header type: %d", header.Typeflag)
 		}
 	}
+
+	// extracting into a directory changes its modification time and may
+	// need write permission on it, so the metadata of directories is
+	// applied last, innermost first. A root directory that existed before
+	// is left as it is.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i] == rootDir && rootExists {
+			continue
+		}
+		if err := setMetadata(te.outputPath(dirs[i].Name), dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMetadata applies the permission bits and modification time of h to the
+// file at path, for each of them that h flags as stored in the unixfs node.
+// The others are left to the umask and the OS.
+func setMetadata(path string, h *tar.Header) error {
+	if _, ok := h.Xattrs[PAXModeKey]; ok {
+		if err := os.Chmod(path, h.FileInfo().Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if _, ok := h.Xattrs[PAXMtimeKey]; ok {
+		return os.Chtimes(path, h.ModTime, h.ModTime)
+	}
 	return nil
 }
 
@@ -107,7 +150,10 @@ func (te *Extractor) extractFile(h *tar.Header, r *tar.Reader, depth int, rootEx
 	}
 	defer file.Close()
 
-	return copyWithProgress(file, r, te.Progress)
+	if err := copyWithProgress(file, r, te.Progress); err != nil {
+		return err
+	}
+	return setMetadata(path, h)
 }
 
 func copyWithProgress(to io.Writer, from io.Reader, cb func(int64) int64) error {
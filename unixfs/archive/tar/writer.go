@@ -9,6 +9,7 @@ import (
 	"time"
 
 	mdag "github.com/ipfs/go-ipfs/merkledag"
+	tarx "github.com/ipfs/go-ipfs/thirdparty/tar"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 	upb "github.com/ipfs/go-ipfs/unixfs/pb"
@@ -36,8 +37,8 @@ func NewWriter(ctx context.Context, dag mdag.DAGService, archive bool, compressi
 	}, nil
 }
 
-func (w *Writer) writeDir(nd *mdag.ProtoNode, fpath string) error {
-	if err := writeDirHeader(w.TarW, fpath); err != nil {
+func (w *Writer) writeDir(nd *mdag.ProtoNode, pb *upb.Data, fpath string) error {
+	if err := writeDirHeader(w.TarW, fpath, pb); err != nil {
 		return err
 	}
 
@@ -57,7 +58,7 @@ func (w *Writer) writeDir(nd *mdag.ProtoNode, fpath string) error {
 }
 
 func (w *Writer) writeFile(nd *mdag.ProtoNode, pb *upb.Data, fpath string) error {
-	if err := writeFileHeader(w.TarW, fpath, pb.GetFilesize()); err != nil {
+	if err := writeFileHeader(w.TarW, fpath, pb.GetFilesize(), pb); err != nil {
 		return err
 	}
 
@@ -81,7 +82,7 @@ func (w *Writer) WriteNode(nd node.Node, fpath string) error {
 		case upb.Data_Metadata:
 			fallthrough
 		case upb.Data_Directory:
-			return w.writeDir(nd, fpath)
+			return w.writeDir(nd, pb, fpath)
 		case upb.Data_Raw:
 			fallthrough
 		case upb.Data_File:
@@ -92,7 +93,7 @@ func (w *Writer) WriteNode(nd node.Node, fpath string) error {
 			return ft.ErrUnrecognizedType
 		}
 	case *mdag.RawNode:
-		if err := writeFileHeader(w.TarW, fpath, uint64(len(nd.RawData()))); err != nil {
+		if err := writeFileHeader(w.TarW, fpath, uint64(len(nd.RawData())), nil); err != nil {
 			return err
 		}
 
@@ -110,25 +111,51 @@ func (w *Writer) Close() error {
 	return w.TarW.Close()
 }
 
-func writeDirHeader(w *tar.Writer, fpath string) error {
-	return w.WriteHeader(&tar.Header{
+// setHeaderMetadata applies the mode and modification time stored in pb to
+// h, flagging each of them with a PAX record for the extractor. pb may be
+// nil.
+func setHeaderMetadata(h *tar.Header, pb *upb.Data) {
+	if pb == nil {
+		return
+	}
+	if m, ok := ft.Mode(pb); ok {
+		h.Mode = int64(m)
+		setPAXFlag(h, tarx.PAXModeKey)
+	}
+	if t, ok := ft.ModTime(pb); ok {
+		h.ModTime = t
+		setPAXFlag(h, tarx.PAXMtimeKey)
+	}
+}
+
+func setPAXFlag(h *tar.Header, key string) {
+	if h.Xattrs == nil {
+		h.Xattrs = make(map[string]string)
+	}
+	h.Xattrs[key] = "1"
+}
+
+func writeDirHeader(w *tar.Writer, fpath string, pb *upb.Data) error {
+	h := &tar.Header{
 		Name:     fpath,
 		Typeflag: tar.TypeDir,
 		Mode:     0777,
 		ModTime:  time.Now(),
-		// TODO: set mode, dates, etc. when added to unixFS
-	})
+	}
+	setHeaderMetadata(h, pb)
+	return w.WriteHeader(h)
 }
 
-func writeFileHeader(w *tar.Writer, fpath string, size uint64) error {
-	return w.WriteHeader(&tar.Header{
+func writeFileHeader(w *tar.Writer, fpath string, size uint64, pb *upb.Data) error {
+	h := &tar.Header{
 		Name:     fpath,
 		Size:     int64(size),
 		Typeflag: tar.TypeReg,
 		Mode:     0644,
 		ModTime:  time.Now(),
-		// TODO: set mode, dates, etc. when added to unixFS
-	})
+	}
+	setHeaderMetadata(h, pb)
+	return w.WriteHeader(h)
 }
 
 func writeSymlinkHeader(w *tar.Writer, target, fpath string) error {
@@ -5,6 +5,8 @@ package unixfs
 
 import (
 	"errors"
+	"os"
+	"time"
 
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	pb "github.com/ipfs/go-ipfs/unixfs/pb"
@@ -126,6 +128,55 @@ func DataSize(data []byte) (uint64, error) {
 	}
 }
 
+// Mode returns the unix permission bits stored in pbd, and whether pbd has
+// any.
+func Mode(pbd *pb.Data) (os.FileMode, bool) {
+	if pbd.Mode == nil {
+		return 0, false
+	}
+	return os.FileMode(pbd.GetMode()) & os.ModePerm, true
+}
+
+// ModTime returns the modification time stored in pbd, and whether pbd has
+// one.
+func ModTime(pbd *pb.Data) (time.Time, bool) {
+	mt := pbd.GetMtime()
+	if mt == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(mt.GetSeconds(), int64(mt.GetFractionalNanoseconds())), true
+}
+
+// SetMode returns a copy of the marshalled unixfs data with its mode set to
+// the permission bits of mode.
+func SetMode(data []byte, mode os.FileMode) ([]byte, error) {
+	pbd, err := FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	pbd.Mode = proto.Uint32(uint32(mode & os.ModePerm))
+	return proto.Marshal(pbd)
+}
+
+// SetModTime returns a copy of the marshalled unixfs data with its
+// modification time set to t.
+func SetModTime(data []byte, t time.Time) ([]byte, error) {
+	pbd, err := FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	pbd.Mtime = unixTime(t)
+	return proto.Marshal(pbd)
+}
+
+func unixTime(t time.Time) *pb.UnixTime {
+	ut := &pb.UnixTime{Seconds: proto.Int64(t.Unix())}
+	if ns := t.Nanosecond(); ns != 0 {
+		ut.FractionalNanoseconds = proto.Uint32(uint32(ns))
+	}
+	return ut
+}
+
 type FSNode struct {
 	Data []byte
 
@@ -137,6 +188,10 @@ type FSNode struct {
 
 	// node type of this node
 	Type pb.Data_DataType
+
+	// unix metadata, carried over unchanged when the node is rewritten
+	mode  *uint32
+	mtime *pb.UnixTime
 }
 
 func FSNodeFromBytes(b []byte) (*FSNode, error) {
@@ -151,6 +206,8 @@ func FSNodeFromBytes(b []byte) (*FSNode, error) {
 	n.blocksizes = pbn.Blocksizes
 	n.subtotal = pbn.GetFilesize() - uint64(len(n.Data))
 	n.Type = pbn.GetType()
+	n.mode = pbn.Mode
+	n.mtime = pbn.Mtime
 	return n, nil
 }
 
@@ -171,6 +228,8 @@ func (n *FSNode) GetBytes() ([]byte, error) {
 	pbn.Filesize = proto.Uint64(uint64(len(n.Data)) + n.subtotal)
 	pbn.Blocksizes = n.blocksizes
 	pbn.Data = n.Data
+	pbn.Mode = n.mode
+	pbn.Mtime = n.mtime
 	return proto.Marshal(pbn)
 }
 
@@ -2,7 +2,9 @@ package unixfs
 
 import (
 	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
 
@@ -159,3 +161,53 @@ func TestMetadata(t *testing.T) {
 	}
 
 }
+
+func TestModeAndModTime(t *testing.T) {
+	data := FilePBData([]byte("hello"), 5)
+
+	pbd, err := FromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Mode(pbd); ok {
+		t.Fatal("new file should not have a mode")
+	}
+	if _, ok := ModTime(pbd); ok {
+		t.Fatal("new file should not have an mtime")
+	}
+
+	data, err = SetMode(data, os.ModeDir|0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Unix(1500000000, 42)
+	data, err = SetModTime(data, mtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rewriting the node through an FSNode must keep the metadata
+	fsn, err := FSNodeFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsn.Data = []byte("hello world")
+	data, err = fsn.GetBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pbd, err = FromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode, ok := Mode(pbd); !ok || mode != 0755 {
+		t.Fatalf("expected mode 0755, got %o (set: %t)", mode, ok)
+	}
+	if mt, ok := ModTime(pbd); !ok || !mt.Equal(mtime) {
+		t.Fatalf("expected mtime %s, got %s (set: %t)", mtime, mt, ok)
+	}
+	if string(pbd.GetData()) != "hello world" {
+		t.Fatal("data was not rewritten")
+	}
+}
@@ -30,6 +30,8 @@ type Directory struct {
 	dirnode *mdag.ProtoNode
 
 	shard *hamt.HamtShard
+
+	shardThreshold int
 }
 
 // NewDirectory returns a Directory. It needs a DAGService to add the Children
@@ -93,10 +95,17 @@ func (d *Directory) SetPrefix(prefix *cid.Prefix) {
 	}
 }
 
+// SetShardThreshold makes an unsharded directory switch to HAMT sharding
+// once it holds more than n entries, regardless of UseHAMTSharding. A value
+// of 0 restores the default behaviour.
+func (d *Directory) SetShardThreshold(n int) {
+	d.shardThreshold = n
+}
+
 // AddChild adds a (name, key)-pair to the root node.
 func (d *Directory) AddChild(ctx context.Context, name string, nd node.Node) error {
 	if d.shard == nil {
-		if !UseHAMTSharding {
+		if !d.needsSharding(name) {
 			_ = d.dirnode.RemoveNodeLink(name)
 			return d.dirnode.AddNodeLinkClean(name, nd)
 		}
@@ -110,6 +119,21 @@ func (d *Directory) AddChild(ctx context.Context, name string, nd node.Node) err
 	return d.shard.Set(ctx, name, nd)
 }
 
+// needsSharding reports whether the unsharded directory has to be sharded
+// before the entry name is added.
+func (d *Directory) needsSharding(name string) bool {
+	if d.shardThreshold <= 0 {
+		return UseHAMTSharding
+	}
+
+	entries := len(d.dirnode.Links())
+	if _, err := d.dirnode.GetNodeLink(name); err == nil {
+		// the entry replaces an existing one
+		entries--
+	}
+	return entries+1 > d.shardThreshold
+}
+
 func (d *Directory) switchToSharding(ctx context.Context) error {
 	s, err := hamt.NewHamtShard(d.dserv, DefaultShardWidth)
 	if err != nil {
@@ -184,6 +208,21 @@ func (d *Directory) RemoveChild(ctx context.Context, name string) error {
 	return d.shard.Remove(ctx, name)
 }
 
+// ErrShardedData is returned by SetData for sharded directories, whose
+// unixfs data is managed by the shard.
+var ErrShardedData = fmt.Errorf("cannot replace the data of a sharded directory")
+
+// SetData replaces the unixfs data of the root node of this Directory, e.g.
+// to change its mode or modification time.
+func (d *Directory) SetData(data []byte) error {
+	if d.shard != nil {
+		return ErrShardedData
+	}
+
+	d.dirnode.SetData(data)
+	return nil
+}
+
 // GetNode returns the root of this Directory
 func (d *Directory) GetNode() (node.Node, error) {
 	if d.shard == nil {
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"testing"
 
+	mdag "github.com/ipfs/go-ipfs/merkledag"
 	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
 	ft "github.com/ipfs/go-ipfs/unixfs"
+	ftpb "github.com/ipfs/go-ipfs/unixfs/pb"
 )
 
 func TestEmptyNode(t *testing.T) {
@@ -156,3 +158,69 @@ func TestDirBuilder(t *testing.T) {
 		t.Fatal("wrong number of links", len(links), count)
 	}
 }
+
+func TestShardThreshold(t *testing.T) {
+	ds := mdtest.Mock()
+	ctx := context.Background()
+
+	child := ft.EmptyDirNode()
+	_, err := ds.Add(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirType := func(dir *Directory) ftpb.Data_DataType {
+		nd, err := dir.GetNode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pbd, err := ft.FromBytes(nd.(*mdag.ProtoNode).Data())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pbd.GetType()
+	}
+
+	dir := NewDirectory(ds)
+	dir.SetShardThreshold(10)
+	for i := 0; i < 10; i++ {
+		if err := dir.AddChild(ctx, fmt.Sprintf("entry %d", i), child); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// replacing an entry must not count as a new one
+	if err := dir.AddChild(ctx, "entry 0", child); err != nil {
+		t.Fatal(err)
+	}
+	if typ := dirType(dir); typ != ft.TDirectory {
+		t.Fatalf("directory at the threshold should not be sharded, got type %s", typ)
+	}
+
+	if err := dir.AddChild(ctx, "entry 10", child); err != nil {
+		t.Fatal(err)
+	}
+	if typ := dirType(dir); typ != ft.THAMTShard {
+		t.Fatalf("directory above the threshold should be sharded, got type %s", typ)
+	}
+
+	// the same entries added in the opposite order give the same node
+	rev := NewDirectory(ds)
+	rev.SetShardThreshold(10)
+	for i := 10; i >= 0; i-- {
+		if err := rev.AddChild(ctx, fmt.Sprintf("entry %d", i), child); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := rev.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Cid().Equals(b.Cid()) {
+		t.Fatalf("directories differ: %s != %s", a.Cid(), b.Cid())
+	}
+}
@@ -67,6 +67,11 @@ func (dm *DagModifier) WriteAt(b []byte, offset int64) (int, error) {
 			dm.wrBuf.Reset()
 		}
 	} else if uint64(offset) != dm.curWrOff {
+		err := dm.Sync()
+		if err != nil {
+			return 0, err
+		}
+
 		size, err := dm.Size()
 		if err != nil {
 			return 0, err
@@ -77,33 +82,18 @@ func (dm *DagModifier) WriteAt(b []byte, offset int64) (int, error) {
 				return 0, err
 			}
 		}
-
-		err = dm.Sync()
-		if err != nil {
-			return 0, err
-		}
 		dm.writeStart = uint64(offset)
 	}
 
 	return dm.Write(b)
 }
 
-// A reader that just returns zeros
-type zeroReader struct{}
-
-func (zr zeroReader) Read(b []byte) (int, error) {
-	for i := range b {
-		b[i] = 0
-	}
-	return len(b), nil
-}
-
-// expandSparse grows the file with zero blocks of 4096
-// A small blocksize is chosen to aid in deduplication
+// expandSparse grows the file by size zeros, appended in the trickle layout
+// like any other data. The zeros come from a zeroSplitter, so they are never
+// read from anywhere, and the repeated subtrees of the gap are only built
+// once.
 func (dm *DagModifier) expandSparse(size int64) error {
-	r := io.LimitReader(zeroReader{}, size)
-	spl := chunk.NewSizeSplitter(r, 4096)
-	nnode, err := dm.appendData(dm.curNode, spl)
+	nnode, err := dm.appendData(dm.curNode, newZeroSplitter(uint64(size)))
 	if err != nil {
 		return err
 	}
@@ -9,6 +9,7 @@ import (
 
 	h "github.com/ipfs/go-ipfs/importer/helpers"
 	trickle "github.com/ipfs/go-ipfs/importer/trickle"
+	mdag "github.com/ipfs/go-ipfs/merkledag"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 	testu "github.com/ipfs/go-ipfs/unixfs/test"
@@ -450,6 +451,169 @@ func TestSparseWrite(t *testing.T) {
 	}
 }
 
+func TestLargeSparseWrite(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	n := testu.GetEmptyNode(t, dserv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dagmod, err := NewDagModifier(ctx, n, dserv, testu.SizeSplitterGen(512))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset := int64(1 << 30)
+	if _, err := dagmod.WriteAt([]byte("hello"), offset); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := dagmod.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != offset+5 {
+		t.Fatalf("expected size %d, got %d", offset+5, size)
+	}
+
+	_, err = dagmod.Seek(offset-3, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(dagmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = testu.ArrComp(out, []byte("\x00\x00\x00hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	nd, err := dagmod.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trickle.VerifyTrickleDagStructure(nd, dserv, h.DefaultLinksPerBlock, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A gigabyte of zeros in 256KiB leaves comes down to a few distinct
+	// nodes per level of the tree.
+	seen := make(map[string]bool)
+	var walk func(nd *mdag.ProtoNode)
+	walk = func(nd *mdag.ProtoNode) {
+		seen[nd.Cid().KeyString()] = true
+		for _, lnk := range nd.Links() {
+			if seen[lnk.Cid.KeyString()] {
+				continue
+			}
+			child, err := lnk.GetNode(ctx, dserv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			walk(child.(*mdag.ProtoNode))
+		}
+	}
+	walk(nd)
+
+	if len(seen) > 10 {
+		t.Fatalf("expected the gap to be made of a few shared blocks, got %d blocks", len(seen))
+	}
+}
+
+func TestHugeSparseWrite(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	n := testu.GetEmptyNode(t, dserv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dagmod, err := NewDagModifier(ctx, n, dserv, testu.SizeSplitterGen(512))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A terabyte of zeros would take ages if every leaf was built.
+	offset := int64(1 << 40)
+	if _, err := dagmod.WriteAt([]byte("hello"), offset); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := dagmod.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != offset+5 {
+		t.Fatalf("expected size %d, got %d", offset+5, size)
+	}
+
+	_, err = dagmod.Seek(offset-3, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(dagmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = testu.ArrComp(out, []byte("\x00\x00\x00hello")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendAfterSparseWrite(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	n := testu.GetEmptyNode(t, dserv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dagmod, err := NewDagModifier(ctx, n, dserv, testu.SizeSplitterGen(512))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := make([]byte, 50000)
+	u.NewTimeSeededRand().Read(head)
+	if _, err := dagmod.Write(head); err != nil {
+		t.Fatal(err)
+	}
+
+	gap := int64(3 << 20)
+	if _, err := dagmod.WriteAt([]byte("middle"), int64(len(head))+gap); err != nil {
+		t.Fatal(err)
+	}
+
+	tail := make([]byte, 100000)
+	u.NewTimeSeededRand().Read(tail)
+	if _, err := dagmod.Write(tail); err != nil {
+		t.Fatal(err)
+	}
+
+	nd, err := dagmod.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = trickle.VerifyTrickleDagStructure(nd, dserv, h.DefaultLinksPerBlock, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := append(head, make([]byte, gap)...)
+	expected = append(expected, []byte("middle")...)
+	expected = append(expected, tail...)
+
+	_, err = dagmod.Seek(0, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(dagmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = testu.ArrComp(out, expected); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSeekPastEndWrite(t *testing.T) {
 	dserv := testu.GetDAGServ()
 	n := testu.GetEmptyNode(t, dserv)
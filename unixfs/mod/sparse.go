@@ -0,0 +1,61 @@
+package mod
+
+import (
+	"io"
+
+	chunk "github.com/ipfs/go-ipfs/importer/chunk"
+)
+
+// zeroLeafSize is the amount of zeros stored in a single leaf of a gap.
+var zeroLeafSize = uint64(chunk.DefaultBlockSize)
+
+// zeroSplitter is a chunk.Splitter that yields size zeros in chunks of
+// zeroLeafSize, without reading them from anywhere. It is a
+// helpers.UniformSplitter, so the importer builds the identical leaves and
+// full subtrees of a gap only once, and a gap of any size takes work and
+// storage in proportion to the depth of the tree rather than to its size.
+type zeroSplitter struct {
+	left uint64
+	buf  []byte
+}
+
+func newZeroSplitter(size uint64) *zeroSplitter {
+	bufsize := zeroLeafSize
+	if size < bufsize {
+		bufsize = size
+	}
+	return &zeroSplitter{
+		left: size,
+		buf:  make([]byte, bufsize),
+	}
+}
+
+func (z *zeroSplitter) NextBytes() ([]byte, error) {
+	if z.left == 0 {
+		return nil, io.EOF
+	}
+
+	n := uint64(len(z.buf))
+	if z.left < n {
+		n = z.left
+	}
+	z.left -= n
+	return z.buf[:n], nil
+}
+
+func (z *zeroSplitter) Reader() io.Reader {
+	return nil
+}
+
+// UniformChunks returns the number of full chunks left. Only the last chunk
+// can be shorter.
+func (z *zeroSplitter) UniformChunks() uint64 {
+	if len(z.buf) == 0 {
+		return 0
+	}
+	return z.left / uint64(len(z.buf))
+}
+
+func (z *zeroSplitter) SkipChunks(n uint64) {
+	z.left -= n * uint64(len(z.buf))
+}